@@ -1,6 +1,8 @@
 package configs
 
 import (
+	"errors"
+	"io/fs"
 	"log"
 	"os"
 
@@ -8,12 +10,24 @@ import (
 )
 
 type Config struct {
-	PORT         string
-	MODE         string
-	TOKEN        string
-	APP_URL      string
-	USER_SERVICE_URL string
-	LOAN_SERVICE_URL string
+	PORT                     string
+	MODE                     string
+	TOKEN                    string
+	IDENTITY_SECRET          string
+	CSRF_SECRET              string
+	TLS_ENABLED              string
+	TLS_CERT_FILE            string
+	TLS_KEY_FILE             string
+	TLS_CA_FILE              string
+	TLS_DEV_MODE             string
+	TLS_DEV_DIR              string
+	APP_URL                  string
+	USER_SERVICE_URL         string
+	LOAN_SERVICE_URL         string
+	NOTIFICATION_SERVICE_URL string
+	WALLET_SERVICE_URL       string
+	SESSION_CACHE_TTL        string
+	TRUSTED_PROXIES          string
 }
 
 var Env *Config
@@ -23,8 +37,9 @@ func init() {
 	Env = &Config{}
 
 	if os.Getenv("MODE") != "production" {
+		// Without a .env file, such as under go test, settings come from the environment alone
 		err := godotenv.Load()
-		if err != nil {
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
 			log.Fatalf("Error loading .env file: %v", err)
 		}
 
@@ -34,6 +49,18 @@ func init() {
 	Env.APP_URL = os.Getenv("APP_URL")
 	Env.MODE = os.Getenv("MODE")
 	Env.TOKEN = os.Getenv("TOKEN")
+	Env.IDENTITY_SECRET = os.Getenv("IDENTITY_SECRET")
+	Env.CSRF_SECRET = os.Getenv("CSRF_SECRET")
+	Env.TLS_ENABLED = os.Getenv("TLS_ENABLED")
+	Env.TLS_CERT_FILE = os.Getenv("TLS_CERT_FILE")
+	Env.TLS_KEY_FILE = os.Getenv("TLS_KEY_FILE")
+	Env.TLS_CA_FILE = os.Getenv("TLS_CA_FILE")
+	Env.TLS_DEV_MODE = os.Getenv("TLS_DEV_MODE")
+	Env.TLS_DEV_DIR = os.Getenv("TLS_DEV_DIR")
 	Env.USER_SERVICE_URL = os.Getenv("USER_SERVICE_URL")
 	Env.LOAN_SERVICE_URL = os.Getenv("LOAN_SERVICE_URL")
+	Env.NOTIFICATION_SERVICE_URL = os.Getenv("NOTIFICATION_SERVICE_URL")
+	Env.WALLET_SERVICE_URL = os.Getenv("WALLET_SERVICE_URL")
+	Env.SESSION_CACHE_TTL = os.Getenv("SESSION_CACHE_TTL")
+	Env.TRUSTED_PROXIES = os.Getenv("TRUSTED_PROXIES")
 }
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/dto"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/grpcclient"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/helpers"
+	userPb "github.com/manlikehenryy/loan-management-system-grpc/apiGateway/user"
+)
+
+func CreateApiKey(c *gin.Context) {
+	var createApiKeyDto dto.CreateApiKeyDto
+
+	if err := c.ShouldBindJSON(&createApiKeyDto); err != nil {
+		log.Println("Unable to parse body:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	createApiKeyReq := &userPb.CreateApiKeyRequest{
+		UserId:        createApiKeyDto.UserId,
+		Name:          createApiKeyDto.Name,
+		Routes:        createApiKeyDto.Routes,
+		ExpiresInDays: createApiKeyDto.ExpiresInDays,
+	}
+
+	createApiKeyResp, err_ := userServiceClient.CreateApiKey(ctx, createApiKeyReq)
+
+	if createApiKeyResp == nil {
+		log.Println("Error in CreateApiKey call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !createApiKeyResp.Status {
+		helpers.SendError(c, int(createApiKeyResp.StatusCode), createApiKeyResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusCreated, gin.H{
+		"message": createApiKeyResp.Message,
+		"data": gin.H{
+			"id":     createApiKeyResp.Id,
+			"apiKey": createApiKeyResp.ApiKey,
+		},
+	})
+}
+
+func ListApiKeys(c *gin.Context) {
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	listApiKeysResp, err_ := userServiceClient.ListApiKeys(ctx, &userPb.ListApiKeysRequest{UserId: c.Query("userId")})
+
+	if listApiKeysResp == nil {
+		log.Println("Error in ListApiKeys call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !listApiKeysResp.Status {
+		helpers.SendError(c, int(listApiKeysResp.StatusCode), listApiKeysResp.Message)
+		return
+	}
+
+	apiKeys := make([]gin.H, 0, len(listApiKeysResp.ApiKeys))
+	for _, apiKey := range listApiKeysResp.ApiKeys {
+		apiKeys = append(apiKeys, gin.H{
+			"id":         apiKey.Id,
+			"userId":     apiKey.UserId,
+			"name":       apiKey.Name,
+			"prefix":     apiKey.Prefix,
+			"routes":     apiKey.Routes,
+			"createdAt":  apiKey.CreatedAt,
+			"expiresAt":  apiKey.ExpiresAt,
+			"lastUsedAt": apiKey.LastUsedAt,
+			"lastUsedIp": apiKey.LastUsedIp,
+			"revoked":    apiKey.Revoked,
+		})
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": listApiKeysResp.Message,
+		"data":    apiKeys,
+	})
+}
+
+func RevokeApiKey(c *gin.Context) {
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	revokeApiKeyResp, err_ := userServiceClient.RevokeApiKey(ctx, &userPb.RevokeApiKeyRequest{Id: c.Param("id")})
+
+	if revokeApiKeyResp == nil {
+		log.Println("Error in RevokeApiKey call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !revokeApiKeyResp.Status {
+		helpers.SendError(c, int(revokeApiKeyResp.StatusCode), revokeApiKeyResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": revokeApiKeyResp.Message,
+	})
+}
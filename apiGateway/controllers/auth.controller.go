@@ -4,7 +4,7 @@ import (
 	"context"
 	"log"
 	"net/http"
-	"time"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
@@ -85,8 +85,10 @@ func Login(c *gin.Context) {
 
     // Create the request and send it
     loginReq := &userPb.LoginUserRequest{
-        Username: loginDto.Username,
-        Password: loginDto.Password,
+        Username:  loginDto.Username,
+        Password:  loginDto.Password,
+        UserAgent: c.Request.UserAgent(),
+        IpAddress: c.ClientIP(),
     }
 
     loginResp, err := userServiceClient.LoginUser(ctx, loginReq)
@@ -99,26 +101,237 @@ func Login(c *gin.Context) {
     }
 
     if !loginResp.Status {
+        if loginResp.RetryAfter > 0 {
+            c.Header("Retry-After", strconv.FormatInt(loginResp.RetryAfter, 10))
+        }
         helpers.SendError(c, int(loginResp.StatusCode), loginResp.Message)
         return
     }
 
-    maxAge := int(time.Hour * 24 / time.Second)
-    c.SetCookie("jwt", loginResp.Token, maxAge, "/", configs.Env.APP_URL, configs.Env.MODE == "production", true)
+    // No session exists yet; the client finishes logging in at /api/login/mfa
+    if loginResp.MfaRequired {
+        helpers.SendJSON(c, http.StatusAccepted, gin.H{
+            "message": loginResp.Message,
+            "data": gin.H{
+                "mfaRequired":  true,
+                "mfaChallenge": loginResp.MfaChallenge,
+            },
+        })
+        return
+    }
+
+    sendLoggedIn(c, loginResp, loginDto.ReturnTokens)
+}
+
+func LoginMfa(c *gin.Context) {
+    var mfaLoginDto dto.MfaLoginDto
+
+    if err := c.ShouldBindJSON(&mfaLoginDto); err != nil {
+        log.Println("Unable to parse body:", err)
+        helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+        return
+    }
+
+    userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+    if err != nil {
+        log.Println("Failed to connect to UserService:", err)
+        helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+        return
+    }
+    defer cleanup()
+
+    ctx := grpcclient.NewAuthContext(context.Background(), configs.Env.TOKEN)
+
+    loginResp, err := userServiceClient.VerifyMfaLogin(ctx, &userPb.VerifyMfaLoginRequest{
+        MfaChallenge: mfaLoginDto.MfaChallenge,
+        Code:         mfaLoginDto.Code,
+        UserAgent:    c.Request.UserAgent(),
+        IpAddress:    c.ClientIP(),
+    })
+
+    if loginResp == nil || err != nil {
+        log.Println("Error in VerifyMfaLogin call:", err)
+        helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+        return
+    }
+
+    if !loginResp.Status {
+        if loginResp.RetryAfter > 0 {
+            c.Header("Retry-After", strconv.FormatInt(loginResp.RetryAfter, 10))
+        }
+        helpers.SendError(c, int(loginResp.StatusCode), loginResp.Message)
+        return
+    }
+
+    sendLoggedIn(c, loginResp, mfaLoginDto.ReturnTokens)
+}
+
+// sendLoggedIn sets the auth cookies for a new session and returns its token lifetimes
+func sendLoggedIn(c *gin.Context, loginResp *userPb.LoginUserResponse, returnTokens bool) {
+    if err := setAuthCookies(c, loginResp.SessionId, loginResp.Token, loginResp.ExpiresIn, loginResp.RefreshToken, loginResp.RefreshExpiresIn); err != nil {
+        log.Println("Failed to issue CSRF token:", err)
+        helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+        return
+    }
+
+    data := gin.H{
+        "expiresIn":        loginResp.ExpiresIn,
+        "refreshExpiresIn": loginResp.RefreshExpiresIn,
+    }
+    if returnTokens {
+        data["token"] = loginResp.Token
+        data["refreshToken"] = loginResp.RefreshToken
+    }
 
     helpers.SendJSON(c, http.StatusOK, gin.H{
         "message": loginResp.Message,
+        "data":    data,
     })
 }
 
+func RefreshToken(c *gin.Context) {
+	var refreshTokenDto dto.RefreshTokenDto
 
-func Logout(c *gin.Context) {
+	// Browsers send the refresh cookie, which RefreshCookie has read; other clients may post the token instead
+	refreshToken := c.GetString("refreshToken")
+	if refreshToken == "" {
+		if err := c.ShouldBindJSON(&refreshTokenDto); err != nil || refreshTokenDto.RefreshToken == "" {
+			helpers.SendError(c, http.StatusUnauthorized, "Unauthorized: No refresh token provided")
+			return
+		}
+		refreshToken = refreshTokenDto.RefreshToken
+	}
+
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewAuthContext(context.Background(), configs.Env.TOKEN)
 
-	maxAge := -1 * int(time.Hour*24/time.Second)
+	refreshTokenReq := &userPb.RefreshTokenRequest{
+		RefreshToken: refreshToken,
+		UserAgent:    c.Request.UserAgent(),
+		IpAddress:    c.ClientIP(),
+	}
+
+	refreshTokenResp, err_ := userServiceClient.RefreshToken(ctx, refreshTokenReq)
+
+	if refreshTokenResp == nil {
+		log.Println("Error in RefreshToken call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !refreshTokenResp.Status {
+		clearAuthCookies(c)
+		helpers.SendError(c, int(refreshTokenResp.StatusCode), refreshTokenResp.Message)
+		return
+	}
+
+	if err := setAuthCookies(c, refreshTokenResp.SessionId, refreshTokenResp.Token, refreshTokenResp.ExpiresIn, refreshTokenResp.RefreshToken, refreshTokenResp.RefreshExpiresIn); err != nil {
+		log.Println("Failed to issue CSRF token:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+
+	data := gin.H{
+		"expiresIn":        refreshTokenResp.ExpiresIn,
+		"refreshExpiresIn": refreshTokenResp.RefreshExpiresIn,
+	}
+	// Clients that posted the token don't use cookies, so hand the new pair back directly
+	if refreshTokenDto.RefreshToken != "" {
+		data["token"] = refreshTokenResp.Token
+		data["refreshToken"] = refreshTokenResp.RefreshToken
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": refreshTokenResp.Message,
+		"data":    data,
+	})
+}
 
-	c.SetCookie("jwt", "", maxAge, "/", configs.Env.APP_URL, configs.Env.MODE == "production", true)
+func Logout(c *gin.Context) {
+	if refreshToken := c.GetString("refreshToken"); refreshToken != "" {
+		revokeRefreshToken(c, refreshToken)
+	}
+
+	clearAuthCookies(c)
 
 	helpers.SendJSON(c, http.StatusOK, gin.H{
 		"message": "Logged out successfully",
 	})
 }
+
+// revokeRefreshToken ends the session behind a refresh token
+func revokeRefreshToken(c *gin.Context, refreshToken string) {
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewAuthContext(context.Background(), configs.Env.TOKEN)
+
+	revokeSessionResp, err := userServiceClient.RevokeSession(ctx, &userPb.RevokeSessionRequest{RefreshToken: refreshToken})
+	if revokeSessionResp == nil || err != nil {
+		log.Println("Error in RevokeSession call:", err)
+	}
+}
+
+func setAuthCookies(c *gin.Context, sessionId string, token string, expiresIn int64, refreshToken string, refreshExpiresIn int64) error {
+	csrfToken, err := helpers.CSRFToken(sessionId)
+	if err != nil {
+		return err
+	}
+
+	secure := configs.Env.MODE == "production"
+
+	// Strict keeps browsers from attaching the cookies to cross-site requests at all
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie("jwt", token, int(expiresIn), "/", configs.Env.APP_URL, secure, true)
+	// The refresh token is only ever needed by the auth endpoints
+	c.SetCookie("refresh_token", refreshToken, int(refreshExpiresIn), "/api", configs.Env.APP_URL, secure, true)
+	// Readable by the frontend, which echoes it back in the X-CSRF-Token header
+	c.SetCookie("csrf_token", csrfToken, int(refreshExpiresIn), "/", configs.Env.APP_URL, secure, false)
+	return nil
+}
+
+func clearAuthCookies(c *gin.Context) {
+	secure := configs.Env.MODE == "production"
+
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie("jwt", "", -1, "/", configs.Env.APP_URL, secure, true)
+	c.SetCookie("refresh_token", "", -1, "/api", configs.Env.APP_URL, secure, true)
+	c.SetCookie("csrf_token", "", -1, "/", configs.Env.APP_URL, secure, false)
+}
+
+// CSRFToken returns the CSRF token for the current cookie session
+func CSRFToken(c *gin.Context) {
+	if c.GetString("authMethod") != "cookie" {
+		helpers.SendError(c, http.StatusBadRequest, "CSRF tokens are only needed for cookie sessions")
+		return
+	}
+
+	csrfToken, err := helpers.CSRFToken(c.GetString("sessionId"))
+	if err != nil {
+		log.Println("Failed to issue CSRF token:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": "CSRF token retrieved",
+		"data": gin.H{
+			"csrfToken": csrfToken,
+		},
+	})
+}
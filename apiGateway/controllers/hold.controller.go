@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/dto"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/grpcclient"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/helpers"
+	walletPb "github.com/manlikehenryy/loan-management-system-grpc/apiGateway/wallet"
+)
+
+func PlaceHold(c *gin.Context) {
+	var placeHoldDto dto.PlaceHoldDto
+
+	if err := c.ShouldBindJSON(&placeHoldDto); err != nil {
+		log.Println("Unable to parse body:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Initialize the gRPC client
+	walletServiceClient, cleanup, err := grpcclient.NewWalletServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to WalletService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	placeHoldResp, err_ := walletServiceClient.PlaceHold(ctx, &walletPb.PlaceHoldRequest{
+		UserId:           c.Param("id"),
+		Amount:           placeHoldDto.Amount,
+		Reason:           placeHoldDto.Reason,
+		Reference:        placeHoldDto.Reference,
+		ExpiresInSeconds: placeHoldDto.ExpiresInSeconds,
+	})
+
+	if placeHoldResp == nil {
+		log.Println("Error in PlaceHold call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !placeHoldResp.Status {
+		helpers.SendError(c, int(placeHoldResp.StatusCode), placeHoldResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, int(placeHoldResp.StatusCode), gin.H{
+		"message": placeHoldResp.Message,
+		"data":    holdJSON(placeHoldResp.Hold),
+	})
+}
+
+func CaptureHold(c *gin.Context) {
+	var captureHoldDto dto.CaptureHoldDto
+
+	// The body is optional: without an amount the whole hold is captured
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&captureHoldDto); err != nil {
+			log.Println("Unable to parse body:", err)
+			helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	// Initialize the gRPC client
+	walletServiceClient, cleanup, err := grpcclient.NewWalletServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to WalletService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	captureHoldResp, err_ := walletServiceClient.CaptureHold(ctx, &walletPb.CaptureHoldRequest{
+		HoldId: c.Param("id"),
+		Amount: captureHoldDto.Amount,
+	})
+
+	if captureHoldResp == nil {
+		log.Println("Error in CaptureHold call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !captureHoldResp.Status {
+		helpers.SendError(c, int(captureHoldResp.StatusCode), captureHoldResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": captureHoldResp.Message,
+		"data":    holdJSON(captureHoldResp.Hold),
+	})
+}
+
+func ReleaseHold(c *gin.Context) {
+	// Initialize the gRPC client
+	walletServiceClient, cleanup, err := grpcclient.NewWalletServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to WalletService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	releaseHoldResp, err_ := walletServiceClient.ReleaseHold(ctx, &walletPb.ReleaseHoldRequest{HoldId: c.Param("id")})
+
+	if releaseHoldResp == nil {
+		log.Println("Error in ReleaseHold call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !releaseHoldResp.Status {
+		helpers.SendError(c, int(releaseHoldResp.StatusCode), releaseHoldResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": releaseHoldResp.Message,
+		"data":    holdJSON(releaseHoldResp.Hold),
+	})
+}
+
+func DebitWallet(c *gin.Context) {
+	var debitWalletDto dto.DebitWalletDto
+
+	if err := c.ShouldBindJSON(&debitWalletDto); err != nil {
+		log.Println("Unable to parse body:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Initialize the gRPC client
+	walletServiceClient, cleanup, err := grpcclient.NewWalletServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to WalletService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	debitWalletResp, err_ := walletServiceClient.DebitWallet(ctx, &walletPb.DebitWalletRequest{
+		UserId:    c.Param("id"),
+		Amount:    debitWalletDto.Amount,
+		Reference: debitWalletDto.Reference,
+	})
+
+	if debitWalletResp == nil {
+		log.Println("Error in DebitWallet call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !debitWalletResp.Status {
+		helpers.SendError(c, int(debitWalletResp.StatusCode), debitWalletResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": debitWalletResp.Message,
+		"data": gin.H{
+			"availableBalance": debitWalletResp.AvailableBalance,
+		},
+	})
+}
+
+func holdJSON(hold *walletPb.Hold) gin.H {
+	return gin.H{
+		"id":             hold.GetId(),
+		"userId":         hold.GetUserId(),
+		"amount":         hold.GetAmount(),
+		"capturedAmount": hold.GetCapturedAmount(),
+		"reason":         hold.GetReason(),
+		"reference":      hold.GetReference(),
+		"status":         hold.GetStatus(),
+		"expiresAt":      hold.GetExpiresAt(),
+		"createdAt":      hold.GetCreatedAt(),
+	}
+}
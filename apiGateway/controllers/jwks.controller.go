@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/grpcclient"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/helpers"
+	userPb "github.com/manlikehenryy/loan-management-system-grpc/apiGateway/user"
+)
+
+// Jwks publishes the keys that verify access tokens (RFC 7517)
+func Jwks(c *gin.Context) {
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewAuthContext(context.Background(), configs.Env.TOKEN)
+
+	getJwksResp, err_ := userServiceClient.GetJwks(ctx, &userPb.GetJwksRequest{})
+
+	if getJwksResp == nil {
+		log.Println("Error in GetJwks call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !getJwksResp.Status {
+		helpers.SendError(c, int(getJwksResp.StatusCode), getJwksResp.Message)
+		return
+	}
+
+	keys := make([]gin.H, 0, len(getJwksResp.Keys))
+	for _, key := range getJwksResp.Keys {
+		jwk := gin.H{"kid": key.Kid, "kty": key.Kty, "alg": key.Alg, "use": key.Use}
+		if key.Kty == "OKP" {
+			jwk["crv"] = key.Crv
+			jwk["x"] = key.X
+		} else {
+			jwk["n"] = key.N
+			jwk["e"] = key.E
+		}
+		keys = append(keys, jwk)
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
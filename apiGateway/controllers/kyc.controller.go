@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/dto"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/grpcclient"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/helpers"
+	userPb "github.com/manlikehenryy/loan-management-system-grpc/apiGateway/user"
+	"google.golang.org/grpc"
+)
+
+// maxKycFileSize matches userService's upload limit
+const maxKycFileSize = 5 << 20
+
+func UploadKycDocument(c *gin.Context) {
+	// Leaves room for the multipart framing and the other form fields
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxKycFileSize+(1<<20))
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		log.Println("Unable to read upload:", err)
+		helpers.SendError(c, http.StatusBadRequest, "A file is required, up to 5 MB")
+		return
+	}
+	if fileHeader.Size > maxKycFileSize {
+		helpers.SendError(c, http.StatusRequestEntityTooLarge, "File must be at most 5 MB")
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		log.Println("Unable to open upload:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid file")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxKycFileSize+1))
+	if err != nil {
+		log.Println("Unable to read upload:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid file")
+		return
+	}
+
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	uploadReq := &userPb.UploadKycDocumentRequest{
+		Type:         c.PostForm("type"),
+		DocumentType: c.PostForm("documentType"),
+		FileName:     fileHeader.Filename,
+		Data:         data,
+	}
+
+	uploadResp, err_ := userServiceClient.UploadKycDocument(ctx, uploadReq)
+
+	if uploadResp == nil {
+		log.Println("Error in UploadKycDocument call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !uploadResp.Status {
+		helpers.SendError(c, int(uploadResp.StatusCode), uploadResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusCreated, gin.H{
+		"message": uploadResp.Message,
+		"data":    kycDocumentJSON(uploadResp.Document),
+	})
+}
+
+func ListKycDocuments(c *gin.Context) {
+	listKycDocuments(c, "")
+}
+
+func ListUserKycDocuments(c *gin.Context) {
+	listKycDocuments(c, c.Param("id"))
+}
+
+func listKycDocuments(c *gin.Context, userId string) {
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	listResp, err_ := userServiceClient.ListKycDocuments(ctx, &userPb.ListKycDocumentsRequest{UserId: userId})
+
+	if listResp == nil {
+		log.Println("Error in ListKycDocuments call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !listResp.Status {
+		helpers.SendError(c, int(listResp.StatusCode), listResp.Message)
+		return
+	}
+
+	documents := make([]gin.H, 0, len(listResp.Documents))
+	for _, document := range listResp.Documents {
+		documents = append(documents, kycDocumentJSON(document))
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": listResp.Message,
+		"data": gin.H{
+			"kycTier":   listResp.KycTier,
+			"documents": documents,
+		},
+	})
+}
+
+func ListKycReviewQueue(c *gin.Context) {
+	page, _ := strconv.Atoi(c.Query("page"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	queueResp, err_ := userServiceClient.ListKycReviewQueue(ctx, &userPb.ListKycReviewQueueRequest{Page: int32(page), Limit: int32(limit)})
+
+	if queueResp == nil {
+		log.Println("Error in ListKycReviewQueue call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !queueResp.Status {
+		helpers.SendError(c, int(queueResp.StatusCode), queueResp.Message)
+		return
+	}
+
+	documents := make([]gin.H, 0, len(queueResp.Documents))
+	for _, document := range queueResp.Documents {
+		documents = append(documents, kycDocumentJSON(document))
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": queueResp.Message,
+		"data": gin.H{
+			"documents": documents,
+			"total":     queueResp.Total,
+			"page":      page,
+			"limit":     limit,
+		},
+	})
+}
+
+func GetKycDocumentFile(c *gin.Context) {
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	// Files can be larger than the default 4 MB response limit
+	fileResp, err_ := userServiceClient.GetKycDocumentFile(ctx, &userPb.GetKycDocumentFileRequest{DocumentId: c.Param("id")}, grpc.MaxCallRecvMsgSize(maxKycFileSize+(1<<20)))
+
+	if fileResp == nil {
+		log.Println("Error in GetKycDocumentFile call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !fileResp.Status {
+		helpers.SendError(c, int(fileResp.StatusCode), fileResp.Message)
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileResp.FileName}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, fileResp.ContentType, fileResp.Data)
+}
+
+func ReviewKycDocument(c *gin.Context) {
+	var reviewDto dto.ReviewKycDocumentDto
+
+	if err := c.ShouldBindJSON(&reviewDto); err != nil {
+		log.Println("Unable to parse body:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	reviewReq := &userPb.ReviewKycDocumentRequest{
+		DocumentId: c.Param("id"),
+		Decision:   reviewDto.Decision,
+		Reason:     reviewDto.Reason,
+	}
+
+	reviewResp, err_ := userServiceClient.ReviewKycDocument(ctx, reviewReq)
+
+	if reviewResp == nil {
+		log.Println("Error in ReviewKycDocument call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !reviewResp.Status {
+		helpers.SendError(c, int(reviewResp.StatusCode), reviewResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": reviewResp.Message,
+		"data": gin.H{
+			"document": kycDocumentJSON(reviewResp.Document),
+			"kycTier":  reviewResp.KycTier,
+		},
+	})
+}
+
+func kycDocumentJSON(document *userPb.KycDocument) gin.H {
+	return gin.H{
+		"id":           document.GetId(),
+		"userId":       document.GetUserId(),
+		"type":         document.GetType(),
+		"documentType": document.GetDocumentType(),
+		"fileName":     document.GetFileName(),
+		"contentType":  document.GetContentType(),
+		"size":         document.GetSize(),
+		"status":       document.GetStatus(),
+		"reason":       document.GetReason(),
+		"reviewedBy":   document.GetReviewedBy(),
+		"reviewedAt":   document.GetReviewedAt(),
+		"createdAt":    document.GetCreatedAt(),
+	}
+}
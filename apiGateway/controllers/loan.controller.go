@@ -13,8 +13,6 @@ import (
 )
 
 func ApplyLoan(c *gin.Context) {
-	userId:= c.MustGet("userId").(string)
-	
 	var applyLoanDto dto.ApplyLoanDto
 
 	if err := c.ShouldBindJSON(&applyLoanDto); err != nil {
@@ -33,10 +31,9 @@ func ApplyLoan(c *gin.Context) {
     defer cleanup()
 
     // Set up the context with authorization metadata
-    ctx := grpcclient.NewAuthContext(c, configs.Env.TOKEN)
+    ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
 
 	applyLoanReq := &loanPb.ApplyLoanRequest{
-		UserId: userId,
 		Amount: applyLoanDto.Amount,
 		Duration: applyLoanDto.Duration,
 	}
@@ -63,8 +60,6 @@ func ApplyLoan(c *gin.Context) {
 }
 
 func ApproveLoan(c *gin.Context) {
-	userId:= c.MustGet("userId").(string)
-	
 	var approveLoanDto dto.ApproveLoanDto
 
 	if err := c.ShouldBindJSON(&approveLoanDto); err != nil {
@@ -83,10 +78,9 @@ func ApproveLoan(c *gin.Context) {
     defer cleanup()
 
     // Set up the context with authorization metadata
-    ctx := grpcclient.NewAuthContext(c, configs.Env.TOKEN)
+    ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
 
 	approveLoanReq := &loanPb.ApproveLoanRequest{
-		UserId: userId,
 		LoanId: approveLoanDto.LoanId,
 		ApprovedAmount: approveLoanDto.ApprovedAmount,
 		Tenure: approveLoanDto.Tenure,
@@ -114,8 +108,6 @@ func ApproveLoan(c *gin.Context) {
 }
 
 func RejectLoan(c *gin.Context) {
-	userId:= c.MustGet("userId").(string)
-	
 	var rejectLoanDto dto.RejectLoanDto
 
 	if err := c.ShouldBindJSON(&rejectLoanDto); err != nil {
@@ -134,10 +126,9 @@ func RejectLoan(c *gin.Context) {
     defer cleanup()
 
     // Set up the context with authorization metadata
-    ctx := grpcclient.NewAuthContext(c, configs.Env.TOKEN)
+    ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
 
 	rejectLoanReq := &loanPb.RejectLoanRequest{
-		UserId: userId,
 		LoanId: rejectLoanDto.LoanId,
 	}
 
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/dto"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/grpcclient"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/helpers"
+	userPb "github.com/manlikehenryy/loan-management-system-grpc/apiGateway/user"
+)
+
+func EnrollMfa(c *gin.Context) {
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	enrollMfaResp, err_ := userServiceClient.EnrollMfa(ctx, &userPb.EnrollMfaRequest{})
+
+	if enrollMfaResp == nil {
+		log.Println("Error in EnrollMfa call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !enrollMfaResp.Status {
+		helpers.SendError(c, int(enrollMfaResp.StatusCode), enrollMfaResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": enrollMfaResp.Message,
+		"data": gin.H{
+			"secret":     enrollMfaResp.Secret,
+			"otpauthUri": enrollMfaResp.OtpauthUri,
+		},
+	})
+}
+
+func ConfirmMfa(c *gin.Context) {
+	var mfaCodeDto dto.MfaCodeDto
+
+	if err := c.ShouldBindJSON(&mfaCodeDto); err != nil {
+		log.Println("Unable to parse body:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	confirmMfaResp, err_ := userServiceClient.ConfirmMfa(ctx, &userPb.ConfirmMfaRequest{Code: mfaCodeDto.Code})
+
+	if confirmMfaResp == nil {
+		log.Println("Error in ConfirmMfa call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !confirmMfaResp.Status {
+		helpers.SendError(c, int(confirmMfaResp.StatusCode), confirmMfaResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": confirmMfaResp.Message,
+		"data": gin.H{
+			"recoveryCodes": confirmMfaResp.RecoveryCodes,
+		},
+	})
+}
+
+func DisableMfa(c *gin.Context) {
+	var mfaCodeDto dto.MfaCodeDto
+
+	if err := c.ShouldBindJSON(&mfaCodeDto); err != nil {
+		log.Println("Unable to parse body:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	disableMfaResp, err_ := userServiceClient.DisableMfa(ctx, &userPb.DisableMfaRequest{Code: mfaCodeDto.Code})
+
+	if disableMfaResp == nil {
+		log.Println("Error in DisableMfa call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !disableMfaResp.Status {
+		helpers.SendError(c, int(disableMfaResp.StatusCode), disableMfaResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": disableMfaResp.Message,
+	})
+}
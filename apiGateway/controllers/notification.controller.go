@@ -0,0 +1,319 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/dto"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/grpcclient"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/helpers"
+	notificationPb "github.com/manlikehenryy/loan-management-system-grpc/apiGateway/notification"
+)
+
+const (
+	// notificationStreamMaxAge ends each stream so the client reconnects and is authenticated
+	// again. Browsers' EventSource reconnects by itself and resumes from the last event ID.
+	notificationStreamMaxAge = 15 * time.Minute
+	notificationStreamPing   = 25 * time.Second
+	notificationStreamRetry  = 5 * time.Second
+)
+
+func GetNotificationPreferences(c *gin.Context) {
+	// Initialize the gRPC client
+	notificationServiceClient, cleanup, err := grpcclient.NewNotificationServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to NotificationService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	getPreferencesResp, err_ := notificationServiceClient.GetPreferences(ctx, &notificationPb.GetPreferencesRequest{})
+
+	if getPreferencesResp == nil {
+		log.Println("Error in GetPreferences call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !getPreferencesResp.Status {
+		helpers.SendError(c, int(getPreferencesResp.StatusCode), getPreferencesResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": getPreferencesResp.Message,
+		"data":    preferencesJSON(getPreferencesResp.Preferences),
+	})
+}
+
+func UpdateNotificationPreferences(c *gin.Context) {
+	var preferencesDto dto.NotificationPreferencesDto
+
+	if err := c.ShouldBindJSON(&preferencesDto); err != nil {
+		log.Println("Unable to parse body:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if preferencesDto.Email == nil || preferencesDto.Sms == nil || preferencesDto.InApp == nil {
+		helpers.SendError(c, http.StatusBadRequest, "email, sms and inApp are required")
+		return
+	}
+
+	// Initialize the gRPC client
+	notificationServiceClient, cleanup, err := grpcclient.NewNotificationServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to NotificationService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	updatePreferencesReq := &notificationPb.UpdatePreferencesRequest{
+		Preferences: &notificationPb.ChannelPreferences{
+			Email: *preferencesDto.Email,
+			Sms:   *preferencesDto.Sms,
+			InApp: *preferencesDto.InApp,
+		},
+	}
+
+	updatePreferencesResp, err_ := notificationServiceClient.UpdatePreferences(ctx, updatePreferencesReq)
+
+	if updatePreferencesResp == nil {
+		log.Println("Error in UpdatePreferences call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !updatePreferencesResp.Status {
+		helpers.SendError(c, int(updatePreferencesResp.StatusCode), updatePreferencesResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": updatePreferencesResp.Message,
+		"data":    preferencesJSON(updatePreferencesResp.Preferences),
+	})
+}
+
+func ListNotifications(c *gin.Context) {
+	page, _ := strconv.Atoi(c.Query("page"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	unreadOnly := c.Query("unread") == "true"
+
+	// Initialize the gRPC client
+	notificationServiceClient, cleanup, err := grpcclient.NewNotificationServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to NotificationService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	listInboxReq := &notificationPb.ListInboxRequest{Page: int32(page), Limit: int32(limit), UnreadOnly: unreadOnly}
+
+	listInboxResp, err_ := notificationServiceClient.ListInbox(ctx, listInboxReq)
+
+	if listInboxResp == nil {
+		log.Println("Error in ListInbox call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !listInboxResp.Status {
+		helpers.SendError(c, int(listInboxResp.StatusCode), listInboxResp.Message)
+		return
+	}
+
+	notifications := make([]gin.H, 0, len(listInboxResp.Notifications))
+	for _, notification := range listInboxResp.Notifications {
+		notifications = append(notifications, notificationJSON(notification))
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": listInboxResp.Message,
+		"data": gin.H{
+			"notifications": notifications,
+			"total":         listInboxResp.Total,
+			"unreadCount":   listInboxResp.UnreadCount,
+			"page":          page,
+			"limit":         limit,
+		},
+	})
+}
+
+func MarkNotificationsRead(c *gin.Context) {
+	var markReadDto dto.MarkNotificationsReadDto
+
+	if err := c.ShouldBindJSON(&markReadDto); err != nil {
+		log.Println("Unable to parse body:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if !markReadDto.All && len(markReadDto.Ids) == 0 {
+		helpers.SendError(c, http.StatusBadRequest, "ids or all is required")
+		return
+	}
+
+	// Initialize the gRPC client
+	notificationServiceClient, cleanup, err := grpcclient.NewNotificationServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to NotificationService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	markInboxReadResp, err_ := notificationServiceClient.MarkInboxRead(ctx, &notificationPb.MarkInboxReadRequest{Ids: markReadDto.Ids, All: markReadDto.All})
+
+	if markInboxReadResp == nil {
+		log.Println("Error in MarkInboxRead call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !markInboxReadResp.Status {
+		helpers.SendError(c, int(markInboxReadResp.StatusCode), markInboxReadResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": markInboxReadResp.Message,
+		"data": gin.H{
+			"updated":     markInboxReadResp.Updated,
+			"unreadCount": markInboxReadResp.UnreadCount,
+		},
+	})
+}
+
+// StreamNotifications pushes the user's new notifications as server-sent events. Each gateway
+// connection has its own stream from notificationService, so it doesn't matter which gateway
+// instance a client is connected to.
+func StreamNotifications(c *gin.Context) {
+	notificationServiceClient, err := grpcclient.SharedNotificationServiceClient()
+	if err != nil {
+		log.Println("Failed to connect to NotificationService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+
+	// EventSource sends the last ID it saw when it reconnects; other clients can pass it as a query parameter
+	lastEventId := c.GetHeader("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = c.Query("lastEventId")
+	}
+
+	streamCtx, cancel := context.WithTimeout(c.Request.Context(), notificationStreamMaxAge)
+	defer cancel()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(streamCtx, configs.Env.TOKEN, c.GetString("identity"))
+
+	stream, err_ := notificationServiceClient.StreamInbox(ctx, &notificationPb.StreamInboxRequest{After: lastEventId})
+	if err_ != nil {
+		log.Println("Error in StreamInbox call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	notifications := make(chan *notificationPb.InboxNotification)
+	streamErr := make(chan error, 1)
+	go func() {
+		for {
+			notification, err := stream.Recv()
+			if err != nil {
+				streamErr <- err
+				return
+			}
+			select {
+			case notifications <- notification:
+			case <-streamCtx.Done():
+				return
+			}
+		}
+	}()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Stop proxies from buffering events
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", notificationStreamRetry.Milliseconds())
+	c.Writer.Flush()
+
+	ping := time.NewTicker(notificationStreamPing)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-streamCtx.Done():
+			return
+		case err := <-streamErr:
+			if err != io.EOF && streamCtx.Err() == nil {
+				log.Println("Notification stream ended:", err)
+				fmt.Fprint(c.Writer, "event: error\ndata: {\"message\":\"Notification stream interrupted\"}\n\n")
+				c.Writer.Flush()
+			}
+			return
+		case <-ping.C:
+			// A comment keeps idle connections from being closed by proxies
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+		case notification := <-notifications:
+			data, err := json.Marshal(notificationJSON(notification))
+			if err != nil {
+				log.Println("Failed to encode notification:", err)
+				continue
+			}
+			fmt.Fprintf(c.Writer, "id: %s\nevent: notification\ndata: %s\n\n", notification.Id, data)
+			c.Writer.Flush()
+		}
+	}
+}
+
+func notificationJSON(notification *notificationPb.InboxNotification) gin.H {
+	data := notification.Data
+	if data == nil {
+		data = map[string]string{}
+	}
+	return gin.H{
+		"id":        notification.Id,
+		"eventType": notification.EventType,
+		"title":     notification.Title,
+		"body":      notification.Body,
+		"data":      data,
+		"read":      notification.Read,
+		"createdAt": notification.CreatedAt,
+	}
+}
+
+func preferencesJSON(preferences *notificationPb.ChannelPreferences) gin.H {
+	return gin.H{
+		"email": preferences.GetEmail(),
+		"sms":   preferences.GetSms(),
+		"inApp": preferences.GetInApp(),
+	}
+}
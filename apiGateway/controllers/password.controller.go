@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/dto"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/grpcclient"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/helpers"
+	userPb "github.com/manlikehenryy/loan-management-system-grpc/apiGateway/user"
+)
+
+func ChangePassword(c *gin.Context) {
+	var changePasswordDto dto.ChangePasswordDto
+
+	if err := c.ShouldBindJSON(&changePasswordDto); err != nil {
+		log.Println("Unable to parse body:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	changePasswordReq := &userPb.ChangePasswordRequest{
+		CurrentPassword: changePasswordDto.CurrentPassword,
+		NewPassword:     changePasswordDto.NewPassword,
+		SessionId:       c.GetString("sessionId"),
+	}
+
+	changePasswordResp, err_ := userServiceClient.ChangePassword(ctx, changePasswordReq)
+
+	if changePasswordResp == nil {
+		log.Println("Error in ChangePassword call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !changePasswordResp.Status {
+		helpers.SendError(c, int(changePasswordResp.StatusCode), changePasswordResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": changePasswordResp.Message,
+	})
+}
+
+func RequestPasswordReset(c *gin.Context) {
+	var requestPasswordResetDto dto.RequestPasswordResetDto
+
+	if err := c.ShouldBindJSON(&requestPasswordResetDto); err != nil {
+		log.Println("Unable to parse body:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewAuthContext(context.Background(), configs.Env.TOKEN)
+
+	requestPasswordResetResp, err_ := userServiceClient.RequestPasswordReset(ctx, &userPb.RequestPasswordResetRequest{
+		Username: requestPasswordResetDto.Username,
+	})
+
+	if requestPasswordResetResp == nil {
+		log.Println("Error in RequestPasswordReset call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !requestPasswordResetResp.Status {
+		helpers.SendError(c, int(requestPasswordResetResp.StatusCode), requestPasswordResetResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": requestPasswordResetResp.Message,
+	})
+}
+
+func ConfirmPasswordReset(c *gin.Context) {
+	var confirmPasswordResetDto dto.ConfirmPasswordResetDto
+
+	if err := c.ShouldBindJSON(&confirmPasswordResetDto); err != nil {
+		log.Println("Unable to parse body:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewAuthContext(context.Background(), configs.Env.TOKEN)
+
+	confirmPasswordResetResp, err_ := userServiceClient.ConfirmPasswordReset(ctx, &userPb.ConfirmPasswordResetRequest{
+		Token:       confirmPasswordResetDto.Token,
+		NewPassword: confirmPasswordResetDto.NewPassword,
+	})
+
+	if confirmPasswordResetResp == nil {
+		log.Println("Error in ConfirmPasswordReset call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !confirmPasswordResetResp.Status {
+		helpers.SendError(c, int(confirmPasswordResetResp.StatusCode), confirmPasswordResetResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": confirmPasswordResetResp.Message,
+	})
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/grpcclient"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/helpers"
+	walletPb "github.com/manlikehenryy/loan-management-system-grpc/apiGateway/wallet"
+)
+
+const maxWebhookSize = 64 << 10
+
+// PaymentWebhook passes a payment provider's webhook to walletService as it was received. The
+// provider signs the raw body, so walletService verifies it; the gateway doesn't parse it.
+func PaymentWebhook(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookSize)
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			helpers.SendError(c, http.StatusRequestEntityTooLarge, "Webhook must be at most 64 KB")
+			return
+		}
+		log.Println("Unable to read webhook:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Credentials never belong in a webhook, so they aren't passed on
+	headers := map[string]string{}
+	for name, values := range c.Request.Header {
+		name = strings.ToLower(name)
+		if name == "cookie" || name == "authorization" {
+			continue
+		}
+		headers[name] = strings.Join(values, ",")
+	}
+
+	// Initialize the gRPC client
+	walletServiceClient, cleanup, err := grpcclient.NewWalletServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to WalletService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Webhooks come from the provider, not a user, so there's no identity to pass on
+	ctx := grpcclient.NewAuthContext(c, configs.Env.TOKEN)
+
+	webhookResp, err_ := walletServiceClient.HandlePaymentWebhook(ctx, &walletPb.HandlePaymentWebhookRequest{
+		Provider: c.Param("provider"),
+		Payload:  payload,
+		Headers:  headers,
+	})
+
+	if webhookResp == nil {
+		log.Println("Error in HandlePaymentWebhook call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !webhookResp.Status {
+		helpers.SendError(c, int(webhookResp.StatusCode), webhookResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": webhookResp.Message,
+	})
+}
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/dto"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/grpcclient"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/helpers"
+	userPb "github.com/manlikehenryy/loan-management-system-grpc/apiGateway/user"
+)
+
+func GetProfile(c *gin.Context) {
+	getProfile(c, "")
+}
+
+func GetUserProfile(c *gin.Context) {
+	getProfile(c, c.Param("id"))
+}
+
+func getProfile(c *gin.Context, userId string) {
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	getProfileResp, err_ := userServiceClient.GetProfile(ctx, &userPb.GetProfileRequest{UserId: userId})
+
+	if getProfileResp == nil {
+		log.Println("Error in GetProfile call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !getProfileResp.Status {
+		helpers.SendError(c, int(getProfileResp.StatusCode), getProfileResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": getProfileResp.Message,
+		"data":    profileJSON(getProfileResp.Profile),
+	})
+}
+
+func UpdateProfile(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		log.Println("Unable to read body:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// The keys present in the body say which fields to change, so a field can be cleared with ""
+	var present map[string]json.RawMessage
+	var updateProfileDto dto.UpdateProfileDto
+	if err := json.Unmarshal(body, &present); err != nil {
+		log.Println("Unable to parse body:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := json.Unmarshal(body, &updateProfileDto); err != nil {
+		log.Println("Unable to parse body:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	fields := make([]string, 0, len(present))
+	for field := range present {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	profile := &userPb.UserProfile{
+		Email:            updateProfileDto.Email,
+		Phone:            updateProfileDto.Phone,
+		DateOfBirth:      updateProfileDto.DateOfBirth,
+		NationalIdNumber: updateProfileDto.NationalIdNumber,
+	}
+	if address := updateProfileDto.Address; address != nil {
+		profile.Address = &userPb.Address{
+			Line1:      address.Line1,
+			Line2:      address.Line2,
+			City:       address.City,
+			State:      address.State,
+			PostalCode: address.PostalCode,
+			Country:    address.Country,
+		}
+	}
+	if employment := updateProfileDto.Employment; employment != nil {
+		profile.Employment = &userPb.Employment{
+			Status:          employment.Status,
+			EmployerName:    employment.EmployerName,
+			JobTitle:        employment.JobTitle,
+			MonthlyIncome:   employment.MonthlyIncome,
+			MonthlyExpenses: employment.MonthlyExpenses,
+		}
+	}
+
+	updateProfileResp, err_ := userServiceClient.UpdateProfile(ctx, &userPb.UpdateProfileRequest{Profile: profile, Fields: fields})
+
+	if updateProfileResp == nil {
+		log.Println("Error in UpdateProfile call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !updateProfileResp.Status {
+		helpers.SendError(c, int(updateProfileResp.StatusCode), updateProfileResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": updateProfileResp.Message,
+		"data":    profileJSON(updateProfileResp.Profile),
+	})
+}
+
+func profileJSON(profile *userPb.UserProfile) gin.H {
+	data := gin.H{
+		"email":            profile.GetEmail(),
+		"phone":            profile.GetPhone(),
+		"dateOfBirth":      profile.GetDateOfBirth(),
+		"address":          nil,
+		"nationalIdNumber": profile.GetNationalIdNumber(),
+		"employment":       nil,
+		"updatedAt":        profile.GetUpdatedAt(),
+		"emailVerified":    profile.GetEmailVerified(),
+		"phoneVerified":    profile.GetPhoneVerified(),
+	}
+	if address := profile.GetAddress(); address != nil {
+		data["address"] = gin.H{
+			"line1":      address.GetLine1(),
+			"line2":      address.GetLine2(),
+			"city":       address.GetCity(),
+			"state":      address.GetState(),
+			"postalCode": address.GetPostalCode(),
+			"country":    address.GetCountry(),
+		}
+	}
+	if employment := profile.GetEmployment(); employment != nil {
+		data["employment"] = gin.H{
+			"status":          employment.GetStatus(),
+			"employerName":    employment.GetEmployerName(),
+			"jobTitle":        employment.GetJobTitle(),
+			"monthlyIncome":   employment.GetMonthlyIncome(),
+			"monthlyExpenses": employment.GetMonthlyExpenses(),
+		}
+	}
+	return data
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/grpcclient"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/helpers"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/middleware"
+	userPb "github.com/manlikehenryy/loan-management-system-grpc/apiGateway/user"
+)
+
+func ListSessions(c *gin.Context) {
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	listSessionsResp, err_ := userServiceClient.ListSessions(ctx, &userPb.ListSessionsRequest{})
+
+	if listSessionsResp == nil {
+		log.Println("Error in ListSessions call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !listSessionsResp.Status {
+		helpers.SendError(c, int(listSessionsResp.StatusCode), listSessionsResp.Message)
+		return
+	}
+
+	currentSessionId := c.GetString("sessionId")
+	sessions := make([]gin.H, 0, len(listSessionsResp.Sessions))
+	for _, session := range listSessionsResp.Sessions {
+		sessions = append(sessions, gin.H{
+			"id":         session.Id,
+			"userAgent":  session.UserAgent,
+			"ipAddress":  session.IpAddress,
+			"createdAt":  session.CreatedAt,
+			"lastUsedAt": session.LastUsedAt,
+			"expiresAt":  session.ExpiresAt,
+			"current":    session.Id == currentSessionId,
+		})
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": listSessionsResp.Message,
+		"data":    sessions,
+	})
+}
+
+func RevokeSession(c *gin.Context) {
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	revokeSessionReq := &userPb.RevokeSessionRequest{
+		SessionId: c.Param("id"),
+	}
+
+	revokeSessionResp, err_ := userServiceClient.RevokeSession(ctx, revokeSessionReq)
+
+	if revokeSessionResp == nil {
+		log.Println("Error in RevokeSession call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !revokeSessionResp.Status {
+		helpers.SendError(c, int(revokeSessionResp.StatusCode), revokeSessionResp.Message)
+		return
+	}
+
+	middleware.ForgetSession(c.Param("id"))
+	if c.Param("id") == c.GetString("sessionId") {
+		clearAuthCookies(c)
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": revokeSessionResp.Message,
+	})
+}
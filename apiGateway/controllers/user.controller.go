@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/dto"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/grpcclient"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/helpers"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/middleware"
+	userPb "github.com/manlikehenryy/loan-management-system-grpc/apiGateway/user"
+)
+
+func ListUsers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	listUsersReq := &userPb.ListUsersRequest{
+		Query:         c.Query("q"),
+		Role:          c.Query("role"),
+		AccountStatus: c.Query("status"),
+		Page:          int32(page),
+		Limit:         int32(limit),
+	}
+
+	listUsersResp, err_ := userServiceClient.ListUsers(ctx, listUsersReq)
+
+	if listUsersResp == nil {
+		log.Println("Error in ListUsers call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !listUsersResp.Status {
+		helpers.SendError(c, int(listUsersResp.StatusCode), listUsersResp.Message)
+		return
+	}
+
+	users := make([]gin.H, 0, len(listUsersResp.Users))
+	for _, user := range listUsersResp.Users {
+		users = append(users, userJSON(user))
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": listUsersResp.Message,
+		"data": gin.H{
+			"users": users,
+			"total": listUsersResp.Total,
+			"page":  page,
+			"limit": limit,
+		},
+	})
+}
+
+func GetUser(c *gin.Context) {
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	getUserResp, err_ := userServiceClient.GetUser(ctx, &userPb.GetUserRequest{UserId: c.Param("id")})
+
+	if getUserResp == nil {
+		log.Println("Error in GetUser call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !getUserResp.Status {
+		helpers.SendError(c, int(getUserResp.StatusCode), getUserResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": getUserResp.Message,
+		"data":    userJSON(getUserResp.User),
+	})
+}
+
+func UpdateUserRole(c *gin.Context) {
+	var updateUserRoleDto dto.UpdateUserRoleDto
+
+	if err := c.ShouldBindJSON(&updateUserRoleDto); err != nil {
+		log.Println("Unable to parse body:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	updateUserRoleReq := &userPb.UpdateUserRoleRequest{
+		UserId: c.Param("id"),
+		Role:   updateUserRoleDto.Role,
+	}
+
+	updateUserRoleResp, err_ := userServiceClient.UpdateUserRole(ctx, updateUserRoleReq)
+
+	if updateUserRoleResp == nil {
+		log.Println("Error in UpdateUserRole call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !updateUserRoleResp.Status {
+		helpers.SendError(c, int(updateUserRoleResp.StatusCode), updateUserRoleResp.Message)
+		return
+	}
+
+	middleware.ForgetPermissions(c.Param("id"))
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": updateUserRoleResp.Message,
+	})
+}
+
+func SuspendUser(c *gin.Context) {
+	setUserStatus(c, "suspended")
+}
+
+func ReactivateUser(c *gin.Context) {
+	setUserStatus(c, "active")
+}
+
+func UnlockUser(c *gin.Context) {
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	unlockUserResp, err_ := userServiceClient.UnlockUser(ctx, &userPb.UnlockUserRequest{UserId: c.Param("id")})
+
+	if unlockUserResp == nil {
+		log.Println("Error in UnlockUser call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !unlockUserResp.Status {
+		helpers.SendError(c, int(unlockUserResp.StatusCode), unlockUserResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": unlockUserResp.Message,
+	})
+}
+
+func setUserStatus(c *gin.Context, accountStatus string) {
+	var setUserStatusDto dto.SetUserStatusDto
+
+	// The reason is optional, so an empty body is allowed
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&setUserStatusDto); err != nil {
+			log.Println("Unable to parse body:", err)
+			helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	setUserStatusReq := &userPb.SetUserStatusRequest{
+		UserId:        c.Param("id"),
+		AccountStatus: accountStatus,
+		Reason:        setUserStatusDto.Reason,
+	}
+
+	setUserStatusResp, err_ := userServiceClient.SetUserStatus(ctx, setUserStatusReq)
+
+	if setUserStatusResp == nil {
+		log.Println("Error in SetUserStatus call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !setUserStatusResp.Status {
+		helpers.SendError(c, int(setUserStatusResp.StatusCode), setUserStatusResp.Message)
+		return
+	}
+
+	// Suspension revokes the user's sessions, so stop trusting what this gateway cached about them
+	middleware.ForgetPermissions(c.Param("id"))
+	middleware.ForgetUserSessions(c.Param("id"))
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": setUserStatusResp.Message,
+	})
+}
+
+func userJSON(user *userPb.UserDetails) gin.H {
+	return gin.H{
+		"id":                 user.GetId(),
+		"username":           user.GetUsername(),
+		"firstName":          user.GetFirstName(),
+		"lastName":           user.GetLastName(),
+		"role":               user.GetRole(),
+		"status":             user.GetAccountStatus(),
+		"mfaEnabled":         user.GetMfaEnabled(),
+		"kycTier":            user.GetKycTier(),
+		"verificationStatus": user.GetVerificationStatus(),
+	}
+}
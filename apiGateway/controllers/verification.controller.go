@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/dto"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/grpcclient"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/helpers"
+	userPb "github.com/manlikehenryy/loan-management-system-grpc/apiGateway/user"
+)
+
+func SendVerificationCode(c *gin.Context) {
+	var sendVerificationCodeDto dto.SendVerificationCodeDto
+
+	if err := c.ShouldBindJSON(&sendVerificationCodeDto); err != nil {
+		log.Println("Unable to parse body:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	sendVerificationCodeResp, err_ := userServiceClient.SendVerificationCode(ctx, &userPb.SendVerificationCodeRequest{Channel: sendVerificationCodeDto.Channel})
+
+	if sendVerificationCodeResp == nil {
+		log.Println("Error in SendVerificationCode call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !sendVerificationCodeResp.Status {
+		helpers.SendError(c, int(sendVerificationCodeResp.StatusCode), sendVerificationCodeResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": sendVerificationCodeResp.Message,
+		"data": gin.H{
+			"destination":      sendVerificationCodeResp.Destination,
+			"expiresInSeconds": sendVerificationCodeResp.ExpiresInSeconds,
+		},
+	})
+}
+
+func VerifyContact(c *gin.Context) {
+	var verifyContactDto dto.VerifyContactDto
+
+	if err := c.ShouldBindJSON(&verifyContactDto); err != nil {
+		log.Println("Unable to parse body:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Initialize the gRPC client
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	verifyContactReq := &userPb.VerifyContactRequest{
+		Channel: verifyContactDto.Channel,
+		Code:    verifyContactDto.Code,
+	}
+
+	verifyContactResp, err_ := userServiceClient.VerifyContact(ctx, verifyContactReq)
+
+	if verifyContactResp == nil {
+		log.Println("Error in VerifyContact call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !verifyContactResp.Status {
+		helpers.SendError(c, int(verifyContactResp.StatusCode), verifyContactResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": verifyContactResp.Message,
+		"data": gin.H{
+			"verificationStatus": verifyContactResp.VerificationStatus,
+		},
+	})
+}
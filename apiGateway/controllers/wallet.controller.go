@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/dto"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/grpcclient"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/helpers"
+	walletPb "github.com/manlikehenryy/loan-management-system-grpc/apiGateway/wallet"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func GetWallet(c *gin.Context) {
+	// Initialize the gRPC client
+	walletServiceClient, cleanup, err := grpcclient.NewWalletServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to WalletService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	getBalanceResp, err_ := walletServiceClient.GetBalance(ctx, &walletPb.GetBalanceRequest{})
+
+	if getBalanceResp == nil {
+		log.Println("Error in GetBalance call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !getBalanceResp.Status {
+		helpers.SendError(c, int(getBalanceResp.StatusCode), getBalanceResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": getBalanceResp.Message,
+		"data": gin.H{
+			"ledgerBalance":    getBalanceResp.LedgerBalance,
+			"availableBalance": getBalanceResp.AvailableBalance,
+			"heldBalance":      getBalanceResp.HeldBalance,
+		},
+	})
+}
+
+func InitiateTopUp(c *gin.Context) {
+	var topUpDto dto.TopUpDto
+
+	if err := c.ShouldBindJSON(&topUpDto); err != nil {
+		log.Println("Unable to parse body:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Initialize the gRPC client
+	walletServiceClient, cleanup, err := grpcclient.NewWalletServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to WalletService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	initiateTopUpResp, err_ := walletServiceClient.InitiateTopUp(ctx, &walletPb.InitiateTopUpRequest{Amount: topUpDto.Amount})
+
+	if initiateTopUpResp == nil {
+		log.Println("Error in InitiateTopUp call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !initiateTopUpResp.Status {
+		helpers.SendError(c, int(initiateTopUpResp.StatusCode), initiateTopUpResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusCreated, gin.H{
+		"message": initiateTopUpResp.Message,
+		"data":    topUpJSON(initiateTopUpResp.TopUp),
+	})
+}
+
+func GetTopUp(c *gin.Context) {
+	// Initialize the gRPC client
+	walletServiceClient, cleanup, err := grpcclient.NewWalletServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to WalletService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	getTopUpResp, err_ := walletServiceClient.GetTopUp(ctx, &walletPb.GetTopUpRequest{TopUpId: c.Param("id")})
+
+	if getTopUpResp == nil {
+		log.Println("Error in GetTopUp call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !getTopUpResp.Status {
+		helpers.SendError(c, int(getTopUpResp.StatusCode), getTopUpResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": getTopUpResp.Message,
+		"data":    topUpJSON(getTopUpResp.TopUp),
+	})
+}
+
+// GetStatement sends the user's wallet statement for ?from= to ?to=, as JSON, CSV or PDF depending
+// on ?format=. The statement is passed on as walletService streams it, so it is never held whole.
+func GetStatement(c *gin.Context) {
+	// Initialize the gRPC client
+	walletServiceClient, cleanup, err := grpcclient.NewWalletServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to WalletService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata, ending the stream if the client goes away
+	ctx := grpcclient.NewIdentityContext(c.Request.Context(), configs.Env.TOKEN, c.GetString("identity"))
+
+	stream, err_ := walletServiceClient.GetStatement(ctx, &walletPb.GetStatementRequest{
+		UserId: c.Query("userId"),
+		From:   c.Query("from"),
+		To:     c.Query("to"),
+		Format: c.Query("format"),
+	})
+	if err_ != nil {
+		log.Println("Error in GetStatement call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	// The first chunk describes the document; an error before it means there is no statement
+	head, err_ := stream.Recv()
+	if err_ != nil {
+		st := status.Convert(err_)
+		helpers.SendError(c, statementErrorStatus(st.Code()), st.Message())
+		return
+	}
+
+	c.Header("Content-Type", head.ContentType)
+	c.Header("Content-Disposition", `attachment; filename="`+head.Filename+`"`)
+	c.Status(http.StatusOK)
+
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			// The status has been sent, so all that can be done is to cut the document short
+			log.Println("Statement stream ended:", err)
+			return
+		}
+		if _, err := c.Writer.Write(chunk.Data); err != nil {
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// statementErrorStatus maps an error from GetStatement to an HTTP status
+func statementErrorStatus(code codes.Code) int {
+	switch code {
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func topUpJSON(topUp *walletPb.TopUp) gin.H {
+	return gin.H{
+		"id":                topUp.GetId(),
+		"amount":            topUp.GetAmount(),
+		"provider":          topUp.GetProvider(),
+		"providerReference": topUp.GetProviderReference(),
+		"checkoutUrl":       topUp.GetCheckoutUrl(),
+		"status":            topUp.GetStatus(),
+		"createdAt":         topUp.GetCreatedAt(),
+		"completedAt":       topUp.GetCompletedAt(),
+	}
+}
@@ -0,0 +1,329 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/dto"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/grpcclient"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/helpers"
+	walletPb "github.com/manlikehenryy/loan-management-system-grpc/apiGateway/wallet"
+)
+
+func AddBankAccount(c *gin.Context) {
+	var bankAccountDto dto.BankAccountDto
+
+	if err := c.ShouldBindJSON(&bankAccountDto); err != nil {
+		log.Println("Unable to parse body:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Initialize the gRPC client
+	walletServiceClient, cleanup, err := grpcclient.NewWalletServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to WalletService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	addBankAccountResp, err_ := walletServiceClient.AddBankAccount(ctx, &walletPb.AddBankAccountRequest{
+		BankCode:      bankAccountDto.BankCode,
+		AccountNumber: bankAccountDto.AccountNumber,
+		AccountName:   bankAccountDto.AccountName,
+	})
+
+	if addBankAccountResp == nil {
+		log.Println("Error in AddBankAccount call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !addBankAccountResp.Status {
+		helpers.SendError(c, int(addBankAccountResp.StatusCode), addBankAccountResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusCreated, gin.H{
+		"message": addBankAccountResp.Message,
+		"data":    bankAccountJSON(addBankAccountResp.BankAccount),
+	})
+}
+
+func ListBankAccounts(c *gin.Context) {
+	// Initialize the gRPC client
+	walletServiceClient, cleanup, err := grpcclient.NewWalletServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to WalletService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	listBankAccountsResp, err_ := walletServiceClient.ListBankAccounts(ctx, &walletPb.ListBankAccountsRequest{})
+
+	if listBankAccountsResp == nil {
+		log.Println("Error in ListBankAccounts call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !listBankAccountsResp.Status {
+		helpers.SendError(c, int(listBankAccountsResp.StatusCode), listBankAccountsResp.Message)
+		return
+	}
+
+	bankAccounts := make([]gin.H, 0, len(listBankAccountsResp.BankAccounts))
+	for _, bankAccount := range listBankAccountsResp.BankAccounts {
+		bankAccounts = append(bankAccounts, bankAccountJSON(bankAccount))
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": listBankAccountsResp.Message,
+		"data":    bankAccounts,
+	})
+}
+
+func RemoveBankAccount(c *gin.Context) {
+	// Initialize the gRPC client
+	walletServiceClient, cleanup, err := grpcclient.NewWalletServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to WalletService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	removeBankAccountResp, err_ := walletServiceClient.RemoveBankAccount(ctx, &walletPb.RemoveBankAccountRequest{BankAccountId: c.Param("id")})
+
+	if removeBankAccountResp == nil {
+		log.Println("Error in RemoveBankAccount call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !removeBankAccountResp.Status {
+		helpers.SendError(c, int(removeBankAccountResp.StatusCode), removeBankAccountResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": removeBankAccountResp.Message,
+	})
+}
+
+func RequestWithdrawal(c *gin.Context) {
+	var withdrawalDto dto.WithdrawalDto
+
+	if err := c.ShouldBindJSON(&withdrawalDto); err != nil {
+		log.Println("Unable to parse body:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Initialize the gRPC client
+	walletServiceClient, cleanup, err := grpcclient.NewWalletServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to WalletService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	requestWithdrawalResp, err_ := walletServiceClient.RequestWithdrawal(ctx, &walletPb.RequestWithdrawalRequest{
+		BankAccountId: withdrawalDto.BankAccountId,
+		Amount:        withdrawalDto.Amount,
+	})
+
+	if requestWithdrawalResp == nil {
+		log.Println("Error in RequestWithdrawal call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !requestWithdrawalResp.Status {
+		helpers.SendError(c, int(requestWithdrawalResp.StatusCode), requestWithdrawalResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusCreated, gin.H{
+		"message": requestWithdrawalResp.Message,
+		"data":    withdrawalJSON(requestWithdrawalResp.Withdrawal),
+	})
+}
+
+func ListWithdrawals(c *gin.Context) {
+	listWithdrawals(c, false)
+}
+
+// ListAllWithdrawals lists every user's withdrawals for staff, optionally only those with ?status=
+func ListAllWithdrawals(c *gin.Context) {
+	listWithdrawals(c, true)
+}
+
+func listWithdrawals(c *gin.Context, allUsers bool) {
+	page, _ := strconv.Atoi(c.Query("page"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	// Initialize the gRPC client
+	walletServiceClient, cleanup, err := grpcclient.NewWalletServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to WalletService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	listWithdrawalsResp, err_ := walletServiceClient.ListWithdrawals(ctx, &walletPb.ListWithdrawalsRequest{
+		Page:     int32(page),
+		Limit:    int32(limit),
+		Status:   c.Query("status"),
+		AllUsers: allUsers,
+	})
+
+	if listWithdrawalsResp == nil {
+		log.Println("Error in ListWithdrawals call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !listWithdrawalsResp.Status {
+		helpers.SendError(c, int(listWithdrawalsResp.StatusCode), listWithdrawalsResp.Message)
+		return
+	}
+
+	withdrawals := make([]gin.H, 0, len(listWithdrawalsResp.Withdrawals))
+	for _, withdrawal := range listWithdrawalsResp.Withdrawals {
+		withdrawals = append(withdrawals, withdrawalJSON(withdrawal))
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": listWithdrawalsResp.Message,
+		"data": gin.H{
+			"withdrawals": withdrawals,
+			"total":       listWithdrawalsResp.Total,
+			"page":        page,
+			"limit":       limit,
+		},
+	})
+}
+
+func GetWithdrawal(c *gin.Context) {
+	// Initialize the gRPC client
+	walletServiceClient, cleanup, err := grpcclient.NewWalletServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to WalletService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	getWithdrawalResp, err_ := walletServiceClient.GetWithdrawal(ctx, &walletPb.GetWithdrawalRequest{WithdrawalId: c.Param("id")})
+
+	if getWithdrawalResp == nil {
+		log.Println("Error in GetWithdrawal call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !getWithdrawalResp.Status {
+		helpers.SendError(c, int(getWithdrawalResp.StatusCode), getWithdrawalResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": getWithdrawalResp.Message,
+		"data":    withdrawalJSON(getWithdrawalResp.Withdrawal),
+	})
+}
+
+func ResolveWithdrawal(c *gin.Context) {
+	var resolveDto dto.ResolveWithdrawalDto
+
+	if err := c.ShouldBindJSON(&resolveDto); err != nil {
+		log.Println("Unable to parse body:", err)
+		helpers.SendError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Initialize the gRPC client
+	walletServiceClient, cleanup, err := grpcclient.NewWalletServiceClient(c)
+	if err != nil {
+		log.Println("Failed to connect to WalletService:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		return
+	}
+	defer cleanup()
+
+	// Set up the context with authorization metadata
+	ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+	resolveWithdrawalResp, err_ := walletServiceClient.ResolveWithdrawal(ctx, &walletPb.ResolveWithdrawalRequest{
+		WithdrawalId: c.Param("id"),
+		Status:       resolveDto.Status,
+		Reason:       resolveDto.Reason,
+	})
+
+	if resolveWithdrawalResp == nil {
+		log.Println("Error in ResolveWithdrawal call:", err_)
+		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		return
+	}
+
+	if err_ != nil || !resolveWithdrawalResp.Status {
+		helpers.SendError(c, int(resolveWithdrawalResp.StatusCode), resolveWithdrawalResp.Message)
+		return
+	}
+
+	helpers.SendJSON(c, http.StatusOK, gin.H{
+		"message": resolveWithdrawalResp.Message,
+		"data":    withdrawalJSON(resolveWithdrawalResp.Withdrawal),
+	})
+}
+
+func bankAccountJSON(bankAccount *walletPb.BankAccount) gin.H {
+	return gin.H{
+		"id":            bankAccount.GetId(),
+		"bankCode":      bankAccount.GetBankCode(),
+		"accountNumber": bankAccount.GetAccountNumber(),
+		"accountName":   bankAccount.GetAccountName(),
+		"createdAt":     bankAccount.GetCreatedAt(),
+	}
+}
+
+func withdrawalJSON(withdrawal *walletPb.Withdrawal) gin.H {
+	return gin.H{
+		"id":                withdrawal.GetId(),
+		"userId":            withdrawal.GetUserId(),
+		"amount":            withdrawal.GetAmount(),
+		"bankAccount":       bankAccountJSON(withdrawal.GetBankAccount()),
+		"status":            withdrawal.GetStatus(),
+		"failureReason":     withdrawal.GetFailureReason(),
+		"provider":          withdrawal.GetProvider(),
+		"providerReference": withdrawal.GetProviderReference(),
+		"createdAt":         withdrawal.GetCreatedAt(),
+		"updatedAt":         withdrawal.GetUpdatedAt(),
+	}
+}
@@ -8,6 +8,102 @@ type RegisterDto struct {
 }
 
 type LoginDto struct {
-	Username  string `json:"username"`
-	Password  string `json:"password"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	ReturnTokens bool   `json:"returnTokens"` // For clients using the Authorization header instead of cookies
+}
+
+type UpdateUserRoleDto struct {
+	Role string `json:"role"`
+}
+
+type SetUserStatusDto struct {
+	Reason string `json:"reason"`
+}
+
+type RefreshTokenDto struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type CreateApiKeyDto struct {
+	UserId        string   `json:"userId"`
+	Name          string   `json:"name"`
+	Routes        []string `json:"routes"`
+	ExpiresInDays int32    `json:"expiresInDays"`
+}
+
+type MfaLoginDto struct {
+	MfaChallenge string `json:"mfaChallenge"`
+	Code         string `json:"code"`
+	ReturnTokens bool   `json:"returnTokens"`
+}
+
+type MfaCodeDto struct {
+	Code string `json:"code"`
+}
+
+type ChangePasswordDto struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+type RequestPasswordResetDto struct {
+	Username string `json:"username"`
+}
+
+type ConfirmPasswordResetDto struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"newPassword"`
+}
+
+type AddressDto struct {
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2"`
+	City       string `json:"city"`
+	State      string `json:"state"`
+	PostalCode string `json:"postalCode"`
+	Country    string `json:"country"`
+}
+
+type EmploymentDto struct {
+	Status          string  `json:"status"`
+	EmployerName    string  `json:"employerName"`
+	JobTitle        string  `json:"jobTitle"`
+	MonthlyIncome   float64 `json:"monthlyIncome"`
+	MonthlyExpenses float64 `json:"monthlyExpenses"`
+}
+
+// UpdateProfileDto is a partial update: only the keys present in the body are changed
+type UpdateProfileDto struct {
+	Email            string         `json:"email"`
+	Phone            string         `json:"phone"`
+	DateOfBirth      string         `json:"dateOfBirth"`
+	Address          *AddressDto    `json:"address"`
+	NationalIdNumber string         `json:"nationalIdNumber"`
+	Employment       *EmploymentDto `json:"employment"`
+}
+
+type ReviewKycDocumentDto struct {
+	Decision string `json:"decision"`
+	Reason   string `json:"reason"`
+}
+
+type SendVerificationCodeDto struct {
+	Channel string `json:"channel"`
+}
+
+type VerifyContactDto struct {
+	Channel string `json:"channel"`
+	Code    string `json:"code"`
+}
+
+type NotificationPreferencesDto struct {
+	Email *bool `json:"email"`
+	Sms   *bool `json:"sms"`
+	InApp *bool `json:"inApp"`
+}
+
+type MarkNotificationsReadDto struct {
+	Ids []string `json:"ids"`
+	All bool     `json:"all"`
 }
@@ -0,0 +1,37 @@
+package dto
+
+type TopUpDto struct {
+	Amount float32 `json:"amount"`
+}
+
+type BankAccountDto struct {
+	BankCode      string `json:"bankCode"`
+	AccountNumber string `json:"accountNumber"`
+	AccountName   string `json:"accountName"`
+}
+
+type WithdrawalDto struct {
+	BankAccountId string  `json:"bankAccountId"`
+	Amount        float32 `json:"amount"`
+}
+
+type ResolveWithdrawalDto struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+type PlaceHoldDto struct {
+	Amount           float32 `json:"amount"`
+	Reason           string  `json:"reason"`
+	Reference        string  `json:"reference"`
+	ExpiresInSeconds int64   `json:"expiresInSeconds"`
+}
+
+type CaptureHoldDto struct {
+	Amount float32 `json:"amount"`
+}
+
+type DebitWalletDto struct {
+	Amount    float32 `json:"amount"`
+	Reference string  `json:"reference"`
+}
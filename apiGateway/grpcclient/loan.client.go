@@ -4,17 +4,22 @@ import (
 	"context"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/helpers"
 	loanPb "github.com/manlikehenryy/loan-management-system-grpc/apiGateway/loan" // Import your generated proto package
 )
 
 // NewLoanServiceClient initializes a new LoanServiceClient with context
 func NewLoanServiceClient(ctx context.Context) (loanPb.LoanServiceClient, func(), error) {
 
+	creds, err := helpers.ClientCredentials()
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Establish the connection to the LoanService
-	conn, err := grpc.NewClient(configs.Env.LOAN_SERVICE_URL, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(configs.Env.LOAN_SERVICE_URL, grpc.WithTransportCredentials(creds))
 	if err != nil {
 		return nil, nil, err
 	}
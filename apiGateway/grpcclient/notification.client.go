@@ -0,0 +1,62 @@
+package grpcclient
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/helpers"
+	notificationPb "github.com/manlikehenryy/loan-management-system-grpc/apiGateway/notification" // Import your generated proto package
+)
+
+// NewNotificationServiceClient initializes a new NotificationServiceClient with context
+func NewNotificationServiceClient(ctx context.Context) (notificationPb.NotificationServiceClient, func(), error) {
+
+	creds, err := helpers.ClientCredentials()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Establish the connection to the NotificationService
+	conn, err := grpc.NewClient(configs.Env.NOTIFICATION_SERVICE_URL, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Define a cleanup function to close the connection
+	cleanup := func() {
+		conn.Close()
+	}
+
+	return notificationPb.NewNotificationServiceClient(conn), cleanup, nil
+}
+
+var (
+	sharedNotificationClient     notificationPb.NotificationServiceClient
+	sharedNotificationClientErr  error
+	sharedNotificationClientOnce sync.Once
+)
+
+// SharedNotificationServiceClient returns a NotificationServiceClient on a connection kept open
+// for the life of the gateway. Notification streams share it rather than each opening a connection.
+func SharedNotificationServiceClient() (notificationPb.NotificationServiceClient, error) {
+	sharedNotificationClientOnce.Do(func() {
+		creds, err := helpers.ClientCredentials()
+		if err != nil {
+			sharedNotificationClientErr = err
+			return
+		}
+
+		conn, err := grpc.NewClient(configs.Env.NOTIFICATION_SERVICE_URL, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			sharedNotificationClientErr = err
+			return
+		}
+
+		sharedNotificationClient = notificationPb.NewNotificationServiceClient(conn)
+	})
+
+	return sharedNotificationClient, sharedNotificationClientErr
+}
@@ -2,20 +2,26 @@ package grpcclient
 
 import (
 	"context"
+	"sync"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
 
 	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/helpers"
 	userPb "github.com/manlikehenryy/loan-management-system-grpc/apiGateway/user" // Import your generated proto package
 )
 
 // NewUserServiceClient initializes a new UserServiceClient with context
 func NewUserServiceClient(ctx context.Context) (userPb.UserServiceClient, func(), error) {
 
+	creds, err := helpers.ClientCredentials()
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Establish the connection to the UserService
-	conn, err := grpc.NewClient(configs.Env.USER_SERVICE_URL, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(configs.Env.USER_SERVICE_URL, grpc.WithTransportCredentials(creds))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -28,6 +34,43 @@ func NewUserServiceClient(ctx context.Context) (userPb.UserServiceClient, func()
 	return userPb.NewUserServiceClient(conn), cleanup, nil
 }
 
+var (
+	sharedUserClient     userPb.UserServiceClient
+	sharedUserClientErr  error
+	sharedUserClientOnce sync.Once
+)
+
+// SharedUserServiceClient returns a UserServiceClient on a connection kept open for the
+// life of the gateway, for calls made on every request
+func SharedUserServiceClient() (userPb.UserServiceClient, error) {
+	sharedUserClientOnce.Do(func() {
+		creds, err := helpers.ClientCredentials()
+		if err != nil {
+			sharedUserClientErr = err
+			return
+		}
+
+		conn, err := grpc.NewClient(configs.Env.USER_SERVICE_URL, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			sharedUserClientErr = err
+			return
+		}
+
+		sharedUserClient = userPb.NewUserServiceClient(conn)
+	})
+
+	return sharedUserClient, sharedUserClientErr
+}
+
 func NewAuthContext(ctx context.Context, token string) context.Context {
-	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token, "x-caller", helpers.ServiceName)
+}
+
+// NewIdentityContext adds the service token and the signed end-user identity assertion
+func NewIdentityContext(ctx context.Context, token string, identity string) context.Context {
+	ctx = NewAuthContext(ctx, token)
+	if identity == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, "x-identity", identity)
 }
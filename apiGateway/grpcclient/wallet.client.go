@@ -4,18 +4,22 @@ import (
 	"context"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/metadata"
 
-	"github.com/manlikehenryy/loan-management-system-grpc/userService/configs"
-	walletPb "github.com/manlikehenryy/loan-management-system-grpc/userService/wallet" // Import your generated proto package
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/helpers"
+	walletPb "github.com/manlikehenryy/loan-management-system-grpc/apiGateway/wallet" // Import your generated proto package
 )
 
 // NewWalletServiceClient initializes a new WalletServiceClient with context
 func NewWalletServiceClient(ctx context.Context) (walletPb.WalletServiceClient, func(), error) {
 
-	// Establish the connection to the UserService
-	conn, err := grpc.NewClient(configs.Env.WALLET_SERVICE_URL, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	creds, err := helpers.ClientCredentials()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Establish the connection to the WalletService
+	conn, err := grpc.NewClient(configs.Env.WALLET_SERVICE_URL, grpc.WithTransportCredentials(creds))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -27,7 +31,3 @@ func NewWalletServiceClient(ctx context.Context) (walletPb.WalletServiceClient,
 
 	return walletPb.NewWalletServiceClient(conn), cleanup, nil
 }
-
-func NewAuthContext(ctx context.Context, token string) context.Context {
-	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
-}
@@ -0,0 +1,35 @@
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+)
+
+// CSRFToken derives the CSRF token for a session. It is bound to the session, so a token
+// obtained by an attacker for their own session is useless against anyone else's.
+func CSRFToken(sessionId string) (string, error) {
+	if configs.Env.CSRF_SECRET == "" {
+		return "", errors.New("CSRF_SECRET is not set")
+	}
+
+	mac := hmac.New(sha256.New, []byte(configs.Env.CSRF_SECRET))
+	mac.Write([]byte("csrf:" + sessionId))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// VerifyCSRFToken reports whether token is the CSRF token for the session
+func VerifyCSRFToken(sessionId string, token string) bool {
+	if sessionId == "" || token == "" {
+		return false
+	}
+
+	expected, err := CSRFToken(sessionId)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(expected), []byte(token))
+}
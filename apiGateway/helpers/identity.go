@@ -0,0 +1,56 @@
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+)
+
+// identityTTL keeps assertions short-lived; they only need to survive a single request fan-out
+const identityTTL = 2 * time.Minute
+
+// IdentityClaims is the end-user identity forwarded to downstream services
+type IdentityClaims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Roles     []string `json:"roles,omitempty"`
+	ApiKeyId  string   `json:"akid,omitempty"` // Set when the user authenticated with an API key
+	RequestId string   `json:"rid,omitempty"`
+	IssuedAt  int64    `json:"iat"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// SignIdentity creates a signed identity assertion for the given user. apiKeyId is the key the
+// user authenticated with, or empty for a session.
+func SignIdentity(userId string, roles []string, apiKeyId string, requestId string) (string, error) {
+	if configs.Env.IDENTITY_SECRET == "" {
+		return "", errors.New("IDENTITY_SECRET is not set")
+	}
+
+	now := time.Now()
+	claims := IdentityClaims{
+		Issuer:    "apiGateway",
+		Subject:   userId,
+		Roles:     roles,
+		ApiKeyId:  apiKeyId,
+		RequestId: requestId,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(identityTTL).Unix(),
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(configs.Env.IDENTITY_SECRET))
+	mac.Write([]byte(encodedPayload))
+
+	return encodedPayload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
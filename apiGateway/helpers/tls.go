@@ -0,0 +1,316 @@
+package helpers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// ServiceName is the identity this service presents in its certificate
+const ServiceName = "apiGateway"
+
+// reloadInterval bounds how often certificate files are checked for rotation
+const reloadInterval = 10 * time.Second
+
+var (
+	reloader     *certReloader
+	reloaderErr  error
+	reloaderOnce sync.Once
+)
+
+// certReloader keeps the certificate, key and CA pool in memory and reloads them when the files change
+type certReloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	caPool    *x509.CertPool
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+func tlsEnabled() bool {
+	return configs.Env.TLS_ENABLED == "true"
+}
+
+func getReloader() (*certReloader, error) {
+	reloaderOnce.Do(func() {
+		certFile, keyFile, caFile := configs.Env.TLS_CERT_FILE, configs.Env.TLS_KEY_FILE, configs.Env.TLS_CA_FILE
+
+		if configs.Env.TLS_DEV_MODE == "true" {
+			certFile, keyFile, caFile, reloaderErr = ensureDevCertificates()
+			if reloaderErr != nil {
+				return
+			}
+		}
+
+		if certFile == "" || keyFile == "" || caFile == "" {
+			reloaderErr = errors.New("TLS_CERT_FILE, TLS_KEY_FILE and TLS_CA_FILE must be set when TLS is enabled")
+			return
+		}
+
+		reloader = &certReloader{certFile: certFile, keyFile: keyFile, caFile: caFile}
+		reloaderErr = reloader.reload(true)
+	})
+
+	return reloader, reloaderErr
+}
+
+// reload re-reads the files if any of them changed since the last load
+func (r *certReloader) reload(force bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !force && time.Since(r.checkedAt) < reloadInterval {
+		return nil
+	}
+	r.checkedAt = time.Now()
+
+	var latest time.Time
+	for _, file := range []string{r.certFile, r.keyFile, r.caFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	if !force && !latest.After(r.modTime) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load key pair: %w", err)
+	}
+
+	caPEM, err := os.ReadFile(r.caFile)
+	if err != nil {
+		return fmt.Errorf("failed to read CA file: %w", err)
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caPEM) {
+		return errors.New("no certificates found in CA file")
+	}
+
+	r.cert, r.caPool, r.modTime = &cert, caPool, latest
+	if !force {
+		log.Println("Reloaded TLS certificates")
+	}
+
+	return nil
+}
+
+func (r *certReloader) current() (*tls.Certificate, *x509.CertPool) {
+	if err := r.reload(false); err != nil {
+		// Keep serving with the last good certificates while a rotation is in progress
+		log.Println("Failed to reload TLS certificates:", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cert, r.caPool
+}
+
+// ClientCredentials returns the transport credentials used to dial other services
+func ClientCredentials() (credentials.TransportCredentials, error) {
+	if !tlsEnabled() {
+		return insecure.NewCredentials(), nil
+	}
+
+	r, err := getReloader()
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials.NewTLS(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// The server is verified by VerifyConnection against the CA pool as it is at each
+		// handshake, so a rotated CA is picked up; RootCAs would be fixed when the client is built
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			_, caPool := r.current()
+			return verifyServer(cs, caPool)
+		},
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			return cert, nil
+		},
+	}), nil
+}
+
+// verifyServer checks the server's certificate chain against caPool, and that the certificate was
+// issued for the server name that was dialled
+func verifyServer(cs tls.ConnectionState, caPool *x509.CertPool) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("server presented no certificate")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       cs.ServerName,
+		Roots:         caPool,
+		Intermediates: intermediates,
+	})
+	return err
+}
+
+// ensureDevCertificates creates a local CA shared by every service and issues this service a certificate from it
+func ensureDevCertificates() (string, string, string, error) {
+	dir := configs.Env.TLS_DEV_DIR
+	if dir == "" {
+		dir = "../.dev-certs"
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", "", "", err
+	}
+
+	caFile := filepath.Join(dir, "ca.pem")
+	caKeyFile := filepath.Join(dir, "ca-key.pem")
+	if err := ensureDevCA(dir, caFile, caKeyFile); err != nil {
+		return "", "", "", fmt.Errorf("failed to create dev CA: %w", err)
+	}
+
+	certFile := filepath.Join(dir, ServiceName+".pem")
+	keyFile := filepath.Join(dir, ServiceName+"-key.pem")
+	if certValid(certFile) {
+		return certFile, keyFile, caFile, nil
+	}
+
+	caCert, err := tls.LoadX509KeyPair(caFile, caKeyFile)
+	if err != nil {
+		return "", "", "", err
+	}
+	ca, err := x509.ParseCertificate(caCert.Certificate[0])
+	if err != nil {
+		return "", "", "", err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: randomSerial(),
+		Subject:      pkix.Name{CommonName: ServiceName, Organization: []string{"loan-management-system dev"}},
+		DNSNames:     []string{ServiceName, "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1"), net.IPv6loopback},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(30 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caCert.PrivateKey)
+	if err != nil {
+		return "", "", "", err
+	}
+	if err := writeKeyPair(certFile, keyFile, der, key); err != nil {
+		return "", "", "", err
+	}
+
+	log.Printf("Issued dev certificate for %s in %s", ServiceName, dir)
+	return certFile, keyFile, caFile, nil
+}
+
+func ensureDevCA(dir, caFile, caKeyFile string) error {
+	if _, err := os.Stat(caFile); err == nil {
+		return nil
+	}
+
+	// Services may start at the same time, so only the one holding the lock creates the CA
+	lockFile := filepath.Join(dir, "ca.lock")
+	lock, err := os.OpenFile(lockFile, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		for i := 0; i < 50; i++ {
+			if _, err := os.Stat(caFile); err == nil {
+				return nil
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		return fmt.Errorf("timed out waiting for %s", caFile)
+	}
+	defer os.Remove(lockFile)
+	defer lock.Close()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          randomSerial(),
+		Subject:               pkix.Name{CommonName: "loan-management-system dev CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Created dev CA in", dir)
+	return writeKeyPair(caFile, caKeyFile, der, key)
+}
+
+func certValid(certFile string) bool {
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return false
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return false
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false
+	}
+	return time.Now().Add(24 * time.Hour).Before(cert.NotAfter)
+}
+
+func writeKeyPair(certFile, keyFile string, der []byte, key *ecdsa.PrivateKey) error {
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+}
+
+func randomSerial() *big.Int {
+	serial, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	return serial
+}
@@ -26,7 +26,7 @@ type ApplyLoanRequest struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	UserId   string  `protobuf:"bytes,1,opt,name=userId,proto3" json:"userId,omitempty"`
+	UserId   string  `protobuf:"bytes,1,opt,name=userId,proto3" json:"userId,omitempty"` // Ignored: the applicant is taken from the caller's identity assertion
 	Amount   float32 `protobuf:"fixed32,2,opt,name=amount,proto3" json:"amount,omitempty"`
 	Duration int32   `protobuf:"varint,3,opt,name=duration,proto3" json:"duration,omitempty"`
 }
@@ -159,7 +159,7 @@ type ApproveLoanRequest struct {
 
 	LoanId           string  `protobuf:"bytes,1,opt,name=loanId,proto3" json:"loanId,omitempty"`
 	ApprovedAmount   float32 `protobuf:"fixed32,2,opt,name=approvedAmount,proto3" json:"approvedAmount,omitempty"`
-	UserId           string  `protobuf:"bytes,3,opt,name=userId,proto3" json:"userId,omitempty"`
+	UserId           string  `protobuf:"bytes,3,opt,name=userId,proto3" json:"userId,omitempty"` // Ignored: the approver is taken from the caller's identity assertion
 	Tenure           int32   `protobuf:"varint,4,opt,name=tenure,proto3" json:"tenure,omitempty"`
 	MonthlyRepayment float32 `protobuf:"fixed32,5,opt,name=monthlyRepayment,proto3" json:"monthlyRepayment,omitempty"`
 	EffectiveDate    string  `protobuf:"bytes,6,opt,name=effectiveDate,proto3" json:"effectiveDate,omitempty"`
@@ -312,7 +312,7 @@ type RejectLoanRequest struct {
 	unknownFields protoimpl.UnknownFields
 
 	LoanId string `protobuf:"bytes,1,opt,name=loanId,proto3" json:"loanId,omitempty"`
-	UserId string `protobuf:"bytes,2,opt,name=userId,proto3" json:"userId,omitempty"`
+	UserId string `protobuf:"bytes,2,opt,name=userId,proto3" json:"userId,omitempty"` // Ignored: the rejecter is taken from the caller's identity assertion
 }
 
 func (x *RejectLoanRequest) Reset() {
@@ -420,6 +420,265 @@ func (x *RejectLoanResponse) GetStatusCode() int32 {
 	return 0
 }
 
+// Request message for WatchLoan. The borrower can watch their own loan; anyone else needs
+// loan.view_any. Without a resume token, the first update is a snapshot of the loan.
+type WatchLoanRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	LoanId      string `protobuf:"bytes,1,opt,name=loanId,proto3" json:"loanId,omitempty"`
+	ResumeToken string `protobuf:"bytes,2,opt,name=resumeToken,proto3" json:"resumeToken,omitempty"` // From the last update received, to continue after it
+}
+
+func (x *WatchLoanRequest) Reset() {
+	*x = WatchLoanRequest{}
+	mi := &file_loan_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchLoanRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchLoanRequest) ProtoMessage() {}
+
+func (x *WatchLoanRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_loan_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchLoanRequest.ProtoReflect.Descriptor instead.
+func (*WatchLoanRequest) Descriptor() ([]byte, []int) {
+	return file_loan_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *WatchLoanRequest) GetLoanId() string {
+	if x != nil {
+		return x.LoanId
+	}
+	return ""
+}
+
+func (x *WatchLoanRequest) GetResumeToken() string {
+	if x != nil {
+		return x.ResumeToken
+	}
+	return ""
+}
+
+// A loan as it is when an update is sent
+type LoanDetails struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id               string  `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	UserId           string  `protobuf:"bytes,2,opt,name=userId,proto3" json:"userId,omitempty"`
+	Amount           float32 `protobuf:"fixed32,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	Status           string  `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	ApprovedAmount   float32 `protobuf:"fixed32,5,opt,name=approvedAmount,proto3" json:"approvedAmount,omitempty"`
+	Tenure           int32   `protobuf:"varint,6,opt,name=tenure,proto3" json:"tenure,omitempty"`
+	MonthlyRepayment float32 `protobuf:"fixed32,7,opt,name=monthlyRepayment,proto3" json:"monthlyRepayment,omitempty"`
+	EffectiveDate    string  `protobuf:"bytes,8,opt,name=effectiveDate,proto3" json:"effectiveDate,omitempty"`
+	ExpiryDate       string  `protobuf:"bytes,9,opt,name=expiryDate,proto3" json:"expiryDate,omitempty"`
+	AmountPaid       float32 `protobuf:"fixed32,10,opt,name=amountPaid,proto3" json:"amountPaid,omitempty"`
+}
+
+func (x *LoanDetails) Reset() {
+	*x = LoanDetails{}
+	mi := &file_loan_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LoanDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LoanDetails) ProtoMessage() {}
+
+func (x *LoanDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_loan_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LoanDetails.ProtoReflect.Descriptor instead.
+func (*LoanDetails) Descriptor() ([]byte, []int) {
+	return file_loan_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *LoanDetails) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *LoanDetails) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *LoanDetails) GetAmount() float32 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *LoanDetails) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *LoanDetails) GetApprovedAmount() float32 {
+	if x != nil {
+		return x.ApprovedAmount
+	}
+	return 0
+}
+
+func (x *LoanDetails) GetTenure() int32 {
+	if x != nil {
+		return x.Tenure
+	}
+	return 0
+}
+
+func (x *LoanDetails) GetMonthlyRepayment() float32 {
+	if x != nil {
+		return x.MonthlyRepayment
+	}
+	return 0
+}
+
+func (x *LoanDetails) GetEffectiveDate() string {
+	if x != nil {
+		return x.EffectiveDate
+	}
+	return ""
+}
+
+func (x *LoanDetails) GetExpiryDate() string {
+	if x != nil {
+		return x.ExpiryDate
+	}
+	return ""
+}
+
+func (x *LoanDetails) GetAmountPaid() float32 {
+	if x != nil {
+		return x.AmountPaid
+	}
+	return 0
+}
+
+// An update sent by WatchLoan
+type LoanUpdate struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type        string            `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`       // "Snapshot", or the event, e.g. "LoanApproved"
+	EventId     string            `protobuf:"bytes,2,opt,name=eventId,proto3" json:"eventId,omitempty"` // Empty for a snapshot
+	Loan        *LoanDetails      `protobuf:"bytes,3,opt,name=loan,proto3" json:"loan,omitempty"`
+	Data        map[string]string `protobuf:"bytes,4,rep,name=data,proto3" json:"data,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"` // The event's data
+	OccurredAt  string            `protobuf:"bytes,5,opt,name=occurredAt,proto3" json:"occurredAt,omitempty"`                                                                             // RFC 3339
+	ResumeToken string            `protobuf:"bytes,6,opt,name=resumeToken,proto3" json:"resumeToken,omitempty"`
+}
+
+func (x *LoanUpdate) Reset() {
+	*x = LoanUpdate{}
+	mi := &file_loan_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LoanUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LoanUpdate) ProtoMessage() {}
+
+func (x *LoanUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_loan_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LoanUpdate.ProtoReflect.Descriptor instead.
+func (*LoanUpdate) Descriptor() ([]byte, []int) {
+	return file_loan_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *LoanUpdate) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *LoanUpdate) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *LoanUpdate) GetLoan() *LoanDetails {
+	if x != nil {
+		return x.Loan
+	}
+	return nil
+}
+
+func (x *LoanUpdate) GetData() map[string]string {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *LoanUpdate) GetOccurredAt() string {
+	if x != nil {
+		return x.OccurredAt
+	}
+	return ""
+}
+
+func (x *LoanUpdate) GetResumeToken() string {
+	if x != nil {
+		return x.ResumeToken
+	}
+	return ""
+}
+
 var File_loan_proto protoreflect.FileDescriptor
 
 var file_loan_proto_rawDesc = []byte{
@@ -470,24 +729,67 @@ var file_loan_proto_rawDesc = []byte{
 	0x73, 0x61, 0x67, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x02,
 	0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x1e, 0x0a, 0x0a,
 	0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x43, 0x6f, 0x64, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05,
-	0x52, 0x0a, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x43, 0x6f, 0x64, 0x65, 0x32, 0xb2, 0x01, 0x0a,
-	0x0b, 0x4c, 0x6f, 0x61, 0x6e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x32, 0x0a, 0x09,
-	0x41, 0x70, 0x70, 0x6c, 0x79, 0x4c, 0x6f, 0x61, 0x6e, 0x12, 0x11, 0x2e, 0x41, 0x70, 0x70, 0x6c,
-	0x79, 0x4c, 0x6f, 0x61, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x41,
-	0x70, 0x70, 0x6c, 0x79, 0x4c, 0x6f, 0x61, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
-	0x12, 0x38, 0x0a, 0x0b, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x4c, 0x6f, 0x61, 0x6e, 0x12,
-	0x13, 0x2e, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x4c, 0x6f, 0x61, 0x6e, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x4c, 0x6f,
-	0x61, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x35, 0x0a, 0x0a, 0x52, 0x65,
-	0x6a, 0x65, 0x63, 0x74, 0x4c, 0x6f, 0x61, 0x6e, 0x12, 0x12, 0x2e, 0x52, 0x65, 0x6a, 0x65, 0x63,
-	0x74, 0x4c, 0x6f, 0x61, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x52,
-	0x65, 0x6a, 0x65, 0x63, 0x74, 0x4c, 0x6f, 0x61, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x42, 0x47, 0x5a, 0x45, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f,
-	0x6d, 0x61, 0x6e, 0x6c, 0x69, 0x6b, 0x65, 0x68, 0x65, 0x6e, 0x72, 0x79, 0x79, 0x2f, 0x6c, 0x6f,
-	0x61, 0x6e, 0x2d, 0x6d, 0x61, 0x6e, 0x61, 0x67, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x2d, 0x73, 0x79,
-	0x73, 0x74, 0x65, 0x6d, 0x2d, 0x67, 0x72, 0x70, 0x63, 0x2f, 0x6c, 0x6f, 0x61, 0x6e, 0x53, 0x65,
-	0x72, 0x76, 0x69, 0x63, 0x65, 0x2f, 0x6c, 0x6f, 0x61, 0x6e, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x33,
+	0x52, 0x0a, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x43, 0x6f, 0x64, 0x65, 0x22, 0x4c, 0x0a, 0x10,
+	0x57, 0x61, 0x74, 0x63, 0x68, 0x4c, 0x6f, 0x61, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x12, 0x16, 0x0a, 0x06, 0x6c, 0x6f, 0x61, 0x6e, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x6c, 0x6f, 0x61, 0x6e, 0x49, 0x64, 0x12, 0x20, 0x0a, 0x0b, 0x72, 0x65, 0x73, 0x75,
+	0x6d, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x72,
+	0x65, 0x73, 0x75, 0x6d, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x22, 0xb7, 0x02, 0x0a, 0x0b, 0x4c,
+	0x6f, 0x61, 0x6e, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x73, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x75, 0x73,
+	0x65, 0x72, 0x49, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x75, 0x73, 0x65, 0x72,
+	0x49, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x02, 0x52, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74,
+	0x61, 0x74, 0x75, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x12, 0x26, 0x0a, 0x0e, 0x61, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x64, 0x41, 0x6d,
+	0x6f, 0x75, 0x6e, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x02, 0x52, 0x0e, 0x61, 0x70, 0x70, 0x72,
+	0x6f, 0x76, 0x65, 0x64, 0x41, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x65,
+	0x6e, 0x75, 0x72, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x74, 0x65, 0x6e, 0x75,
+	0x72, 0x65, 0x12, 0x2a, 0x0a, 0x10, 0x6d, 0x6f, 0x6e, 0x74, 0x68, 0x6c, 0x79, 0x52, 0x65, 0x70,
+	0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x02, 0x52, 0x10, 0x6d, 0x6f,
+	0x6e, 0x74, 0x68, 0x6c, 0x79, 0x52, 0x65, 0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x24,
+	0x0a, 0x0d, 0x65, 0x66, 0x66, 0x65, 0x63, 0x74, 0x69, 0x76, 0x65, 0x44, 0x61, 0x74, 0x65, 0x18,
+	0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x65, 0x66, 0x66, 0x65, 0x63, 0x74, 0x69, 0x76, 0x65,
+	0x44, 0x61, 0x74, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x65, 0x78, 0x70, 0x69, 0x72, 0x79, 0x44, 0x61,
+	0x74, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x65, 0x78, 0x70, 0x69, 0x72, 0x79,
+	0x44, 0x61, 0x74, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x50, 0x61,
+	0x69, 0x64, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x02, 0x52, 0x0a, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74,
+	0x50, 0x61, 0x69, 0x64, 0x22, 0x82, 0x02, 0x0a, 0x0a, 0x4c, 0x6f, 0x61, 0x6e, 0x55, 0x70, 0x64,
+	0x61, 0x74, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x65, 0x76, 0x65, 0x6e, 0x74,
+	0x49, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x49,
+	0x64, 0x12, 0x20, 0x0a, 0x04, 0x6c, 0x6f, 0x61, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x0c, 0x2e, 0x4c, 0x6f, 0x61, 0x6e, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x73, 0x52, 0x04, 0x6c,
+	0x6f, 0x61, 0x6e, 0x12, 0x29, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x04, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x15, 0x2e, 0x4c, 0x6f, 0x61, 0x6e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x2e, 0x44,
+	0x61, 0x74, 0x61, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x12, 0x1e,
+	0x0a, 0x0a, 0x6f, 0x63, 0x63, 0x75, 0x72, 0x72, 0x65, 0x64, 0x41, 0x74, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0a, 0x6f, 0x63, 0x63, 0x75, 0x72, 0x72, 0x65, 0x64, 0x41, 0x74, 0x12, 0x20,
+	0x0a, 0x0b, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0b, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e,
+	0x1a, 0x37, 0x0a, 0x09, 0x44, 0x61, 0x74, 0x61, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a,
+	0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12,
+	0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x32, 0xe1, 0x01, 0x0a, 0x0b, 0x4c, 0x6f,
+	0x61, 0x6e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x32, 0x0a, 0x09, 0x41, 0x70, 0x70,
+	0x6c, 0x79, 0x4c, 0x6f, 0x61, 0x6e, 0x12, 0x11, 0x2e, 0x41, 0x70, 0x70, 0x6c, 0x79, 0x4c, 0x6f,
+	0x61, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x41, 0x70, 0x70, 0x6c,
+	0x79, 0x4c, 0x6f, 0x61, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x38, 0x0a,
+	0x0b, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x4c, 0x6f, 0x61, 0x6e, 0x12, 0x13, 0x2e, 0x41,
+	0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x4c, 0x6f, 0x61, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x14, 0x2e, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x4c, 0x6f, 0x61, 0x6e, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x35, 0x0a, 0x0a, 0x52, 0x65, 0x6a, 0x65, 0x63,
+	0x74, 0x4c, 0x6f, 0x61, 0x6e, 0x12, 0x12, 0x2e, 0x52, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x4c, 0x6f,
+	0x61, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x52, 0x65, 0x6a, 0x65,
+	0x63, 0x74, 0x4c, 0x6f, 0x61, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2d,
+	0x0a, 0x09, 0x57, 0x61, 0x74, 0x63, 0x68, 0x4c, 0x6f, 0x61, 0x6e, 0x12, 0x11, 0x2e, 0x57, 0x61,
+	0x74, 0x63, 0x68, 0x4c, 0x6f, 0x61, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0b,
+	0x2e, 0x4c, 0x6f, 0x61, 0x6e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x30, 0x01, 0x42, 0x47, 0x5a,
+	0x45, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6d, 0x61, 0x6e, 0x6c,
+	0x69, 0x6b, 0x65, 0x68, 0x65, 0x6e, 0x72, 0x79, 0x79, 0x2f, 0x6c, 0x6f, 0x61, 0x6e, 0x2d, 0x6d,
+	0x61, 0x6e, 0x61, 0x67, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x2d, 0x73, 0x79, 0x73, 0x74, 0x65, 0x6d,
+	0x2d, 0x67, 0x72, 0x70, 0x63, 0x2f, 0x6c, 0x6f, 0x61, 0x6e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63,
+	0x65, 0x2f, 0x6c, 0x6f, 0x61, 0x6e, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -502,7 +804,7 @@ func file_loan_proto_rawDescGZIP() []byte {
 	return file_loan_proto_rawDescData
 }
 
-var file_loan_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_loan_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
 var file_loan_proto_goTypes = []any{
 	(*ApplyLoanRequest)(nil),    // 0: ApplyLoanRequest
 	(*ApplyLoanResponse)(nil),   // 1: ApplyLoanResponse
@@ -510,19 +812,27 @@ var file_loan_proto_goTypes = []any{
 	(*ApproveLoanResponse)(nil), // 3: ApproveLoanResponse
 	(*RejectLoanRequest)(nil),   // 4: RejectLoanRequest
 	(*RejectLoanResponse)(nil),  // 5: RejectLoanResponse
+	(*WatchLoanRequest)(nil),    // 6: WatchLoanRequest
+	(*LoanDetails)(nil),         // 7: LoanDetails
+	(*LoanUpdate)(nil),          // 8: LoanUpdate
+	nil,                         // 9: LoanUpdate.DataEntry
 }
 var file_loan_proto_depIdxs = []int32{
-	0, // 0: LoanService.ApplyLoan:input_type -> ApplyLoanRequest
-	2, // 1: LoanService.ApproveLoan:input_type -> ApproveLoanRequest
-	4, // 2: LoanService.RejectLoan:input_type -> RejectLoanRequest
-	1, // 3: LoanService.ApplyLoan:output_type -> ApplyLoanResponse
-	3, // 4: LoanService.ApproveLoan:output_type -> ApproveLoanResponse
-	5, // 5: LoanService.RejectLoan:output_type -> RejectLoanResponse
-	3, // [3:6] is the sub-list for method output_type
-	0, // [0:3] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	7, // 0: LoanUpdate.loan:type_name -> LoanDetails
+	9, // 1: LoanUpdate.data:type_name -> LoanUpdate.DataEntry
+	0, // 2: LoanService.ApplyLoan:input_type -> ApplyLoanRequest
+	2, // 3: LoanService.ApproveLoan:input_type -> ApproveLoanRequest
+	4, // 4: LoanService.RejectLoan:input_type -> RejectLoanRequest
+	6, // 5: LoanService.WatchLoan:input_type -> WatchLoanRequest
+	1, // 6: LoanService.ApplyLoan:output_type -> ApplyLoanResponse
+	3, // 7: LoanService.ApproveLoan:output_type -> ApproveLoanResponse
+	5, // 8: LoanService.RejectLoan:output_type -> RejectLoanResponse
+	8, // 9: LoanService.WatchLoan:output_type -> LoanUpdate
+	6, // [6:10] is the sub-list for method output_type
+	2, // [2:6] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_loan_proto_init() }
@@ -536,7 +846,7 @@ func file_loan_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_loan_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   10,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -22,6 +22,7 @@ const (
 	LoanService_ApplyLoan_FullMethodName   = "/LoanService/ApplyLoan"
 	LoanService_ApproveLoan_FullMethodName = "/LoanService/ApproveLoan"
 	LoanService_RejectLoan_FullMethodName  = "/LoanService/RejectLoan"
+	LoanService_WatchLoan_FullMethodName   = "/LoanService/WatchLoan"
 )
 
 // LoanServiceClient is the client API for LoanService service.
@@ -33,6 +34,7 @@ type LoanServiceClient interface {
 	ApplyLoan(ctx context.Context, in *ApplyLoanRequest, opts ...grpc.CallOption) (*ApplyLoanResponse, error)
 	ApproveLoan(ctx context.Context, in *ApproveLoanRequest, opts ...grpc.CallOption) (*ApproveLoanResponse, error)
 	RejectLoan(ctx context.Context, in *RejectLoanRequest, opts ...grpc.CallOption) (*RejectLoanResponse, error)
+	WatchLoan(ctx context.Context, in *WatchLoanRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LoanUpdate], error)
 }
 
 type loanServiceClient struct {
@@ -73,6 +75,25 @@ func (c *loanServiceClient) RejectLoan(ctx context.Context, in *RejectLoanReques
 	return out, nil
 }
 
+func (c *loanServiceClient) WatchLoan(ctx context.Context, in *WatchLoanRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LoanUpdate], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &LoanService_ServiceDesc.Streams[0], LoanService_WatchLoan_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchLoanRequest, LoanUpdate]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LoanService_WatchLoanClient = grpc.ServerStreamingClient[LoanUpdate]
+
 // LoanServiceServer is the server API for LoanService service.
 // All implementations must embed UnimplementedLoanServiceServer
 // for forward compatibility.
@@ -82,6 +103,7 @@ type LoanServiceServer interface {
 	ApplyLoan(context.Context, *ApplyLoanRequest) (*ApplyLoanResponse, error)
 	ApproveLoan(context.Context, *ApproveLoanRequest) (*ApproveLoanResponse, error)
 	RejectLoan(context.Context, *RejectLoanRequest) (*RejectLoanResponse, error)
+	WatchLoan(*WatchLoanRequest, grpc.ServerStreamingServer[LoanUpdate]) error
 	mustEmbedUnimplementedLoanServiceServer()
 }
 
@@ -101,6 +123,9 @@ func (UnimplementedLoanServiceServer) ApproveLoan(context.Context, *ApproveLoanR
 func (UnimplementedLoanServiceServer) RejectLoan(context.Context, *RejectLoanRequest) (*RejectLoanResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RejectLoan not implemented")
 }
+func (UnimplementedLoanServiceServer) WatchLoan(*WatchLoanRequest, grpc.ServerStreamingServer[LoanUpdate]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchLoan not implemented")
+}
 func (UnimplementedLoanServiceServer) mustEmbedUnimplementedLoanServiceServer() {}
 func (UnimplementedLoanServiceServer) testEmbeddedByValue()                     {}
 
@@ -176,6 +201,17 @@ func _LoanService_RejectLoan_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _LoanService_WatchLoan_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchLoanRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LoanServiceServer).WatchLoan(m, &grpc.GenericServerStream[WatchLoanRequest, LoanUpdate]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LoanService_WatchLoanServer = grpc.ServerStreamingServer[LoanUpdate]
+
 // LoanService_ServiceDesc is the grpc.ServiceDesc for LoanService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -196,6 +232,12 @@ var LoanService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _LoanService_RejectLoan_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchLoan",
+			Handler:       _LoanService_WatchLoan_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "loan.proto",
 }
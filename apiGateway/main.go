@@ -1,8 +1,11 @@
 package main
 
 import (
+	"strings"
+
 	"github.com/gin-gonic/gin"
 	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/middleware"
 	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/routes"
 )
 
@@ -17,9 +20,19 @@ func main() {
 	// Create a new Gin router
 	app := gin.New()
 
+	// Only trust X-Forwarded-For from our own proxies, since client IPs drive rate limiting and lockouts
+	var trustedProxies []string
+	if configs.Env.TRUSTED_PROXIES != "" {
+		trustedProxies = strings.Split(configs.Env.TRUSTED_PROXIES, ",")
+	}
+	if err := app.SetTrustedProxies(trustedProxies); err != nil {
+		panic("Invalid TRUSTED_PROXIES: " + err.Error())
+	}
+
 	// Apply middleware
 	app.Use(gin.Logger())
 	app.Use(gin.Recovery())
+	app.Use(middleware.RequestId)
 
 	// Set up routes
 	routes.Setup(app)
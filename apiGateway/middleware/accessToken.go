@@ -0,0 +1,271 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/grpcclient"
+	userPb "github.com/manlikehenryy/loan-management-system-grpc/apiGateway/user"
+)
+
+// tokenIssuer must match the iss claim userService puts in access tokens
+const tokenIssuer = "userService"
+
+const (
+	jwksRefreshInterval    = 5 * time.Minute
+	jwksMinRefreshInterval = 30 * time.Second
+	defaultSessionCacheTTL = 30 * time.Second
+	maxSessionCacheEntries = 10000
+)
+
+// accessClaims are the claims carried by an access token
+type accessClaims struct {
+	SessionId string   `json:"sid"`
+	Roles     []string `json:"roles"`
+	jwt.RegisteredClaims
+}
+
+type verificationKey struct {
+	alg string
+	key interface{}
+}
+
+// keySet caches userService's published signing keys
+type keySet struct {
+	mu        sync.RWMutex
+	keys      map[string]verificationKey
+	fetchedAt time.Time
+}
+
+type sessionStatus struct {
+	active    bool
+	expiresAt time.Time
+}
+
+// sessionCache remembers recent revocation checks so most requests skip the round-trip
+type sessionCache struct {
+	mu      sync.Mutex
+	entries map[string]sessionStatus
+}
+
+var (
+	signingKeys = &keySet{}
+	sessions    = &sessionCache{entries: map[string]sessionStatus{}}
+)
+
+// verifyAccessToken checks an access token's signature and claims without calling userService
+func verifyAccessToken(ctx context.Context, tokenString string) (*accessClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &accessClaims{}, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := signingKeys.get(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if key.alg != t.Method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.key, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	claims, ok := token.Claims.(*accessClaims)
+	if !ok || claims.Subject == "" || claims.SessionId == "" {
+		return nil, errors.New("invalid claims")
+	}
+
+	return claims, nil
+}
+
+// get returns the key with the given kid, refetching the JWKS when it is stale or the kid is new
+func (s *keySet) get(ctx context.Context, kid string) (verificationKey, error) {
+	s.mu.RLock()
+	key, ok := s.keys[kid]
+	age := time.Since(s.fetchedAt)
+	s.mu.RUnlock()
+
+	if (ok && age < jwksRefreshInterval) || (!ok && age < jwksMinRefreshInterval) {
+		if !ok {
+			return verificationKey{}, fmt.Errorf("unknown signing key %q", kid)
+		}
+		return key, nil
+	}
+
+	if err := s.refresh(ctx); err != nil {
+		// Keep using the keys we have if userService is briefly unreachable
+		if ok {
+			return key, nil
+		}
+		return verificationKey{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	return verificationKey{}, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (s *keySet) refresh(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Another request may have refreshed while we waited for the lock
+	if time.Since(s.fetchedAt) < jwksMinRefreshInterval {
+		return nil
+	}
+
+	userServiceClient, err := grpcclient.SharedUserServiceClient()
+	if err != nil {
+		return err
+	}
+
+	getJwksResp, err := userServiceClient.GetJwks(grpcclient.NewAuthContext(ctx, configs.Env.TOKEN), &userPb.GetJwksRequest{})
+	if err != nil {
+		return err
+	}
+	if !getJwksResp.Status {
+		return errors.New(getJwksResp.Message)
+	}
+
+	keys := map[string]verificationKey{}
+	for _, jwk := range getJwksResp.Keys {
+		key, err := parseJwk(jwk)
+		if err != nil {
+			return fmt.Errorf("key %s: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	return nil
+}
+
+func parseJwk(jwk *userPb.JsonWebKey) (verificationKey, error) {
+	switch {
+	case jwk.Kty == "RSA" && jwk.Alg == jwt.SigningMethodRS256.Alg():
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		return verificationKey{alg: jwk.Alg, key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}}, nil
+	case jwk.Kty == "OKP" && jwk.Crv == "Ed25519" && jwk.Alg == jwt.SigningMethodEdDSA.Alg():
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return verificationKey{}, errors.New("invalid Ed25519 key")
+		}
+		return verificationKey{alg: jwk.Alg, key: ed25519.PublicKey(x)}, nil
+	default:
+		return verificationKey{}, fmt.Errorf("unsupported key type %s/%s", jwk.Kty, jwk.Alg)
+	}
+}
+
+// sessionActive reports whether the session has not been revoked, asking userService at most once per TTL
+func sessionActive(ctx context.Context, sessionId string, userId string) (bool, error) {
+	cacheKey := userId + ":" + sessionId
+
+	sessions.mu.Lock()
+	status, ok := sessions.entries[cacheKey]
+	sessions.mu.Unlock()
+	if ok && time.Now().Before(status.expiresAt) {
+		return status.active, nil
+	}
+
+	userServiceClient, err := grpcclient.SharedUserServiceClient()
+	if err != nil {
+		return false, err
+	}
+
+	isSessionActiveResp, err := userServiceClient.IsSessionActive(grpcclient.NewAuthContext(ctx, configs.Env.TOKEN), &userPb.IsSessionActiveRequest{
+		SessionId: sessionId,
+		UserId:    userId,
+	})
+	if err != nil {
+		return false, err
+	}
+	if !isSessionActiveResp.Status {
+		return false, errors.New(isSessionActiveResp.Message)
+	}
+
+	sessions.set(cacheKey, isSessionActiveResp.Active)
+	return isSessionActiveResp.Active, nil
+}
+
+func (c *sessionCache) set(key string, active bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.entries) >= maxSessionCacheEntries {
+		for k, status := range c.entries {
+			if now.After(status.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	if len(c.entries) >= maxSessionCacheEntries {
+		c.entries = map[string]sessionStatus{}
+	}
+
+	c.entries[key] = sessionStatus{active: active, expiresAt: now.Add(sessionCacheTTL())}
+}
+
+// ForgetSession drops the cached revocation check and permission grants of a session revoked
+// through this gateway. The session may belong to another user when an admin revokes it.
+func ForgetSession(sessionId string) {
+	sessions.mu.Lock()
+	for key := range sessions.entries {
+		if strings.HasSuffix(key, ":"+sessionId) {
+			delete(sessions.entries, key)
+		}
+	}
+	sessions.mu.Unlock()
+
+	forgetSessionPermissions(sessionId)
+}
+
+// ForgetUserSessions drops the cached revocation checks of every session belonging to a user,
+// so a suspension made through this gateway takes effect at once
+func ForgetUserSessions(userId string) {
+	sessions.mu.Lock()
+	defer sessions.mu.Unlock()
+
+	for key := range sessions.entries {
+		if strings.HasPrefix(key, userId+":") {
+			delete(sessions.entries, key)
+		}
+	}
+}
+
+func sessionCacheTTL() time.Duration {
+	seconds, err := strconv.Atoi(configs.Env.SESSION_CACHE_TTL)
+	if err != nil || seconds < 0 {
+		return defaultSessionCacheTTL
+	}
+	return time.Duration(seconds) * time.Second
+}
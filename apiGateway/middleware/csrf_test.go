@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/helpers"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	configs.Env.CSRF_SECRET = "test-csrf-secret"
+}
+
+func csrfToken(t *testing.T, sessionId string) string {
+	t.Helper()
+	token, err := helpers.CSRFToken(sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestCSRFProtect(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		authMethod string
+		sessionId  string
+		token      string
+		want       int
+	}{
+		{name: "cookie POST without token", method: http.MethodPost, authMethod: "cookie", sessionId: "session-a", want: http.StatusForbidden},
+		{name: "cookie POST with wrong token", method: http.MethodPost, authMethod: "cookie", sessionId: "session-a", token: "not-the-token", want: http.StatusForbidden},
+		{name: "cookie POST with valid token", method: http.MethodPost, authMethod: "cookie", sessionId: "session-a", token: csrfToken(t, "session-a"), want: http.StatusOK},
+		{name: "cookie POST with another session's token", method: http.MethodPost, authMethod: "cookie", sessionId: "session-a", token: csrfToken(t, "session-b"), want: http.StatusForbidden},
+		{name: "cookie POST without a session", method: http.MethodPost, authMethod: "cookie", token: csrfToken(t, ""), want: http.StatusForbidden},
+		{name: "cookie DELETE without token", method: http.MethodDelete, authMethod: "cookie", sessionId: "session-a", want: http.StatusForbidden},
+		{name: "cookie GET without token", method: http.MethodGet, authMethod: "cookie", sessionId: "session-a", want: http.StatusOK},
+		{name: "bearer POST without token", method: http.MethodPost, authMethod: "bearer", sessionId: "session-a", want: http.StatusOK},
+		{name: "API key POST without token", method: http.MethodPost, authMethod: "api_key", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				c.Set("authMethod", tt.authMethod)
+				c.Set("sessionId", tt.sessionId)
+			})
+			router.Handle(tt.method, "/", CSRFProtect, func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tt.method, "/", nil)
+			if tt.token != "" {
+				req.Header.Set(CSRFHeader, tt.token)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("got status %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefreshCookieCSRF(t *testing.T) {
+	tests := []struct {
+		name         string
+		refreshToken string
+		token        string
+		want         int
+	}{
+		{name: "refresh cookie without token", refreshToken: "session-a.secret", want: http.StatusForbidden},
+		{name: "refresh cookie with wrong token", refreshToken: "session-a.secret", token: "not-the-token", want: http.StatusForbidden},
+		{name: "refresh cookie with another session's token", refreshToken: "session-a.secret", token: csrfToken(t, "session-b"), want: http.StatusForbidden},
+		{name: "refresh cookie with valid token", refreshToken: "session-a.secret", token: csrfToken(t, "session-a"), want: http.StatusOK},
+		{name: "no refresh cookie", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/api/refresh-token", RefreshCookie, CSRFProtect, func(c *gin.Context) {
+				if c.GetString("refreshToken") != tt.refreshToken {
+					t.Errorf("got refresh token %q, want %q", c.GetString("refreshToken"), tt.refreshToken)
+				}
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/refresh-token", nil)
+			if tt.refreshToken != "" {
+				req.AddCookie(&http.Cookie{Name: "refresh_token", Value: tt.refreshToken})
+			}
+			if tt.token != "" {
+				req.Header.Set(CSRFHeader, tt.token)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("got status %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,10 @@
 package middleware
 
 import (
-	"context"
 	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/configs"
@@ -13,45 +14,209 @@ import (
 )
 
 func IsAuthenticated(c *gin.Context) {
-	// Retrieve the JWT token from the cookie
-	token, err := c.Cookie("jwt")
+	if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+		authenticateApiKey(c, apiKey)
+		return
+	}
+
+	// Native and server-to-server clients send the token in the Authorization header; browsers use the cookie
+	authMethod := "bearer"
+	token, ok := bearerToken(c)
+	if !ok {
+		authMethod = "cookie"
+		cookie, err := c.Cookie("jwt")
+		if err != nil || cookie == "" {
+			helpers.SendError(c, http.StatusUnauthorized, "Unauthorized: No JWT token provided")
+			c.Abort() // Abort the request pipeline if authentication fails
+			return
+		}
+		token = cookie
+	}
+
+	// Verify the signature locally against the published signing keys
+	claims, err := verifyAccessToken(c, token)
+	if err != nil {
+		helpers.SendError(c, http.StatusUnauthorized, "Unauthorized: Invalid JWT token")
+		c.Abort()
+		return
+	}
+
+	active, err := sessionActive(c, claims.SessionId, claims.Subject)
+	if err != nil {
+		log.Println("Failed to check session:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		c.Abort()
+		return
+	}
+	if !active {
+		helpers.SendError(c, http.StatusUnauthorized, "Unauthorized: Session revoked")
+		c.Abort()
+		return
+	}
+
+	// Sign the identity assertion forwarded to downstream services
+	identity, err := helpers.SignIdentity(claims.Subject, claims.Roles, "", c.GetString("requestId"))
 	if err != nil {
-		helpers.SendError(c, http.StatusUnauthorized, "Unauthorized: No JWT token provided")
-		c.Abort() // Abort the request pipeline if authentication fails
+		log.Println("Failed to sign identity:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		c.Abort()
 		return
 	}
 
-	// Initialize the gRPC client
-	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(c)
+	// Store the user ID in the request context
+	c.Set("userId", claims.Subject)
+	c.Set("roles", claims.Roles)
+	c.Set("sessionId", claims.SessionId)
+	c.Set("authMethod", authMethod)
+	c.Set("identity", identity)
+
+	// Continue to the next middleware or handler
+	c.Next()
+}
+
+// authenticateApiKey authenticates a partner request. Keys are only valid for the routes they were issued for.
+func authenticateApiKey(c *gin.Context, apiKey string) {
+	userServiceClient, err := grpcclient.SharedUserServiceClient()
 	if err != nil {
-		log.Fatalf("Failed to connect to UserService: %v", err)
+		log.Println("Failed to connect to UserService:", err)
 		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		c.Abort()
 		return
 	}
-	defer cleanup()
 
 	// Set up the context with authorization metadata
-	ctx := grpcclient.NewAuthContext(context.Background(), configs.Env.TOKEN)
+	ctx := grpcclient.NewAuthContext(c, configs.Env.TOKEN)
 
-	verifyTokenReq := &userPb.VerifyTokenRequest{
-		Token: token,
+	verifyApiKeyReq := &userPb.VerifyApiKeyRequest{
+		ApiKey:    apiKey,
+		Method:    c.Request.Method,
+		Route:     c.FullPath(),
+		IpAddress: c.ClientIP(),
 	}
 
-	verifyTokenResp, err_ := userServiceClient.VerifyToken(ctx, verifyTokenReq)
+	verifyApiKeyResp, err_ := userServiceClient.VerifyApiKey(ctx, verifyApiKeyReq)
 
-	if verifyTokenResp == nil {
+	if verifyApiKeyResp == nil {
+		log.Println("Error in VerifyApiKey call:", err_)
 		helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+		c.Abort()
 		return
 	}
 
-	if err_ != nil || !verifyTokenResp.Valid {
-		helpers.SendError(c, int(verifyTokenResp.StatusCode), verifyTokenResp.Message)
+	if err_ != nil || !verifyApiKeyResp.Valid {
+		helpers.SendError(c, int(verifyApiKeyResp.StatusCode), verifyApiKeyResp.Message)
+		c.Abort()
 		return
 	}
 
-	// Store the user ID in the request context
-	c.Set("userId", verifyTokenResp.UserId)
+	// Sign the identity assertion forwarded to downstream services
+	identity, err := helpers.SignIdentity(verifyApiKeyResp.UserId, verifyApiKeyResp.Roles, verifyApiKeyResp.KeyId, c.GetString("requestId"))
+	if err != nil {
+		log.Println("Failed to sign identity:", err)
+		helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+		c.Abort()
+		return
+	}
+
+	c.Set("userId", verifyApiKeyResp.UserId)
+	c.Set("roles", verifyApiKeyResp.Roles)
+	c.Set("apiKeyId", verifyApiKeyResp.KeyId)
+	c.Set("authMethod", "api_key")
+	c.Set("identity", identity)
 
-	// Continue to the next middleware or handler
 	c.Next()
 }
+
+// bearerToken returns the token from an "Authorization: Bearer" header
+func bearerToken(c *gin.Context) (string, bool) {
+	scheme, token, found := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// grantedPermissions remembers recent permission grants, keyed by user, session, API key and permission, so
+// most requests to a guarded route skip the round-trip. Denials aren't cached, so a user who is
+// just granted a permission can use it at once.
+var grantedPermissions = &sessionCache{entries: map[string]sessionStatus{}}
+
+// RequirePermission only lets the request through if the authenticated user has the given permission.
+// A grant is cached for as long as a session check, so a permission taken away can keep working on
+// other gateway instances for up to that long.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cacheKey := c.GetString("userId") + ":" + c.GetString("sessionId") + ":" + c.GetString("apiKeyId") + ":" + permission
+		if permissionGranted(cacheKey) {
+			c.Next()
+			return
+		}
+
+		userServiceClient, err := grpcclient.SharedUserServiceClient()
+		if err != nil {
+			log.Println("Failed to connect to UserService:", err)
+			helpers.SendError(c, http.StatusInternalServerError, "Internal service error")
+			c.Abort()
+			return
+		}
+
+		// Set up the context with authorization metadata
+		ctx := grpcclient.NewIdentityContext(c, configs.Env.TOKEN, c.GetString("identity"))
+
+		checkPermissionReq := &userPb.CheckPermissionRequest{
+			UserId:     c.GetString("userId"),
+			Permission: permission,
+		}
+
+		checkPermissionResp, err_ := userServiceClient.CheckPermission(ctx, checkPermissionReq)
+
+		if checkPermissionResp == nil {
+			log.Println("Error in CheckPermission call:", err_)
+			helpers.SendError(c, http.StatusInternalServerError, "Unexpected service response")
+			c.Abort()
+			return
+		}
+
+		if err_ != nil || !checkPermissionResp.Allowed {
+			helpers.SendError(c, int(checkPermissionResp.StatusCode), checkPermissionResp.Message)
+			c.Abort()
+			return
+		}
+
+		grantedPermissions.set(cacheKey, true)
+		c.Next()
+	}
+}
+
+func permissionGranted(cacheKey string) bool {
+	grantedPermissions.mu.Lock()
+	defer grantedPermissions.mu.Unlock()
+
+	status, ok := grantedPermissions.entries[cacheKey]
+	return ok && status.active && time.Now().Before(status.expiresAt)
+}
+
+// ForgetPermissions drops the cached grants of a user whose role or status was changed through this gateway
+func ForgetPermissions(userId string) {
+	grantedPermissions.mu.Lock()
+	defer grantedPermissions.mu.Unlock()
+
+	for key := range grantedPermissions.entries {
+		if strings.HasPrefix(key, userId+":") {
+			delete(grantedPermissions.entries, key)
+		}
+	}
+}
+
+// forgetSessionPermissions drops the cached grants made to requests on a revoked session
+func forgetSessionPermissions(sessionId string) {
+	grantedPermissions.mu.Lock()
+	defer grantedPermissions.mu.Unlock()
+
+	for key := range grantedPermissions.entries {
+		if parts := strings.SplitN(key, ":", 3); len(parts) == 3 && parts[1] == sessionId {
+			delete(grantedPermissions.entries, key)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import "testing"
+
+func resetCaches() {
+	sessions.entries = map[string]sessionStatus{}
+	grantedPermissions.entries = map[string]sessionStatus{}
+}
+
+func TestForgetSession(t *testing.T) {
+	resetCaches()
+	sessions.set("user-a:session-a", true)
+	sessions.set("user-a:session-b", true)
+	grantedPermissions.set("user-a:session-a::loan.approve", true)
+	grantedPermissions.set("user-a:session-b::loan.approve", true)
+
+	// An admin revoking someone else's session doesn't know whose it is
+	ForgetSession("session-a")
+
+	if _, ok := sessions.entries["user-a:session-a"]; ok {
+		t.Error("revoked session is still cached")
+	}
+	if permissionGranted("user-a:session-a::loan.approve") {
+		t.Error("revoked session still has a cached grant")
+	}
+	if _, ok := sessions.entries["user-a:session-b"]; !ok {
+		t.Error("other session was forgotten")
+	}
+	if !permissionGranted("user-a:session-b::loan.approve") {
+		t.Error("other session's grant was forgotten")
+	}
+}
+
+func TestForgetUser(t *testing.T) {
+	resetCaches()
+	sessions.set("user-a:session-a", true)
+	sessions.set("user-b:session-b", true)
+	grantedPermissions.set("user-a:session-a::loan.approve", true)
+	grantedPermissions.set("user-b:session-b::loan.approve", true)
+
+	ForgetPermissions("user-a")
+	ForgetUserSessions("user-a")
+
+	if _, ok := sessions.entries["user-a:session-a"]; ok {
+		t.Error("suspended user's session is still cached")
+	}
+	if permissionGranted("user-a:session-a::loan.approve") {
+		t.Error("suspended user still has a cached grant")
+	}
+	if _, ok := sessions.entries["user-b:session-b"]; !ok {
+		t.Error("another user's session was forgotten")
+	}
+	if !permissionGranted("user-b:session-b::loan.approve") {
+		t.Error("another user's grant was forgotten")
+	}
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/helpers"
+)
+
+// rateLimitCleanupInterval is how often idle clients are dropped from a limiter
+const rateLimitCleanupInterval = 10 * time.Minute
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// rateLimiter is an in-memory token bucket per client IP
+type rateLimiter struct {
+	mu          sync.Mutex
+	buckets     map[string]*bucket
+	burst       float64
+	refillRate  float64 // tokens per second
+	lastCleanup time.Time
+}
+
+// RateLimit allows each client IP up to limit requests per period, with bursts of up to limit
+func RateLimit(limit int, period time.Duration) gin.HandlerFunc {
+	limiter := &rateLimiter{
+		buckets:     map[string]*bucket{},
+		burst:       float64(limit),
+		refillRate:  float64(limit) / period.Seconds(),
+		lastCleanup: time.Now(),
+	}
+
+	return func(c *gin.Context) {
+		allowed, retryAfter := limiter.take(c.ClientIP())
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			helpers.SendError(c, http.StatusTooManyRequests, "Too many requests, please try again later")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// take spends a token for key, or returns how long until one is available
+func (l *rateLimiter) take(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastCleanup) > rateLimitCleanupInterval {
+		for k, b := range l.buckets {
+			if now.Sub(b.lastSeen).Seconds()*l.refillRate >= l.burst {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastCleanup = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, lastSeen: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.lastSeen).Seconds()*l.refillRate)
+	b.lastSeen = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.refillRate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
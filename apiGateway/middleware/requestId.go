@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestId tags every request with an ID, reusing the caller's X-Request-ID when present
+func RequestId(c *gin.Context) {
+	requestId := c.GetHeader("X-Request-ID")
+	if requestId == "" || len(requestId) > 64 {
+		b := make([]byte, 16)
+		rand.Read(b)
+		requestId = hex.EncodeToString(b)
+	}
+
+	c.Set("requestId", requestId)
+	c.Header("X-Request-ID", requestId)
+
+	c.Next()
+}
@@ -1,6 +1,8 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/controllers"
 	"github.com/manlikehenryy/loan-management-system-grpc/apiGateway/middleware"
@@ -9,13 +11,84 @@ import (
 func Setup(app *gin.Engine) {
 
 
-	app.POST("/api/register", controllers.Register)
-	app.POST("/api/login", controllers.Login)
-	app.GET("/api/logout", controllers.Logout)
+	app.GET("/.well-known/jwks.json", controllers.Jwks)
+
+	app.POST("/api/register", middleware.RateLimit(5, time.Minute), controllers.Register)
+	app.POST("/api/login", middleware.RateLimit(10, time.Minute), controllers.Login)
+	app.POST("/api/login/mfa", middleware.RateLimit(10, time.Minute), controllers.LoginMfa)
+	app.POST("/api/password/reset-request", middleware.RateLimit(5, time.Minute), controllers.RequestPasswordReset)
+	app.POST("/api/password/reset", middleware.RateLimit(10, time.Minute), controllers.ConfirmPasswordReset)
+	app.POST("/api/refresh-token", middleware.RefreshCookie, middleware.CSRFProtect, controllers.RefreshToken)
+	app.POST("/api/logout", middleware.RefreshCookie, middleware.CSRFProtect, controllers.Logout)
+
+	app.POST("/api/payments/webhook/:provider", controllers.PaymentWebhook)
 
 	app.Use(middleware.IsAuthenticated)
+	app.Use(middleware.CSRFProtect)
+
+	app.GET("/api/csrf-token", controllers.CSRFToken)
+
+	app.GET("/api/sessions", controllers.ListSessions)
+	app.DELETE("/api/sessions/:id", controllers.RevokeSession)
+
+	app.POST("/api/password/change", controllers.ChangePassword)
+
+	app.GET("/api/profile", controllers.GetProfile)
+	app.PATCH("/api/profile", controllers.UpdateProfile)
+
+	app.POST("/api/verification/send", controllers.SendVerificationCode)
+	app.POST("/api/verification/verify", middleware.RateLimit(10, time.Minute), controllers.VerifyContact)
+
+	app.GET("/api/notifications/preferences", controllers.GetNotificationPreferences)
+	app.PUT("/api/notifications/preferences", controllers.UpdateNotificationPreferences)
+	app.GET("/api/notifications", controllers.ListNotifications)
+	app.POST("/api/notifications/read", controllers.MarkNotificationsRead)
+	app.GET("/api/notifications/stream", controllers.StreamNotifications)
+
+	app.GET("/api/wallet", controllers.GetWallet)
+	app.GET("/api/wallet/statement", controllers.GetStatement)
+	app.POST("/api/wallet/top-ups", controllers.InitiateTopUp)
+	app.GET("/api/wallet/top-ups/:id", controllers.GetTopUp)
+	app.POST("/api/wallet/bank-accounts", controllers.AddBankAccount)
+	app.GET("/api/wallet/bank-accounts", controllers.ListBankAccounts)
+	app.DELETE("/api/wallet/bank-accounts/:id", controllers.RemoveBankAccount)
+	app.POST("/api/wallet/withdrawals", controllers.RequestWithdrawal)
+	app.GET("/api/wallet/withdrawals", controllers.ListWithdrawals)
+	app.GET("/api/wallet/withdrawals/:id", controllers.GetWithdrawal)
+
+	app.POST("/api/kyc/documents", controllers.UploadKycDocument)
+	app.GET("/api/kyc/documents", controllers.ListKycDocuments)
+	app.GET("/api/kyc/documents/:id/file", controllers.GetKycDocumentFile)
+
+	app.POST("/api/mfa/enroll", controllers.EnrollMfa)
+	app.POST("/api/mfa/verify", controllers.ConfirmMfa)
+	app.POST("/api/mfa/disable", controllers.DisableMfa)
 
 	app.POST("/api/loan/apply-loan", controllers.ApplyLoan)
-	app.PUT("/api/loan/approve-loan", controllers.ApproveLoan)
-	app.PUT("/api/loan/reject-loan", controllers.RejectLoan)
+	app.PUT("/api/loan/approve-loan", middleware.RequirePermission("loan.approve"), controllers.ApproveLoan)
+	app.PUT("/api/loan/reject-loan", middleware.RequirePermission("loan.reject"), controllers.RejectLoan)
+
+	app.GET("/api/admin/users", middleware.RequirePermission("user.view"), controllers.ListUsers)
+	app.GET("/api/admin/users/:id", middleware.RequirePermission("user.view"), controllers.GetUser)
+	app.GET("/api/admin/users/:id/profile", middleware.RequirePermission("user.view"), controllers.GetUserProfile)
+	app.PUT("/api/admin/users/:id/role", middleware.RequirePermission("user.manage"), controllers.UpdateUserRole)
+	app.PUT("/api/admin/users/:id/suspend", middleware.RequirePermission("user.manage"), controllers.SuspendUser)
+	app.PUT("/api/admin/users/:id/reactivate", middleware.RequirePermission("user.manage"), controllers.ReactivateUser)
+	app.PUT("/api/admin/users/:id/unlock", middleware.RequirePermission("user.manage"), controllers.UnlockUser)
+
+	app.GET("/api/admin/users/:id/kyc", middleware.RequirePermission("kyc.review"), controllers.ListUserKycDocuments)
+	app.GET("/api/admin/kyc/queue", middleware.RequirePermission("kyc.review"), controllers.ListKycReviewQueue)
+	app.PUT("/api/admin/kyc/documents/:id/review", middleware.RequirePermission("kyc.review"), controllers.ReviewKycDocument)
+
+	app.POST("/api/admin/wallets/:id/holds", middleware.RequirePermission("wallet.adjust"), controllers.PlaceHold)
+	app.POST("/api/admin/wallets/:id/debit", middleware.RequirePermission("wallet.adjust"), controllers.DebitWallet)
+	app.POST("/api/admin/holds/:id/capture", middleware.RequirePermission("wallet.adjust"), controllers.CaptureHold)
+	app.POST("/api/admin/holds/:id/release", middleware.RequirePermission("wallet.adjust"), controllers.ReleaseHold)
+
+	app.GET("/api/admin/withdrawals", middleware.RequirePermission("wallet.view_any"), controllers.ListAllWithdrawals)
+	app.PUT("/api/admin/withdrawals/:id/resolve", middleware.RequirePermission("withdrawal.resolve"), controllers.ResolveWithdrawal)
+
+	app.POST("/api/admin/api-keys", middleware.RequirePermission("apikey.manage"), controllers.CreateApiKey)
+	app.GET("/api/admin/api-keys", middleware.RequirePermission("apikey.manage"), controllers.ListApiKeys)
+	app.DELETE("/api/admin/api-keys/:id", middleware.RequirePermission("apikey.manage"), controllers.RevokeApiKey)
 }
\ No newline at end of file
@@ -0,0 +1,196 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Message statuses
+const (
+	MessagePending    = "pending"
+	MessageProcessing = "processing"
+	MessageDead       = "dead" // Gave up after mongoMaxAttempts; kept for inspection
+)
+
+const (
+	mongoPollInterval = time.Second
+	mongoLease        = time.Minute
+	mongoRetryDelay   = 5 * time.Second // Doubled after each failed attempt
+	mongoMaxAttempts  = 10
+	duplicateKeyCode  = 11000
+)
+
+// mongoSubscription records that a group wants events of the given types. Events are queued for
+// the groups in consumerGroups and for any other group that had subscribed when they were
+// published.
+type mongoSubscription struct {
+	Group      string    `bson:"_id"`
+	EventTypes []string  `bson:"eventTypes"`
+	UpdatedAt  time.Time `bson:"updatedAt"`
+}
+
+// mongoMessage is an event waiting to be processed by one group. Its ID combines the group and
+// the event ID, so publishing an event again doesn't queue it twice.
+type mongoMessage struct {
+	ID            string    `bson:"_id"`
+	Group         string    `bson:"group"`
+	Event         Event     `bson:"event"`
+	Status        string    `bson:"status"`
+	Attempts      int       `bson:"attempts"`
+	LastError     string    `bson:"lastError,omitempty"`
+	NextAttemptAt time.Time `bson:"nextAttemptAt"`
+	LeaseUntil    time.Time `bson:"leaseUntil,omitempty"` // While processing, so another instance doesn't pick it up
+	CreatedAt     time.Time `bson:"createdAt"`
+}
+
+// MongoBroker keeps a queue per subscription group in a database every service shares. A message
+// is removed once its handler succeeds, and a message whose handler dies with it is picked up
+// again when its lease runs out.
+type MongoBroker struct {
+	db *mongo.Database
+}
+
+func NewMongoBroker(dbName string) *MongoBroker {
+	return &MongoBroker{db: database.Client.Database(dbName)}
+}
+
+func (b *MongoBroker) Publish(ctx context.Context, event Event) error {
+	cursor, err := b.db.Collection("subscriptions").Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	var subscriptions []mongoSubscription
+	if err := cursor.All(ctx, &subscriptions); err != nil {
+		return err
+	}
+
+	groups := map[string]bool{}
+	for group, eventTypes := range consumerGroups {
+		if subscribed(eventTypes, event.Type) {
+			groups[group] = true
+		}
+	}
+	for _, subscription := range subscriptions {
+		if subscribed(subscription.EventTypes, event.Type) {
+			groups[subscription.Group] = true
+		}
+	}
+
+	now := time.Now()
+	var messages []interface{}
+	for group := range groups {
+		messages = append(messages, mongoMessage{
+			ID:            group + "/" + event.ID,
+			Group:         group,
+			Event:         event,
+			Status:        MessagePending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	_, err = b.db.Collection("messages").InsertMany(ctx, messages, options.InsertMany().SetOrdered(false))
+	if err != nil && !onlyDuplicates(err) {
+		return err
+	}
+	return nil
+}
+
+func (b *MongoBroker) Subscribe(ctx context.Context, group string, eventTypes []string, handler Handler) error {
+	_, err := b.db.Collection("subscriptions").UpdateOne(ctx,
+		bson.M{"_id": group},
+		bson.M{"$set": bson.M{"eventTypes": eventTypes, "updatedAt": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			b.consume(ctx, group, handler)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(mongoPollInterval):
+			}
+		}
+	}()
+	return nil
+}
+
+// consume processes group's due messages until there are none left
+func (b *MongoBroker) consume(ctx context.Context, group string, handler Handler) {
+	messagesCollection := b.db.Collection("messages")
+
+	for ctx.Err() == nil {
+		now := time.Now()
+
+		var message mongoMessage
+		err := messagesCollection.FindOneAndUpdate(ctx,
+			bson.M{"group": group, "$or": []bson.M{
+				{"status": MessagePending, "nextAttemptAt": bson.M{"$lte": now}},
+				{"status": MessageProcessing, "leaseUntil": bson.M{"$lt": now}},
+			}},
+			bson.M{"$set": bson.M{"status": MessageProcessing, "leaseUntil": now.Add(mongoLease)}},
+			options.FindOneAndUpdate().SetSort(bson.M{"nextAttemptAt": 1}).SetReturnDocument(options.After),
+		).Decode(&message)
+		if err != nil {
+			if err != mongo.ErrNoDocuments {
+				log.Println("Failed to claim message:", err)
+			}
+			return
+		}
+
+		if err := handler(ctx, message.Event); err != nil {
+			b.retry(ctx, &message, err)
+			continue
+		}
+
+		if _, err := messagesCollection.DeleteOne(ctx, bson.M{"_id": message.ID}); err != nil {
+			log.Println("Failed to acknowledge message:", err)
+		}
+	}
+}
+
+// retry schedules a failed message again, or gives up on it after mongoMaxAttempts
+func (b *MongoBroker) retry(ctx context.Context, message *mongoMessage, handlerErr error) {
+	update := bson.M{"attempts": message.Attempts + 1, "lastError": handlerErr.Error()}
+	if message.Attempts+1 >= mongoMaxAttempts {
+		log.Printf("Giving up on %s event %s for %s: %v", message.Event.Type, message.Event.ID, message.Group, handlerErr)
+		update["status"] = MessageDead
+	} else {
+		update["status"] = MessagePending
+		update["nextAttemptAt"] = time.Now().Add(mongoRetryDelay << message.Attempts)
+	}
+
+	if _, err := b.db.Collection("messages").UpdateOne(ctx, bson.M{"_id": message.ID}, bson.M{"$set": update}); err != nil {
+		log.Println("Failed to record message failure:", err)
+	}
+}
+
+// onlyDuplicates reports whether every write in a failed bulk insert failed on a duplicate key
+func onlyDuplicates(err error) bool {
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil || len(bulkErr.WriteErrors) == 0 {
+		return false
+	}
+	for _, writeErr := range bulkErr.WriteErrors {
+		if writeErr.Code != duplicateKeyCode {
+			return false
+		}
+	}
+	return true
+}
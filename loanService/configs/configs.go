@@ -8,12 +8,26 @@ import (
 )
 
 type Config struct {
-	PORT               string
-	MONGO_DB_URI       string
-	MODE               string
-	TOKEN              string
-	USER_SERVICE_URL   string
-	WALLET_SERVICE_URL string
+	PORT                    string
+	MONGO_DB_URI            string
+	MODE                    string
+	TOKEN                   string
+	IDENTITY_SECRET         string
+	TLS_ENABLED             string
+	TLS_CERT_FILE           string
+	TLS_KEY_FILE            string
+	TLS_CA_FILE             string
+	TLS_DEV_MODE            string
+	TLS_DEV_DIR             string
+	POLICY_FILE             string
+	USER_SERVICE_URL        string
+	WALLET_SERVICE_URL      string
+	LOAN_APPROVAL_LIMIT     string
+	KYC_TIER1_MAX_AMOUNT    string
+	REPAYMENT_REMINDER_DAYS string
+	BROKER                  string
+	BROKER_DB               string
+	TRUST_DECLARED_CALLER   string
 }
 
 var Env *Config
@@ -34,6 +48,20 @@ func init() {
 	Env.MONGO_DB_URI = os.Getenv("MONGO_DB_URI")
 	Env.MODE = os.Getenv("MODE")
 	Env.TOKEN = os.Getenv("TOKEN")
+	Env.IDENTITY_SECRET = os.Getenv("IDENTITY_SECRET")
+	Env.TLS_ENABLED = os.Getenv("TLS_ENABLED")
+	Env.TLS_CERT_FILE = os.Getenv("TLS_CERT_FILE")
+	Env.TLS_KEY_FILE = os.Getenv("TLS_KEY_FILE")
+	Env.TLS_CA_FILE = os.Getenv("TLS_CA_FILE")
+	Env.TLS_DEV_MODE = os.Getenv("TLS_DEV_MODE")
+	Env.TLS_DEV_DIR = os.Getenv("TLS_DEV_DIR")
+	Env.POLICY_FILE = os.Getenv("POLICY_FILE")
 	Env.USER_SERVICE_URL = os.Getenv("USER_SERVICE_URL")
 	Env.WALLET_SERVICE_URL = os.Getenv("WALLET_SERVICE_URL")
+	Env.LOAN_APPROVAL_LIMIT = os.Getenv("LOAN_APPROVAL_LIMIT")
+	Env.KYC_TIER1_MAX_AMOUNT = os.Getenv("KYC_TIER1_MAX_AMOUNT")
+	Env.REPAYMENT_REMINDER_DAYS = os.Getenv("REPAYMENT_REMINDER_DAYS")
+	Env.BROKER = os.Getenv("BROKER")
+	Env.BROKER_DB = os.Getenv("BROKER_DB")
+	Env.TRUST_DECLARED_CALLER = os.Getenv("TRUST_DECLARED_CALLER")
 }
@@ -4,17 +4,22 @@ import (
 	"context"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/manlikehenryy/loan-management-system-grpc/loanService/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/helpers"
 	walletPb "github.com/manlikehenryy/loan-management-system-grpc/loanService/wallet" // Import your generated proto package
 )
 
 // NewWalletServiceClient initializes a new WalletServiceClient with context
 func NewWalletServiceClient(ctx context.Context) (walletPb.WalletServiceClient, func(), error) {
 
+	creds, err := helpers.ClientCredentials()
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Establish the connection to the UserService
-	conn, err := grpc.NewClient(configs.Env.WALLET_SERVICE_URL, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(configs.Env.WALLET_SERVICE_URL, grpc.WithTransportCredentials(creds))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -1,13 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net"
 
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/broker"
 	"github.com/manlikehenryy/loan-management-system-grpc/loanService/configs"
 	"github.com/manlikehenryy/loan-management-system-grpc/loanService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/helpers"
 	pb "github.com/manlikehenryy/loan-management-system-grpc/loanService/loan"
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/outbox"
 	"github.com/manlikehenryy/loan-management-system-grpc/loanService/service"
 
 	"google.golang.org/grpc"
@@ -29,7 +33,34 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
-	s := grpc.NewServer(grpc.UnaryInterceptor(service.TokenInterceptor))
+
+	policyFile := configs.Env.POLICY_FILE
+	if policyFile == "" {
+		policyFile = "policy.json"
+	}
+	if err := service.LoadPolicy(policyFile); err != nil {
+		log.Fatalf("Failed to load authorization policy: %v", err)
+	}
+
+	creds, err := helpers.ServerCredentials()
+	if err != nil {
+		log.Fatalf("Failed to load TLS credentials: %v", err)
+	}
+
+	eventBroker, err := broker.New()
+	if err != nil {
+		log.Fatalf("Failed to set up event broker: %v", err)
+	}
+	// The broker only queues events for undeclared groups once they have subscribed, so every
+	// subscription is registered before the relay starts publishing
+	if err := service.StartDisbursements(context.Background(), eventBroker); err != nil {
+		log.Fatalf("Failed to start loan disbursements: %v", err)
+	}
+	outbox.StartRelay(context.Background(), eventBroker)
+
+	service.StartRepaymentReminders(context.Background())
+
+	s := grpc.NewServer(grpc.Creds(creds), grpc.UnaryInterceptor(service.TokenInterceptor), grpc.StreamInterceptor(service.TokenStreamInterceptor))
 	pb.RegisterLoanServiceServer(s, service.NewLoanServiceServer())
 
 	fmt.Printf("Loan Service running on port %s...", port)
@@ -0,0 +1,91 @@
+package outbox
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/broker"
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrInvalidResumeToken means a resume token is malformed, or so old that MongoDB no longer has
+// the events after it. The watcher has to start again from the current state.
+var ErrInvalidResumeToken = errors.New("invalid or expired resume token")
+
+// MongoDB error codes for a change stream that can't be resumed
+const (
+	invalidResumeTokenCode      = 260
+	changeStreamHistoryLostCode = 286
+)
+
+// Watcher follows the events recorded to the outbox, as they are committed. It reads from a
+// MongoDB change stream, which only moves on when Next is called, so a slow reader holds the
+// stream back rather than having events queue up in memory.
+type Watcher struct {
+	stream *mongo.ChangeStream
+}
+
+// Watch follows the outbox events whose fields match filter, e.g. {"aggregateId": loanId}.
+// Given a resume token from an earlier watcher, it starts with the first event after it.
+func Watch(ctx context.Context, filter bson.M, resumeToken string) (*Watcher, error) {
+	match := bson.M{"operationType": "insert"}
+	for field, value := range filter {
+		match["fullDocument."+field] = value
+	}
+
+	streamOptions := options.ChangeStream()
+	if resumeToken != "" {
+		token, err := base64.RawURLEncoding.DecodeString(resumeToken)
+		if err != nil || bson.Raw(token).Validate() != nil {
+			return nil, ErrInvalidResumeToken
+		}
+		streamOptions.SetStartAfter(bson.Raw(token))
+	}
+
+	stream, err := database.GetCollection("outbox").Watch(ctx, mongo.Pipeline{{{Key: "$match", Value: match}}}, streamOptions)
+	if err != nil {
+		var serverErr mongo.ServerError
+		if resumeToken != "" && errors.As(err, &serverErr) &&
+			(serverErr.HasErrorCode(invalidResumeTokenCode) || serverErr.HasErrorCode(changeStreamHistoryLostCode)) {
+			return nil, ErrInvalidResumeToken
+		}
+		return nil, err
+	}
+	return &Watcher{stream: stream}, nil
+}
+
+// Next waits for the next event. It returns ctx's error once ctx is done.
+func (w *Watcher) Next(ctx context.Context) (broker.Event, error) {
+	if !w.stream.Next(ctx) {
+		if err := w.stream.Err(); err != nil {
+			return broker.Event{}, err
+		}
+		return broker.Event{}, ctx.Err()
+	}
+
+	var change struct {
+		FullDocument record `bson:"fullDocument"`
+	}
+	if err := w.stream.Decode(&change); err != nil {
+		return broker.Event{}, err
+	}
+	return change.FullDocument.Event, nil
+}
+
+// ResumeToken returns a token for resuming after the last event Next returned, or from when the
+// watcher started if it hasn't returned one
+func (w *Watcher) ResumeToken() string {
+	token := w.stream.ResumeToken()
+	if token == nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(token)
+}
+
+func (w *Watcher) Close(ctx context.Context) error {
+	return w.stream.Close(ctx)
+}
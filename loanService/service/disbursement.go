@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/broker"
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/grpcclient"
+	walletPb "github.com/manlikehenryy/loan-management-system-grpc/loanService/wallet"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// disbursementGroup is the broker subscription every loanService instance shares to pay out
+// approved loans
+const disbursementGroup = "loanService.disbursement"
+
+const (
+	disbursementSweepInterval = 10 * time.Minute
+	// disbursementSweepDelay is longer than the broker spends retrying an event before giving up
+	// on it, so the sweep only picks up loans the broker has stopped trying to pay out
+	disbursementSweepDelay = time.Hour
+)
+
+// StartDisbursements pays approved loans into their borrowers' wallets until ctx is done.
+// Approving a loan only records LoanApproved; the loan is paid out when the event comes back
+// through the broker, which retries a disbursement that fails instead of losing it. A sweep
+// pays out any approved loan the broker gave up on.
+func StartDisbursements(ctx context.Context, b broker.Broker) error {
+	if err := b.Subscribe(ctx, disbursementGroup, []string{EventLoanApproved}, disburseLoan); err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(disbursementSweepInterval):
+			}
+
+			sweepDisbursements(ctx, time.Now())
+		}
+	}()
+	return nil
+}
+
+// sweepDisbursements retries every loan approved more than disbursementSweepDelay ago that still
+// hasn't been paid out. Loans approved before approvals were timestamped are left alone, since
+// they were paid out without being marked.
+func sweepDisbursements(ctx context.Context, now time.Time) {
+	cursor, err := database.GetCollection("loans").Find(ctx, bson.M{
+		"status":      LoanApproved,
+		"disbursedAt": nil,
+		"approvedAt":  bson.M{"$lte": now.Add(-disbursementSweepDelay)},
+	})
+	if err != nil {
+		log.Println("Failed to load undisbursed loans:", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var loan Loan
+		if err := cursor.Decode(&loan); err != nil {
+			log.Println("Failed to decode loan:", err)
+			continue
+		}
+
+		if err := disburse(ctx, &loan); err != nil {
+			log.Printf("ALERT: loan %s approved at %s is still not disbursed: %v", loan.ID.Hex(), loan.ApprovedAt.Format(time.RFC3339), err)
+		}
+	}
+}
+
+// disburseLoan credits the wallet for the loan an event approved
+func disburseLoan(ctx context.Context, event broker.Event) error {
+	loanId, err := primitive.ObjectIDFromHex(event.AggregateID)
+	if err != nil {
+		// Retrying won't help, so the event is acknowledged and dropped
+		log.Printf("Dropping %s event %s: invalid loan ID %q", event.Type, event.ID, event.AggregateID)
+		return nil
+	}
+
+	var loan Loan
+	if err := database.GetCollection("loans").FindOne(ctx, bson.M{"_id": loanId}).Decode(&loan); err != nil {
+		if err == mongo.ErrNoDocuments {
+			log.Printf("Dropping %s event %s: loan %s not found", event.Type, event.ID, loanId.Hex())
+			return nil
+		}
+		return err
+	}
+	if loan.DisbursedAt != nil {
+		return nil
+	}
+	if loan.Status != LoanApproved {
+		log.Printf("Dropping %s event %s: loan %s is %s", event.Type, event.ID, loanId.Hex(), loan.Status)
+		return nil
+	}
+
+	return disburse(ctx, &loan)
+}
+
+// disburse credits the borrower's wallet with an approved loan and marks it disbursed. The wallet
+// is credited with the loan's ID as the reference, which walletService credits only once, so a
+// repeated or retried event, or the sweep, can't pay the loan out twice.
+func disburse(ctx context.Context, loan *Loan) error {
+	loanId := loan.ID
+	loansCollection := database.GetCollection("loans")
+
+	// Initialize the gRPC client
+	walletServiceClient, cleanup, err := grpcclient.NewWalletServiceClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to WalletService: %w", err)
+	}
+	defer cleanup()
+
+	creditWalletResp, err := walletServiceClient.CreditWallet(grpcclient.NewAuthContext(ctx, configs.Env.TOKEN), &walletPb.CreditWalletRequest{
+		UserId:    loan.UserID.Hex(),
+		Amount:    loan.ApprovedAmount,
+		Reference: loanId.Hex(),
+	})
+	if creditWalletResp == nil {
+		return fmt.Errorf("CreditWallet call failed: %w", err)
+	}
+	if err != nil || !creditWalletResp.Status {
+		return errors.New("CreditWallet failed: " + creditWalletResp.Message)
+	}
+
+	// Marked in one transaction with LoanDisbursed, so the event is recorded exactly once
+	return database.WithTransaction(ctx, func(ctx mongo.SessionContext) error {
+		result, err := loansCollection.UpdateOne(ctx,
+			bson.M{"_id": loanId, "disbursedAt": nil},
+			bson.M{"$set": bson.M{"disbursedAt": time.Now()}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.ModifiedCount == 0 {
+			return nil
+		}
+		return recordLoanEvent(ctx, EventLoanDisbursed, loanId.Hex(), loan.UserID.Hex(), map[string]string{
+			"loanId": loanId.Hex(),
+			"amount": formatAmount(loan.ApprovedAmount),
+		})
+	})
+}
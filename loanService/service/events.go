@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/broker"
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/outbox"
+)
+
+// Domain events recorded in the outbox
+const (
+	EventLoanApplied   = "LoanApplied"
+	EventLoanApproved  = "LoanApproved"
+	EventLoanRejected  = "LoanRejected"
+	EventLoanDisbursed = "LoanDisbursed"
+	EventRepaymentDue  = "RepaymentDue"
+)
+
+// recordLoanEvent writes an event about userId's loan to the outbox. Pass a transaction's context
+// to record it together with the change it describes.
+func recordLoanEvent(ctx context.Context, eventType string, loanId string, userId string, data map[string]string) error {
+	return outbox.Record(ctx, broker.Event{Type: eventType, AggregateID: loanId, UserID: userId, Data: data})
+}
+
+func formatAmount(amount float32) string {
+	return fmt.Sprintf("%.2f", amount)
+}
@@ -4,6 +4,7 @@ import (
     "context"
 
     "github.com/manlikehenryy/loan-management-system-grpc/loanService/configs"
+    "github.com/manlikehenryy/loan-management-system-grpc/loanService/helpers"
     "google.golang.org/grpc"
     "google.golang.org/grpc/codes"
     "google.golang.org/grpc/metadata"
@@ -16,6 +17,40 @@ func TokenInterceptor(
     info *grpc.UnaryServerInfo,
     handler grpc.UnaryHandler,
 ) (interface{}, error) {
+    ctx, err := authenticate(ctx, info.FullMethod)
+    if err != nil {
+        return nil, err
+    }
+
+    return handler(ctx, req)
+}
+
+func TokenStreamInterceptor(
+    srv interface{},
+    ss grpc.ServerStream,
+    info *grpc.StreamServerInfo,
+    handler grpc.StreamHandler,
+) error {
+    ctx, err := authenticate(ss.Context(), info.FullMethod)
+    if err != nil {
+        return err
+    }
+
+    return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticatedStream exposes the context carrying the principal to stream handlers
+type authenticatedStream struct {
+    grpc.ServerStream
+    ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+    return s.ctx
+}
+
+// authenticate validates the service token and identity assertion, then applies the authorization policy
+func authenticate(ctx context.Context, method string) (context.Context, error) {
     md, ok := metadata.FromIncomingContext(ctx)
     if !ok {
         return nil, status.Errorf(codes.Unauthenticated, "no metadata provided")
@@ -36,5 +71,48 @@ func TokenInterceptor(
         return nil, status.Errorf(codes.Unauthenticated, "invalid auth token")
     }
 
-    return handler(ctx, req)
+    caller, err := callerIdentity(ctx, md)
+    if err != nil {
+        return nil, err
+    }
+
+    // Calls made on behalf of an end-user carry a signed identity assertion from the gateway
+    var principal *helpers.Principal
+    if identity := md["x-identity"]; len(identity) > 0 {
+        principal, err = helpers.VerifyIdentity(identity[0])
+        if err != nil {
+            return nil, status.Errorf(codes.Unauthenticated, "invalid identity assertion: %v", err)
+        }
+        ctx = helpers.NewPrincipalContext(ctx, principal)
+    }
+
+    if err := authorize(method, caller, principal); err != nil {
+        return nil, err
+    }
+
+    return ctx, nil
+}
+
+// callerIdentity returns the calling service's name.
+// With mTLS it comes from the client certificate. Without it, the name the caller declares is only
+// trusted when TRUST_DECLARED_CALLER is set outside production, for local development; otherwise
+// every call is refused, since any client could claim to be any service.
+func callerIdentity(ctx context.Context, md metadata.MD) (string, error) {
+    if configs.Env.TLS_ENABLED == "true" {
+        caller, ok := helpers.PeerIdentity(ctx)
+        if !ok {
+            return "", status.Errorf(codes.Unauthenticated, "no client certificate provided")
+        }
+        return caller, nil
+    }
+
+    if configs.Env.TRUST_DECLARED_CALLER != "true" || configs.Env.MODE == "production" {
+        return "", status.Errorf(codes.Unauthenticated, "mTLS is required to identify the calling service")
+    }
+
+    if caller := md["x-caller"]; len(caller) > 0 {
+        return caller[0], nil
+    }
+
+    return "", nil
 }
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/grpcclient"
+	userPb "github.com/manlikehenryy/loan-management-system-grpc/loanService/user"
+)
+
+// KYC tiers, as stored by userService
+const (
+	KycTierBasic    = 1 // Approved ID document
+	KycTierVerified = 2 // Approved ID document and selfie
+)
+
+const defaultKycTier1MaxAmount = 100000
+
+// requiredKycTier is the tier a loan of amount needs. Every loan needs at least a verified ID.
+func requiredKycTier(amount float32) int32 {
+	if amount > kycTier1MaxAmount() {
+		return KycTierVerified
+	}
+	return KycTierBasic
+}
+
+// VerificationVerified is the verification status, as stored by userService, of a user whose
+// email and phone are both verified
+const VerificationVerified = "verified"
+
+// getUserStatus fetches userId's account, KYC and verification status from userService
+func getUserStatus(ctx context.Context, userId string) (*userPb.GetUserStatusResponse, string, int) {
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(ctx)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		return nil, "Internal service error", http.StatusInternalServerError
+	}
+	defer cleanup()
+
+	c := grpcclient.NewIdentityContext(ctx, configs.Env.TOKEN)
+
+	userStatusResp, err := userServiceClient.GetUserStatus(c, &userPb.GetUserStatusRequest{UserId: userId})
+	if userStatusResp == nil {
+		log.Println("Error in GetUserStatus call:", err)
+		return nil, "Unexpected service response", http.StatusInternalServerError
+	}
+	if err != nil || !userStatusResp.Status {
+		return nil, userStatusResp.Message, int(userStatusResp.StatusCode)
+	}
+	return userStatusResp, "", http.StatusOK
+}
+
+// checkKycTier makes sure the user's KYC tier covers a loan of amount. It returns http.StatusOK if it does.
+func checkKycTier(userStatus *userPb.GetUserStatusResponse, amount float32) (string, int) {
+	required := requiredKycTier(amount)
+	if userStatus.KycTier < required {
+		return fmt.Sprintf("Identity verification required: this amount needs KYC tier %d, the applicant has tier %d", required, userStatus.KycTier), http.StatusForbidden
+	}
+	return "", http.StatusOK
+}
+
+// kycTier1MaxAmount is the largest loan available at KYC tier 1
+func kycTier1MaxAmount() float32 {
+	limit, err := strconv.ParseFloat(configs.Env.KYC_TIER1_MAX_AMOUNT, 32)
+	if err != nil || limit <= 0 {
+		return defaultKycTier1MaxAmount
+	}
+	return float32(limit)
+}
@@ -2,15 +2,18 @@ package service
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/manlikehenryy/loan-management-system-grpc/loanService/configs"
 	"github.com/manlikehenryy/loan-management-system-grpc/loanService/database"
 	"github.com/manlikehenryy/loan-management-system-grpc/loanService/grpcclient"
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/helpers"
 	pb "github.com/manlikehenryy/loan-management-system-grpc/loanService/loan"
 	userPb "github.com/manlikehenryy/loan-management-system-grpc/loanService/user"
-	walletPb "github.com/manlikehenryy/loan-management-system-grpc/loanService/wallet"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -21,17 +24,38 @@ type Loan struct {
 	ID               primitive.ObjectID `bson:"_id,omitempty"`
 	UserID           primitive.ObjectID `bson:"userId,omitempty"`
 	Amount           float32            `bson:"amount,omitempty"`
-	Status           string             `bson:"status,omitempty"` // "approved", "rejected"
+	Status           string             `bson:"status,omitempty"` // LoanPending, LoanApproved or LoanRejected
 	ApprovedBy       primitive.ObjectID `bson:"approvedBy,omitempty"`
 	RejectedBy       primitive.ObjectID `bson:"rejectedBy,omitempty"`
 	ApprovedAmount   float32            `bson:"approvedAmount,omitempty"`
+	ApprovedAt       *time.Time         `bson:"approvedAt,omitempty"`
 	Tenure           int32              `bson:"tenure,omitempty"`
 	MonthlyRepayment float32            `bson:"monthlyRepayment,omitempty"`
 	EffectiveDate    string             `bson:"effectiveDate,omitempty"`
 	ExpiryDate       string             `bson:"expiryDate,omitempty"`
 	AmountPaid       float32            `bson:"amountPaid,omitempty"`
+	DisbursedAt      *time.Time         `bson:"disbursedAt,omitempty"` // Set once the approved amount is in the wallet
 }
 
+// Loan statuses. Only a pending loan can be approved or rejected.
+const (
+	LoanPending  = "pending"
+	LoanApproved = "approved"
+	LoanRejected = "rejected"
+)
+
+var errLoanNotPending = errors.New("loan is not pending")
+
+// Permissions checked against userService
+const (
+	PermissionLoanApprove           = "loan.approve"
+	PermissionLoanApproveAboveLimit = "loan.approve.above_limit"
+	PermissionLoanReject            = "loan.reject"
+	PermissionLoanViewAny           = "loan.view_any"
+)
+
+const defaultApprovalLimit = 500000
+
 // LoanServiceServer struct to implement gRPC functions
 type LoanServiceServer struct {
 	pb.UnimplementedLoanServiceServer
@@ -43,23 +67,54 @@ func NewLoanServiceServer() *LoanServiceServer {
 
 
 func (s *LoanServiceServer) ApplyLoan(ctx context.Context, req *pb.ApplyLoanRequest) (*pb.ApplyLoanResponse, error) {
+	principal, ok := helpers.PrincipalFromContext(ctx)
+	if !ok {
+		return applyLoanErrorResponse("Unauthorized: No identity provided", http.StatusUnauthorized), nil
+	}
+
 	loansCollection := database.GetCollection("loans")
 
-	userId, err := primitive.ObjectIDFromHex(req.GetUserId())
+	userId, err := primitive.ObjectIDFromHex(principal.UserId)
 	if err != nil {
 		return applyLoanErrorResponse("Invalid user ID", http.StatusBadRequest), nil
 	}
 
-	loan := Loan{UserID: userId, Amount: req.GetAmount(), Status: "pending"}
+	userStatus, message, statusCode := getUserStatus(ctx, principal.UserId)
+	if statusCode != http.StatusOK {
+		return applyLoanErrorResponse(message, statusCode), nil
+	}
+	if userStatus.VerificationStatus != VerificationVerified {
+		return applyLoanErrorResponse("Verify your email address and phone number before applying for a loan", http.StatusForbidden), nil
+	}
+	if message, statusCode := checkKycTier(userStatus, req.GetAmount()); statusCode != http.StatusOK {
+		return applyLoanErrorResponse(message, statusCode), nil
+	}
 
-	result, err_ := loansCollection.InsertOne(context.Background(), loan)
+	loan := Loan{ID: primitive.NewObjectID(), UserID: userId, Amount: req.GetAmount(), Status: LoanPending}
+	loanId := loan.ID.Hex()
+
+	err_ := database.WithTransaction(ctx, func(ctx mongo.SessionContext) error {
+		if _, err := loansCollection.InsertOne(ctx, loan); err != nil {
+			return err
+		}
+		return recordLoanEvent(ctx, EventLoanApplied, loanId, principal.UserId, map[string]string{
+			"loanId": loanId,
+			"amount": formatAmount(req.GetAmount()),
+		})
+	})
 	if err_ != nil {
+		log.Println("Database error:", err_)
 		return applyLoanErrorResponse("Loan application failed", http.StatusInternalServerError), nil
 	}
-	return applyLoanSuccessResponse("Loan application submitted",http.StatusCreated, result.InsertedID.(primitive.ObjectID).Hex()), nil
+
+	return applyLoanSuccessResponse("Loan application submitted",http.StatusCreated, loanId), nil
 }
 
 func (s *LoanServiceServer) ApproveLoan(ctx context.Context, req *pb.ApproveLoanRequest) (*pb.ApproveLoanResponse, error) {
+	principal, ok := helpers.PrincipalFromContext(ctx)
+	if !ok {
+		return approveLoanErrorResponse("Unauthorized: No identity provided", http.StatusUnauthorized), nil
+	}
 
 	// Initialize the gRPC client
 	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(ctx)
@@ -70,21 +125,29 @@ func (s *LoanServiceServer) ApproveLoan(ctx context.Context, req *pb.ApproveLoan
 	defer cleanup()
 
 	// Set up the context with authorization metadata
-	c := grpcclient.NewAuthContext(context.Background(), configs.Env.TOKEN)
+	c := grpcclient.NewIdentityContext(ctx, configs.Env.TOKEN)
 
-	isAdminReq := &userPb.IsAdminRequest{
-		UserId: req.UserId,
+	permissions := []string{PermissionLoanApprove}
+	if req.GetApprovedAmount() > approvalLimit() {
+		permissions = append(permissions, PermissionLoanApproveAboveLimit)
 	}
-	isAdminResp, err_ := userServiceClient.IsAdmin(c, isAdminReq)
 
-	if isAdminResp == nil {
-		log.Println("Error in IsAdmin call:", err)
-		return approveLoanErrorResponse("Unexpected service response", http.StatusInternalServerError), nil
-	}
+	for _, permission := range permissions {
+		checkPermissionReq := &userPb.CheckPermissionRequest{
+			UserId:     principal.UserId,
+			Permission: permission,
+		}
+		checkPermissionResp, err_ := userServiceClient.CheckPermission(c, checkPermissionReq)
 
-	if err_ != nil || !isAdminResp.Valid {
+		if checkPermissionResp == nil {
+			log.Println("Error in CheckPermission call:", err_)
+			return approveLoanErrorResponse("Unexpected service response", http.StatusInternalServerError), nil
+		}
+
+		if err_ != nil || !checkPermissionResp.Allowed {
 
-		return approveLoanErrorResponse(isAdminResp.Message, int(isAdminResp.StatusCode)), nil
+			return approveLoanErrorResponse(checkPermissionResp.Message, int(checkPermissionResp.StatusCode)), nil
+		}
 	}
 
 	loansCollection := database.GetCollection("loans")
@@ -104,16 +167,30 @@ func (s *LoanServiceServer) ApproveLoan(ctx context.Context, req *pb.ApproveLoan
 		}
 	}
 
-	userId, err := primitive.ObjectIDFromHex(req.GetUserId())
+	if existingLoan.Status != LoanPending {
+		return approveLoanErrorResponse("Loan is no longer pending", http.StatusConflict), nil
+	}
+
+	userId, err := primitive.ObjectIDFromHex(principal.UserId)
 	if err != nil {
 		return approveLoanErrorResponse("Invalid user ID", http.StatusBadRequest), nil
 	}
 
+	// Checked again at approval, since the approved amount may need a higher tier than was applied for
+	applicantStatus, message, statusCode := getUserStatus(ctx, existingLoan.UserID.Hex())
+	if statusCode != http.StatusOK {
+		return approveLoanErrorResponse(message, statusCode), nil
+	}
+	if message, statusCode := checkKycTier(applicantStatus, req.GetApprovedAmount()); statusCode != http.StatusOK {
+		return approveLoanErrorResponse(message, statusCode), nil
+	}
+
 	update := bson.M{
 		"$set": bson.M{
 			"approvedBy":       userId,
 			"approvedAmount":   req.GetApprovedAmount(),
-			"status":           "approved",
+			"approvedAt":       time.Now(),
+			"status":           LoanApproved,
 			"tenure":           req.GetTenure(),
 			"monthlyRepayment": req.GetMonthlyRepayment(),
 			"effectiveDate":    req.GetEffectiveDate(),
@@ -124,45 +201,44 @@ func (s *LoanServiceServer) ApproveLoan(ctx context.Context, req *pb.ApproveLoan
 
 	
 
-	result, err := loansCollection.UpdateOne(context.Background(), bson.M{"_id": loanId}, update)
+	err = database.WithTransaction(ctx, func(ctx mongo.SessionContext) error {
+		// Only a pending loan can be approved, so approving twice can't pay the loan out twice
+		result, err := loansCollection.UpdateOne(ctx, bson.M{"_id": loanId, "status": LoanPending}, update)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return loanMissingOr(ctx, loanId, errLoanNotPending)
+		}
+		return recordLoanEvent(ctx, EventLoanApproved, loanId.Hex(), existingLoan.UserID.Hex(), map[string]string{
+			"loanId":           loanId.Hex(),
+			"amount":           formatAmount(req.GetApprovedAmount()),
+			"monthlyRepayment": formatAmount(req.GetMonthlyRepayment()),
+			"tenure":           strconv.Itoa(int(req.GetTenure())),
+			"effectiveDate":    req.GetEffectiveDate(),
+		})
+	})
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return approveLoanErrorResponse("Loan not found", http.StatusNotFound), nil
+		}
+		if errors.Is(err, errLoanNotPending) {
+			return approveLoanErrorResponse("Loan is no longer pending", http.StatusConflict), nil
+		}
 		log.Println("Database error:", err)
 		return approveLoanErrorResponse("Failed to approve loan", http.StatusInternalServerError), nil
 	}
 
-	if result.MatchedCount == 0 {
-
-		return approveLoanErrorResponse("Loan not found", http.StatusNotFound), nil
-	}
-
-	// Initialize the gRPC client
-	walletServiceClient, cleanup, err := grpcclient.NewWalletServiceClient(ctx)
-	if err != nil {
-		log.Fatalf("Failed to connect to WalletService: %v", err)
-		return approveLoanErrorResponse("Internal service error", http.StatusInternalServerError), nil
-	}
-	defer cleanup()
-
-	creditWalletReq := &walletPb.CreditWalletRequest{
-		UserId: existingLoan.UserID.Hex(),
-		Amount: req.ApprovedAmount,
-	}
-	creditWalletResp, err := walletServiceClient.CreditWallet(c, creditWalletReq)
-
-	if creditWalletResp == nil {
-		log.Println("Error in creditWallet call:", err)
-		return approveLoanErrorResponse("Unexpected service response", http.StatusInternalServerError), nil
-	}
-
-	if err != nil || !creditWalletResp.Status {
-
-		return approveLoanErrorResponse(creditWalletResp.Message, int(creditWalletResp.StatusCode)), nil
-	}
-
+	// The loan is paid out by StartDisbursements once LoanApproved reaches the broker
 	return approveLoanSuccessResponse("Loan approved successfully", http.StatusOK), nil
 }
 
 func (s *LoanServiceServer) RejectLoan(ctx context.Context, req *pb.RejectLoanRequest) (*pb.RejectLoanResponse, error) {
+	principal, ok := helpers.PrincipalFromContext(ctx)
+	if !ok {
+		return rejectLoanErrorResponse("Unauthorized: No identity provided", http.StatusUnauthorized), nil
+	}
+
 	// Initialize the gRPC client
 	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(ctx)
 	if err != nil {
@@ -172,27 +248,28 @@ func (s *LoanServiceServer) RejectLoan(ctx context.Context, req *pb.RejectLoanRe
 	defer cleanup()
 
 	// Set up the context with authorization metadata
-	c := grpcclient.NewAuthContext(context.Background(), configs.Env.TOKEN)
+	c := grpcclient.NewIdentityContext(ctx, configs.Env.TOKEN)
 
-	isAdminReq := &userPb.IsAdminRequest{
-		UserId: req.UserId,
+	checkPermissionReq := &userPb.CheckPermissionRequest{
+		UserId:     principal.UserId,
+		Permission: PermissionLoanReject,
 	}
 
-	isAdminResp, err_ := userServiceClient.IsAdmin(c, isAdminReq)
+	checkPermissionResp, err_ := userServiceClient.CheckPermission(c, checkPermissionReq)
 
-	if isAdminResp == nil {
-		log.Println("Error in IsAdmin call:", err)
+	if checkPermissionResp == nil {
+		log.Println("Error in CheckPermission call:", err_)
 		return rejectLoanErrorResponse("Unexpected service response", http.StatusInternalServerError), nil
 	}
 
-	if err_ != nil || !isAdminResp.Valid {
+	if err_ != nil || !checkPermissionResp.Allowed {
 
-		return rejectLoanErrorResponse(isAdminResp.Message, int(isAdminResp.StatusCode)), nil
+		return rejectLoanErrorResponse(checkPermissionResp.Message, int(checkPermissionResp.StatusCode)), nil
 	}
 
 	loansCollection := database.GetCollection("loans")
 
-	userId, err := primitive.ObjectIDFromHex(req.GetUserId())
+	userId, err := primitive.ObjectIDFromHex(principal.UserId)
 	if err != nil {
 		return rejectLoanErrorResponse("Invalid user ID", http.StatusBadRequest), nil
 	}
@@ -200,7 +277,7 @@ func (s *LoanServiceServer) RejectLoan(ctx context.Context, req *pb.RejectLoanRe
 	update := bson.M{
 		"$set": bson.M{
 			"rejectedBy": userId,
-			"status":     "rejected",
+			"status":     LoanRejected,
 		},
 	}
 
@@ -209,18 +286,45 @@ func (s *LoanServiceServer) RejectLoan(ctx context.Context, req *pb.RejectLoanRe
 		return  rejectLoanErrorResponse("Invalid loan ID", http.StatusBadRequest), nil
 	}
 
-	result, err := loansCollection.UpdateOne(context.Background(), bson.M{"_id": loanId}, update)
+	err = database.WithTransaction(ctx, func(ctx mongo.SessionContext) error {
+		var rejectedLoan Loan
+		err := loansCollection.FindOneAndUpdate(ctx, bson.M{"_id": loanId, "status": LoanPending}, update).Decode(&rejectedLoan)
+		if err == mongo.ErrNoDocuments {
+			return loanMissingOr(ctx, loanId, errLoanNotPending)
+		}
+		if err != nil {
+			return err
+		}
+		return recordLoanEvent(ctx, EventLoanRejected, loanId.Hex(), rejectedLoan.UserID.Hex(), map[string]string{
+			"loanId": loanId.Hex(),
+			"amount": formatAmount(rejectedLoan.Amount),
+		})
+	})
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return rejectLoanErrorResponse("Loan not found", http.StatusNotFound), nil
+		}
+		if errors.Is(err, errLoanNotPending) {
+			return rejectLoanErrorResponse("Loan is no longer pending", http.StatusConflict), nil
+		}
 		log.Println("Database error:", err)
 		return rejectLoanErrorResponse("Failed to reject loan", http.StatusInternalServerError), nil
 	}
 
-	if result.MatchedCount == 0 {
+	return rejectLoanSuccessResponse("Loan rejected successfully", http.StatusOK), nil
+}
 
-		return rejectLoanErrorResponse("Loan not found", http.StatusNotFound), nil
+// loanMissingOr returns mongo.ErrNoDocuments if the loan doesn't exist, and err otherwise. It's for
+// telling why a conditional update matched nothing.
+func loanMissingOr(ctx context.Context, loanId primitive.ObjectID, err error) error {
+	count, countErr := database.GetCollection("loans").CountDocuments(ctx, bson.M{"_id": loanId})
+	if countErr != nil {
+		return countErr
 	}
-
-	return rejectLoanSuccessResponse("Loan rejected successfully", http.StatusOK), nil
+	if count == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return err
 }
 
 // Success and Error Response functions for ApplyLoan
@@ -248,4 +352,13 @@ func rejectLoanSuccessResponse(message string, statusCode int) *pb.RejectLoanRes
 
 func rejectLoanErrorResponse(message string, statusCode int) *pb.RejectLoanResponse {
     return &pb.RejectLoanResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
-}
\ No newline at end of file
+}
+
+// approvalLimit is the largest amount that can be approved without loan.approve.above_limit
+func approvalLimit() float32 {
+	limit, err := strconv.ParseFloat(configs.Env.LOAN_APPROVAL_LIMIT, 32)
+	if err != nil || limit <= 0 {
+		return defaultApprovalLimit
+	}
+	return float32(limit)
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/helpers"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MethodPolicy lists who may call a gRPC method.
+// Callers are service names; Roles are end-user roles, where "*" means any authenticated user.
+type MethodPolicy struct {
+	Callers []string `json:"callers"`
+	Roles   []string `json:"roles"`
+}
+
+// Policy maps full gRPC method names to their access rules
+type Policy struct {
+	Methods map[string]MethodPolicy `json:"methods"`
+}
+
+var policy *Policy
+
+// LoadPolicy reads the authorization policy enforced by the interceptors
+func LoadPolicy(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+
+	policy = &p
+	log.Printf("Loaded authorization policy for %d methods", len(p.Methods))
+	return nil
+}
+
+// authorize checks the caller and the end-user principal against the policy for method.
+// Methods missing from the policy are denied.
+func authorize(method string, caller string, principal *helpers.Principal) error {
+	if policy == nil {
+		return status.Errorf(codes.Internal, "authorization policy not loaded")
+	}
+
+	rule, ok := policy.Methods[method]
+	if !ok {
+		return deny(method, caller, principal, "method not in policy")
+	}
+
+	if !contains(rule.Callers, caller) {
+		return deny(method, caller, principal, "caller not allowed")
+	}
+
+	if len(rule.Roles) == 0 {
+		return nil
+	}
+
+	if principal == nil {
+		return deny(method, caller, principal, "no end-user identity")
+	}
+
+	if contains(rule.Roles, "*") {
+		return nil
+	}
+
+	for _, role := range principal.Roles {
+		if contains(rule.Roles, role) {
+			return nil
+		}
+	}
+
+	return deny(method, caller, principal, "role not allowed")
+}
+
+func deny(method string, caller string, principal *helpers.Principal, reason string) error {
+	userId, requestId := "", ""
+	if principal != nil {
+		userId, requestId = principal.UserId, principal.RequestId
+	}
+
+	log.Printf("Denied %s: %s (caller=%q user=%q request=%q)", method, reason, caller, userId, requestId)
+	return status.Errorf(codes.PermissionDenied, "permission denied")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
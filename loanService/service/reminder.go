@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/broker"
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/outbox"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	defaultReminderDays = 3
+	reminderInterval    = time.Hour
+	loanDateLayout      = "2006-01-02"
+)
+
+// StartRepaymentReminders records a RepaymentDue event for every approved loan whose next
+// installment falls due within REPAYMENT_REMINDER_DAYS, checking every hour until ctx is done.
+// Each installment's event has a fixed ID, so repeated checks record it once.
+func StartRepaymentReminders(ctx context.Context) {
+	go func() {
+		for {
+			sendRepaymentReminders(ctx, time.Now())
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(reminderInterval):
+			}
+		}
+	}()
+}
+
+func sendRepaymentReminders(ctx context.Context, now time.Time) {
+	cursor, err := database.GetCollection("loans").Find(ctx, bson.M{"status": LoanApproved})
+	if err != nil {
+		log.Println("Failed to load loans for reminders:", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	horizon := now.AddDate(0, 0, reminderDays())
+	for cursor.Next(ctx) {
+		var loan Loan
+		if err := cursor.Decode(&loan); err != nil {
+			log.Println("Failed to decode loan:", err)
+			continue
+		}
+
+		dueDate, ok := nextInstallmentDue(&loan)
+		if !ok || dueDate.Before(now.Truncate(24*time.Hour)) || dueDate.After(horizon) {
+			continue
+		}
+
+		due := dueDate.Format(loanDateLayout)
+		err := outbox.Record(ctx, broker.Event{
+			ID:          EventRepaymentDue + ":" + loan.ID.Hex() + ":" + due,
+			Type:        EventRepaymentDue,
+			AggregateID: loan.ID.Hex(),
+			UserID:      loan.UserID.Hex(),
+			Data: map[string]string{
+				"loanId":  loan.ID.Hex(),
+				"amount":  formatAmount(loan.MonthlyRepayment),
+				"dueDate": due,
+			},
+		})
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			log.Println("Failed to record RepaymentDue event:", err)
+		}
+	}
+}
+
+// nextInstallmentDue returns the due date of the first installment that isn't fully paid.
+// Installments fall due monthly from the loan's effective date.
+func nextInstallmentDue(loan *Loan) (time.Time, bool) {
+	if loan.MonthlyRepayment <= 0 || loan.Tenure <= 0 {
+		return time.Time{}, false
+	}
+
+	effectiveDate, err := time.Parse(loanDateLayout, loan.EffectiveDate)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	paid := int32(loan.AmountPaid / loan.MonthlyRepayment)
+	if paid >= loan.Tenure {
+		return time.Time{}, false
+	}
+	return effectiveDate.AddDate(0, int(paid)+1, 0), true
+}
+
+func reminderDays() int {
+	days, err := strconv.Atoi(configs.Env.REPAYMENT_REMINDER_DAYS)
+	if err != nil || days <= 0 {
+		return defaultReminderDays
+	}
+	return days
+}
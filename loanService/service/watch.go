@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/grpcclient"
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/helpers"
+	pb "github.com/manlikehenryy/loan-management-system-grpc/loanService/loan"
+	"github.com/manlikehenryy/loan-management-system-grpc/loanService/outbox"
+	userPb "github.com/manlikehenryy/loan-management-system-grpc/loanService/user"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UpdateSnapshot is the type of the first update sent to a watcher that isn't resuming
+const UpdateSnapshot = "Snapshot"
+
+// WatchLoan streams a loan's events as they are recorded. Updates come from the outbox, so a
+// client that reconnects with its last resume token gets every event it missed, in order.
+func (s *LoanServiceServer) WatchLoan(req *pb.WatchLoanRequest, stream grpc.ServerStreamingServer[pb.LoanUpdate]) error {
+	ctx := stream.Context()
+
+	principal, ok := helpers.PrincipalFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "No identity provided")
+	}
+
+	loanId, err := primitive.ObjectIDFromHex(req.GetLoanId())
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "Invalid loan ID")
+	}
+
+	loan, err := findLoan(ctx, loanId)
+	if err != nil {
+		return err
+	}
+
+	if loan.UserID.Hex() != principal.UserId {
+		if message, statusCode := checkPermission(ctx, principal.UserId, PermissionLoanViewAny); statusCode != http.StatusOK {
+			return status.Error(codes.PermissionDenied, message)
+		}
+	}
+
+	watcher, err := outbox.Watch(ctx, bson.M{"aggregateId": loanId.Hex()}, req.GetResumeToken())
+	if err != nil {
+		if errors.Is(err, outbox.ErrInvalidResumeToken) {
+			return status.Error(codes.OutOfRange, "Resume token is invalid or expired; watch again without one")
+		}
+		log.Println("Failed to watch loan:", err)
+		return status.Error(codes.Internal, "Failed to watch loan")
+	}
+	defer watcher.Close(context.Background())
+
+	if req.GetResumeToken() == "" {
+		// Read after the watch starts, so a change made in between is sent as an update
+		if loan, err = findLoan(ctx, loanId); err != nil {
+			return err
+		}
+		snapshot := &pb.LoanUpdate{Type: UpdateSnapshot, Loan: loanDetails(loan), ResumeToken: watcher.ResumeToken()}
+		if err := stream.Send(snapshot); err != nil {
+			return err
+		}
+	}
+
+	for {
+		event, err := watcher.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Println("Loan watch interrupted:", err)
+			return status.Error(codes.Unavailable, "Loan updates interrupted; resume with the last token")
+		}
+
+		if loan, err = findLoan(ctx, loanId); err != nil {
+			return err
+		}
+
+		update := &pb.LoanUpdate{
+			Type:        event.Type,
+			EventId:     event.ID,
+			Loan:        loanDetails(loan),
+			Data:        event.Data,
+			OccurredAt:  event.OccurredAt.Format(time.RFC3339),
+			ResumeToken: watcher.ResumeToken(),
+		}
+		if err := stream.Send(update); err != nil {
+			return err
+		}
+	}
+}
+
+// findLoan loads a loan for a stream, returning a gRPC status error if it can't
+func findLoan(ctx context.Context, loanId primitive.ObjectID) (*Loan, error) {
+	var loan Loan
+	err := database.GetCollection("loans").FindOne(ctx, bson.M{"_id": loanId}).Decode(&loan)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, status.Error(codes.NotFound, "Loan not found")
+		}
+		log.Println("Database error:", err)
+		return nil, status.Error(codes.Internal, "Failed to read loan")
+	}
+	return &loan, nil
+}
+
+// checkPermission asks userService whether userId has permission. It returns http.StatusOK if they do.
+func checkPermission(ctx context.Context, userId string, permission string) (string, int) {
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(ctx)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		return "Internal service error", http.StatusInternalServerError
+	}
+	defer cleanup()
+
+	c := grpcclient.NewIdentityContext(ctx, configs.Env.TOKEN)
+
+	checkPermissionResp, err := userServiceClient.CheckPermission(c, &userPb.CheckPermissionRequest{UserId: userId, Permission: permission})
+	if checkPermissionResp == nil {
+		log.Println("Error in CheckPermission call:", err)
+		return "Unexpected service response", http.StatusInternalServerError
+	}
+	if err != nil || !checkPermissionResp.Allowed {
+		return checkPermissionResp.Message, int(checkPermissionResp.StatusCode)
+	}
+	return "", http.StatusOK
+}
+
+func loanDetails(loan *Loan) *pb.LoanDetails {
+	return &pb.LoanDetails{
+		Id:               loan.ID.Hex(),
+		UserId:           loan.UserID.Hex(),
+		Amount:           loan.Amount,
+		Status:           loan.Status,
+		ApprovedAmount:   loan.ApprovedAmount,
+		Tenure:           loan.Tenure,
+		MonthlyRepayment: loan.MonthlyRepayment,
+		EffectiveDate:    loan.EffectiveDate,
+		ExpiryDate:       loan.ExpiryDate,
+		AmountPaid:       loan.AmountPaid,
+	}
+}
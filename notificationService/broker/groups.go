@@ -0,0 +1,11 @@
+package broker
+
+// consumerGroups declares every consumer group and the event types it takes. Events are queued
+// for these groups as soon as they're published, even before any of the group's instances has
+// subscribed, so nothing is lost on a fresh deploy or while a consumer hasn't started yet. A
+// group that isn't declared only gets the events published after it first subscribes.
+var consumerGroups = map[string][]string{
+	"loanService.disbursement":   {"LoanApproved"},
+	"notificationService":        {"LoanApplied", "LoanApproved", "LoanDisbursed", "LoanRejected", "RepaymentDue", "WalletCredited"},
+	"walletService.provisioning": {"UserRegistered"},
+}
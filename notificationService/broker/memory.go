@@ -0,0 +1,61 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+)
+
+type memorySubscription struct {
+	eventTypes []string
+	handler    Handler
+}
+
+// MemoryBroker delivers events to handlers in the same process, as they are published. A
+// handler's error fails the publish, so the outbox relay retries it.
+type MemoryBroker struct {
+	mu            sync.RWMutex
+	subscriptions map[string]memorySubscription
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subscriptions: map[string]memorySubscription{}}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	delivered := false
+	var errs []error
+	for group, subscription := range b.subscriptions {
+		if !subscribed(subscription.eventTypes, event.Type) {
+			continue
+		}
+		delivered = true
+		if err := subscription.handler(ctx, event); err != nil {
+			errs = append(errs, errors.New(group+": "+err.Error()))
+		}
+	}
+
+	if !delivered {
+		log.Printf("Published %s event %s (no subscribers)", event.Type, event.ID)
+	}
+	return errors.Join(errs...)
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, group string, eventTypes []string, handler Handler) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscriptions[group] = memorySubscription{eventTypes: eventTypes, handler: handler}
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscriptions, group)
+	}()
+	return nil
+}
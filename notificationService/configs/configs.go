@@ -0,0 +1,65 @@
+package configs
+
+import (
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+type Config struct {
+	PORT                  string
+	MONGO_DB_URI          string
+	MODE                  string
+	TOKEN                 string
+	IDENTITY_SECRET       string
+	TLS_ENABLED           string
+	TLS_CERT_FILE         string
+	TLS_KEY_FILE          string
+	TLS_CA_FILE           string
+	TLS_DEV_MODE          string
+	TLS_DEV_DIR           string
+	POLICY_FILE           string
+	USER_SERVICE_URL      string
+	EMAIL_PROVIDER        string
+	SMS_PROVIDER          string
+	NOTIFICATION_DIR      string
+	BROKER                string
+	BROKER_DB             string
+	TRUST_DECLARED_CALLER string
+}
+
+var Env *Config
+
+func init() {
+
+	Env = &Config{}
+
+	if os.Getenv("MODE") != "production" {
+		err := godotenv.Load()
+		if err != nil {
+			log.Fatalf("Error loading .env file: %v", err)
+		}
+
+	}
+
+	Env.PORT = os.Getenv("PORT")
+	Env.MONGO_DB_URI = os.Getenv("MONGO_DB_URI")
+	Env.MODE = os.Getenv("MODE")
+	Env.TOKEN = os.Getenv("TOKEN")
+	Env.IDENTITY_SECRET = os.Getenv("IDENTITY_SECRET")
+	Env.TLS_ENABLED = os.Getenv("TLS_ENABLED")
+	Env.TLS_CERT_FILE = os.Getenv("TLS_CERT_FILE")
+	Env.TLS_KEY_FILE = os.Getenv("TLS_KEY_FILE")
+	Env.TLS_CA_FILE = os.Getenv("TLS_CA_FILE")
+	Env.TLS_DEV_MODE = os.Getenv("TLS_DEV_MODE")
+	Env.TLS_DEV_DIR = os.Getenv("TLS_DEV_DIR")
+	Env.POLICY_FILE = os.Getenv("POLICY_FILE")
+	Env.USER_SERVICE_URL = os.Getenv("USER_SERVICE_URL")
+	Env.EMAIL_PROVIDER = os.Getenv("EMAIL_PROVIDER")
+	Env.SMS_PROVIDER = os.Getenv("SMS_PROVIDER")
+	Env.NOTIFICATION_DIR = os.Getenv("NOTIFICATION_DIR")
+	Env.BROKER = os.Getenv("BROKER")
+	Env.BROKER_DB = os.Getenv("BROKER_DB")
+	Env.TRUST_DECLARED_CALLER = os.Getenv("TRUST_DECLARED_CALLER")
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"log"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/notificationService/configs"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var Client *mongo.Client
+var DB *mongo.Database
+
+// Initialize the MongoDB client
+func Connect() {
+	mongoURI := configs.Env.MONGO_DB_URI
+	if mongoURI == "" {
+		log.Fatal("MONGO_DB_URI environment variable is not set")
+	}
+
+	clientOptions := options.Client().ApplyURI(mongoURI)
+	var err error
+	Client, err = mongo.Connect(context.Background(), clientOptions)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Verify the connection
+	err = Client.Ping(context.Background(), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	DB = Client.Database("notification_service")
+
+	log.Println("Connected to MongoDB")
+}
+
+func GetCollection(name string) *mongo.Collection {
+	return DB.Collection(name)
+}
+
+// WithTransaction runs fn in a transaction, retrying it on transient errors. Pass the context fn
+// receives to every query that should be part of the transaction. MongoDB only supports
+// transactions on a replica set or sharded cluster.
+func WithTransaction(ctx context.Context, fn func(ctx mongo.SessionContext) error) error {
+	session, err := Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(ctx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(ctx)
+	})
+	return err
+}
@@ -0,0 +1,47 @@
+package grpcclient
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/notificationService/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/notificationService/helpers"
+	userPb "github.com/manlikehenryy/loan-management-system-grpc/notificationService/user" // Import your generated proto package
+)
+
+// NewUserServiceClient initializes a new UserServiceClient with context
+func NewUserServiceClient(ctx context.Context) (userPb.UserServiceClient, func(), error) {
+
+	creds, err := helpers.ClientCredentials()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Establish the connection to the UserService
+	conn, err := grpc.NewClient(configs.Env.USER_SERVICE_URL, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Define a cleanup function to close the connection
+	cleanup := func() {
+		conn.Close()
+	}
+
+	return userPb.NewUserServiceClient(conn), cleanup, nil
+}
+
+func NewAuthContext(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token, "x-caller", helpers.ServiceName)
+}
+
+// NewIdentityContext adds the service token and forwards the caller's identity assertion, if any
+func NewIdentityContext(ctx context.Context, token string) context.Context {
+	ctx = NewAuthContext(ctx, token)
+	if principal, ok := helpers.PrincipalFromContext(ctx); ok {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-identity", principal.Token)
+	}
+	return ctx
+}
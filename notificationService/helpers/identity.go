@@ -0,0 +1,88 @@
+package helpers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/notificationService/configs"
+)
+
+// Principal is the end-user on whose behalf a call is made
+type Principal struct {
+	UserId    string
+	Roles     []string
+	RequestId string
+	// Token is the raw assertion, kept so it can be forwarded to other services
+	Token string
+}
+
+type identityClaims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Roles     []string `json:"roles,omitempty"`
+	RequestId string   `json:"rid,omitempty"`
+	IssuedAt  int64    `json:"iat"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+type principalKey struct{}
+
+// VerifyIdentity checks the signature and expiry of an identity assertion issued by the gateway
+func VerifyIdentity(token string) (*Principal, error) {
+	if configs.Env.IDENTITY_SECRET == "" {
+		return nil, errors.New("IDENTITY_SECRET is not set")
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, errors.New("malformed identity assertion")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.New("malformed identity signature")
+	}
+
+	mac := hmac.New(sha256.New, []byte(configs.Env.IDENTITY_SECRET))
+	mac.Write([]byte(parts[0]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, errors.New("invalid identity signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("malformed identity payload")
+	}
+
+	var claims identityClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errors.New("malformed identity payload")
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("identity assertion has no subject")
+	}
+
+	if time.Now().Unix() > claims.ExpiresAt {
+		return nil, errors.New("identity assertion expired")
+	}
+
+	return &Principal{UserId: claims.Subject, Roles: claims.Roles, RequestId: claims.RequestId, Token: token}, nil
+}
+
+// NewPrincipalContext returns a copy of ctx carrying the principal
+func NewPrincipalContext(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal set by the interceptor, if any
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
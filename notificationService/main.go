@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/notificationService/broker"
+	"github.com/manlikehenryy/loan-management-system-grpc/notificationService/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/notificationService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/notificationService/helpers"
+	pb "github.com/manlikehenryy/loan-management-system-grpc/notificationService/notification"
+	"github.com/manlikehenryy/loan-management-system-grpc/notificationService/provider"
+	"github.com/manlikehenryy/loan-management-system-grpc/notificationService/service"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	database.Connect()
+	provider.Initialize()
+
+	port := configs.Env.PORT
+	if port == "" {
+		port = "50055"
+	}
+
+	lis, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
+
+	policyFile := configs.Env.POLICY_FILE
+	if policyFile == "" {
+		policyFile = "policy.json"
+	}
+	if err := service.LoadPolicy(policyFile); err != nil {
+		log.Fatalf("Failed to load authorization policy: %v", err)
+	}
+
+	creds, err := helpers.ServerCredentials()
+	if err != nil {
+		log.Fatalf("Failed to load TLS credentials: %v", err)
+	}
+
+	service.StartDeliveryWorker(context.Background())
+	service.StartInboxWatcher(context.Background())
+
+	eventBroker, err := broker.New()
+	if err != nil {
+		log.Fatalf("Failed to set up event broker: %v", err)
+	}
+	if err := service.StartEventConsumer(context.Background(), eventBroker); err != nil {
+		log.Fatalf("Failed to subscribe to events: %v", err)
+	}
+
+	grpcServer := grpc.NewServer(grpc.Creds(creds), grpc.UnaryInterceptor(service.TokenInterceptor), grpc.StreamInterceptor(service.TokenStreamInterceptor))
+	pb.RegisterNotificationServiceServer(grpcServer, service.NewNotificationServiceServer())
+
+	fmt.Printf("Notification Service running on port %s...\n", port)
+	if err := grpcServer.Serve(lis); err != nil {
+		log.Fatalf("Failed to serve: %v", err)
+	}
+}
@@ -0,0 +1,14 @@
+package provider
+
+import (
+	"context"
+	"log"
+)
+
+// ConsoleProvider logs each message instead of sending it
+type ConsoleProvider struct{}
+
+func (p *ConsoleProvider) Send(ctx context.Context, message Message) error {
+	log.Printf("[%s] to=%q event=%s subject=%q\n%s", message.Channel, message.To, message.EventType, message.Subject, message.Body)
+	return nil
+}
@@ -0,0 +1,42 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileProvider writes each message to a file named after its delivery instead of sending it,
+// so a retried delivery overwrites its earlier file. It's meant for development and tests.
+type FileProvider struct {
+	Dir string
+}
+
+func NewFileProvider(dir string) (*FileProvider, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileProvider{Dir: dir}, nil
+}
+
+func (p *FileProvider) Send(ctx context.Context, message Message) error {
+	if strings.ContainsAny(message.To, "\r\n") || strings.ContainsAny(message.Subject, "\r\n") {
+		return fmt.Errorf("invalid header value")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "To: %s\n", message.To)
+	if message.Subject != "" {
+		fmt.Fprintf(&b, "Subject: %s\n", message.Subject)
+	}
+	fmt.Fprintf(&b, "Date: %s\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "X-Event-Type: %s\n\n", message.EventType)
+	b.WriteString(message.Body)
+	b.WriteString("\n")
+
+	name := fmt.Sprintf("%s.%s", message.ID, message.Channel)
+	return os.WriteFile(filepath.Join(p.Dir, filepath.Base(name)), []byte(b.String()), 0o600)
+}
@@ -0,0 +1,42 @@
+package provider
+
+import (
+	"context"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/notificationService/database"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// InboxMessage is an in-app notification. It has the same ID as the delivery that created it.
+type InboxMessage struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	UserID    primitive.ObjectID `bson:"userId"`
+	EventType string             `bson:"eventType"`
+	Title     string             `bson:"title"`
+	Body      string             `bson:"body"`
+	Data      map[string]string  `bson:"data,omitempty"`
+	CreatedAt time.Time          `bson:"createdAt"`
+	ReadAt    *time.Time         `bson:"readAt,omitempty"`
+}
+
+// InboxProvider stores in-app notifications in the inbox collection
+type InboxProvider struct{}
+
+func (p *InboxProvider) Send(ctx context.Context, message Message) error {
+	id, err := primitive.ObjectIDFromHex(message.ID)
+	if err != nil {
+		return err
+	}
+	userId, err := primitive.ObjectIDFromHex(message.UserID)
+	if err != nil {
+		return err
+	}
+
+	inboxMessage := InboxMessage{ID: id, UserID: userId, EventType: message.EventType, Title: message.Subject, Body: message.Body, Data: message.Data, CreatedAt: time.Now()}
+	if _, err := database.GetCollection("inbox").InsertOne(ctx, inboxMessage); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/notificationService/configs"
+)
+
+// Channels a notification can be delivered on
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+	ChannelInApp = "in_app"
+)
+
+// Message is a rendered notification for one channel. ID is the delivery's ID, which stays the
+// same across retries so providers can drop repeats.
+type Message struct {
+	ID        string
+	Channel   string
+	UserID    string
+	EventType string
+	To        string // Email address or phone number; empty for in-app messages
+	Subject   string
+	Body      string
+	Data      map[string]string // The event's data, kept with in-app messages
+}
+
+// Provider delivers messages on one channel. Email and SMS gateways implement it.
+type Provider interface {
+	Send(ctx context.Context, message Message) error
+}
+
+var providers map[string]Provider
+
+// Initialize sets up the provider for each channel. EMAIL_PROVIDER and SMS_PROVIDER are "file"
+// (the default) or "console". In-app messages always go to the inbox.
+func Initialize() {
+	email, err := New(configs.Env.EMAIL_PROVIDER)
+	if err != nil {
+		panic("Failed to set up email provider: " + err.Error())
+	}
+	sms, err := New(configs.Env.SMS_PROVIDER)
+	if err != nil {
+		panic("Failed to set up SMS provider: " + err.Error())
+	}
+
+	providers = map[string]Provider{
+		ChannelEmail: email,
+		ChannelSMS:   sms,
+		ChannelInApp: &InboxProvider{},
+	}
+}
+
+// New returns the email or SMS provider of the given kind
+func New(kind string) (Provider, error) {
+	switch kind {
+	case "", "file":
+		dir := configs.Env.NOTIFICATION_DIR
+		if dir == "" {
+			dir = "notifications"
+		}
+		return NewFileProvider(dir)
+	case "console":
+		return &ConsoleProvider{}, nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q", kind)
+	}
+}
+
+// Send delivers message with the provider for its channel
+func Send(ctx context.Context, message Message) error {
+	provider, ok := providers[message.Channel]
+	if !ok {
+		return fmt.Errorf("no provider for channel %q", message.Channel)
+	}
+	return provider.Send(ctx, message)
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/notificationService/broker"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// consumerGroup is the broker subscription every notificationService instance shares
+const consumerGroup = "notificationService"
+
+// StartEventConsumer subscribes to the events there are templates for until ctx is done. The
+// broker may deliver an event more than once; repeats are dropped by event ID.
+func StartEventConsumer(ctx context.Context, b broker.Broker) error {
+	eventTypes := make([]string, 0, len(templates))
+	for eventType := range templates {
+		eventTypes = append(eventTypes, eventType)
+	}
+	sort.Strings(eventTypes)
+
+	return b.Subscribe(ctx, consumerGroup, eventTypes, handleBrokerEvent)
+}
+
+func handleBrokerEvent(ctx context.Context, brokerEvent broker.Event) error {
+	userId, err := primitive.ObjectIDFromHex(brokerEvent.UserID)
+	if err != nil {
+		// Retrying won't help, so the event is acknowledged and dropped
+		log.Printf("Dropping %s event %s: invalid user ID %q", brokerEvent.Type, brokerEvent.ID, brokerEvent.UserID)
+		return nil
+	}
+
+	event := Event{
+		ID:         brokerEvent.ID,
+		Type:       brokerEvent.Type,
+		UserID:     userId,
+		Data:       brokerEvent.Data,
+		OccurredAt: brokerEvent.OccurredAt,
+		ReceivedAt: time.Now(),
+	}
+	_, err = receiveEvent(ctx, &event)
+	return err
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/notificationService/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/notificationService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/notificationService/grpcclient"
+	"github.com/manlikehenryy/loan-management-system-grpc/notificationService/provider"
+	userPb "github.com/manlikehenryy/loan-management-system-grpc/notificationService/user"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Delivery is one event sent to one user on one channel
+type Delivery struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	EventID       string             `bson:"eventId"`
+	EventType     string             `bson:"eventType"`
+	UserID        primitive.ObjectID `bson:"userId"`
+	Channel       string             `bson:"channel"`
+	Status        string             `bson:"status"`
+	To            string             `bson:"to,omitempty"`
+	Subject       string             `bson:"subject,omitempty"`
+	Body          string             `bson:"body,omitempty"`
+	Attempts      int                `bson:"attempts"`
+	LastError     string             `bson:"lastError,omitempty"`
+	NextAttemptAt time.Time          `bson:"nextAttemptAt"`
+	LeaseUntil    time.Time          `bson:"leaseUntil,omitempty"` // While sending, so another worker doesn't pick it up
+	CreatedAt     time.Time          `bson:"createdAt"`
+	SentAt        *time.Time         `bson:"sentAt,omitempty"`
+}
+
+// Delivery statuses
+const (
+	DeliveryPending = "pending"
+	DeliverySending = "sending"
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"  // Gave up after deliveryMaxAttempts
+	DeliverySkipped = "skipped" // Turned off by the user, or no verified address for the channel
+)
+
+const (
+	deliveryMaxAttempts  = 5
+	deliveryRetryDelay   = 30 * time.Second // Doubled after each failed attempt
+	deliveryPollInterval = 10 * time.Second
+	deliveryLease        = time.Minute
+)
+
+var deliveryWake = make(chan struct{}, 1)
+
+// scheduleDeliveries records a pending delivery for each channel event goes out on. Channels the
+// user has turned off are recorded as skipped.
+func scheduleDeliveries(ctx context.Context, event *Event, preferences *Preferences) error {
+	now := time.Now()
+
+	var deliveries []interface{}
+	for _, channel := range eventChannels(event.Type) {
+		delivery := Delivery{
+			EventID:       event.ID,
+			EventType:     event.Type,
+			UserID:        event.UserID,
+			Channel:       channel,
+			Status:        DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		}
+		if !preferences.enabled(channel) {
+			delivery.Status = DeliverySkipped
+			delivery.LastError = "turned off by user"
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	_, err := database.GetCollection("deliveries").InsertMany(ctx, deliveries)
+	return err
+}
+
+// StartDeliveryWorker sends due deliveries in the background until ctx is done. Several instances
+// can run at once: each delivery is leased to one worker while it's being sent.
+func StartDeliveryWorker(ctx context.Context) {
+	go func() {
+		for {
+			processDueDeliveries(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-deliveryWake:
+			case <-time.After(deliveryPollInterval):
+			}
+		}
+	}()
+}
+
+// wakeDeliveryWorker makes the worker look for due deliveries now instead of at its next poll
+func wakeDeliveryWorker() {
+	select {
+	case deliveryWake <- struct{}{}:
+	default:
+	}
+}
+
+func processDueDeliveries(ctx context.Context) {
+	deliveriesCollection := database.GetCollection("deliveries")
+
+	for ctx.Err() == nil {
+		now := time.Now()
+
+		// A delivery left "sending" by a worker that died is picked up again once its lease runs out
+		var delivery Delivery
+		err := deliveriesCollection.FindOneAndUpdate(ctx,
+			bson.M{"$or": []bson.M{
+				{"status": DeliveryPending, "nextAttemptAt": bson.M{"$lte": now}},
+				{"status": DeliverySending, "leaseUntil": bson.M{"$lt": now}},
+			}},
+			bson.M{"$set": bson.M{"status": DeliverySending, "leaseUntil": now.Add(deliveryLease)}},
+			options.FindOneAndUpdate().SetSort(bson.M{"nextAttemptAt": 1}).SetReturnDocument(options.After),
+		).Decode(&delivery)
+		if err != nil {
+			if err != mongo.ErrNoDocuments {
+				log.Println("Failed to claim delivery:", err)
+			}
+			return
+		}
+
+		deliver(ctx, &delivery)
+	}
+}
+
+// deliver renders and sends a claimed delivery, then records the outcome
+func deliver(ctx context.Context, delivery *Delivery) {
+	message, skipReason, err := buildMessage(ctx, delivery)
+	if err == nil && skipReason == "" {
+		err = provider.Send(ctx, message)
+	}
+
+	now := time.Now()
+	update := bson.M{"to": message.To, "subject": message.Subject, "body": message.Body, "attempts": delivery.Attempts + 1}
+	switch {
+	case skipReason != "":
+		update["status"] = DeliverySkipped
+		update["lastError"] = skipReason
+		update["attempts"] = delivery.Attempts
+	case err == nil:
+		update["status"] = DeliverySent
+		update["sentAt"] = now
+		update["lastError"] = ""
+	case delivery.Attempts+1 >= deliveryMaxAttempts:
+		log.Printf("Giving up on %s delivery %s: %v", delivery.Channel, delivery.ID.Hex(), err)
+		update["status"] = DeliveryFailed
+		update["lastError"] = err.Error()
+	default:
+		update["status"] = DeliveryPending
+		update["lastError"] = err.Error()
+		update["nextAttemptAt"] = now.Add(deliveryRetryDelay << delivery.Attempts)
+	}
+
+	if _, err := database.GetCollection("deliveries").UpdateOne(ctx, bson.M{"_id": delivery.ID}, bson.M{"$set": update}); err != nil {
+		log.Println("Failed to record delivery:", err)
+	}
+}
+
+// buildMessage renders delivery's message and looks up where to send it. A non-empty reason means
+// the delivery can never be made and should be skipped; an error is worth retrying.
+func buildMessage(ctx context.Context, delivery *Delivery) (provider.Message, string, error) {
+	message := provider.Message{ID: delivery.ID.Hex(), Channel: delivery.Channel, UserID: delivery.UserID.Hex(), EventType: delivery.EventType}
+
+	var event Event
+	if err := database.GetCollection("events").FindOne(ctx, bson.M{"_id": delivery.EventID}).Decode(&event); err != nil {
+		return message, "", err
+	}
+
+	contact, err := userContact(ctx, delivery.UserID.Hex())
+	if err != nil {
+		return message, "", err
+	}
+
+	// Only verified addresses are used, so messages don't go to someone else's inbox or phone
+	switch delivery.Channel {
+	case provider.ChannelEmail:
+		if contact.Email == "" || !contact.EmailVerified {
+			return message, "no verified email address", nil
+		}
+		message.To = contact.Email
+	case provider.ChannelSMS:
+		if contact.Phone == "" || !contact.PhoneVerified {
+			return message, "no verified phone number", nil
+		}
+		message.To = contact.Phone
+	}
+
+	data := map[string]string{"firstName": contact.FirstName}
+	for key, value := range event.Data {
+		data[key] = value
+	}
+
+	message.Data = event.Data
+	message.Subject, message.Body, err = renderMessage(event.Type, delivery.Channel, data)
+	if err != nil {
+		return message, "template error: " + err.Error(), nil
+	}
+	return message, "", nil
+}
+
+// userContact fetches the user's name and addresses from userService
+func userContact(ctx context.Context, userId string) (*userPb.GetUserContactResponse, error) {
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	c := grpcclient.NewAuthContext(ctx, configs.Env.TOKEN)
+
+	contactResp, err := userServiceClient.GetUserContact(c, &userPb.GetUserContactRequest{UserId: userId})
+	if contactResp == nil {
+		return nil, fmt.Errorf("GetUserContact: %v", err)
+	}
+	if err != nil || !contactResp.Status {
+		return nil, fmt.Errorf("GetUserContact: %s", contactResp.Message)
+	}
+	return contactResp, nil
+}
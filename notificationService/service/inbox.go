@@ -0,0 +1,382 @@
+package service
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/notificationService/database"
+	pb "github.com/manlikehenryy/loan-management-system-grpc/notificationService/notification"
+	"github.com/manlikehenryy/loan-management-system-grpc/notificationService/provider"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+const (
+	inboxPollInterval = time.Second
+	// inboxPollOverlap is how far back each poll looks, so a message written by another instance
+	// with an earlier createdAt than the last poll is still picked up
+	inboxPollOverlap = 5 * time.Second
+	// inboxReplayLimit caps how many missed notifications a resumed stream is sent
+	inboxReplayLimit   = 100
+	inboxStreamBacklog = 16
+)
+
+// inboxSubscriber is one open StreamInbox call
+type inboxSubscriber struct {
+	userId   primitive.ObjectID
+	messages chan provider.InboxMessage
+}
+
+// inboxHub hands new inbox messages to the StreamInbox calls open on this instance
+type inboxHub struct {
+	mu          sync.Mutex
+	subscribers map[primitive.ObjectID]map[*inboxSubscriber]struct{}
+}
+
+var inbox = &inboxHub{subscribers: map[primitive.ObjectID]map[*inboxSubscriber]struct{}{}}
+
+func (h *inboxHub) subscribe(userId primitive.ObjectID) *inboxSubscriber {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subscriber := &inboxSubscriber{userId: userId, messages: make(chan provider.InboxMessage, inboxStreamBacklog)}
+	if h.subscribers[userId] == nil {
+		h.subscribers[userId] = map[*inboxSubscriber]struct{}{}
+	}
+	h.subscribers[userId][subscriber] = struct{}{}
+	return subscriber
+}
+
+func (h *inboxHub) unsubscribe(subscriber *inboxSubscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(subscriber)
+}
+
+// remove drops subscriber and closes its channel. h.mu must be held.
+func (h *inboxHub) remove(subscriber *inboxSubscriber) {
+	subscribers, ok := h.subscribers[subscriber.userId]
+	if !ok {
+		return
+	}
+	if _, ok := subscribers[subscriber]; !ok {
+		return
+	}
+	delete(subscribers, subscriber)
+	close(subscriber.messages)
+	if len(subscribers) == 0 {
+		delete(h.subscribers, subscriber.userId)
+	}
+}
+
+func (h *inboxHub) userIds() []primitive.ObjectID {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	userIds := make([]primitive.ObjectID, 0, len(h.subscribers))
+	for userId := range h.subscribers {
+		userIds = append(userIds, userId)
+	}
+	return userIds
+}
+
+// publish sends message to its user's streams. A stream that has fallen behind is closed, and
+// the client resumes from the last notification it received.
+func (h *inboxHub) publish(message provider.InboxMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for subscriber := range h.subscribers[message.UserID] {
+		select {
+		case subscriber.messages <- message:
+		default:
+			h.remove(subscriber)
+		}
+	}
+}
+
+// StartInboxWatcher polls the inbox for new messages for users with an open stream until ctx is
+// done. Any instance's delivery worker can write a message, so the collection is the source of
+// truth rather than this instance's own writes.
+func StartInboxWatcher(ctx context.Context) {
+	go func() {
+		since := time.Now()
+		seen := map[primitive.ObjectID]time.Time{}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(inboxPollInterval):
+			}
+
+			since = pollInbox(ctx, since, seen)
+		}
+	}()
+}
+
+// pollInbox publishes messages created since the last poll, less the overlap, that haven't been
+// seen yet. It returns the time to poll from next.
+func pollInbox(ctx context.Context, since time.Time, seen map[primitive.ObjectID]time.Time) time.Time {
+	now := time.Now()
+	from := since.Add(-inboxPollOverlap)
+
+	for id, createdAt := range seen {
+		if createdAt.Before(from) {
+			delete(seen, id)
+		}
+	}
+
+	userIds := inbox.userIds()
+	if len(userIds) == 0 {
+		return now
+	}
+
+	cursor, err := database.GetCollection("inbox").Find(ctx,
+		bson.M{"userId": bson.M{"$in": userIds}, "createdAt": bson.M{"$gte": from}},
+		options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}),
+	)
+	if err != nil {
+		log.Println("Failed to poll inbox:", err)
+		return since
+	}
+
+	var messages []provider.InboxMessage
+	if err := cursor.All(ctx, &messages); err != nil {
+		log.Println("Failed to poll inbox:", err)
+		return since
+	}
+
+	for _, message := range messages {
+		if _, ok := seen[message.ID]; ok {
+			continue
+		}
+		seen[message.ID] = message.CreatedAt
+		inbox.publish(message)
+	}
+	return now
+}
+
+func (s *NotificationServiceServer) ListInbox(ctx context.Context, req *pb.ListInboxRequest) (*pb.ListInboxResponse, error) {
+	userId, message, statusCode := principalUserId(ctx)
+	if statusCode != http.StatusOK {
+		return listInboxErrorResponse(message, statusCode), nil
+	}
+
+	page, limit := int64(req.GetPage()), int64(req.GetLimit())
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	inboxCollection := database.GetCollection("inbox")
+
+	filter := bson.M{"userId": userId}
+	if req.GetUnreadOnly() {
+		filter["readAt"] = nil
+	}
+
+	total, err := inboxCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		log.Println("Database error:", err)
+		return listInboxErrorResponse("Failed to list notifications", http.StatusInternalServerError), nil
+	}
+
+	unreadCount, err := inboxCollection.CountDocuments(ctx, bson.M{"userId": userId, "readAt": nil})
+	if err != nil {
+		log.Println("Database error:", err)
+		return listInboxErrorResponse("Failed to list notifications", http.StatusInternalServerError), nil
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+
+	cursor, err := inboxCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		log.Println("Database error:", err)
+		return listInboxErrorResponse("Failed to list notifications", http.StatusInternalServerError), nil
+	}
+
+	var messages []provider.InboxMessage
+	if err := cursor.All(ctx, &messages); err != nil {
+		log.Println("Database error:", err)
+		return listInboxErrorResponse("Failed to list notifications", http.StatusInternalServerError), nil
+	}
+
+	notifications := make([]*pb.InboxNotification, 0, len(messages))
+	for i := range messages {
+		notifications = append(notifications, inboxNotificationDetails(&messages[i]))
+	}
+
+	return listInboxSuccessResponse("Notifications retrieved", http.StatusOK, notifications, total, unreadCount), nil
+}
+
+func (s *NotificationServiceServer) MarkInboxRead(ctx context.Context, req *pb.MarkInboxReadRequest) (*pb.MarkInboxReadResponse, error) {
+	userId, message, statusCode := principalUserId(ctx)
+	if statusCode != http.StatusOK {
+		return markInboxReadErrorResponse(message, statusCode), nil
+	}
+
+	if !req.GetAll() && len(req.GetIds()) == 0 {
+		return markInboxReadErrorResponse("Missing required field(s)", http.StatusBadRequest), nil
+	}
+	if len(req.GetIds()) > maxPageSize {
+		return markInboxReadErrorResponse("Too many notification IDs", http.StatusBadRequest), nil
+	}
+
+	filter := bson.M{"userId": userId, "readAt": nil}
+	if !req.GetAll() {
+		ids := make([]primitive.ObjectID, 0, len(req.GetIds()))
+		for _, hex := range req.GetIds() {
+			id, err := primitive.ObjectIDFromHex(hex)
+			if err != nil {
+				return markInboxReadErrorResponse("Invalid notification ID", http.StatusBadRequest), nil
+			}
+			ids = append(ids, id)
+		}
+		filter["_id"] = bson.M{"$in": ids}
+	}
+
+	inboxCollection := database.GetCollection("inbox")
+
+	result, err := inboxCollection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"readAt": time.Now()}})
+	if err != nil {
+		log.Println("Database error:", err)
+		return markInboxReadErrorResponse("Failed to mark notifications read", http.StatusInternalServerError), nil
+	}
+
+	unreadCount, err := inboxCollection.CountDocuments(ctx, bson.M{"userId": userId, "readAt": nil})
+	if err != nil {
+		log.Println("Database error:", err)
+		return markInboxReadErrorResponse("Failed to mark notifications read", http.StatusInternalServerError), nil
+	}
+
+	return markInboxReadSuccessResponse("Notifications marked read", http.StatusOK, result.ModifiedCount, unreadCount), nil
+}
+
+func (s *NotificationServiceServer) StreamInbox(req *pb.StreamInboxRequest, stream grpc.ServerStreamingServer[pb.InboxNotification]) error {
+	ctx := stream.Context()
+
+	userId, message, statusCode := principalUserId(ctx)
+	if statusCode != http.StatusOK {
+		if statusCode == http.StatusUnauthorized {
+			return status.Error(codes.Unauthenticated, message)
+		}
+		return status.Error(codes.InvalidArgument, message)
+	}
+
+	// Subscribe before replaying so nothing written in between is missed. The client may see a
+	// notification twice and should drop repeats by ID.
+	subscriber := inbox.subscribe(userId)
+	defer inbox.unsubscribe(subscriber)
+
+	replayed := map[primitive.ObjectID]bool{}
+	if req.GetAfter() != "" {
+		missed, err := missedInboxMessages(ctx, userId, req.GetAfter())
+		if err != nil {
+			log.Println("Database error:", err)
+			return status.Error(codes.Internal, "Failed to read notifications")
+		}
+		for i := range missed {
+			if err := stream.Send(inboxNotificationDetails(&missed[i])); err != nil {
+				return err
+			}
+			replayed[missed[i].ID] = true
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message, ok := <-subscriber.messages:
+			if !ok {
+				return status.Error(codes.Unavailable, "Stream fell behind; reconnect to resume")
+			}
+			if replayed[message.ID] {
+				continue
+			}
+			if err := stream.Send(inboxNotificationDetails(&message)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// missedInboxMessages returns userId's messages created since the one with ID after, oldest
+// first. Messages from the same millisecond are included, so a few may be repeats. An unknown ID
+// replays nothing.
+func missedInboxMessages(ctx context.Context, userId primitive.ObjectID, after string) ([]provider.InboxMessage, error) {
+	afterId, err := primitive.ObjectIDFromHex(after)
+	if err != nil {
+		return nil, nil
+	}
+
+	inboxCollection := database.GetCollection("inbox")
+
+	var last provider.InboxMessage
+	if err := inboxCollection.FindOne(ctx, bson.M{"_id": afterId, "userId": userId}).Decode(&last); err != nil {
+		return nil, nil
+	}
+
+	cursor, err := inboxCollection.Find(ctx,
+		bson.M{"userId": userId, "createdAt": bson.M{"$gte": last.CreatedAt}, "_id": bson.M{"$ne": afterId}},
+		options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}).SetLimit(inboxReplayLimit),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []provider.InboxMessage
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func inboxNotificationDetails(message *provider.InboxMessage) *pb.InboxNotification {
+	return &pb.InboxNotification{
+		Id:        message.ID.Hex(),
+		EventType: message.EventType,
+		Title:     message.Title,
+		Body:      message.Body,
+		Data:      message.Data,
+		Read:      message.ReadAt != nil,
+		CreatedAt: message.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func listInboxSuccessResponse(message string, statusCode int, notifications []*pb.InboxNotification, total int64, unreadCount int64) *pb.ListInboxResponse {
+	return &pb.ListInboxResponse{Message: message, Status: true, StatusCode: int32(statusCode), Notifications: notifications, Total: total, UnreadCount: unreadCount}
+}
+
+func listInboxErrorResponse(message string, statusCode int) *pb.ListInboxResponse {
+	return &pb.ListInboxResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func markInboxReadSuccessResponse(message string, statusCode int, updated int64, unreadCount int64) *pb.MarkInboxReadResponse {
+	return &pb.MarkInboxReadResponse{Message: message, Status: true, StatusCode: int32(statusCode), Updated: updated, UnreadCount: unreadCount}
+}
+
+func markInboxReadErrorResponse(message string, statusCode int) *pb.MarkInboxReadResponse {
+	return &pb.MarkInboxReadResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
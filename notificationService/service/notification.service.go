@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/notificationService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/notificationService/helpers"
+	pb "github.com/manlikehenryy/loan-management-system-grpc/notificationService/notification"
+	"github.com/manlikehenryy/loan-management-system-grpc/notificationService/provider"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Event is a domain event received from another service. Its ID is the publisher's event ID,
+// which makes a repeated event a duplicate key.
+type Event struct {
+	ID         string             `bson:"_id"`
+	Type       string             `bson:"type"`
+	UserID     primitive.ObjectID `bson:"userId"`
+	Data       map[string]string  `bson:"data,omitempty"`
+	OccurredAt time.Time          `bson:"occurredAt"`
+	ReceivedAt time.Time          `bson:"receivedAt"`
+}
+
+// Preferences are the channels a user has turned off. Every other channel is on.
+type Preferences struct {
+	UserID    primitive.ObjectID `bson:"_id"`
+	Disabled  []string           `bson:"disabled"`
+	UpdatedAt time.Time          `bson:"updatedAt"`
+}
+
+type NotificationServiceServer struct {
+	pb.UnimplementedNotificationServiceServer
+}
+
+func NewNotificationServiceServer() *NotificationServiceServer {
+	return &NotificationServiceServer{}
+}
+
+// receiveEvent records event and schedules its deliveries in one transaction, so an event is
+// either fully handled or can be received again. It reports whether the event had already been
+// received, in which case nothing is done.
+func receiveEvent(ctx context.Context, event *Event) (bool, error) {
+	err := database.WithTransaction(ctx, func(ctx mongo.SessionContext) error {
+		if _, err := database.GetCollection("events").InsertOne(ctx, event); err != nil {
+			return err
+		}
+
+		preferences, err := findPreferences(ctx, event.UserID)
+		if err != nil {
+			return err
+		}
+		return scheduleDeliveries(ctx, event, preferences)
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	wakeDeliveryWorker()
+	return false, nil
+}
+
+func (s *NotificationServiceServer) GetPreferences(ctx context.Context, req *pb.GetPreferencesRequest) (*pb.GetPreferencesResponse, error) {
+	userId, message, statusCode := principalUserId(ctx)
+	if statusCode != http.StatusOK {
+		return getPreferencesErrorResponse(message, statusCode), nil
+	}
+
+	preferences, err := findPreferences(ctx, userId)
+	if err != nil {
+		log.Println("Database error:", err)
+		return getPreferencesErrorResponse("Failed to read preferences", http.StatusInternalServerError), nil
+	}
+
+	return getPreferencesSuccessResponse("Preferences retrieved", http.StatusOK, preferencesDetails(preferences)), nil
+}
+
+func (s *NotificationServiceServer) UpdatePreferences(ctx context.Context, req *pb.UpdatePreferencesRequest) (*pb.UpdatePreferencesResponse, error) {
+	userId, message, statusCode := principalUserId(ctx)
+	if statusCode != http.StatusOK {
+		return updatePreferencesErrorResponse(message, statusCode), nil
+	}
+
+	if req.GetPreferences() == nil {
+		return updatePreferencesErrorResponse("Missing required field(s)", http.StatusBadRequest), nil
+	}
+
+	disabled := []string{}
+	for channel, enabled := range map[string]bool{
+		provider.ChannelEmail: req.GetPreferences().GetEmail(),
+		provider.ChannelSMS:   req.GetPreferences().GetSms(),
+		provider.ChannelInApp: req.GetPreferences().GetInApp(),
+	} {
+		if !enabled {
+			disabled = append(disabled, channel)
+		}
+	}
+
+	preferences := Preferences{UserID: userId, Disabled: disabled, UpdatedAt: time.Now()}
+	_, err := database.GetCollection("preferences").ReplaceOne(ctx, bson.M{"_id": userId}, preferences, options.Replace().SetUpsert(true))
+	if err != nil {
+		log.Println("Database error:", err)
+		return updatePreferencesErrorResponse("Failed to update preferences", http.StatusInternalServerError), nil
+	}
+
+	return updatePreferencesSuccessResponse("Preferences updated", http.StatusOK, preferencesDetails(&preferences)), nil
+}
+
+// findPreferences returns userId's preferences, or the defaults if they have never set any
+func findPreferences(ctx context.Context, userId primitive.ObjectID) (*Preferences, error) {
+	var preferences Preferences
+	err := database.GetCollection("preferences").FindOne(ctx, bson.M{"_id": userId}).Decode(&preferences)
+	if err == mongo.ErrNoDocuments {
+		return &Preferences{UserID: userId}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &preferences, nil
+}
+
+func (p *Preferences) enabled(channel string) bool {
+	return !contains(p.Disabled, channel)
+}
+
+func preferencesDetails(preferences *Preferences) *pb.ChannelPreferences {
+	return &pb.ChannelPreferences{
+		Email: preferences.enabled(provider.ChannelEmail),
+		Sms:   preferences.enabled(provider.ChannelSMS),
+		InApp: preferences.enabled(provider.ChannelInApp),
+	}
+}
+
+// principalUserId returns the ID of the end-user the call is made for
+func principalUserId(ctx context.Context) (primitive.ObjectID, string, int) {
+	principal, ok := helpers.PrincipalFromContext(ctx)
+	if !ok {
+		return primitive.NilObjectID, "Unauthorized: No identity provided", http.StatusUnauthorized
+	}
+
+	userId, err := primitive.ObjectIDFromHex(principal.UserId)
+	if err != nil {
+		return primitive.NilObjectID, "Invalid user ID", http.StatusBadRequest
+	}
+	return userId, "", http.StatusOK
+}
+
+func getPreferencesSuccessResponse(message string, statusCode int, preferences *pb.ChannelPreferences) *pb.GetPreferencesResponse {
+	return &pb.GetPreferencesResponse{Message: message, Status: true, StatusCode: int32(statusCode), Preferences: preferences}
+}
+
+func getPreferencesErrorResponse(message string, statusCode int) *pb.GetPreferencesResponse {
+	return &pb.GetPreferencesResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func updatePreferencesSuccessResponse(message string, statusCode int, preferences *pb.ChannelPreferences) *pb.UpdatePreferencesResponse {
+	return &pb.UpdatePreferencesResponse{Message: message, Status: true, StatusCode: int32(statusCode), Preferences: preferences}
+}
+
+func updatePreferencesErrorResponse(message string, statusCode int) *pb.UpdatePreferencesResponse {
+	return &pb.UpdatePreferencesResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/notificationService/provider"
+)
+
+// Event types published by loanService and walletService
+const (
+	EventLoanApplied    = "LoanApplied"
+	EventLoanApproved   = "LoanApproved"
+	EventLoanRejected   = "LoanRejected"
+	EventLoanDisbursed  = "LoanDisbursed"
+	EventRepaymentDue   = "RepaymentDue"
+	EventWalletCredited = "WalletCredited"
+)
+
+// messageTemplate is the text/template source for one event on one channel. SMS messages have
+// no subject; for in-app messages it's the title.
+type messageTemplate struct {
+	Subject string
+	Body    string
+}
+
+// templateSources lists the channels each event goes out on. Templates see the event's data plus
+// firstName.
+var templateSources = map[string]map[string]messageTemplate{
+	EventLoanApplied: {
+		provider.ChannelEmail: {
+			Subject: "We've received your loan application",
+			Body:    "Hi {{.firstName}},\n\nThanks for applying for a loan of {{.amount}}. We'll let you know once it has been reviewed.\n\nApplication reference: {{.loanId}}",
+		},
+		provider.ChannelInApp: {
+			Subject: "Application received",
+			Body:    "Your application for {{.amount}} is being reviewed.",
+		},
+	},
+	EventLoanApproved: {
+		provider.ChannelEmail: {
+			Subject: "Your loan has been approved",
+			Body:    "Hi {{.firstName}},\n\nYour loan of {{.amount}} has been approved. You'll repay {{.monthlyRepayment}} a month over {{.tenure}} months, starting from {{.effectiveDate}}.\n\nLoan reference: {{.loanId}}",
+		},
+		provider.ChannelSMS: {
+			Body: "Your loan of {{.amount}} has been approved. Repayments: {{.monthlyRepayment}} a month for {{.tenure}} months.",
+		},
+		provider.ChannelInApp: {
+			Subject: "Loan approved",
+			Body:    "Your loan of {{.amount}} has been approved.",
+		},
+	},
+	EventLoanRejected: {
+		provider.ChannelEmail: {
+			Subject: "Update on your loan application",
+			Body:    "Hi {{.firstName}},\n\nWe're sorry, your application for a loan of {{.amount}} wasn't approved this time.\n\nApplication reference: {{.loanId}}",
+		},
+		provider.ChannelInApp: {
+			Subject: "Application not approved",
+			Body:    "Your application for {{.amount}} wasn't approved.",
+		},
+	},
+	EventLoanDisbursed: {
+		provider.ChannelEmail: {
+			Subject: "Your loan has been paid into your wallet",
+			Body:    "Hi {{.firstName}},\n\n{{.amount}} from your loan has been paid into your wallet.\n\nLoan reference: {{.loanId}}",
+		},
+		provider.ChannelSMS: {
+			Body: "{{.amount}} from your loan has been paid into your wallet.",
+		},
+		provider.ChannelInApp: {
+			Subject: "Loan paid out",
+			Body:    "{{.amount}} has been paid into your wallet.",
+		},
+	},
+	EventRepaymentDue: {
+		provider.ChannelEmail: {
+			Subject: "Your loan repayment is due soon",
+			Body:    "Hi {{.firstName}},\n\nA repayment of {{.amount}} on your loan is due on {{.dueDate}}. Please make sure your wallet has enough funds.\n\nLoan reference: {{.loanId}}",
+		},
+		provider.ChannelSMS: {
+			Body: "Reminder: your loan repayment of {{.amount}} is due on {{.dueDate}}.",
+		},
+		provider.ChannelInApp: {
+			Subject: "Repayment due soon",
+			Body:    "{{.amount}} is due on {{.dueDate}}.",
+		},
+	},
+	EventWalletCredited: {
+		provider.ChannelInApp: {
+			Subject: "Wallet credited",
+			Body:    "{{.amount}} has been added to your wallet.",
+		},
+	},
+}
+
+type compiledTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+var templates = compileTemplates()
+
+func compileTemplates() map[string]map[string]compiledTemplate {
+	compiled := map[string]map[string]compiledTemplate{}
+	for eventType, channels := range templateSources {
+		compiled[eventType] = map[string]compiledTemplate{}
+		for channel, source := range channels {
+			name := eventType + "." + channel
+			compiled[eventType][channel] = compiledTemplate{
+				subject: template.Must(template.New(name + ".subject").Option("missingkey=error").Parse(source.Subject)),
+				body:    template.Must(template.New(name + ".body").Option("missingkey=error").Parse(source.Body)),
+			}
+		}
+	}
+	return compiled
+}
+
+// eventChannels returns the channels eventType is sent on, in a fixed order
+func eventChannels(eventType string) []string {
+	var channels []string
+	for _, channel := range []string{provider.ChannelEmail, provider.ChannelSMS, provider.ChannelInApp} {
+		if _, ok := templates[eventType][channel]; ok {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+// renderMessage fills in the subject and body for eventType on channel
+func renderMessage(eventType string, channel string, data map[string]string) (string, string, error) {
+	tmpl, ok := templates[eventType][channel]
+	if !ok {
+		return "", "", fmt.Errorf("no %s template for %s", channel, eventType)
+	}
+
+	var subject, body strings.Builder
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return "", "", err
+	}
+	if err := tmpl.body.Execute(&body, data); err != nil {
+		return "", "", err
+	}
+	return subject.String(), body.String(), nil
+}
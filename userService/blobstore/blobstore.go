@@ -0,0 +1,46 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/configs"
+)
+
+// ErrNotFound is returned by Get for a key that doesn't exist
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore keeps uploaded files, addressed by key
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Default is the store configured by Initialize
+var Default BlobStore
+
+// Initialize sets up Default from BLOB_STORE. Only "local" (the default) is built in; other
+// backends implement BlobStore.
+func Initialize() {
+	store, err := New(configs.Env.BLOB_STORE)
+	if err != nil {
+		panic("Failed to set up blob store: " + err.Error())
+	}
+	Default = store
+}
+
+// New returns the store of the given kind
+func New(kind string) (BlobStore, error) {
+	switch kind {
+	case "", "local":
+		dir := configs.Env.BLOB_STORE_DIR
+		if dir == "" {
+			dir = "uploads"
+		}
+		return NewLocalStore(dir)
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", kind)
+	}
+}
@@ -1,6 +1,8 @@
 package configs
 
 import (
+	"errors"
+	"io/fs"
 	"log"
 	"os"
 
@@ -8,12 +10,47 @@ import (
 )
 
 type Config struct {
-	PORT               string
-	MONGO_DB_URI       string
-	MODE               string
-	JWT_SECRET         string
-	TOKEN              string
-	WALLET_SERVICE_URL string
+	PORT                             string
+	MONGO_DB_URI                     string
+	MODE                             string
+	JWT_KEYS_DIR                     string
+	JWT_SIGNING_KID                  string
+	TOKEN                            string
+	IDENTITY_SECRET                  string
+	TLS_ENABLED                      string
+	TLS_CERT_FILE                    string
+	TLS_KEY_FILE                     string
+	TLS_CA_FILE                      string
+	TLS_DEV_MODE                     string
+	TLS_DEV_DIR                      string
+	POLICY_FILE                      string
+	BOOTSTRAP_SUPERADMIN             string
+	LOGIN_MAX_FAILURES               string
+	LOGIN_LOCKOUT_MINUTES            string
+	NOTIFIER                         string
+	NOTIFIER_DIR                     string
+	SMS_NOTIFIER                     string
+	SMTP_HOST                        string
+	SMTP_PORT                        string
+	SMTP_USERNAME                    string
+	SMTP_PASSWORD                    string
+	SMTP_FROM                        string
+	PASSWORD_RESET_URL               string
+	PASSWORD_MIN_LENGTH              string
+	PASSWORD_MIN_CLASSES             string
+	PASSWORD_BLOCKLIST_FILE          string
+	PASSWORD_HASH_ALGORITHM          string
+	BCRYPT_COST                      string
+	ARGON2_MEMORY_KB                 string
+	ARGON2_ITERATIONS                string
+	ARGON2_PARALLELISM               string
+	PROFILE_ENCRYPTION_KEY           string
+	PROFILE_ENCRYPTION_PREVIOUS_KEYS string
+	BLOB_STORE                       string
+	BLOB_STORE_DIR                   string
+	BROKER                           string
+	BROKER_DB                        string
+	TRUST_DECLARED_CALLER            string
 }
 
 var Env *Config
@@ -23,8 +60,9 @@ func init() {
 	Env = &Config{}
 
 	if os.Getenv("MODE") != "production" {
+		// Without a .env file, such as under go test, settings come from the environment alone
 		err := godotenv.Load()
-		if err != nil {
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
 			log.Fatalf("Error loading .env file: %v", err)
 		}
 
@@ -33,7 +71,42 @@ func init() {
 	Env.PORT = os.Getenv("PORT")
 	Env.MONGO_DB_URI = os.Getenv("MONGO_DB_URI")
 	Env.MODE = os.Getenv("MODE")
-	Env.JWT_SECRET = os.Getenv("JWT_SECRET")
+	Env.JWT_KEYS_DIR = os.Getenv("JWT_KEYS_DIR")
+	Env.JWT_SIGNING_KID = os.Getenv("JWT_SIGNING_KID")
 	Env.TOKEN = os.Getenv("TOKEN")
-	Env.WALLET_SERVICE_URL = os.Getenv("WALLET_SERVICE_URL")
+	Env.IDENTITY_SECRET = os.Getenv("IDENTITY_SECRET")
+	Env.TLS_ENABLED = os.Getenv("TLS_ENABLED")
+	Env.TLS_CERT_FILE = os.Getenv("TLS_CERT_FILE")
+	Env.TLS_KEY_FILE = os.Getenv("TLS_KEY_FILE")
+	Env.TLS_CA_FILE = os.Getenv("TLS_CA_FILE")
+	Env.TLS_DEV_MODE = os.Getenv("TLS_DEV_MODE")
+	Env.TLS_DEV_DIR = os.Getenv("TLS_DEV_DIR")
+	Env.POLICY_FILE = os.Getenv("POLICY_FILE")
+	Env.BOOTSTRAP_SUPERADMIN = os.Getenv("BOOTSTRAP_SUPERADMIN")
+	Env.LOGIN_MAX_FAILURES = os.Getenv("LOGIN_MAX_FAILURES")
+	Env.LOGIN_LOCKOUT_MINUTES = os.Getenv("LOGIN_LOCKOUT_MINUTES")
+	Env.NOTIFIER = os.Getenv("NOTIFIER")
+	Env.NOTIFIER_DIR = os.Getenv("NOTIFIER_DIR")
+	Env.SMS_NOTIFIER = os.Getenv("SMS_NOTIFIER")
+	Env.SMTP_HOST = os.Getenv("SMTP_HOST")
+	Env.SMTP_PORT = os.Getenv("SMTP_PORT")
+	Env.SMTP_USERNAME = os.Getenv("SMTP_USERNAME")
+	Env.SMTP_PASSWORD = os.Getenv("SMTP_PASSWORD")
+	Env.SMTP_FROM = os.Getenv("SMTP_FROM")
+	Env.PASSWORD_RESET_URL = os.Getenv("PASSWORD_RESET_URL")
+	Env.PASSWORD_MIN_LENGTH = os.Getenv("PASSWORD_MIN_LENGTH")
+	Env.PASSWORD_MIN_CLASSES = os.Getenv("PASSWORD_MIN_CLASSES")
+	Env.PASSWORD_BLOCKLIST_FILE = os.Getenv("PASSWORD_BLOCKLIST_FILE")
+	Env.PASSWORD_HASH_ALGORITHM = os.Getenv("PASSWORD_HASH_ALGORITHM")
+	Env.BCRYPT_COST = os.Getenv("BCRYPT_COST")
+	Env.ARGON2_MEMORY_KB = os.Getenv("ARGON2_MEMORY_KB")
+	Env.ARGON2_ITERATIONS = os.Getenv("ARGON2_ITERATIONS")
+	Env.ARGON2_PARALLELISM = os.Getenv("ARGON2_PARALLELISM")
+	Env.PROFILE_ENCRYPTION_KEY = os.Getenv("PROFILE_ENCRYPTION_KEY")
+	Env.PROFILE_ENCRYPTION_PREVIOUS_KEYS = os.Getenv("PROFILE_ENCRYPTION_PREVIOUS_KEYS")
+	Env.BLOB_STORE = os.Getenv("BLOB_STORE")
+	Env.BLOB_STORE_DIR = os.Getenv("BLOB_STORE_DIR")
+	Env.BROKER = os.Getenv("BROKER")
+	Env.BROKER_DB = os.Getenv("BROKER_DB")
+	Env.TRUST_DECLARED_CALLER = os.Getenv("TRUST_DECLARED_CALLER")
 }
@@ -40,4 +40,20 @@ func Connect() {
 
 func GetCollection(name string) *mongo.Collection{
 	return DB.Collection(name)
-}
\ No newline at end of file
+}
+
+// WithTransaction runs fn in a transaction, retrying it on transient errors. Pass the context fn
+// receives to every query that should be part of the transaction. MongoDB only supports
+// transactions on a replica set or sharded cluster.
+func WithTransaction(ctx context.Context, fn func(ctx mongo.SessionContext) error) error {
+	session, err := Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(ctx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(ctx)
+	})
+	return err
+}
@@ -0,0 +1,41 @@
+package helpers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// ApiKeyPrefix marks partner API keys, which look like lms_<prefix>_<secret>
+const ApiKeyPrefix = "lms_"
+
+// GenerateApiKey returns a new API key, its public prefix and the hash to store
+func GenerateApiKey() (string, string, string, error) {
+	prefix := make([]byte, 6)
+	if _, err := rand.Read(prefix); err != nil {
+		return "", "", "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+
+	publicPrefix := hex.EncodeToString(prefix)
+	key := ApiKeyPrefix + publicPrefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return key, publicPrefix, HashToken(key), nil
+}
+
+// ParseApiKey extracts the public prefix used to look an API key up
+func ParseApiKey(key string) (string, error) {
+	rest, ok := strings.CutPrefix(key, ApiKeyPrefix)
+	if !ok {
+		return "", errors.New("malformed API key")
+	}
+	prefix, secret, found := strings.Cut(rest, "_")
+	if !found || len(prefix) != 12 || secret == "" {
+		return "", errors.New("malformed API key")
+	}
+	return prefix, nil
+}
@@ -0,0 +1,224 @@
+package helpers
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/configs"
+)
+
+// fieldKey is an AES-256 key for encrypting fields at rest. Its id is stored with each
+// ciphertext, so data written under a previous key can still be read after rotating.
+type fieldKey struct {
+	Id   string
+	AEAD cipher.AEAD
+	// MACKey is derived from the key for KeyedHash, so the AES key is never used for both
+	MACKey []byte
+}
+
+var (
+	fieldKeys      map[string]*fieldKey
+	currentFieldId string
+)
+
+const devFieldKeyFile = "profile-encryption.key"
+
+// loadFieldKeys reads PROFILE_ENCRYPTION_KEY, the current key, and PROFILE_ENCRYPTION_PREVIOUS_KEYS,
+// a comma-separated list of retired keys that are only used for decryption. Keys are base64-encoded
+// 32-byte values. Outside production a key is generated in JWT_KEYS_DIR if none is set.
+func loadFieldKeys() error {
+	current := configs.Env.PROFILE_ENCRYPTION_KEY
+	if current == "" {
+		if configs.Env.MODE == "production" {
+			return errors.New("PROFILE_ENCRYPTION_KEY is not set")
+		}
+		var err error
+		if current, err = devFieldKey(); err != nil {
+			return fmt.Errorf("failed to create dev encryption key: %w", err)
+		}
+	}
+
+	key, err := parseFieldKey(current)
+	if err != nil {
+		return fmt.Errorf("PROFILE_ENCRYPTION_KEY: %w", err)
+	}
+	keys := map[string]*fieldKey{key.Id: key}
+
+	for _, encoded := range strings.Split(configs.Env.PROFILE_ENCRYPTION_PREVIOUS_KEYS, ",") {
+		if encoded = strings.TrimSpace(encoded); encoded == "" {
+			continue
+		}
+		previous, err := parseFieldKey(encoded)
+		if err != nil {
+			return fmt.Errorf("PROFILE_ENCRYPTION_PREVIOUS_KEYS: %w", err)
+		}
+		keys[previous.Id] = previous
+	}
+
+	fieldKeys = keys
+	currentFieldId = key.Id
+	return nil
+}
+
+func parseFieldKey(encoded string) (*fieldKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != 32 {
+		return nil, errors.New("key must be 32 bytes")
+	}
+
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(raw)
+	mac := hmac.New(sha256.New, raw)
+	mac.Write([]byte("keyed-hash"))
+	return &fieldKey{Id: hex.EncodeToString(sum[:4]), AEAD: aead, MACKey: mac.Sum(nil)}, nil
+}
+
+func devFieldKey() (string, error) {
+	dir := configs.Env.JWT_KEYS_DIR
+	if dir == "" {
+		dir = "keys"
+	}
+	path := filepath.Join(dir, devFieldKeyFile)
+
+	if data, err := os.ReadFile(path); err == nil {
+		return strings.TrimSpace(string(data)), nil
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	encoded := base64.StdEncoding.EncodeToString(raw)
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(encoded), 0600); err != nil {
+		return "", err
+	}
+
+	log.Printf("Created dev profile encryption key in %s", path)
+	return encoded, nil
+}
+
+// EncryptField encrypts a field value with AES-GCM. context (e.g. the user ID and field name) is
+// authenticated but not stored, so a ciphertext can't be copied to another user or field.
+// Empty values stay empty.
+func EncryptField(plaintext string, context string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	id, sealed, err := seal([]byte(plaintext), context)
+	if err != nil {
+		return "", err
+	}
+	return id + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptField reverses EncryptField
+func DecryptField(ciphertext string, context string) (string, error) {
+	if ciphertext == "" {
+		return "", nil
+	}
+
+	id, encoded, found := strings.Cut(ciphertext, ":")
+	if !found {
+		return "", errors.New("malformed encrypted field")
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", errors.New("malformed encrypted field")
+	}
+
+	plaintext, err := open(id, sealed, context)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// EncryptBlob is EncryptField for file contents. The key id is stored as a prefix.
+func EncryptBlob(plaintext []byte, context string) ([]byte, error) {
+	id, sealed, err := seal(plaintext, context)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(id+":"), sealed...), nil
+}
+
+// DecryptBlob reverses EncryptBlob
+func DecryptBlob(ciphertext []byte, context string) ([]byte, error) {
+	id, sealed, found := bytes.Cut(ciphertext, []byte(":"))
+	if !found {
+		return nil, errors.New("malformed encrypted blob")
+	}
+	return open(string(id), sealed, context)
+}
+
+// KeyedHash returns an HMAC of value bound to context, keyed from the current field key. It's for
+// values too guessable to store under a plain hash, such as short one-time codes. Hashes change
+// when the key is rotated.
+func KeyedHash(value string, context string) (string, error) {
+	key := fieldKeys[currentFieldId]
+	if key == nil {
+		return "", errors.New("field encryption is not initialized")
+	}
+
+	mac := hmac.New(sha256.New, key.MACKey)
+	mac.Write([]byte(context))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// seal encrypts plaintext under the current key and returns the key id and nonce+ciphertext
+func seal(plaintext []byte, context string) (string, []byte, error) {
+	key := fieldKeys[currentFieldId]
+	if key == nil {
+		return "", nil, errors.New("field encryption is not initialized")
+	}
+
+	nonce := make([]byte, key.AEAD.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+	return key.Id, key.AEAD.Seal(nonce, nonce, plaintext, []byte(context)), nil
+}
+
+func open(id string, sealed []byte, context string) ([]byte, error) {
+	key, ok := fieldKeys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption key %q", id)
+	}
+	if len(sealed) < key.AEAD.NonceSize() {
+		return nil, errors.New("malformed ciphertext")
+	}
+
+	nonce, sealed := sealed[:key.AEAD.NonceSize()], sealed[key.AEAD.NonceSize():]
+	return key.AEAD.Open(nil, nonce, sealed, []byte(context))
+}
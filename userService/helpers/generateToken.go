@@ -4,43 +4,127 @@ import (
 	"errors"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/manlikehenryy/loan-management-system-grpc/userService/configs"
 )
 
-var secretKey string
+// Access tokens are short-lived; sessions are kept alive with refresh tokens
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// TokenIssuer is the iss claim of every access token
+const TokenIssuer = "userService"
+
+// Claims are the claims carried by an access token
+type Claims struct {
+	SessionId string   `json:"sid"`
+	Roles     []string `json:"roles,omitempty"`
+	jwt.RegisteredClaims
+}
 
 func Initialize() {
 	if configs.Env == nil {
 		panic("configs.Env is not initialized")
 	}
-	secretKey = configs.Env.JWT_SECRET
-	if secretKey == "" {
-		panic("JWT_SECRET is not set in the environment")
+	if err := loadSigningKeys(); err != nil {
+		panic("Failed to load JWT signing keys: " + err.Error())
+	}
+	if err := loadHashingConfig(); err != nil {
+		panic("Invalid password hashing settings: " + err.Error())
+	}
+	if err := loadPasswordPolicy(); err != nil {
+		panic("Invalid password policy: " + err.Error())
+	}
+	if err := loadFieldKeys(); err != nil {
+		panic("Failed to load profile encryption keys: " + err.Error())
+	}
+}
+
+func GenerateJwt(userId string, sessionId string, roles []string) (string, error) {
+	key := currentSigningKey()
+	now := time.Now()
+
+	token := jwt.NewWithClaims(key.Method, Claims{
+		SessionId: sessionId,
+		Roles:     roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    TokenIssuer,
+			Subject:   userId,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL)),
+		},
+	})
+	token.Header["kid"] = key.Kid
+
+	return token.SignedString(key.Private)
+}
+
+// ParseJwt returns the user ID and session ID of a valid access token
+func ParseJwt(tokenString string) (string, string, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return publicKey(kid, t.Method.Alg())
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(TokenIssuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || !token.Valid {
+		return "", "", errors.New("invalid token")
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || claims.Subject == "" || claims.SessionId == "" {
+		return "", "", errors.New("invalid claims")
 	}
+
+	return claims.Subject, claims.SessionId, nil
 }
 
-func GenerateJwt(issuer string) (string, error) {
-	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
-		Issuer: issuer,
-		ExpiresAt: time.Now().Add(time.Hour*24).Unix(),
+// MFAChallengeTTL is how long a user has to enter their MFA code after the password step
+const MFAChallengeTTL = 5 * time.Minute
+
+// mfaChallengeAudience keeps challenge tokens from being accepted anywhere else
+const mfaChallengeAudience = "mfa_challenge"
+
+// GenerateMfaChallenge issues the token that proves the password step of a login succeeded
+func GenerateMfaChallenge(userId string) (string, error) {
+	key := currentSigningKey()
+	now := time.Now()
+
+	token := jwt.NewWithClaims(key.Method, jwt.RegisteredClaims{
+		Issuer:    TokenIssuer,
+		Subject:   userId,
+		Audience:  jwt.ClaimStrings{mfaChallengeAudience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(MFAChallengeTTL)),
 	})
+	token.Header["kid"] = key.Kid
 
-	return claims.SignedString([]byte(secretKey))
+	return token.SignedString(key.Private)
 }
 
-func ParseJwt(tokenString string) (string, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.StandardClaims{}, func(t *jwt.Token) (interface{}, error) {
-        return []byte(secretKey), nil
-    })
-    if err != nil || !token.Valid {
-        return "", errors.New("invalid token")
-    }
-
-    claims, ok := token.Claims.(*jwt.StandardClaims)
-    if !ok {
-        return "", errors.New("invalid claims")
-    }
-
-    return claims.Issuer, nil
-}
\ No newline at end of file
+// ParseMfaChallenge returns the user ID of a valid MFA challenge token
+func ParseMfaChallenge(tokenString string) (string, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return publicKey(kid, t.Method.Alg())
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(TokenIssuer),
+		jwt.WithAudience(mfaChallengeAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || !token.Valid {
+		return "", errors.New("invalid challenge")
+	}
+
+	claims, ok := token.Claims.(*jwt.RegisteredClaims)
+	if !ok || claims.Subject == "" {
+		return "", errors.New("invalid claims")
+	}
+
+	return claims.Subject, nil
+}
@@ -0,0 +1,181 @@
+package helpers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/configs"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hash algorithms, chosen with PASSWORD_HASH_ALGORITHM
+const (
+	HashBcrypt   = "bcrypt"
+	HashArgon2id = "argon2id"
+)
+
+const (
+	defaultBcryptCost        = 14
+	defaultArgon2Memory      = 64 * 1024 // KiB
+	defaultArgon2Iterations  = 3
+	defaultArgon2Parallelism = 2
+	argon2SaltLength         = 16
+	argon2KeyLength          = 32
+)
+
+type argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+type hashingConfig struct {
+	Algorithm  string
+	BcryptCost int
+	Argon2     argon2Params
+}
+
+var hashing = hashingConfig{
+	Algorithm:  HashBcrypt,
+	BcryptCost: defaultBcryptCost,
+	Argon2:     argon2Params{Memory: defaultArgon2Memory, Iterations: defaultArgon2Iterations, Parallelism: defaultArgon2Parallelism},
+}
+
+var errInvalidHash = errors.New("invalid password hash")
+
+// loadHashingConfig reads the hash algorithm and its parameters from the environment
+func loadHashingConfig() error {
+	switch configs.Env.PASSWORD_HASH_ALGORITHM {
+	case "", HashBcrypt:
+		hashing.Algorithm = HashBcrypt
+	case HashArgon2id:
+		hashing.Algorithm = HashArgon2id
+	default:
+		return fmt.Errorf("unknown PASSWORD_HASH_ALGORITHM %q", configs.Env.PASSWORD_HASH_ALGORITHM)
+	}
+
+	var err error
+	if hashing.BcryptCost, err = intSetting(configs.Env.BCRYPT_COST, defaultBcryptCost, bcrypt.MinCost, bcrypt.MaxCost); err != nil {
+		return fmt.Errorf("BCRYPT_COST: %w", err)
+	}
+	memory, err := intSetting(configs.Env.ARGON2_MEMORY_KB, defaultArgon2Memory, 8*1024, 4*1024*1024)
+	if err != nil {
+		return fmt.Errorf("ARGON2_MEMORY_KB: %w", err)
+	}
+	iterations, err := intSetting(configs.Env.ARGON2_ITERATIONS, defaultArgon2Iterations, 1, 100)
+	if err != nil {
+		return fmt.Errorf("ARGON2_ITERATIONS: %w", err)
+	}
+	parallelism, err := intSetting(configs.Env.ARGON2_PARALLELISM, defaultArgon2Parallelism, 1, 255)
+	if err != nil {
+		return fmt.Errorf("ARGON2_PARALLELISM: %w", err)
+	}
+	hashing.Argon2 = argon2Params{Memory: uint32(memory), Iterations: uint32(iterations), Parallelism: uint8(parallelism)}
+	return nil
+}
+
+// HashPassword hashes password with the configured algorithm
+func HashPassword(password string) ([]byte, error) {
+	if hashing.Algorithm == HashArgon2id {
+		return hashArgon2id(password, hashing.Argon2)
+	}
+	return bcrypt.GenerateFromPassword([]byte(password), hashing.BcryptCost)
+}
+
+// ComparePassword checks password against a hash made by either algorithm
+func ComparePassword(hash []byte, password string) error {
+	if !bytes.HasPrefix(hash, []byte("$"+HashArgon2id+"$")) {
+		return bcrypt.CompareHashAndPassword(hash, []byte(password))
+	}
+
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return err
+	}
+	candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(candidate, key) != 1 {
+		return bcrypt.ErrMismatchedHashAndPassword
+	}
+	return nil
+}
+
+// PasswordNeedsRehash reports whether hash was made with a different algorithm or parameters
+// than are configured now. It is checked after a successful login, while the password is at hand.
+func PasswordNeedsRehash(hash []byte) bool {
+	if bytes.HasPrefix(hash, []byte("$"+HashArgon2id+"$")) {
+		if hashing.Algorithm != HashArgon2id {
+			return true
+		}
+		params, _, _, err := decodeArgon2id(hash)
+		return err != nil || params != hashing.Argon2
+	}
+
+	if hashing.Algorithm != HashBcrypt {
+		return true
+	}
+	cost, err := bcrypt.Cost(hash)
+	return err != nil || cost != hashing.BcryptCost
+}
+
+// hashArgon2id returns the hash in the PHC string format: $argon2id$v=19$m=..,t=..,p=..$salt$key
+func hashArgon2id(password string, params argon2Params) ([]byte, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, argon2KeyLength)
+	encoded := fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s", HashArgon2id, argon2.Version,
+		params.Memory, params.Iterations, params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+	return []byte(encoded), nil
+}
+
+func decodeArgon2id(hash []byte) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 || parts[1] != HashArgon2id {
+		return params, nil, nil, errInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errInvalidHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, errInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, errInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errInvalidHash
+	}
+	return params, salt, key, nil
+}
+
+// intSetting parses an optional integer setting, which must lie within [min, max]
+func intSetting(value string, fallback int, min int, max int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if n < min || n > max {
+		return 0, fmt.Errorf("must be between %d and %d", min, max)
+	}
+	return n, nil
+}
@@ -0,0 +1,125 @@
+package helpers
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/configs"
+)
+
+//go:embed common_passwords.txt
+var commonPasswords string
+
+const (
+	defaultPasswordMinLength  = 10
+	defaultPasswordMinClasses = 3
+	// bcrypt only reads the first 72 bytes. The limit applies to argon2id too, so the
+	// algorithm can be switched back without locking anyone out.
+	passwordMaxBytes = 72
+)
+
+// PasswordPolicyError explains why a password was rejected, in words fit for the user
+type PasswordPolicyError struct {
+	Reason string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return e.Reason
+}
+
+type passwordPolicyConfig struct {
+	MinLength  int
+	MinClasses int
+	Blocklist  map[string]struct{}
+}
+
+var passwordPolicy = passwordPolicyConfig{MinLength: defaultPasswordMinLength, MinClasses: defaultPasswordMinClasses}
+
+// loadPasswordPolicy reads the policy settings and builds the blocklist from the built-in list
+// of common passwords plus PASSWORD_BLOCKLIST_FILE, if set (one password per line)
+func loadPasswordPolicy() error {
+	var err error
+	if passwordPolicy.MinLength, err = intSetting(configs.Env.PASSWORD_MIN_LENGTH, defaultPasswordMinLength, 8, passwordMaxBytes); err != nil {
+		return fmt.Errorf("PASSWORD_MIN_LENGTH: %w", err)
+	}
+	if passwordPolicy.MinClasses, err = intSetting(configs.Env.PASSWORD_MIN_CLASSES, defaultPasswordMinClasses, 1, 4); err != nil {
+		return fmt.Errorf("PASSWORD_MIN_CLASSES: %w", err)
+	}
+
+	passwordPolicy.Blocklist = make(map[string]struct{})
+	addToBlocklist(bufio.NewScanner(strings.NewReader(commonPasswords)))
+
+	if path := configs.Env.PASSWORD_BLOCKLIST_FILE; path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("PASSWORD_BLOCKLIST_FILE: %w", err)
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		addToBlocklist(scanner)
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("PASSWORD_BLOCKLIST_FILE: %w", err)
+		}
+	}
+	return nil
+}
+
+func addToBlocklist(scanner *bufio.Scanner) {
+	for scanner.Scan() {
+		if password := strings.TrimSpace(scanner.Text()); password != "" {
+			passwordPolicy.Blocklist[strings.ToLower(password)] = struct{}{}
+		}
+	}
+}
+
+// ValidatePassword checks password against the policy. The error is a *PasswordPolicyError.
+func ValidatePassword(password string, username string) error {
+	if len([]rune(password)) < passwordPolicy.MinLength {
+		return &PasswordPolicyError{Reason: fmt.Sprintf("Password must be at least %d characters long", passwordPolicy.MinLength)}
+	}
+	if len(password) > passwordMaxBytes {
+		return &PasswordPolicyError{Reason: fmt.Sprintf("Password must be at most %d bytes long", passwordMaxBytes)}
+	}
+
+	if characterClasses(password) < passwordPolicy.MinClasses {
+		return &PasswordPolicyError{Reason: fmt.Sprintf("Password must contain at least %d of: lowercase letters, uppercase letters, digits and symbols", passwordPolicy.MinClasses)}
+	}
+
+	lower := strings.ToLower(password)
+	if username = strings.ToLower(strings.TrimSpace(username)); len(username) >= 3 && strings.Contains(lower, username) {
+		return &PasswordPolicyError{Reason: "Password must not contain your username"}
+	}
+	if _, blocked := passwordPolicy.Blocklist[lower]; blocked {
+		return &PasswordPolicyError{Reason: "Password is too common, choose another"}
+	}
+	return nil
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	return classes
+}
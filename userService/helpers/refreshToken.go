@@ -0,0 +1,58 @@
+package helpers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// GenerateRefreshToken returns a new refresh token for the session and the hash to store
+func GenerateRefreshToken(sessionId string) (string, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+
+	token := sessionId + "." + base64.RawURLEncoding.EncodeToString(secret)
+	return token, HashToken(token), nil
+}
+
+// ParseRefreshToken extracts the session ID from a refresh token
+func ParseRefreshToken(token string) (string, error) {
+	sessionId, _, found := strings.Cut(token, ".")
+	if !found || sessionId == "" {
+		return "", errors.New("malformed refresh token")
+	}
+	return sessionId, nil
+}
+
+// HashToken hashes a high-entropy token for storage
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateResetToken returns a new password reset token and the hash to store
+func GenerateResetToken() (string, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(secret)
+	return token, HashToken(token), nil
+}
+
+// GenerateVerificationCode returns a random six-digit one-time code
+func GenerateVerificationCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
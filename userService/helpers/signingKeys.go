@@ -0,0 +1,217 @@
+package helpers
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/configs"
+)
+
+// SigningKey is a private key used to sign access tokens, identified by its kid
+type SigningKey struct {
+	Kid     string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	modTime time.Time
+}
+
+// JWK is the public half of a signing key in JSON Web Key form
+type JWK struct {
+	Kid string
+	Kty string
+	Alg string
+	Use string
+	Crv string
+	X   string
+	N   string
+	E   string
+}
+
+var (
+	signingKeys   map[string]*SigningKey
+	currentKeyKid string
+)
+
+// loadSigningKeys reads every <kid>.pem private key in JWT_KEYS_DIR. All of them are
+// published and accepted, so a retired key can stay until the tokens it signed expire.
+func loadSigningKeys() error {
+	dir := configs.Env.JWT_KEYS_DIR
+	if dir == "" {
+		dir = "keys"
+	}
+
+	keys, err := readSigningKeys(dir)
+	if err != nil {
+		return err
+	}
+
+	if len(keys) == 0 {
+		if configs.Env.MODE == "production" {
+			return fmt.Errorf("no signing keys found in %s", dir)
+		}
+		key, err := generateDevSigningKey(dir)
+		if err != nil {
+			return fmt.Errorf("failed to create dev signing key: %w", err)
+		}
+		keys[key.Kid] = key
+	}
+
+	kid := configs.Env.JWT_SIGNING_KID
+	if kid == "" {
+		// Default to the newest key so dropping a new file in is enough to rotate
+		for _, key := range keys {
+			if kid == "" || key.modTime.After(keys[kid].modTime) {
+				kid = key.Kid
+			}
+		}
+	}
+	if _, ok := keys[kid]; !ok {
+		return fmt.Errorf("signing key %q not found in %s", kid, dir)
+	}
+
+	signingKeys = keys
+	currentKeyKid = kid
+	log.Printf("Loaded %d signing key(s), signing with %s", len(keys), kid)
+	return nil
+}
+
+func readSigningKeys(dir string) (map[string]*SigningKey, error) {
+	keys := map[string]*SigningKey{}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		private, method, err := parsePrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		keys[kid] = &SigningKey{Kid: kid, Method: method, Private: private, modTime: info.ModTime()}
+	}
+
+	return keys, nil
+}
+
+func parsePrivateKey(data []byte) (crypto.Signer, jwt.SigningMethod, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil, errors.New("no PEM block found")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		return k, jwt.SigningMethodRS256, nil
+	case ed25519.PrivateKey:
+		return k, jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, nil, errors.New("only RSA and Ed25519 keys are supported")
+	}
+}
+
+func generateDevSigningKey(dir string) (*SigningKey, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+
+	kid := "dev-" + time.Now().Format("20060102150405")
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Created dev signing key %s in %s", kid, dir)
+	return &SigningKey{Kid: kid, Method: jwt.SigningMethodEdDSA, Private: private, modTime: time.Now()}, nil
+}
+
+func currentSigningKey() *SigningKey {
+	return signingKeys[currentKeyKid]
+}
+
+// publicKey returns the key that verifies tokens with the given kid and algorithm
+func publicKey(kid string, alg string) (crypto.PublicKey, error) {
+	key, ok := signingKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if key.Method.Alg() != alg {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.Private.Public(), nil
+}
+
+// PublicKeys returns every accepted signing key as a JWK, sorted by kid
+func PublicKeys() []JWK {
+	jwks := make([]JWK, 0, len(signingKeys))
+	for _, key := range signingKeys {
+		jwk := JWK{Kid: key.Kid, Alg: key.Method.Alg(), Use: "sig"}
+
+		switch public := key.Private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+
+		jwks = append(jwks, jwk)
+	}
+
+	sort.Slice(jwks, func(i, j int) bool { return jwks[i].Kid < jwks[j].Kid })
+	return jwks
+}
@@ -0,0 +1,96 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed from RFC 6238 appendix B, "12345678901234567890", in base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestVerifyTOTP(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		at       int64 // Unix time the code is checked at
+		wantOK   bool
+		wantStep int64
+	}{
+		// The RFC's 8-digit SHA-1 codes, cut to their last 6 digits
+		{name: "RFC 6238 at 59", code: "287082", at: 59, wantOK: true, wantStep: 1},
+		{name: "RFC 6238 at 1111111109", code: "081804", at: 1111111109, wantOK: true, wantStep: 37037036},
+		{name: "RFC 6238 at 1111111111", code: "050471", at: 1111111111, wantOK: true, wantStep: 37037037},
+		{name: "RFC 6238 at 1234567890", code: "005924", at: 1234567890, wantOK: true, wantStep: 41152263},
+		{name: "RFC 6238 at 2000000000", code: "279037", at: 2000000000, wantOK: true, wantStep: 66666666},
+		{name: "RFC 6238 at 20000000000", code: "353130", at: 20000000000, wantOK: true, wantStep: 666666666},
+
+		{name: "one step late", code: "005924", at: 1234567890 + 30, wantOK: true, wantStep: 41152263},
+		{name: "one step early", code: "005924", at: 1234567890 - 30, wantOK: true, wantStep: 41152263},
+		{name: "two steps late", code: "005924", at: 1234567890 + 60},
+		{name: "two steps early", code: "005924", at: 1234567890 - 60},
+
+		{name: "wrong code", code: "000000", at: 1234567890},
+		{name: "too short", code: "05924", at: 1234567890},
+		{name: "empty", code: "", at: 1234567890},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := VerifyTOTP(rfc6238Secret, tt.code, time.Unix(tt.at, 0))
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("got step %d, %v, want step %d, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+// A code is valid for three steps, so callers store the step it matched and reject any code
+// matching that step or an earlier one
+func TestVerifyTOTPReplay(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code := totpCode([]byte("12345678901234567890"), now.Unix()/totpPeriod)
+
+	lastStep := int64(0)
+	for i, at := range []time.Time{now, now, now.Add(30 * time.Second)} {
+		step, ok := VerifyTOTP(rfc6238Secret, code, at)
+		if !ok {
+			t.Fatalf("use %d: code rejected", i+1)
+		}
+
+		fresh := step > lastStep
+		if want := i == 0; fresh != want {
+			t.Errorf("use %d: got fresh %v, want %v", i+1, fresh, want)
+		}
+		if fresh {
+			lastStep = step
+		}
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := base32NoPadding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret %q isn't base32: %v", secret, err)
+	}
+	if len(key) != 20 {
+		t.Errorf("got a %d-byte secret, want 20", len(key))
+	}
+
+	now := time.Now()
+	if _, ok := VerifyTOTP(secret, totpCode(key, now.Unix()/totpPeriod), now); !ok {
+		t.Error("a code from the new secret was rejected")
+	}
+
+	other, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == secret {
+		t.Error("two secrets were the same")
+	}
+}
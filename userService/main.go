@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net"
 
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/blobstore"
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/broker"
 	"github.com/manlikehenryy/loan-management-system-grpc/userService/configs"
 	"github.com/manlikehenryy/loan-management-system-grpc/userService/database"
 	"github.com/manlikehenryy/loan-management-system-grpc/userService/helpers"
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/notifier"
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/outbox"
 	"github.com/manlikehenryy/loan-management-system-grpc/userService/service"
 	pb "github.com/manlikehenryy/loan-management-system-grpc/userService/user" // Import generated protobuf code
 	"google.golang.org/grpc"
@@ -15,8 +20,13 @@ import (
 
 func main() {
 	helpers.Initialize()
+	notifier.Initialize()
+	blobstore.Initialize()
 	// Connect to the database
 	database.Connect()
+	service.MigrateLegacyRoles(context.Background())
+	service.EncryptLegacyMfaSecrets(context.Background())
+	service.BootstrapSuperadmin(context.Background())
 
 	// Retrieve the port from the config
 	port := configs.Env.PORT
@@ -30,7 +40,27 @@ func main() {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	s := grpc.NewServer(grpc.UnaryInterceptor(service.TokenInterceptor))
+	policyFile := configs.Env.POLICY_FILE
+	if policyFile == "" {
+		policyFile = "policy.json"
+	}
+	if err := service.LoadPolicy(policyFile); err != nil {
+		log.Fatalf("Failed to load authorization policy: %v", err)
+	}
+
+	creds, err := helpers.ServerCredentials()
+	if err != nil {
+		log.Fatalf("Failed to load TLS credentials: %v", err)
+	}
+
+	eventBroker, err := broker.New()
+	if err != nil {
+		log.Fatalf("Failed to set up event broker: %v", err)
+	}
+	outbox.StartRelay(context.Background(), eventBroker)
+
+	// KYC uploads arrive in a single message, so the default 4 MB limit is raised to fit them
+	s := grpc.NewServer(grpc.Creds(creds), grpc.MaxRecvMsgSize(service.MaxKycFileSize+(1<<20)), grpc.UnaryInterceptor(service.TokenInterceptor), grpc.StreamInterceptor(service.TokenStreamInterceptor))
 	pb.RegisterUserServiceServer(s, service.NewUserServiceServer()) // Register UserServiceServer
 	fmt.Printf("User Service running on port %s...", port)
 	log.Fatal(s.Serve(lis))
@@ -0,0 +1,45 @@
+package notifier
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileNotifier writes each message to its own file instead of sending it: .eml for email and
+// .sms for SMS. It's meant for development and tests, which can read the files to pick up
+// links and codes.
+type FileNotifier struct {
+	Dir string
+}
+
+func NewFileNotifier(dir string) (*FileNotifier, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileNotifier{Dir: dir}, nil
+}
+
+func (n *FileNotifier) Send(ctx context.Context, message Message) error {
+	body, err := formatMessage("", message, time.Now())
+	if err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+
+	extension := "eml"
+	if message.Channel == ChannelSMS {
+		extension = "sms"
+	}
+
+	name := fmt.Sprintf("%d-%s.%s", time.Now().UnixNano(), hex.EncodeToString(suffix), extension)
+	return os.WriteFile(filepath.Join(n.Dir, name), body, 0o600)
+}
@@ -0,0 +1,99 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/configs"
+)
+
+// Channels a message can be sent on
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+// Message is a notification addressed to one user. Channel defaults to email; SMS
+// messages have no subject.
+type Message struct {
+	Channel string
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages to users
+type Notifier interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// Default is the notifier configured by Initialize
+var Default Notifier
+
+// Initialize sets up Default from NOTIFIER for email, "file" (the default) or "smtp", and
+// SMS_NOTIFIER for SMS. Only "file" is built in for SMS; providers implement Notifier.
+func Initialize() {
+	email, err := New(configs.Env.NOTIFIER)
+	if err != nil {
+		panic("Failed to set up notifier: " + err.Error())
+	}
+	sms, err := NewSMS(configs.Env.SMS_NOTIFIER)
+	if err != nil {
+		panic("Failed to set up SMS notifier: " + err.Error())
+	}
+	Default = &channelNotifier{email: email, sms: sms}
+}
+
+// New returns the email notifier of the given kind
+func New(kind string) (Notifier, error) {
+	switch kind {
+	case "", "file":
+		return NewFileNotifier(notifierDir())
+	case "smtp":
+		return NewSMTPNotifier(configs.Env.SMTP_HOST, configs.Env.SMTP_PORT, configs.Env.SMTP_USERNAME, configs.Env.SMTP_PASSWORD, configs.Env.SMTP_FROM)
+	default:
+		return nil, fmt.Errorf("unknown notifier %q", kind)
+	}
+}
+
+// NewSMS returns the SMS notifier of the given kind
+func NewSMS(kind string) (Notifier, error) {
+	switch kind {
+	case "", "file":
+		return NewFileNotifier(notifierDir())
+	default:
+		return nil, fmt.Errorf("unknown SMS notifier %q", kind)
+	}
+}
+
+func notifierDir() string {
+	if configs.Env.NOTIFIER_DIR == "" {
+		return "mail"
+	}
+	return configs.Env.NOTIFIER_DIR
+}
+
+// channelNotifier hands each message to the notifier for its channel
+type channelNotifier struct {
+	email Notifier
+	sms   Notifier
+}
+
+func (n *channelNotifier) Send(ctx context.Context, message Message) error {
+	switch message.Channel {
+	case "", ChannelEmail:
+		return n.email.Send(ctx, message)
+	case ChannelSMS:
+		return n.sms.Send(ctx, message)
+	default:
+		return fmt.Errorf("unknown channel %q", message.Channel)
+	}
+}
+
+// Send delivers message with the Default notifier
+func Send(ctx context.Context, message Message) error {
+	if Default == nil {
+		return fmt.Errorf("notifier is not initialized")
+	}
+	return Default.Send(ctx, message)
+}
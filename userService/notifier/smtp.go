@@ -0,0 +1,69 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPNotifier sends messages through an SMTP server. Authentication is skipped when no
+// username is set, as with a local SMTP sink.
+type SMTPNotifier struct {
+	Addr string
+	From string
+	Auth smtp.Auth
+}
+
+func NewSMTPNotifier(host string, port string, username string, password string, from string) (*SMTPNotifier, error) {
+	if host == "" || from == "" {
+		return nil, errors.New("SMTP_HOST and SMTP_FROM are required")
+	}
+	if port == "" {
+		port = "587"
+	}
+
+	notifier := &SMTPNotifier{Addr: net.JoinHostPort(host, port), From: from}
+	if username != "" {
+		notifier.Auth = smtp.PlainAuth("", username, password, host)
+	}
+	return notifier, nil
+}
+
+func (n *SMTPNotifier) Send(ctx context.Context, message Message) error {
+	if message.Channel != "" && message.Channel != ChannelEmail {
+		return fmt.Errorf("SMTP can't send %s messages", message.Channel)
+	}
+
+	body, err := formatMessage(n.From, message, time.Now())
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(n.Addr, n.Auth, n.From, []string{message.To}, body)
+}
+
+// formatMessage renders message as a plain-text email
+func formatMessage(from string, message Message, date time.Time) ([]byte, error) {
+	// The recipient is user-supplied, so it must not be able to add headers
+	if message.To == "" || strings.ContainsAny(message.To, "\r\n") {
+		return nil, errors.New("invalid recipient")
+	}
+
+	var buf bytes.Buffer
+	if from != "" {
+		fmt.Fprintf(&buf, "From: %s\r\n", from)
+	}
+	fmt.Fprintf(&buf, "To: %s\r\n", message.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	buf.WriteString(message.Body)
+	buf.WriteString("\r\n")
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,476 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/helpers"
+	pb "github.com/manlikehenryy/loan-management-system-grpc/userService/user"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditLog records an administrative change made to a user
+type AuditLog struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	ActorID      primitive.ObjectID `bson:"actorId"`
+	TargetUserID primitive.ObjectID `bson:"targetUserId"`
+	Action       string             `bson:"action"` // e.g. "role_changed", "suspended", "unlocked", "mfa_enabled", "api_key_created"
+	From         string             `bson:"from,omitempty"`
+	To           string             `bson:"to,omitempty"`
+	Reason       string             `bson:"reason,omitempty"`
+	RequestID    string             `bson:"requestId,omitempty"`
+	CreatedAt    time.Time          `bson:"createdAt"`
+}
+
+const (
+	defaultPageSize    = 20
+	maxPageSize        = 100
+	maxUserIdsPageSize = 1000
+)
+
+func (s *UserServiceServer) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	if _, message, statusCode := authorizeActor(ctx, PermissionUserView); statusCode != http.StatusOK {
+		return listUsersErrorResponse(message, statusCode), nil
+	}
+
+	filter := bson.M{}
+	if query := strings.TrimSpace(req.GetQuery()); query != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(query), Options: "i"}
+		filter["$or"] = bson.A{
+			bson.M{"username": pattern},
+			bson.M{"firstName": pattern},
+			bson.M{"lastName": pattern},
+		}
+	}
+	if req.GetRole() != "" {
+		filter["role"] = req.GetRole()
+	}
+	switch req.GetAccountStatus() {
+	case "":
+	case StatusActive:
+		filter["status"] = bson.M{"$ne": StatusSuspended}
+	case StatusSuspended:
+		filter["status"] = StatusSuspended
+	default:
+		return listUsersErrorResponse("Invalid account status", http.StatusBadRequest), nil
+	}
+
+	page, limit := int64(req.GetPage()), int64(req.GetLimit())
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	usersCollection := database.GetCollection("users")
+
+	total, err := usersCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		log.Println("Database error:", err)
+		return listUsersErrorResponse("Failed to list users", http.StatusInternalServerError), nil
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "username", Value: 1}}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit).
+		SetProjection(bson.M{"password": 0})
+
+	cursor, err := usersCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		log.Println("Database error:", err)
+		return listUsersErrorResponse("Failed to list users", http.StatusInternalServerError), nil
+	}
+	defer cursor.Close(ctx)
+
+	var users []User
+	if err := cursor.All(ctx, &users); err != nil {
+		log.Println("Database error:", err)
+		return listUsersErrorResponse("Failed to list users", http.StatusInternalServerError), nil
+	}
+
+	details := make([]*pb.UserDetails, 0, len(users))
+	for i := range users {
+		details = append(details, userDetails(&users[i]))
+	}
+
+	return listUsersSuccessResponse("Users retrieved", http.StatusOK, details, total), nil
+}
+
+// ListUserIds lists users' IDs in order, a page at a time, for services that keep something for
+// every user. It's only granted to services, so it isn't scoped to an actor.
+func (s *UserServiceServer) ListUserIds(ctx context.Context, req *pb.ListUserIdsRequest) (*pb.ListUserIdsResponse, error) {
+	filter := bson.M{}
+	if req.GetAfterId() != "" {
+		afterId, err := primitive.ObjectIDFromHex(req.GetAfterId())
+		if err != nil {
+			return listUserIdsErrorResponse("Invalid user ID", http.StatusBadRequest), nil
+		}
+		filter["_id"] = bson.M{"$gt": afterId}
+	}
+
+	limit := int64(req.GetLimit())
+	if limit < 1 || limit > maxUserIdsPageSize {
+		limit = maxUserIdsPageSize
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(limit).
+		SetProjection(bson.M{"_id": 1})
+
+	cursor, err := database.GetCollection("users").Find(ctx, filter, findOptions)
+	if err != nil {
+		log.Println("Database error:", err)
+		return listUserIdsErrorResponse("Failed to list users", http.StatusInternalServerError), nil
+	}
+	defer cursor.Close(ctx)
+
+	var users []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &users); err != nil {
+		log.Println("Database error:", err)
+		return listUserIdsErrorResponse("Failed to list users", http.StatusInternalServerError), nil
+	}
+
+	userIds := make([]string, 0, len(users))
+	for _, user := range users {
+		userIds = append(userIds, user.ID.Hex())
+	}
+
+	return &pb.ListUserIdsResponse{Message: "Users retrieved", Status: true, StatusCode: http.StatusOK, UserIds: userIds}, nil
+}
+
+func (s *UserServiceServer) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.GetUserResponse, error) {
+	if _, message, statusCode := authorizeActor(ctx, PermissionUserView); statusCode != http.StatusOK {
+		return getUserErrorResponse(message, statusCode), nil
+	}
+
+	userId, err := primitive.ObjectIDFromHex(req.GetUserId())
+	if err != nil {
+		return getUserErrorResponse("Invalid user ID", http.StatusBadRequest), nil
+	}
+
+	user, message, statusCode := findUser(ctx, userId)
+	if statusCode != http.StatusOK {
+		return getUserErrorResponse(message, statusCode), nil
+	}
+
+	return getUserSuccessResponse("User retrieved", http.StatusOK, userDetails(user)), nil
+}
+
+func (s *UserServiceServer) UpdateUserRole(ctx context.Context, req *pb.UpdateUserRoleRequest) (*pb.UpdateUserRoleResponse, error) {
+	actor, message, statusCode := authorizeActor(ctx, PermissionUserManage)
+	if statusCode != http.StatusOK {
+		return updateUserRoleErrorResponse(message, statusCode), nil
+	}
+
+	if !IsValidRole(req.GetRole()) {
+		return updateUserRoleErrorResponse("Invalid role", http.StatusBadRequest), nil
+	}
+
+	userId, err := primitive.ObjectIDFromHex(req.GetUserId())
+	if err != nil {
+		return updateUserRoleErrorResponse("Invalid user ID", http.StatusBadRequest), nil
+	}
+
+	user, message, statusCode := findUser(ctx, userId)
+	if statusCode != http.StatusOK {
+		return updateUserRoleErrorResponse(message, statusCode), nil
+	}
+
+	if user.Role == req.GetRole() {
+		return updateUserRoleSuccessResponse("Role unchanged", http.StatusOK), nil
+	}
+
+	update := updateUser
+	if user.Role == RoleSuperadmin && user.Status != StatusSuspended {
+		update = updateSuperadmin
+	}
+	if message, statusCode := update(ctx, userId, actor.ID, bson.M{"role": req.GetRole()}); statusCode != http.StatusOK {
+		return updateUserRoleErrorResponse(message, statusCode), nil
+	}
+
+	recordAudit(ctx, AuditLog{ActorID: actor.ID, TargetUserID: userId, Action: "role_changed", From: user.Role, To: req.GetRole()})
+
+	return updateUserRoleSuccessResponse("Role updated successfully", http.StatusOK), nil
+}
+
+func (s *UserServiceServer) SetUserStatus(ctx context.Context, req *pb.SetUserStatusRequest) (*pb.SetUserStatusResponse, error) {
+	actor, message, statusCode := authorizeActor(ctx, PermissionUserManage)
+	if statusCode != http.StatusOK {
+		return setUserStatusErrorResponse(message, statusCode), nil
+	}
+
+	accountStatus := req.GetAccountStatus()
+	if accountStatus != StatusActive && accountStatus != StatusSuspended {
+		return setUserStatusErrorResponse("Invalid account status", http.StatusBadRequest), nil
+	}
+
+	userId, err := primitive.ObjectIDFromHex(req.GetUserId())
+	if err != nil {
+		return setUserStatusErrorResponse("Invalid user ID", http.StatusBadRequest), nil
+	}
+
+	if userId == actor.ID && accountStatus == StatusSuspended {
+		return setUserStatusErrorResponse("You cannot suspend your own account", http.StatusBadRequest), nil
+	}
+
+	user, message, statusCode := findUser(ctx, userId)
+	if statusCode != http.StatusOK {
+		return setUserStatusErrorResponse(message, statusCode), nil
+	}
+
+	currentStatus := user.Status
+	if currentStatus == "" {
+		currentStatus = StatusActive
+	}
+	if currentStatus == accountStatus {
+		return setUserStatusSuccessResponse("Account status unchanged", http.StatusOK), nil
+	}
+
+	update := updateUser
+	if accountStatus == StatusSuspended && user.Role == RoleSuperadmin {
+		update = updateSuperadmin
+	}
+	if message, statusCode := update(ctx, userId, actor.ID, bson.M{"status": accountStatus}); statusCode != http.StatusOK {
+		return setUserStatusErrorResponse(message, statusCode), nil
+	}
+
+	action := "reactivated"
+	if accountStatus == StatusSuspended {
+		action = "suspended"
+		revokeUserSessions(ctx, userId, "suspended")
+	}
+	recordAudit(ctx, AuditLog{ActorID: actor.ID, TargetUserID: userId, Action: action, From: currentStatus, To: accountStatus, Reason: req.GetReason()})
+
+	return setUserStatusSuccessResponse("Account "+action+" successfully", http.StatusOK), nil
+}
+
+func (s *UserServiceServer) UnlockUser(ctx context.Context, req *pb.UnlockUserRequest) (*pb.UnlockUserResponse, error) {
+	actor, message, statusCode := authorizeActor(ctx, PermissionUserManage)
+	if statusCode != http.StatusOK {
+		return unlockUserErrorResponse(message, statusCode), nil
+	}
+
+	userId, err := primitive.ObjectIDFromHex(req.GetUserId())
+	if err != nil {
+		return unlockUserErrorResponse("Invalid user ID", http.StatusBadRequest), nil
+	}
+
+	user, message, statusCode := findUser(ctx, userId)
+	if statusCode != http.StatusOK {
+		return unlockUserErrorResponse(message, statusCode), nil
+	}
+
+	clearLoginFailures(ctx, user.Username)
+
+	recordAudit(ctx, AuditLog{ActorID: actor.ID, TargetUserID: userId, Action: "unlocked"})
+	recordSecurityEvent(ctx, SecurityEvent{Type: "account_unlocked", Username: user.Username, ActorID: actor.ID.Hex()})
+
+	return unlockUserSuccessResponse("Account unlocked", http.StatusOK), nil
+}
+
+// authorizeActor loads the calling user from the identity assertion and checks they hold permission
+func authorizeActor(ctx context.Context, permission string) (*User, string, int) {
+	principal, ok := helpers.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, "Unauthorized: No identity provided", http.StatusUnauthorized
+	}
+
+	actorId, err := primitive.ObjectIDFromHex(principal.UserId)
+	if err != nil {
+		return nil, "Unauthorized: Invalid user ID", http.StatusUnauthorized
+	}
+
+	actor, message, statusCode := findUser(ctx, actorId)
+	if statusCode == http.StatusNotFound {
+		return nil, "Unauthorized", http.StatusUnauthorized
+	}
+	if statusCode != http.StatusOK {
+		return nil, message, statusCode
+	}
+
+	if actor.Status == StatusSuspended || !HasPermission(actor.Role, permission) {
+		return nil, "Forbidden: missing permission " + permission, http.StatusForbidden
+	}
+
+	return actor, "", http.StatusOK
+}
+
+func findUser(ctx context.Context, userId primitive.ObjectID) (*User, string, int) {
+	usersCollection := database.GetCollection("users")
+
+	var user User
+	err := usersCollection.FindOne(ctx, bson.M{"_id": userId}).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, "User not found", http.StatusNotFound
+		}
+		log.Println("Database error:", err)
+		return nil, "Database error", http.StatusInternalServerError
+	}
+
+	return &user, "", http.StatusOK
+}
+
+func updateUser(ctx context.Context, userId primitive.ObjectID, actorId primitive.ObjectID, fields bson.M) (string, int) {
+	usersCollection := database.GetCollection("users")
+
+	fields["updatedAt"] = time.Now()
+	fields["updatedBy"] = actorId
+
+	result, err := usersCollection.UpdateOne(ctx, bson.M{"_id": userId}, bson.M{"$set": fields})
+	if err != nil {
+		log.Println("Database error:", err)
+		return "Failed to update user", http.StatusInternalServerError
+	}
+	if result.MatchedCount == 0 {
+		return "User not found", http.StatusNotFound
+	}
+
+	return "", http.StatusOK
+}
+
+var errLastSuperadmin = errors.New("no other active superadmin")
+
+// updateSuperadmin updates an active superadmin in a way that may leave them unable to act as one,
+// refusing if no other active superadmin would be left. The check and the update run in one
+// transaction that also writes a shared lock document, so two such changes made at once conflict
+// and the retried one sees the other's result.
+func updateSuperadmin(ctx context.Context, userId primitive.ObjectID, actorId primitive.ObjectID, fields bson.M) (string, int) {
+	usersCollection := database.GetCollection("users")
+
+	fields["updatedAt"] = time.Now()
+	fields["updatedBy"] = actorId
+
+	err := database.WithTransaction(ctx, func(ctx mongo.SessionContext) error {
+		_, err := database.GetCollection("locks").UpdateOne(ctx,
+			bson.M{"_id": "superadmins"},
+			bson.M{"$inc": bson.M{"version": 1}},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return err
+		}
+
+		count, err := usersCollection.CountDocuments(ctx, bson.M{
+			"_id":    bson.M{"$ne": userId},
+			"role":   RoleSuperadmin,
+			"status": bson.M{"$ne": StatusSuspended},
+		})
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return errLastSuperadmin
+		}
+
+		result, err := usersCollection.UpdateOne(ctx, bson.M{"_id": userId}, bson.M{"$set": fields})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+		return nil
+	})
+	switch {
+	case err == nil:
+		return "", http.StatusOK
+	case errors.Is(err, errLastSuperadmin):
+		return "Cannot remove the last superadmin", http.StatusConflict
+	case err == mongo.ErrNoDocuments:
+		return "User not found", http.StatusNotFound
+	}
+	log.Println("Database error:", err)
+	return "Failed to update user", http.StatusInternalServerError
+}
+
+func recordAudit(ctx context.Context, entry AuditLog) {
+	if principal, ok := helpers.PrincipalFromContext(ctx); ok {
+		entry.RequestID = principal.RequestId
+	}
+	entry.CreatedAt = time.Now()
+
+	if _, err := database.GetCollection("user_audit_logs").InsertOne(ctx, entry); err != nil {
+		log.Println("Failed to record audit log:", err)
+	}
+}
+
+func userDetails(user *User) *pb.UserDetails {
+	accountStatus := user.Status
+	if accountStatus == "" {
+		accountStatus = StatusActive
+	}
+
+	return &pb.UserDetails{
+		Id:                 user.ID.Hex(),
+		Username:           user.Username,
+		FirstName:          user.FirstName,
+		LastName:           user.LastName,
+		Role:               user.Role,
+		AccountStatus:      accountStatus,
+		MfaEnabled:         user.MFA.Enabled,
+		KycTier:            int32(user.KycTier),
+		VerificationStatus: user.VerificationStatus(),
+	}
+}
+
+func listUsersSuccessResponse(message string, statusCode int, users []*pb.UserDetails, total int64) *pb.ListUsersResponse {
+	return &pb.ListUsersResponse{Message: message, Status: true, StatusCode: int32(statusCode), Users: users, Total: total}
+}
+
+func listUsersErrorResponse(message string, statusCode int) *pb.ListUsersResponse {
+	return &pb.ListUsersResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func listUserIdsErrorResponse(message string, statusCode int) *pb.ListUserIdsResponse {
+	return &pb.ListUserIdsResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func getUserSuccessResponse(message string, statusCode int, user *pb.UserDetails) *pb.GetUserResponse {
+	return &pb.GetUserResponse{Message: message, Status: true, StatusCode: int32(statusCode), User: user}
+}
+
+func getUserErrorResponse(message string, statusCode int) *pb.GetUserResponse {
+	return &pb.GetUserResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func updateUserRoleSuccessResponse(message string, statusCode int) *pb.UpdateUserRoleResponse {
+	return &pb.UpdateUserRoleResponse{Message: message, Status: true, StatusCode: int32(statusCode)}
+}
+
+func updateUserRoleErrorResponse(message string, statusCode int) *pb.UpdateUserRoleResponse {
+	return &pb.UpdateUserRoleResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func setUserStatusSuccessResponse(message string, statusCode int) *pb.SetUserStatusResponse {
+	return &pb.SetUserStatusResponse{Message: message, Status: true, StatusCode: int32(statusCode)}
+}
+
+func setUserStatusErrorResponse(message string, statusCode int) *pb.SetUserStatusResponse {
+	return &pb.SetUserStatusResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func unlockUserSuccessResponse(message string, statusCode int) *pb.UnlockUserResponse {
+	return &pb.UnlockUserResponse{Message: message, Status: true, StatusCode: int32(statusCode)}
+}
+
+func unlockUserErrorResponse(message string, statusCode int) *pb.UnlockUserResponse {
+	return &pb.UnlockUserResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/helpers"
+	pb "github.com/manlikehenryy/loan-management-system-grpc/userService/user"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ApiKey is a partner API key. Requests made with it act as UserID, limited to Routes.
+type ApiKey struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	UserID     primitive.ObjectID `bson:"userId"`
+	Name       string             `bson:"name"`
+	Prefix     string             `bson:"prefix"`
+	KeyHash    string             `bson:"keyHash"`
+	Routes     []string           `bson:"routes"`
+	CreatedBy  primitive.ObjectID `bson:"createdBy"`
+	CreatedAt  time.Time          `bson:"createdAt"`
+	ExpiresAt  *time.Time         `bson:"expiresAt,omitempty"`
+	LastUsedAt *time.Time         `bson:"lastUsedAt,omitempty"`
+	LastUsedIP string             `bson:"lastUsedIp,omitempty"`
+	RevokedAt  *time.Time         `bson:"revokedAt,omitempty"`
+	RevokedBy  primitive.ObjectID `bson:"revokedBy,omitempty"`
+}
+
+// lastUsedGranularity limits last-used tracking to one write per key per interval
+const lastUsedGranularity = time.Minute
+
+var apiKeyMethods = map[string]bool{"GET": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true}
+
+func (s *UserServiceServer) CreateApiKey(ctx context.Context, req *pb.CreateApiKeyRequest) (*pb.CreateApiKeyResponse, error) {
+	actor, message, statusCode := authorizeActor(ctx, PermissionApiKeyManage)
+	if statusCode != http.StatusOK {
+		return createApiKeyErrorResponse(message, statusCode), nil
+	}
+
+	name := strings.TrimSpace(req.GetName())
+	if name == "" {
+		return createApiKeyErrorResponse("Name is required", http.StatusBadRequest), nil
+	}
+	if len(req.GetRoutes()) == 0 {
+		return createApiKeyErrorResponse("At least one route is required", http.StatusBadRequest), nil
+	}
+	routes := make([]string, 0, len(req.GetRoutes()))
+	for _, route := range req.GetRoutes() {
+		route, ok := normalizeRoute(route)
+		if !ok {
+			return createApiKeyErrorResponse("Invalid route: routes look like \"GET /api/path\" or \"GET /api/path/*\"", http.StatusBadRequest), nil
+		}
+		routes = append(routes, route)
+	}
+	if req.GetExpiresInDays() < 0 {
+		return createApiKeyErrorResponse("Invalid expiry", http.StatusBadRequest), nil
+	}
+
+	userId, err := primitive.ObjectIDFromHex(req.GetUserId())
+	if err != nil {
+		return createApiKeyErrorResponse("Invalid user ID", http.StatusBadRequest), nil
+	}
+	if _, message, statusCode := findUser(ctx, userId); statusCode != http.StatusOK {
+		return createApiKeyErrorResponse(message, statusCode), nil
+	}
+
+	key, prefix, keyHash, err := helpers.GenerateApiKey()
+	if err != nil {
+		log.Println("API key generation error:", err)
+		return createApiKeyErrorResponse("Failed to create API key", http.StatusInternalServerError), nil
+	}
+
+	apiKey := ApiKey{
+		UserID:    userId,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   keyHash,
+		Routes:    routes,
+		CreatedBy: actor.ID,
+		CreatedAt: time.Now(),
+	}
+	if req.GetExpiresInDays() > 0 {
+		expiresAt := apiKey.CreatedAt.AddDate(0, 0, int(req.GetExpiresInDays()))
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	result, err := database.GetCollection("api_keys").InsertOne(ctx, apiKey)
+	if err != nil {
+		log.Println("Database error:", err)
+		return createApiKeyErrorResponse("Failed to create API key", http.StatusInternalServerError), nil
+	}
+	keyId := result.InsertedID.(primitive.ObjectID)
+
+	recordAudit(ctx, AuditLog{ActorID: actor.ID, TargetUserID: userId, Action: "api_key_created", To: keyId.Hex()})
+
+	return createApiKeySuccessResponse("API key created. Store it now, it cannot be shown again", http.StatusCreated, keyId.Hex(), key), nil
+}
+
+func (s *UserServiceServer) RevokeApiKey(ctx context.Context, req *pb.RevokeApiKeyRequest) (*pb.RevokeApiKeyResponse, error) {
+	actor, message, statusCode := authorizeActor(ctx, PermissionApiKeyManage)
+	if statusCode != http.StatusOK {
+		return revokeApiKeyErrorResponse(message, statusCode), nil
+	}
+
+	keyId, err := primitive.ObjectIDFromHex(req.GetId())
+	if err != nil {
+		return revokeApiKeyErrorResponse("Invalid API key ID", http.StatusBadRequest), nil
+	}
+
+	apiKeysCollection := database.GetCollection("api_keys")
+
+	var apiKey ApiKey
+	err = apiKeysCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": keyId, "revokedAt": nil},
+		bson.M{"$set": bson.M{"revokedAt": time.Now(), "revokedBy": actor.ID}},
+	).Decode(&apiKey)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return revokeApiKeyErrorResponse("API key not found", http.StatusNotFound), nil
+		}
+		log.Println("Database error:", err)
+		return revokeApiKeyErrorResponse("Failed to revoke API key", http.StatusInternalServerError), nil
+	}
+
+	recordAudit(ctx, AuditLog{ActorID: actor.ID, TargetUserID: apiKey.UserID, Action: "api_key_revoked", From: keyId.Hex()})
+
+	return revokeApiKeySuccessResponse("API key revoked", http.StatusOK), nil
+}
+
+func (s *UserServiceServer) ListApiKeys(ctx context.Context, req *pb.ListApiKeysRequest) (*pb.ListApiKeysResponse, error) {
+	if _, message, statusCode := authorizeActor(ctx, PermissionApiKeyManage); statusCode != http.StatusOK {
+		return listApiKeysErrorResponse(message, statusCode), nil
+	}
+
+	filter := bson.M{}
+	if req.GetUserId() != "" {
+		userId, err := primitive.ObjectIDFromHex(req.GetUserId())
+		if err != nil {
+			return listApiKeysErrorResponse("Invalid user ID", http.StatusBadRequest), nil
+		}
+		filter["userId"] = userId
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetProjection(bson.M{"keyHash": 0})
+	cursor, err := database.GetCollection("api_keys").Find(ctx, filter, findOptions)
+	if err != nil {
+		log.Println("Database error:", err)
+		return listApiKeysErrorResponse("Failed to list API keys", http.StatusInternalServerError), nil
+	}
+	defer cursor.Close(ctx)
+
+	var apiKeys []ApiKey
+	if err := cursor.All(ctx, &apiKeys); err != nil {
+		log.Println("Database error:", err)
+		return listApiKeysErrorResponse("Failed to list API keys", http.StatusInternalServerError), nil
+	}
+
+	details := make([]*pb.ApiKeyDetails, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		details = append(details, &pb.ApiKeyDetails{
+			Id:         apiKey.ID.Hex(),
+			UserId:     apiKey.UserID.Hex(),
+			Name:       apiKey.Name,
+			Prefix:     apiKey.Prefix,
+			Routes:     apiKey.Routes,
+			CreatedAt:  apiKey.CreatedAt.Format(time.RFC3339),
+			ExpiresAt:  formatOptionalTime(apiKey.ExpiresAt),
+			LastUsedAt: formatOptionalTime(apiKey.LastUsedAt),
+			LastUsedIp: apiKey.LastUsedIP,
+			Revoked:    apiKey.RevokedAt != nil,
+		})
+	}
+
+	return listApiKeysSuccessResponse("API keys retrieved", http.StatusOK, details), nil
+}
+
+func (s *UserServiceServer) VerifyApiKey(ctx context.Context, req *pb.VerifyApiKeyRequest) (*pb.VerifyApiKeyResponse, error) {
+	prefix, err := helpers.ParseApiKey(req.GetApiKey())
+	if err != nil {
+		return verifyApiKeyErrorResponse("Unauthorized: Invalid API key", http.StatusUnauthorized), nil
+	}
+
+	apiKeysCollection := database.GetCollection("api_keys")
+
+	var apiKey ApiKey
+	err = apiKeysCollection.FindOne(ctx, bson.M{"prefix": prefix}).Decode(&apiKey)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return verifyApiKeyErrorResponse("Unauthorized: Invalid API key", http.StatusUnauthorized), nil
+		}
+		log.Println("Database error:", err)
+		return verifyApiKeyErrorResponse("Database error", http.StatusInternalServerError), nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(helpers.HashToken(req.GetApiKey())), []byte(apiKey.KeyHash)) != 1 {
+		return verifyApiKeyErrorResponse("Unauthorized: Invalid API key", http.StatusUnauthorized), nil
+	}
+
+	now := time.Now()
+	if apiKey.RevokedAt != nil {
+		return verifyApiKeyErrorResponse("Unauthorized: API key revoked", http.StatusUnauthorized), nil
+	}
+	if apiKey.ExpiresAt != nil && now.After(*apiKey.ExpiresAt) {
+		return verifyApiKeyErrorResponse("Unauthorized: API key expired", http.StatusUnauthorized), nil
+	}
+
+	user, message, statusCode := findUser(ctx, apiKey.UserID)
+	if statusCode == http.StatusNotFound {
+		return verifyApiKeyErrorResponse("Unauthorized: User not found", http.StatusUnauthorized), nil
+	}
+	if statusCode != http.StatusOK {
+		return verifyApiKeyErrorResponse(message, statusCode), nil
+	}
+	if user.Status == StatusSuspended {
+		return verifyApiKeyErrorResponse("Unauthorized: Account suspended", http.StatusUnauthorized), nil
+	}
+
+	if !routeAllowed(apiKey.Routes, req.GetMethod(), req.GetRoute()) {
+		return verifyApiKeyErrorResponse("Forbidden: API key is not permitted to use this route", http.StatusForbidden), nil
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > lastUsedGranularity || apiKey.LastUsedIP != req.GetIpAddress() {
+		_, err := apiKeysCollection.UpdateOne(ctx, bson.M{"_id": apiKey.ID}, bson.M{
+			"$set": bson.M{"lastUsedAt": now, "lastUsedIp": req.GetIpAddress()},
+		})
+		if err != nil {
+			log.Println("Failed to record API key use:", err)
+		}
+	}
+
+	return verifyApiKeySuccessResponse("API key valid", http.StatusOK, user.ID.Hex(), []string{user.Role}, apiKey.ID.Hex()), nil
+}
+
+// normalizeRoute validates a "METHOD /path" route and upper-cases the method
+func normalizeRoute(route string) (string, bool) {
+	method, path, found := strings.Cut(strings.TrimSpace(route), " ")
+	method = strings.ToUpper(method)
+	path = strings.TrimSpace(path)
+
+	if !found || !apiKeyMethods[method] || !strings.HasPrefix(path, "/api/") {
+		return "", false
+	}
+	if strings.Contains(strings.TrimSuffix(path, "/*"), "*") {
+		return "", false
+	}
+
+	return method + " " + path, true
+}
+
+// routeAllowed matches a request against a key's routes. A trailing /* matches everything below that path.
+func routeAllowed(routes []string, method string, route string) bool {
+	for _, allowed := range routes {
+		allowedMethod, allowedPath, _ := strings.Cut(allowed, " ")
+		if allowedMethod != method {
+			continue
+		}
+		if allowedPath == route {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowedPath, "*"); ok && strings.HasPrefix(route, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func createApiKeySuccessResponse(message string, statusCode int, id string, apiKey string) *pb.CreateApiKeyResponse {
+	return &pb.CreateApiKeyResponse{Message: message, Status: true, StatusCode: int32(statusCode), Id: id, ApiKey: apiKey}
+}
+
+func createApiKeyErrorResponse(message string, statusCode int) *pb.CreateApiKeyResponse {
+	return &pb.CreateApiKeyResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func revokeApiKeySuccessResponse(message string, statusCode int) *pb.RevokeApiKeyResponse {
+	return &pb.RevokeApiKeyResponse{Message: message, Status: true, StatusCode: int32(statusCode)}
+}
+
+func revokeApiKeyErrorResponse(message string, statusCode int) *pb.RevokeApiKeyResponse {
+	return &pb.RevokeApiKeyResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func listApiKeysSuccessResponse(message string, statusCode int, apiKeys []*pb.ApiKeyDetails) *pb.ListApiKeysResponse {
+	return &pb.ListApiKeysResponse{Message: message, Status: true, StatusCode: int32(statusCode), ApiKeys: apiKeys}
+}
+
+func listApiKeysErrorResponse(message string, statusCode int) *pb.ListApiKeysResponse {
+	return &pb.ListApiKeysResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func verifyApiKeySuccessResponse(message string, statusCode int, userId string, roles []string, keyId string) *pb.VerifyApiKeyResponse {
+	return &pb.VerifyApiKeyResponse{Message: message, Valid: true, StatusCode: int32(statusCode), UserId: userId, Roles: roles, KeyId: keyId}
+}
+
+func verifyApiKeyErrorResponse(message string, statusCode int) *pb.VerifyApiKeyResponse {
+	return &pb.VerifyApiKeyResponse{Message: message, Valid: false, StatusCode: int32(statusCode)}
+}
@@ -0,0 +1,19 @@
+package service
+
+import (
+	"context"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/broker"
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/outbox"
+)
+
+// Domain events recorded in the outbox
+const (
+	EventUserRegistered = "UserRegistered"
+)
+
+// recordUserEvent writes an event about userId to the outbox. Pass a transaction's context to
+// record it together with the change it describes.
+func recordUserEvent(ctx context.Context, eventType string, userId string, data map[string]string) error {
+	return outbox.Record(ctx, broker.Event{Type: eventType, AggregateID: userId, UserID: userId, Data: data})
+}
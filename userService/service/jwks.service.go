@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/helpers"
+	pb "github.com/manlikehenryy/loan-management-system-grpc/userService/user"
+)
+
+func (s *UserServiceServer) GetJwks(ctx context.Context, req *pb.GetJwksRequest) (*pb.GetJwksResponse, error) {
+	publicKeys := helpers.PublicKeys()
+
+	keys := make([]*pb.JsonWebKey, 0, len(publicKeys))
+	for _, key := range publicKeys {
+		keys = append(keys, &pb.JsonWebKey{
+			Kid: key.Kid,
+			Kty: key.Kty,
+			Alg: key.Alg,
+			Use: key.Use,
+			Crv: key.Crv,
+			X:   key.X,
+			N:   key.N,
+			E:   key.E,
+		})
+	}
+
+	return getJwksSuccessResponse("Signing keys retrieved", http.StatusOK, keys), nil
+}
+
+func getJwksSuccessResponse(message string, statusCode int, keys []*pb.JsonWebKey) *pb.GetJwksResponse {
+	return &pb.GetJwksResponse{Message: message, Status: true, StatusCode: int32(statusCode), Keys: keys}
+}
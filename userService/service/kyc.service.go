@@ -0,0 +1,480 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/blobstore"
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/helpers"
+	pb "github.com/manlikehenryy/loan-management-system-grpc/userService/user"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// KycDocument is an uploaded identity document or selfie. The file is encrypted and kept
+// in the blob store under BlobKey.
+type KycDocument struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	UserID       primitive.ObjectID `bson:"userId"`
+	Type         string             `bson:"type"`
+	DocumentType string             `bson:"documentType,omitempty"`
+	FileName     string             `bson:"fileName"`
+	ContentType  string             `bson:"contentType"`
+	Size         int64              `bson:"size"`
+	SHA256       string             `bson:"sha256"`
+	BlobKey      string             `bson:"blobKey"`
+	Status       string             `bson:"status"`
+	Reason       string             `bson:"reason,omitempty"`
+	ReviewedBy   primitive.ObjectID `bson:"reviewedBy,omitempty"`
+	ReviewedAt   *time.Time         `bson:"reviewedAt,omitempty"`
+	CreatedAt    time.Time          `bson:"createdAt"`
+}
+
+// KYC document types
+const (
+	KycTypeIdDocument = "id_document"
+	KycTypeSelfie     = "selfie"
+)
+
+// KYC document statuses
+const (
+	KycStatusPending    = "pending"
+	KycStatusApproved   = "approved"
+	KycStatusRejected   = "rejected"
+	KycStatusSuperseded = "superseded"
+)
+
+// KYC tiers. Tier 1 needs an approved ID document, tier 2 an approved selfie as well.
+// loanService decides which tier a loan amount needs.
+const (
+	KycTierNone     = 0
+	KycTierBasic    = 1
+	KycTierVerified = 2
+)
+
+// MaxKycFileSize is the largest accepted upload
+const MaxKycFileSize = 5 << 20
+
+var idDocumentTypes = map[string]bool{"passport": true, "national_id": true, "drivers_license": true}
+
+// kycContentTypes are the accepted file types for each document type, detected from the content
+var kycContentTypes = map[string][]string{
+	KycTypeIdDocument: {"image/jpeg", "image/png", "application/pdf"},
+	KycTypeSelfie:     {"image/jpeg", "image/png"},
+}
+
+func (s *UserServiceServer) UploadKycDocument(ctx context.Context, req *pb.UploadKycDocumentRequest) (*pb.UploadKycDocumentResponse, error) {
+	user, message, statusCode := principalUser(ctx)
+	if statusCode != http.StatusOK {
+		return uploadKycDocumentErrorResponse(message, statusCode), nil
+	}
+
+	allowedTypes, ok := kycContentTypes[req.GetType()]
+	if !ok {
+		return uploadKycDocumentErrorResponse("Type must be id_document or selfie", http.StatusBadRequest), nil
+	}
+	documentType := ""
+	if req.GetType() == KycTypeIdDocument {
+		documentType = req.GetDocumentType()
+		if !idDocumentTypes[documentType] {
+			return uploadKycDocumentErrorResponse("Document type must be passport, national_id or drivers_license", http.StatusBadRequest), nil
+		}
+	}
+
+	data := req.GetData()
+	if len(data) == 0 {
+		return uploadKycDocumentErrorResponse("File is empty", http.StatusBadRequest), nil
+	}
+	if len(data) > MaxKycFileSize {
+		return uploadKycDocumentErrorResponse(fmt.Sprintf("File must be at most %d MB", MaxKycFileSize>>20), http.StatusRequestEntityTooLarge), nil
+	}
+
+	// The client's content type isn't trusted
+	contentType := http.DetectContentType(data)
+	if !contains(allowedTypes, contentType) {
+		return uploadKycDocumentErrorResponse("File must be one of: "+strings.Join(allowedTypes, ", "), http.StatusUnsupportedMediaType), nil
+	}
+
+	document := KycDocument{
+		ID:           primitive.NewObjectID(),
+		UserID:       user.ID,
+		Type:         req.GetType(),
+		DocumentType: documentType,
+		FileName:     cleanFileName(req.GetFileName()),
+		ContentType:  contentType,
+		Size:         int64(len(data)),
+		Status:       KycStatusPending,
+		CreatedAt:    time.Now(),
+	}
+	sum := sha256.Sum256(data)
+	document.SHA256 = hex.EncodeToString(sum[:])
+	document.BlobKey = "kyc/" + user.ID.Hex() + "/" + document.ID.Hex()
+
+	encrypted, err := helpers.EncryptBlob(data, kycBlobContext(&document))
+	if err != nil {
+		log.Println("KYC document encryption error:", err)
+		return uploadKycDocumentErrorResponse("Failed to store document", http.StatusInternalServerError), nil
+	}
+	if err := blobstore.Default.Put(ctx, document.BlobKey, encrypted); err != nil {
+		log.Println("Blob store error:", err)
+		return uploadKycDocumentErrorResponse("Failed to store document", http.StatusInternalServerError), nil
+	}
+
+	kycDocumentsCollection := database.GetCollection("kyc_documents")
+
+	if _, err := kycDocumentsCollection.InsertOne(ctx, document); err != nil {
+		log.Println("Database error:", err)
+		if err := blobstore.Default.Delete(ctx, document.BlobKey); err != nil {
+			log.Println("Blob store error:", err)
+		}
+		return uploadKycDocumentErrorResponse("Failed to store document", http.StatusInternalServerError), nil
+	}
+
+	// Only the newest upload of each type waits for review
+	_, err = kycDocumentsCollection.UpdateMany(ctx,
+		bson.M{"userId": user.ID, "type": document.Type, "status": KycStatusPending, "_id": bson.M{"$ne": document.ID}},
+		bson.M{"$set": bson.M{"status": KycStatusSuperseded}},
+	)
+	if err != nil {
+		log.Println("Failed to supersede KYC documents:", err)
+	}
+
+	recordAudit(ctx, AuditLog{ActorID: user.ID, TargetUserID: user.ID, Action: "kyc_document_uploaded", To: document.Type})
+
+	return uploadKycDocumentSuccessResponse("Document uploaded and waiting for review", http.StatusCreated, kycDocumentDetails(&document)), nil
+}
+
+func (s *UserServiceServer) ListKycDocuments(ctx context.Context, req *pb.ListKycDocumentsRequest) (*pb.ListKycDocumentsResponse, error) {
+	user, message, statusCode := principalUser(ctx)
+	if statusCode != http.StatusOK {
+		return listKycDocumentsErrorResponse(message, statusCode), nil
+	}
+
+	if req.GetUserId() != "" && req.GetUserId() != user.ID.Hex() {
+		if _, message, statusCode := authorizeActor(ctx, PermissionKycReview); statusCode != http.StatusOK {
+			return listKycDocumentsErrorResponse(message, statusCode), nil
+		}
+
+		userId, err := primitive.ObjectIDFromHex(req.GetUserId())
+		if err != nil {
+			return listKycDocumentsErrorResponse("Invalid user ID", http.StatusBadRequest), nil
+		}
+		if user, message, statusCode = findUser(ctx, userId); statusCode != http.StatusOK {
+			return listKycDocumentsErrorResponse(message, statusCode), nil
+		}
+	}
+
+	documents, err := findKycDocuments(ctx, bson.M{"userId": user.ID}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}))
+	if err != nil {
+		log.Println("Database error:", err)
+		return listKycDocumentsErrorResponse("Failed to list documents", http.StatusInternalServerError), nil
+	}
+
+	return listKycDocumentsSuccessResponse("Documents retrieved", http.StatusOK, documents, int32(user.KycTier)), nil
+}
+
+func (s *UserServiceServer) ListKycReviewQueue(ctx context.Context, req *pb.ListKycReviewQueueRequest) (*pb.ListKycReviewQueueResponse, error) {
+	if _, message, statusCode := authorizeActor(ctx, PermissionKycReview); statusCode != http.StatusOK {
+		return listKycReviewQueueErrorResponse(message, statusCode), nil
+	}
+
+	page, limit := int64(req.GetPage()), int64(req.GetLimit())
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	filter := bson.M{"status": KycStatusPending}
+
+	total, err := database.GetCollection("kyc_documents").CountDocuments(ctx, filter)
+	if err != nil {
+		log.Println("Database error:", err)
+		return listKycReviewQueueErrorResponse("Failed to list documents", http.StatusInternalServerError), nil
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: 1}}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+
+	documents, err := findKycDocuments(ctx, filter, findOptions)
+	if err != nil {
+		log.Println("Database error:", err)
+		return listKycReviewQueueErrorResponse("Failed to list documents", http.StatusInternalServerError), nil
+	}
+
+	return listKycReviewQueueSuccessResponse("Review queue retrieved", http.StatusOK, documents, total), nil
+}
+
+func (s *UserServiceServer) GetKycDocumentFile(ctx context.Context, req *pb.GetKycDocumentFileRequest) (*pb.GetKycDocumentFileResponse, error) {
+	user, message, statusCode := principalUser(ctx)
+	if statusCode != http.StatusOK {
+		return getKycDocumentFileErrorResponse(message, statusCode), nil
+	}
+
+	document, message, statusCode := findKycDocument(ctx, req.GetDocumentId())
+	if statusCode != http.StatusOK {
+		return getKycDocumentFileErrorResponse(message, statusCode), nil
+	}
+
+	if document.UserID != user.ID {
+		if _, message, statusCode := authorizeActor(ctx, PermissionKycReview); statusCode != http.StatusOK {
+			return getKycDocumentFileErrorResponse(message, statusCode), nil
+		}
+		recordAudit(ctx, AuditLog{ActorID: user.ID, TargetUserID: document.UserID, Action: "kyc_document_viewed", To: document.ID.Hex()})
+	}
+
+	encrypted, err := blobstore.Default.Get(ctx, document.BlobKey)
+	if err != nil {
+		log.Println("Blob store error:", err)
+		return getKycDocumentFileErrorResponse("Failed to read document", http.StatusInternalServerError), nil
+	}
+	data, err := helpers.DecryptBlob(encrypted, kycBlobContext(document))
+	if err != nil {
+		log.Println("KYC document decryption error:", err)
+		return getKycDocumentFileErrorResponse("Failed to read document", http.StatusInternalServerError), nil
+	}
+
+	return getKycDocumentFileSuccessResponse("Document retrieved", http.StatusOK, document.FileName, document.ContentType, data), nil
+}
+
+func (s *UserServiceServer) ReviewKycDocument(ctx context.Context, req *pb.ReviewKycDocumentRequest) (*pb.ReviewKycDocumentResponse, error) {
+	actor, message, statusCode := authorizeActor(ctx, PermissionKycReview)
+	if statusCode != http.StatusOK {
+		return reviewKycDocumentErrorResponse(message, statusCode), nil
+	}
+
+	var status string
+	switch req.GetDecision() {
+	case "approve":
+		status = KycStatusApproved
+	case "reject":
+		status = KycStatusRejected
+		if strings.TrimSpace(req.GetReason()) == "" {
+			return reviewKycDocumentErrorResponse("A reason is required when rejecting", http.StatusBadRequest), nil
+		}
+	default:
+		return reviewKycDocumentErrorResponse("Decision must be approve or reject", http.StatusBadRequest), nil
+	}
+
+	document, message, statusCode := findKycDocument(ctx, req.GetDocumentId())
+	if statusCode != http.StatusOK {
+		return reviewKycDocumentErrorResponse(message, statusCode), nil
+	}
+	if document.UserID == actor.ID {
+		return reviewKycDocumentErrorResponse("You cannot review your own documents", http.StatusForbidden), nil
+	}
+
+	// Only a pending document can be reviewed, so two reviewers can't both decide it
+	now := time.Now()
+	err := database.GetCollection("kyc_documents").FindOneAndUpdate(ctx,
+		bson.M{"_id": document.ID, "status": KycStatusPending},
+		bson.M{"$set": bson.M{"status": status, "reason": strings.TrimSpace(req.GetReason()), "reviewedBy": actor.ID, "reviewedAt": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(document)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return reviewKycDocumentErrorResponse("Document is not awaiting review", http.StatusConflict), nil
+		}
+		log.Println("Database error:", err)
+		return reviewKycDocumentErrorResponse("Failed to review document", http.StatusInternalServerError), nil
+	}
+
+	tier, err := updateKycTier(ctx, document.UserID, actor.ID)
+	if err != nil {
+		log.Println("Failed to update KYC tier:", err)
+		return reviewKycDocumentErrorResponse("Failed to update KYC tier", http.StatusInternalServerError), nil
+	}
+
+	recordAudit(ctx, AuditLog{ActorID: actor.ID, TargetUserID: document.UserID, Action: "kyc_document_" + status, To: document.ID.Hex(), Reason: document.Reason})
+
+	return reviewKycDocumentSuccessResponse("Document "+status, http.StatusOK, kycDocumentDetails(document), int32(tier)), nil
+}
+
+func (s *UserServiceServer) GetUserStatus(ctx context.Context, req *pb.GetUserStatusRequest) (*pb.GetUserStatusResponse, error) {
+	userId, err := primitive.ObjectIDFromHex(req.GetUserId())
+	if err != nil {
+		return getUserStatusErrorResponse("Invalid user ID", http.StatusBadRequest), nil
+	}
+
+	user, message, statusCode := findUser(ctx, userId)
+	if statusCode != http.StatusOK {
+		return getUserStatusErrorResponse(message, statusCode), nil
+	}
+
+	return getUserStatusSuccessResponse("User status retrieved", http.StatusOK, userDetails(user).AccountStatus, int32(user.KycTier), user.VerificationStatus()), nil
+}
+
+// updateKycTier recomputes the user's tier from their approved documents and stores it
+func updateKycTier(ctx context.Context, userId primitive.ObjectID, actorId primitive.ObjectID) (int, error) {
+	approved, err := database.GetCollection("kyc_documents").Distinct(ctx, "type", bson.M{"userId": userId, "status": KycStatusApproved})
+	if err != nil {
+		return 0, err
+	}
+
+	types := map[string]bool{}
+	for _, t := range approved {
+		if s, ok := t.(string); ok {
+			types[s] = true
+		}
+	}
+
+	tier := KycTierNone
+	if types[KycTypeIdDocument] {
+		tier = KycTierBasic
+		if types[KycTypeSelfie] {
+			tier = KycTierVerified
+		}
+	}
+
+	if message, statusCode := updateUser(ctx, userId, actorId, bson.M{"kycTier": tier}); statusCode != http.StatusOK {
+		return 0, fmt.Errorf("%s", message)
+	}
+	return tier, nil
+}
+
+func findKycDocument(ctx context.Context, documentIdStr string) (*KycDocument, string, int) {
+	documentId, err := primitive.ObjectIDFromHex(documentIdStr)
+	if err != nil {
+		return nil, "Invalid document ID", http.StatusBadRequest
+	}
+
+	var document KycDocument
+	err = database.GetCollection("kyc_documents").FindOne(ctx, bson.M{"_id": documentId}).Decode(&document)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, "Document not found", http.StatusNotFound
+		}
+		log.Println("Database error:", err)
+		return nil, "Database error", http.StatusInternalServerError
+	}
+	return &document, "", http.StatusOK
+}
+
+func findKycDocuments(ctx context.Context, filter bson.M, findOptions *options.FindOptions) ([]*pb.KycDocument, error) {
+	cursor, err := database.GetCollection("kyc_documents").Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var documents []KycDocument
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+
+	details := make([]*pb.KycDocument, 0, len(documents))
+	for i := range documents {
+		details = append(details, kycDocumentDetails(&documents[i]))
+	}
+	return details, nil
+}
+
+// kycBlobContext binds an encrypted file to its document
+func kycBlobContext(document *KycDocument) string {
+	return "kyc:" + document.UserID.Hex() + ":" + document.ID.Hex()
+}
+
+// cleanFileName keeps only the base name of an uploaded file, for display
+func cleanFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = filepath.Base(strings.ReplaceAll(strings.TrimSpace(name), "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		return "upload"
+	}
+	if runes := []rune(name); len(runes) > 100 {
+		name = string(runes[len(runes)-100:])
+	}
+	return name
+}
+
+func kycDocumentDetails(document *KycDocument) *pb.KycDocument {
+	details := &pb.KycDocument{
+		Id:           document.ID.Hex(),
+		UserId:       document.UserID.Hex(),
+		Type:         document.Type,
+		DocumentType: document.DocumentType,
+		FileName:     document.FileName,
+		ContentType:  document.ContentType,
+		Size:         document.Size,
+		Status:       document.Status,
+		Reason:       document.Reason,
+		CreatedAt:    document.CreatedAt.Format(time.RFC3339),
+	}
+	if !document.ReviewedBy.IsZero() {
+		details.ReviewedBy = document.ReviewedBy.Hex()
+	}
+	if document.ReviewedAt != nil {
+		details.ReviewedAt = document.ReviewedAt.Format(time.RFC3339)
+	}
+	return details
+}
+
+func uploadKycDocumentSuccessResponse(message string, statusCode int, document *pb.KycDocument) *pb.UploadKycDocumentResponse {
+	return &pb.UploadKycDocumentResponse{Message: message, Status: true, StatusCode: int32(statusCode), Document: document}
+}
+
+func uploadKycDocumentErrorResponse(message string, statusCode int) *pb.UploadKycDocumentResponse {
+	return &pb.UploadKycDocumentResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func listKycDocumentsSuccessResponse(message string, statusCode int, documents []*pb.KycDocument, kycTier int32) *pb.ListKycDocumentsResponse {
+	return &pb.ListKycDocumentsResponse{Message: message, Status: true, StatusCode: int32(statusCode), Documents: documents, KycTier: kycTier}
+}
+
+func listKycDocumentsErrorResponse(message string, statusCode int) *pb.ListKycDocumentsResponse {
+	return &pb.ListKycDocumentsResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func listKycReviewQueueSuccessResponse(message string, statusCode int, documents []*pb.KycDocument, total int64) *pb.ListKycReviewQueueResponse {
+	return &pb.ListKycReviewQueueResponse{Message: message, Status: true, StatusCode: int32(statusCode), Documents: documents, Total: total}
+}
+
+func listKycReviewQueueErrorResponse(message string, statusCode int) *pb.ListKycReviewQueueResponse {
+	return &pb.ListKycReviewQueueResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func getKycDocumentFileSuccessResponse(message string, statusCode int, fileName string, contentType string, data []byte) *pb.GetKycDocumentFileResponse {
+	return &pb.GetKycDocumentFileResponse{Message: message, Status: true, StatusCode: int32(statusCode), FileName: fileName, ContentType: contentType, Data: data}
+}
+
+func getKycDocumentFileErrorResponse(message string, statusCode int) *pb.GetKycDocumentFileResponse {
+	return &pb.GetKycDocumentFileResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func reviewKycDocumentSuccessResponse(message string, statusCode int, document *pb.KycDocument, kycTier int32) *pb.ReviewKycDocumentResponse {
+	return &pb.ReviewKycDocumentResponse{Message: message, Status: true, StatusCode: int32(statusCode), Document: document, KycTier: kycTier}
+}
+
+func reviewKycDocumentErrorResponse(message string, statusCode int) *pb.ReviewKycDocumentResponse {
+	return &pb.ReviewKycDocumentResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func getUserStatusSuccessResponse(message string, statusCode int, accountStatus string, kycTier int32, verificationStatus string) *pb.GetUserStatusResponse {
+	return &pb.GetUserStatusResponse{Message: message, Status: true, StatusCode: int32(statusCode), AccountStatus: accountStatus, KycTier: kycTier, VerificationStatus: verificationStatus}
+}
+
+func getUserStatusErrorResponse(message string, statusCode int) *pb.GetUserStatusResponse {
+	return &pb.GetUserStatusResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
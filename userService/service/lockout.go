@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LoginAttempts counts recent failed logins for one username ("user:<username>") or one IP ("ip:<address>")
+type LoginAttempts struct {
+	Key           string     `bson:"_id"`
+	Failures      int        `bson:"failures"`
+	LastFailureAt time.Time  `bson:"lastFailureAt"`
+	LockedUntil   *time.Time `bson:"lockedUntil,omitempty"`
+}
+
+// SecurityEvent is recorded for security review
+type SecurityEvent struct {
+	Type      string    `bson:"type"` // "account_locked", "ip_locked", "account_unlocked"
+	Username  string    `bson:"username,omitempty"`
+	IPAddress string    `bson:"ipAddress,omitempty"`
+	ActorID   string    `bson:"actorId,omitempty"`
+	Failures  int       `bson:"failures,omitempty"`
+	Until     time.Time `bson:"until,omitempty"`
+	CreatedAt time.Time `bson:"createdAt"`
+}
+
+const (
+	defaultMaxLoginFailures = 5
+	defaultLockoutDuration  = 15 * time.Minute
+	// An IP may try several accounts, so it gets more room before it is locked out
+	ipFailureMultiplier = 4
+	// Failures before retries are spaced out, and the longest enforced wait
+	freeLoginFailures = 2
+	maxLoginDelay     = time.Minute
+)
+
+// loginThrottle returns how long the caller must wait before another login attempt for username from ipAddress
+func loginThrottle(ctx context.Context, username string, ipAddress string) time.Duration {
+	var wait time.Duration
+	for _, key := range attemptKeys(username, ipAddress) {
+		var attempts LoginAttempts
+		err := database.GetCollection("login_attempts").FindOne(ctx, bson.M{"_id": key}).Decode(&attempts)
+		if err != nil {
+			if err != mongo.ErrNoDocuments {
+				log.Println("Database error:", err)
+			}
+			continue
+		}
+
+		now := time.Now()
+		if attempts.LockedUntil != nil && now.Before(*attempts.LockedUntil) {
+			wait = max(wait, attempts.LockedUntil.Sub(now))
+			continue
+		}
+		if now.Sub(attempts.LastFailureAt) > lockoutDuration() {
+			continue
+		}
+		wait = max(wait, time.Until(attempts.LastFailureAt.Add(loginDelay(attempts.Failures))))
+	}
+
+	return wait
+}
+
+// recordLoginFailure counts a failed login and locks the username or IP out once it has failed too often
+func recordLoginFailure(ctx context.Context, username string, ipAddress string) {
+	for _, key := range attemptKeys(username, ipAddress) {
+		limit := maxLoginFailures()
+		if key != "user:"+username {
+			limit *= ipFailureMultiplier
+		}
+
+		attempts, err := incrementFailures(ctx, key)
+		if err != nil {
+			log.Println("Failed to record login failure:", err)
+			continue
+		}
+		if attempts.Failures < limit {
+			continue
+		}
+
+		until := time.Now().Add(lockoutDuration())
+		_, err = database.GetCollection("login_attempts").UpdateOne(ctx, bson.M{"_id": key}, bson.M{
+			"$set": bson.M{"lockedUntil": until, "failures": 0},
+		})
+		if err != nil {
+			log.Println("Failed to lock out login:", err)
+			continue
+		}
+
+		event := SecurityEvent{Type: "account_locked", Username: username, IPAddress: ipAddress, Failures: attempts.Failures, Until: until}
+		if key != "user:"+username {
+			event.Type = "ip_locked"
+		}
+		recordSecurityEvent(ctx, event)
+	}
+}
+
+// clearLoginFailures resets the username's count after a successful login. The IP count is left
+// alone, so an attacker can't reset it by logging into an account of their own.
+func clearLoginFailures(ctx context.Context, username string) {
+	if _, err := database.GetCollection("login_attempts").DeleteOne(ctx, bson.M{"_id": "user:" + username}); err != nil {
+		log.Println("Failed to clear login failures:", err)
+	}
+}
+
+// incrementFailures adds a failure, starting a fresh count if the last one is older than the lockout window
+func incrementFailures(ctx context.Context, key string) (*LoginAttempts, error) {
+	loginAttemptsCollection := database.GetCollection("login_attempts")
+	now := time.Now()
+
+	var attempts LoginAttempts
+	err := loginAttemptsCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": key, "lastFailureAt": bson.M{"$gte": now.Add(-lockoutDuration())}},
+		bson.M{"$inc": bson.M{"failures": 1}, "$set": bson.M{"lastFailureAt": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&attempts)
+	if err == nil {
+		return &attempts, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	attempts = LoginAttempts{Key: key, Failures: 1, LastFailureAt: now}
+	_, err = loginAttemptsCollection.ReplaceOne(ctx, bson.M{"_id": key}, attempts, options.Replace().SetUpsert(true))
+	if err != nil {
+		return nil, err
+	}
+	return &attempts, nil
+}
+
+func recordSecurityEvent(ctx context.Context, event SecurityEvent) {
+	event.CreatedAt = time.Now()
+	if _, err := database.GetCollection("security_events").InsertOne(ctx, event); err != nil {
+		log.Println("Failed to record security event:", err)
+	}
+}
+
+func attemptKeys(username string, ipAddress string) []string {
+	keys := []string{"user:" + username}
+	if ipAddress != "" {
+		keys = append(keys, "ip:"+ipAddress)
+	}
+	return keys
+}
+
+// loginDelay doubles the wait between attempts for each failure past the free ones
+func loginDelay(failures int) time.Duration {
+	if failures <= freeLoginFailures {
+		return 0
+	}
+	delay := time.Duration(math.Pow(2, float64(failures-freeLoginFailures-1))) * time.Second
+	return min(delay, maxLoginDelay)
+}
+
+func maxLoginFailures() int {
+	failures, err := strconv.Atoi(configs.Env.LOGIN_MAX_FAILURES)
+	if err != nil || failures < 1 {
+		return defaultMaxLoginFailures
+	}
+	return failures
+}
+
+func lockoutDuration() time.Duration {
+	minutes, err := strconv.Atoi(configs.Env.LOGIN_LOCKOUT_MINUTES)
+	if err != nil || minutes < 1 {
+		return defaultLockoutDuration
+	}
+	return time.Duration(minutes) * time.Minute
+}
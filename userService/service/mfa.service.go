@@ -0,0 +1,318 @@
+package service
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/helpers"
+	pb "github.com/manlikehenryy/loan-management-system-grpc/userService/user"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const recoveryCodeCount = 10
+
+func (s *UserServiceServer) EnrollMfa(ctx context.Context, req *pb.EnrollMfaRequest) (*pb.EnrollMfaResponse, error) {
+	user, message, statusCode := principalUser(ctx)
+	if statusCode != http.StatusOK {
+		return enrollMfaErrorResponse(message, statusCode), nil
+	}
+
+	if user.MFA.Enabled {
+		return enrollMfaErrorResponse("MFA is already enabled", http.StatusConflict), nil
+	}
+
+	secret, err := helpers.GenerateTOTPSecret()
+	if err != nil {
+		log.Println("TOTP secret generation error:", err)
+		return enrollMfaErrorResponse("Failed to start MFA enrollment", http.StatusInternalServerError), nil
+	}
+
+	encrypted, err := helpers.EncryptField(secret, mfaSecretContext(user.ID))
+	if err != nil {
+		log.Println("MFA secret encryption error:", err)
+		return enrollMfaErrorResponse("Failed to start MFA enrollment", http.StatusInternalServerError), nil
+	}
+
+	if message, statusCode := updateUser(ctx, user.ID, user.ID, bson.M{"mfa.pendingSecret": encrypted}); statusCode != http.StatusOK {
+		return enrollMfaErrorResponse(message, statusCode), nil
+	}
+
+	return enrollMfaSuccessResponse("Scan the code with an authenticator app, then confirm with a code from it", http.StatusOK, secret, helpers.TOTPURI(user.Username, secret)), nil
+}
+
+func (s *UserServiceServer) ConfirmMfa(ctx context.Context, req *pb.ConfirmMfaRequest) (*pb.ConfirmMfaResponse, error) {
+	user, message, statusCode := principalUser(ctx)
+	if statusCode != http.StatusOK {
+		return confirmMfaErrorResponse(message, statusCode), nil
+	}
+
+	if user.MFA.Enabled {
+		return confirmMfaErrorResponse("MFA is already enabled", http.StatusConflict), nil
+	}
+	if user.MFA.PendingSecret == "" {
+		return confirmMfaErrorResponse("Start MFA enrollment first", http.StatusBadRequest), nil
+	}
+
+	pendingSecret, err := helpers.DecryptField(user.MFA.PendingSecret, mfaSecretContext(user.ID))
+	if err != nil {
+		log.Println("MFA secret decryption error:", err)
+		return confirmMfaErrorResponse("Failed to enable MFA", http.StatusInternalServerError), nil
+	}
+
+	step, ok := helpers.VerifyTOTP(pendingSecret, strings.TrimSpace(req.GetCode()), time.Now())
+	if !ok {
+		return confirmMfaErrorResponse("Invalid MFA code", http.StatusBadRequest), nil
+	}
+
+	recoveryCodes, recoveryCodeHashes, err := helpers.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		log.Println("Recovery code generation error:", err)
+		return confirmMfaErrorResponse("Failed to enable MFA", http.StatusInternalServerError), nil
+	}
+
+	// The pending secret is encrypted under the same context, so it's kept as it is
+	mfa := MFA{Enabled: true, Secret: user.MFA.PendingSecret, RecoveryCodes: recoveryCodeHashes, LastStep: step}
+	if message, statusCode := updateUser(ctx, user.ID, user.ID, bson.M{"mfa": mfa}); statusCode != http.StatusOK {
+		return confirmMfaErrorResponse(message, statusCode), nil
+	}
+
+	recordAudit(ctx, AuditLog{ActorID: user.ID, TargetUserID: user.ID, Action: "mfa_enabled"})
+
+	return confirmMfaSuccessResponse("MFA enabled. Store the recovery codes somewhere safe, they cannot be shown again", http.StatusOK, recoveryCodes), nil
+}
+
+func (s *UserServiceServer) DisableMfa(ctx context.Context, req *pb.DisableMfaRequest) (*pb.DisableMfaResponse, error) {
+	user, message, statusCode := principalUser(ctx)
+	if statusCode != http.StatusOK {
+		return disableMfaErrorResponse(message, statusCode), nil
+	}
+
+	if !user.MFA.Enabled {
+		return disableMfaErrorResponse("MFA is not enabled", http.StatusBadRequest), nil
+	}
+	if RequiresMfa(user.Role) {
+		return disableMfaErrorResponse("MFA is required for the "+user.Role+" role", http.StatusForbidden), nil
+	}
+
+	ok, err := verifyMfaCode(ctx, user, req.GetCode())
+	if err != nil {
+		log.Println("Database error:", err)
+		return disableMfaErrorResponse("Failed to disable MFA", http.StatusInternalServerError), nil
+	}
+	if !ok {
+		return disableMfaErrorResponse("Invalid MFA code", http.StatusBadRequest), nil
+	}
+
+	if message, statusCode := updateUser(ctx, user.ID, user.ID, bson.M{"mfa": MFA{}}); statusCode != http.StatusOK {
+		return disableMfaErrorResponse(message, statusCode), nil
+	}
+
+	recordAudit(ctx, AuditLog{ActorID: user.ID, TargetUserID: user.ID, Action: "mfa_disabled"})
+
+	return disableMfaSuccessResponse("MFA disabled", http.StatusOK), nil
+}
+
+func (s *UserServiceServer) VerifyMfaLogin(ctx context.Context, req *pb.VerifyMfaLoginRequest) (*pb.LoginUserResponse, error) {
+	userIdStr, err := helpers.ParseMfaChallenge(req.GetMfaChallenge())
+	if err != nil {
+		return loginUserErrorResponse("Unauthorized: Invalid or expired MFA challenge", http.StatusUnauthorized, ""), nil
+	}
+	userId, err := primitive.ObjectIDFromHex(userIdStr)
+	if err != nil {
+		return loginUserErrorResponse("Unauthorized: Invalid or expired MFA challenge", http.StatusUnauthorized, ""), nil
+	}
+
+	user, message, statusCode := findUser(ctx, userId)
+	if statusCode == http.StatusNotFound {
+		return loginUserErrorResponse("Unauthorized: Invalid or expired MFA challenge", http.StatusUnauthorized, ""), nil
+	}
+	if statusCode != http.StatusOK {
+		return loginUserErrorResponse(message, statusCode, ""), nil
+	}
+
+	// MFA codes are guessable too, so they share the password's throttling and lockout
+	if wait := loginThrottle(ctx, user.Username, req.GetIpAddress()); wait > 0 {
+		return loginThrottledResponse(wait), nil
+	}
+
+	if user.Status == StatusSuspended {
+		return loginUserErrorResponse("Account suspended", http.StatusForbidden, ""), nil
+	}
+	if !user.MFA.Enabled {
+		return loginUserErrorResponse("MFA is not enabled", http.StatusBadRequest, ""), nil
+	}
+
+	ok, err := verifyMfaCode(ctx, user, req.GetCode())
+	if err != nil {
+		log.Println("Database error:", err)
+		return loginUserErrorResponse("Database error", http.StatusInternalServerError, ""), nil
+	}
+	if !ok {
+		recordLoginFailure(ctx, user.Username, req.GetIpAddress())
+		return loginUserErrorResponse("Invalid MFA code", http.StatusUnauthorized, ""), nil
+	}
+
+	clearLoginFailures(ctx, user.Username)
+
+	sessionId, token, refreshToken, err := createSession(ctx, user, req.GetUserAgent(), req.GetIpAddress())
+	if err != nil {
+		log.Println("Token generation error:", err)
+		return loginUserErrorResponse("Failed to generate token", http.StatusInternalServerError, ""), nil
+	}
+
+	return loginUserSuccessResponse("Logged in successfully", http.StatusOK, sessionId, token, refreshToken), nil
+}
+
+// verifyMfaCode accepts a TOTP code that hasn't been used yet, or consumes a recovery code
+func verifyMfaCode(ctx context.Context, user *User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	usersCollection := database.GetCollection("users")
+
+	secret, err := helpers.DecryptField(user.MFA.Secret, mfaSecretContext(user.ID))
+	if err != nil {
+		return false, err
+	}
+
+	if step, ok := helpers.VerifyTOTP(secret, code, time.Now()); ok {
+		// Only the first use of a time step wins, so an observed code can't be replayed
+		result, err := usersCollection.UpdateOne(ctx,
+			bson.M{"_id": user.ID, "$or": bson.A{
+				bson.M{"mfa.lastStep": bson.M{"$lt": step}},
+				bson.M{"mfa.lastStep": bson.M{"$exists": false}},
+			}},
+			bson.M{"$set": bson.M{"mfa.lastStep": step}},
+		)
+		if err != nil {
+			return false, err
+		}
+		return result.MatchedCount == 1, nil
+	}
+
+	if code == "" {
+		return false, nil
+	}
+
+	hash := helpers.HashRecoveryCode(code)
+	result, err := usersCollection.UpdateOne(ctx,
+		bson.M{"_id": user.ID, "mfa.recoveryCodes": hash},
+		bson.M{"$pull": bson.M{"mfa.recoveryCodes": hash}},
+	)
+	if err != nil {
+		return false, err
+	}
+	if result.MatchedCount == 1 {
+		recordAudit(ctx, AuditLog{ActorID: user.ID, TargetUserID: user.ID, Action: "mfa_recovery_code_used"})
+		return true, nil
+	}
+	return false, nil
+}
+
+// mfaSecretContext binds an encrypted TOTP secret to its user
+func mfaSecretContext(userId primitive.ObjectID) string {
+	return "mfa:" + userId.Hex()
+}
+
+// EncryptLegacyMfaSecrets encrypts the TOTP secrets stored in plaintext before they were encrypted
+// at rest. Plaintext secrets are base32, so they never contain the ":" of an encrypted one.
+func EncryptLegacyMfaSecrets(ctx context.Context) {
+	usersCollection := database.GetCollection("users")
+	plaintext := primitive.Regex{Pattern: "^[A-Z2-7]+=*$"}
+
+	cursor, err := usersCollection.Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"mfa.secret": plaintext},
+		bson.M{"mfa.pendingSecret": plaintext},
+	}})
+	if err != nil {
+		log.Println("Failed to find plaintext MFA secrets:", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	encrypted := 0
+	for cursor.Next(ctx) {
+		var user User
+		if err := cursor.Decode(&user); err != nil {
+			log.Println("Failed to decode user:", err)
+			continue
+		}
+
+		for field, secret := range map[string]string{"mfa.secret": user.MFA.Secret, "mfa.pendingSecret": user.MFA.PendingSecret} {
+			if secret == "" || strings.Contains(secret, ":") {
+				continue
+			}
+			ciphertext, err := helpers.EncryptField(secret, mfaSecretContext(user.ID))
+			if err != nil {
+				log.Println("MFA secret encryption error:", err)
+				return
+			}
+			// Only if it hasn't changed since it was read, so a new enrollment isn't overwritten
+			if _, err := usersCollection.UpdateOne(ctx, bson.M{"_id": user.ID, field: secret}, bson.M{"$set": bson.M{field: ciphertext}}); err != nil {
+				log.Println("Failed to encrypt MFA secret:", err)
+				continue
+			}
+			encrypted++
+		}
+	}
+
+	if encrypted > 0 {
+		log.Printf("Encrypted %d plaintext MFA secrets", encrypted)
+	}
+}
+
+// principalUser loads the calling user from the identity assertion
+func principalUser(ctx context.Context) (*User, string, int) {
+	principal, ok := helpers.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, "Unauthorized: No identity provided", http.StatusUnauthorized
+	}
+
+	userId, err := primitive.ObjectIDFromHex(principal.UserId)
+	if err != nil {
+		return nil, "Unauthorized: Invalid user ID", http.StatusUnauthorized
+	}
+
+	user, message, statusCode := findUser(ctx, userId)
+	if statusCode == http.StatusNotFound {
+		return nil, "Unauthorized", http.StatusUnauthorized
+	}
+	return user, message, statusCode
+}
+
+func mfaChallengeResponse(challenge string) *pb.LoginUserResponse {
+	return &pb.LoginUserResponse{
+		Message:      "Enter the code from your authenticator app",
+		Status:       true,
+		StatusCode:   http.StatusAccepted,
+		MfaRequired:  true,
+		MfaChallenge: challenge,
+	}
+}
+
+func enrollMfaSuccessResponse(message string, statusCode int, secret string, otpauthUri string) *pb.EnrollMfaResponse {
+	return &pb.EnrollMfaResponse{Message: message, Status: true, StatusCode: int32(statusCode), Secret: secret, OtpauthUri: otpauthUri}
+}
+
+func enrollMfaErrorResponse(message string, statusCode int) *pb.EnrollMfaResponse {
+	return &pb.EnrollMfaResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func confirmMfaSuccessResponse(message string, statusCode int, recoveryCodes []string) *pb.ConfirmMfaResponse {
+	return &pb.ConfirmMfaResponse{Message: message, Status: true, StatusCode: int32(statusCode), RecoveryCodes: recoveryCodes}
+}
+
+func confirmMfaErrorResponse(message string, statusCode int) *pb.ConfirmMfaResponse {
+	return &pb.ConfirmMfaResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func disableMfaSuccessResponse(message string, statusCode int) *pb.DisableMfaResponse {
+	return &pb.DisableMfaResponse{Message: message, Status: true, StatusCode: int32(statusCode)}
+}
+
+func disableMfaErrorResponse(message string, statusCode int) *pb.DisableMfaResponse {
+	return &pb.DisableMfaResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/helpers"
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/notifier"
+	pb "github.com/manlikehenryy/loan-management-system-grpc/userService/user"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// PasswordReset is a single-use password reset token. Only its hash is stored.
+type PasswordReset struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `bson:"userId"`
+	TokenHash string             `bson:"tokenHash"`
+	CreatedAt time.Time          `bson:"createdAt"`
+	ExpiresAt time.Time          `bson:"expiresAt"`
+	UsedAt    *time.Time         `bson:"usedAt,omitempty"`
+}
+
+const (
+	passwordResetTTL = 30 * time.Minute
+	// A new reset email isn't sent while one from the last minute is still outstanding
+	passwordResetInterval = time.Minute
+)
+
+const passwordResetRequestedMessage = "If the account exists, a password reset link has been sent"
+
+func (s *UserServiceServer) ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) (*pb.ChangePasswordResponse, error) {
+	user, message, statusCode := principalUser(ctx)
+	if statusCode != http.StatusOK {
+		return changePasswordErrorResponse(message, statusCode), nil
+	}
+
+	if req.GetNewPassword() == "" {
+		return changePasswordErrorResponse("Missing required field(s)", http.StatusBadRequest), nil
+	}
+	if err := user.ComparePassword(req.GetCurrentPassword()); err != nil {
+		return changePasswordErrorResponse("Current password is incorrect", http.StatusBadRequest), nil
+	}
+
+	if err := user.SetPassword(req.GetNewPassword()); err != nil {
+		message, statusCode := setPasswordError(err)
+		return changePasswordErrorResponse(message, statusCode), nil
+	}
+	if message, statusCode := updateUser(ctx, user.ID, user.ID, bson.M{"password": user.Password}); statusCode != http.StatusOK {
+		return changePasswordErrorResponse(message, statusCode), nil
+	}
+
+	// Anyone else holding a session may be the reason for the change
+	revokeOtherSessions(ctx, user.ID, req.GetSessionId(), "password_changed")
+	recordAudit(ctx, AuditLog{ActorID: user.ID, TargetUserID: user.ID, Action: "password_changed"})
+
+	return changePasswordSuccessResponse("Password changed", http.StatusOK), nil
+}
+
+func (s *UserServiceServer) RequestPasswordReset(ctx context.Context, req *pb.RequestPasswordResetRequest) (*pb.RequestPasswordResetResponse, error) {
+	username := strings.TrimSpace(req.GetUsername())
+	if username == "" {
+		return requestPasswordResetErrorResponse("Missing required field(s)", http.StatusBadRequest), nil
+	}
+
+	var user User
+	err := database.GetCollection("users").FindOne(ctx, bson.M{"username": username}).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			// The response doesn't reveal whether the account exists
+			return requestPasswordResetSuccessResponse(passwordResetRequestedMessage, http.StatusOK), nil
+		}
+		log.Println("Database error:", err)
+		return requestPasswordResetErrorResponse("Database error", http.StatusInternalServerError), nil
+	}
+
+	if user.Status == StatusSuspended {
+		return requestPasswordResetSuccessResponse(passwordResetRequestedMessage, http.StatusOK), nil
+	}
+
+	passwordResetsCollection := database.GetCollection("password_resets")
+	now := time.Now()
+
+	recent, err := passwordResetsCollection.CountDocuments(ctx, bson.M{
+		"userId":    user.ID,
+		"usedAt":    nil,
+		"createdAt": bson.M{"$gt": now.Add(-passwordResetInterval)},
+	})
+	if err != nil {
+		log.Println("Database error:", err)
+		return requestPasswordResetErrorResponse("Database error", http.StatusInternalServerError), nil
+	}
+	if recent > 0 {
+		return requestPasswordResetSuccessResponse(passwordResetRequestedMessage, http.StatusOK), nil
+	}
+
+	// Only the newest link works
+	if _, err := passwordResetsCollection.DeleteMany(ctx, bson.M{"userId": user.ID, "usedAt": nil}); err != nil {
+		log.Println("Database error:", err)
+		return requestPasswordResetErrorResponse("Database error", http.StatusInternalServerError), nil
+	}
+
+	token, tokenHash, err := helpers.GenerateResetToken()
+	if err != nil {
+		log.Println("Reset token generation error:", err)
+		return requestPasswordResetErrorResponse("Failed to start password reset", http.StatusInternalServerError), nil
+	}
+
+	reset := PasswordReset{UserID: user.ID, TokenHash: tokenHash, CreatedAt: now, ExpiresAt: now.Add(passwordResetTTL)}
+	if _, err := passwordResetsCollection.InsertOne(ctx, reset); err != nil {
+		log.Println("Database error:", err)
+		return requestPasswordResetErrorResponse("Failed to start password reset", http.StatusInternalServerError), nil
+	}
+
+	err = notifier.Send(ctx, notifier.Message{
+		To:      user.Username,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %d minutes and can only be used once.\n\n%s\n\nIf you didn't ask for this, you can ignore this email.",
+			user.FirstName, int(passwordResetTTL.Minutes()), passwordResetLink(token)),
+	})
+	if err != nil {
+		log.Println("Failed to send password reset:", err)
+		return requestPasswordResetErrorResponse("Failed to send password reset", http.StatusInternalServerError), nil
+	}
+
+	recordAudit(ctx, AuditLog{ActorID: user.ID, TargetUserID: user.ID, Action: "password_reset_requested"})
+
+	return requestPasswordResetSuccessResponse(passwordResetRequestedMessage, http.StatusOK), nil
+}
+
+func (s *UserServiceServer) ConfirmPasswordReset(ctx context.Context, req *pb.ConfirmPasswordResetRequest) (*pb.ConfirmPasswordResetResponse, error) {
+	if req.GetToken() == "" || req.GetNewPassword() == "" {
+		return confirmPasswordResetErrorResponse("Missing required field(s)", http.StatusBadRequest), nil
+	}
+
+	// Marking the token used in the same write that finds it means it can only be redeemed once
+	now := time.Now()
+	var reset PasswordReset
+	err := database.GetCollection("password_resets").FindOneAndUpdate(ctx,
+		bson.M{"tokenHash": helpers.HashToken(req.GetToken()), "usedAt": nil, "expiresAt": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"usedAt": now}},
+	).Decode(&reset)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return confirmPasswordResetErrorResponse("Invalid or expired reset link", http.StatusBadRequest), nil
+		}
+		log.Println("Database error:", err)
+		return confirmPasswordResetErrorResponse("Database error", http.StatusInternalServerError), nil
+	}
+
+	user, message, statusCode := findUser(ctx, reset.UserID)
+	if statusCode != http.StatusOK {
+		return confirmPasswordResetErrorResponse(message, statusCode), nil
+	}
+	if user.Status == StatusSuspended {
+		return confirmPasswordResetErrorResponse("Account suspended", http.StatusForbidden), nil
+	}
+
+	if err := user.SetPassword(req.GetNewPassword()); err != nil {
+		message, statusCode := setPasswordError(err)
+		return confirmPasswordResetErrorResponse(message, statusCode), nil
+	}
+	if message, statusCode := updateUser(ctx, user.ID, user.ID, bson.M{"password": user.Password}); statusCode != http.StatusOK {
+		return confirmPasswordResetErrorResponse(message, statusCode), nil
+	}
+
+	revokeUserSessions(ctx, user.ID, "password_reset")
+	clearLoginFailures(ctx, user.Username)
+	recordAudit(ctx, AuditLog{ActorID: user.ID, TargetUserID: user.ID, Action: "password_reset"})
+
+	return confirmPasswordResetSuccessResponse("Password reset. Log in with your new password", http.StatusOK), nil
+}
+
+func passwordResetLink(token string) string {
+	baseUrl := configs.Env.PASSWORD_RESET_URL
+	if baseUrl == "" {
+		baseUrl = "http://localhost:8080/reset-password"
+	}
+	return baseUrl + "?token=" + url.QueryEscape(token)
+}
+
+func changePasswordSuccessResponse(message string, statusCode int) *pb.ChangePasswordResponse {
+	return &pb.ChangePasswordResponse{Message: message, Status: true, StatusCode: int32(statusCode)}
+}
+
+func changePasswordErrorResponse(message string, statusCode int) *pb.ChangePasswordResponse {
+	return &pb.ChangePasswordResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func requestPasswordResetSuccessResponse(message string, statusCode int) *pb.RequestPasswordResetResponse {
+	return &pb.RequestPasswordResetResponse{Message: message, Status: true, StatusCode: int32(statusCode)}
+}
+
+func requestPasswordResetErrorResponse(message string, statusCode int) *pb.RequestPasswordResetResponse {
+	return &pb.RequestPasswordResetResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func confirmPasswordResetSuccessResponse(message string, statusCode int) *pb.ConfirmPasswordResetResponse {
+	return &pb.ConfirmPasswordResetResponse{Message: message, Status: true, StatusCode: int32(statusCode)}
+}
+
+func confirmPasswordResetErrorResponse(message string, statusCode int) *pb.ConfirmPasswordResetResponse {
+	return &pb.ConfirmPasswordResetResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
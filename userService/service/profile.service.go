@@ -0,0 +1,404 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/helpers"
+	pb "github.com/manlikehenryy/loan-management-system-grpc/userService/user"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Profile holds a user's personal details. All fields except Email and UpdatedAt hold
+// helpers.EncryptField ciphertexts; Address and Employment are encrypted JSON.
+type Profile struct {
+	Email            string    `bson:"email,omitempty"`
+	Phone            string    `bson:"phone,omitempty"`
+	DateOfBirth      string    `bson:"dateOfBirth,omitempty"`
+	Address          string    `bson:"address,omitempty"`
+	NationalIDNumber string    `bson:"nationalIdNumber,omitempty"`
+	Employment       string    `bson:"employment,omitempty"`
+	UpdatedAt        time.Time `bson:"updatedAt,omitempty"`
+}
+
+// ProfileAddress is the plaintext form of Profile.Address
+type ProfileAddress struct {
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2,omitempty"`
+	City       string `json:"city"`
+	State      string `json:"state,omitempty"`
+	PostalCode string `json:"postalCode,omitempty"`
+	Country    string `json:"country"`
+}
+
+// ProfileEmployment is the plaintext form of Profile.Employment
+type ProfileEmployment struct {
+	Status          string  `json:"status"`
+	EmployerName    string  `json:"employerName,omitempty"`
+	JobTitle        string  `json:"jobTitle,omitempty"`
+	MonthlyIncome   float64 `json:"monthlyIncome"`
+	MonthlyExpenses float64 `json:"monthlyExpenses"`
+}
+
+// Employment statuses
+const (
+	EmploymentEmployed     = "employed"
+	EmploymentSelfEmployed = "self_employed"
+	EmploymentUnemployed   = "unemployed"
+	EmploymentStudent      = "student"
+	EmploymentRetired      = "retired"
+)
+
+const (
+	minApplicantAge   = 18
+	maxApplicantAge   = 120
+	maxAddressLength  = 100
+	maxMonthlyAmount  = 1e9
+	dateOfBirthLayout = "2006-01-02"
+)
+
+var (
+	phonePattern      = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+	countryPattern    = regexp.MustCompile(`^[A-Z]{2}$`)
+	postalCodePattern = regexp.MustCompile(`^[A-Z0-9 -]{2,12}$`)
+	nationalIdPattern = regexp.MustCompile(`^[A-Z0-9-]{5,20}$`)
+)
+
+func (s *UserServiceServer) GetProfile(ctx context.Context, req *pb.GetProfileRequest) (*pb.GetProfileResponse, error) {
+	user, message, statusCode := principalUser(ctx)
+	if statusCode != http.StatusOK {
+		return getProfileErrorResponse(message, statusCode), nil
+	}
+
+	if req.GetUserId() != "" && req.GetUserId() != user.ID.Hex() {
+		if _, message, statusCode := authorizeActor(ctx, PermissionUserView); statusCode != http.StatusOK {
+			return getProfileErrorResponse(message, statusCode), nil
+		}
+
+		userId, err := primitive.ObjectIDFromHex(req.GetUserId())
+		if err != nil {
+			return getProfileErrorResponse("Invalid user ID", http.StatusBadRequest), nil
+		}
+		if user, message, statusCode = findUser(ctx, userId); statusCode != http.StatusOK {
+			return getProfileErrorResponse(message, statusCode), nil
+		}
+	}
+
+	profile, err := profileDetails(user)
+	if err != nil {
+		log.Println("Profile decryption error:", err)
+		return getProfileErrorResponse("Failed to read profile", http.StatusInternalServerError), nil
+	}
+
+	return getProfileSuccessResponse("Profile retrieved", http.StatusOK, profile), nil
+}
+
+func (s *UserServiceServer) UpdateProfile(ctx context.Context, req *pb.UpdateProfileRequest) (*pb.UpdateProfileResponse, error) {
+	user, message, statusCode := principalUser(ctx)
+	if statusCode != http.StatusOK {
+		return updateProfileErrorResponse(message, statusCode), nil
+	}
+
+	if len(req.GetFields()) == 0 {
+		return updateProfileErrorResponse("No fields to update", http.StatusBadRequest), nil
+	}
+
+	update := req.GetProfile()
+	if update == nil {
+		update = &pb.UserProfile{}
+	}
+
+	fields := bson.M{}
+	for _, field := range req.GetFields() {
+		value, message := profileFieldValue(update, field)
+		if message != "" {
+			return updateProfileErrorResponse(message, http.StatusBadRequest), nil
+		}
+
+		if field == "email" {
+			if value != user.Profile.Email {
+				if value != "" {
+					if message, statusCode := checkEmailAvailable(ctx, user.ID, value); statusCode != http.StatusOK {
+						return updateProfileErrorResponse(message, statusCode), nil
+					}
+				}
+				fields["verification.emailVerifiedAt"] = nil
+			}
+			fields["profile.email"] = value
+			continue
+		}
+
+		if field == "phone" {
+			// A new number has to be verified again
+			if current, err := helpers.DecryptField(user.Profile.Phone, profileFieldContext(user.ID, field)); err != nil || current != value {
+				fields["verification.phoneVerifiedAt"] = nil
+			}
+		}
+
+		encrypted, err := helpers.EncryptField(value, profileFieldContext(user.ID, field))
+		if err != nil {
+			log.Println("Profile encryption error:", err)
+			return updateProfileErrorResponse("Failed to update profile", http.StatusInternalServerError), nil
+		}
+		fields["profile."+field] = encrypted
+	}
+	fields["profile.updatedAt"] = time.Now()
+
+	if message, statusCode := updateUser(ctx, user.ID, user.ID, fields); statusCode != http.StatusOK {
+		return updateProfileErrorResponse(message, statusCode), nil
+	}
+
+	recordAudit(ctx, AuditLog{ActorID: user.ID, TargetUserID: user.ID, Action: "profile_updated", Reason: strings.Join(req.GetFields(), ",")})
+
+	updated, message, statusCode := findUser(ctx, user.ID)
+	if statusCode != http.StatusOK {
+		return updateProfileErrorResponse(message, statusCode), nil
+	}
+	profile, err := profileDetails(updated)
+	if err != nil {
+		log.Println("Profile decryption error:", err)
+		return updateProfileErrorResponse("Failed to read profile", http.StatusInternalServerError), nil
+	}
+
+	return updateProfileSuccessResponse("Profile updated", http.StatusOK, profile), nil
+}
+
+// profileFieldValue validates one field of update and returns the value to store, before
+// encryption. An empty value clears the field. A non-empty message means the field is invalid.
+func profileFieldValue(update *pb.UserProfile, field string) (string, string) {
+	switch field {
+	case "email":
+		email := strings.ToLower(strings.TrimSpace(update.GetEmail()))
+		if email == "" {
+			return "", ""
+		}
+		address, err := mail.ParseAddress(email)
+		if err != nil || address.Address != email || len(email) > 254 {
+			return "", "Invalid email address"
+		}
+		return email, ""
+
+	case "phone":
+		phone := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(update.GetPhone())
+		if phone == "" {
+			return "", ""
+		}
+		if !phonePattern.MatchString(phone) {
+			return "", "Phone number must be in international format, e.g. +2348012345678"
+		}
+		return phone, ""
+
+	case "dateOfBirth":
+		dateOfBirth := strings.TrimSpace(update.GetDateOfBirth())
+		if dateOfBirth == "" {
+			return "", ""
+		}
+		date, err := time.Parse(dateOfBirthLayout, dateOfBirth)
+		if err != nil {
+			return "", "Date of birth must be in YYYY-MM-DD format"
+		}
+		now := time.Now()
+		if date.After(now.AddDate(-minApplicantAge, 0, 0)) {
+			return "", fmt.Sprintf("You must be at least %d years old", minApplicantAge)
+		}
+		if date.Before(now.AddDate(-maxApplicantAge, 0, 0)) {
+			return "", "Invalid date of birth"
+		}
+		return dateOfBirth, ""
+
+	case "address":
+		if update.GetAddress() == nil {
+			return "", ""
+		}
+		address := ProfileAddress{
+			Line1:      strings.TrimSpace(update.GetAddress().GetLine1()),
+			Line2:      strings.TrimSpace(update.GetAddress().GetLine2()),
+			City:       strings.TrimSpace(update.GetAddress().GetCity()),
+			State:      strings.TrimSpace(update.GetAddress().GetState()),
+			PostalCode: strings.ToUpper(strings.TrimSpace(update.GetAddress().GetPostalCode())),
+			Country:    strings.ToUpper(strings.TrimSpace(update.GetAddress().GetCountry())),
+		}
+		if address == (ProfileAddress{}) {
+			return "", ""
+		}
+		if address.Line1 == "" || address.City == "" || address.Country == "" {
+			return "", "Address needs line1, city and country"
+		}
+		for _, part := range []string{address.Line1, address.Line2, address.City, address.State} {
+			if len(part) > maxAddressLength {
+				return "", fmt.Sprintf("Address lines must be at most %d characters", maxAddressLength)
+			}
+		}
+		if !countryPattern.MatchString(address.Country) {
+			return "", "Country must be a two-letter ISO 3166 code"
+		}
+		if address.PostalCode != "" && !postalCodePattern.MatchString(address.PostalCode) {
+			return "", "Invalid postal code"
+		}
+		return marshalProfileField(address)
+
+	case "nationalIdNumber":
+		nationalId := strings.ToUpper(strings.ReplaceAll(update.GetNationalIdNumber(), " ", ""))
+		if nationalId == "" {
+			return "", ""
+		}
+		if !nationalIdPattern.MatchString(nationalId) {
+			return "", "Invalid national ID number"
+		}
+		return nationalId, ""
+
+	case "employment":
+		if update.GetEmployment() == nil {
+			return "", ""
+		}
+		employment := ProfileEmployment{
+			Status:          strings.TrimSpace(update.GetEmployment().GetStatus()),
+			EmployerName:    strings.TrimSpace(update.GetEmployment().GetEmployerName()),
+			JobTitle:        strings.TrimSpace(update.GetEmployment().GetJobTitle()),
+			MonthlyIncome:   update.GetEmployment().GetMonthlyIncome(),
+			MonthlyExpenses: update.GetEmployment().GetMonthlyExpenses(),
+		}
+		switch employment.Status {
+		case EmploymentEmployed:
+			if employment.EmployerName == "" {
+				return "", "Employer name is required"
+			}
+		case EmploymentSelfEmployed, EmploymentUnemployed, EmploymentStudent, EmploymentRetired:
+		default:
+			return "", "Employment status must be one of employed, self_employed, unemployed, student, retired"
+		}
+		if len(employment.EmployerName) > maxAddressLength || len(employment.JobTitle) > maxAddressLength {
+			return "", fmt.Sprintf("Employer name and job title must be at most %d characters", maxAddressLength)
+		}
+		for _, amount := range []float64{employment.MonthlyIncome, employment.MonthlyExpenses} {
+			if math.IsNaN(amount) || amount < 0 || amount > maxMonthlyAmount {
+				return "", "Monthly income and expenses must be between 0 and 1000000000"
+			}
+		}
+		return marshalProfileField(employment)
+
+	default:
+		return "", "Unknown profile field " + field
+	}
+}
+
+func marshalProfileField(value interface{}) (string, string) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", "Invalid profile field"
+	}
+	return string(data), ""
+}
+
+// checkEmailAvailable makes sure no other user has already claimed email
+func checkEmailAvailable(ctx context.Context, userId primitive.ObjectID, email string) (string, int) {
+	count, err := database.GetCollection("users").CountDocuments(ctx, bson.M{"profile.email": email, "_id": bson.M{"$ne": userId}})
+	if err != nil {
+		log.Println("Database error:", err)
+		return "Database error", http.StatusInternalServerError
+	}
+	if count > 0 {
+		return "Email is already in use", http.StatusConflict
+	}
+	return "", http.StatusOK
+}
+
+// profileFieldContext binds a ciphertext to the user and field it was written for
+func profileFieldContext(userId primitive.ObjectID, field string) string {
+	return "profile:" + userId.Hex() + ":" + field
+}
+
+// decryptProfile returns user's profile with every field decrypted
+func decryptProfile(user *User) (*pb.UserProfile, error) {
+	decrypt := func(field string, ciphertext string) (string, error) {
+		return helpers.DecryptField(ciphertext, profileFieldContext(user.ID, field))
+	}
+
+	profile := &pb.UserProfile{Email: user.Profile.Email}
+	var err error
+	if profile.Phone, err = decrypt("phone", user.Profile.Phone); err != nil {
+		return nil, err
+	}
+	if profile.DateOfBirth, err = decrypt("dateOfBirth", user.Profile.DateOfBirth); err != nil {
+		return nil, err
+	}
+	if profile.NationalIdNumber, err = decrypt("nationalIdNumber", user.Profile.NationalIDNumber); err != nil {
+		return nil, err
+	}
+
+	address, err := decrypt("address", user.Profile.Address)
+	if err != nil {
+		return nil, err
+	}
+	if address != "" {
+		var a ProfileAddress
+		if err := json.Unmarshal([]byte(address), &a); err != nil {
+			return nil, err
+		}
+		profile.Address = &pb.Address{Line1: a.Line1, Line2: a.Line2, City: a.City, State: a.State, PostalCode: a.PostalCode, Country: a.Country}
+	}
+
+	employment, err := decrypt("employment", user.Profile.Employment)
+	if err != nil {
+		return nil, err
+	}
+	if employment != "" {
+		var e ProfileEmployment
+		if err := json.Unmarshal([]byte(employment), &e); err != nil {
+			return nil, err
+		}
+		profile.Employment = &pb.Employment{Status: e.Status, EmployerName: e.EmployerName, JobTitle: e.JobTitle, MonthlyIncome: e.MonthlyIncome, MonthlyExpenses: e.MonthlyExpenses}
+	}
+
+	profile.EmailVerified = user.Verification.EmailVerifiedAt != nil
+	profile.PhoneVerified = user.Verification.PhoneVerifiedAt != nil
+
+	if !user.Profile.UpdatedAt.IsZero() {
+		profile.UpdatedAt = user.Profile.UpdatedAt.Format(time.RFC3339)
+	}
+	return profile, nil
+}
+
+// profileDetails is the profile as returned to callers, with the national ID masked
+func profileDetails(user *User) (*pb.UserProfile, error) {
+	profile, err := decryptProfile(user)
+	if err != nil {
+		return nil, err
+	}
+	profile.NationalIdNumber = maskValue(profile.NationalIdNumber, 4)
+	return profile, nil
+}
+
+// maskValue hides all but the last visible characters of value
+func maskValue(value string, visible int) string {
+	if len(value) <= visible {
+		return strings.Repeat("*", len(value))
+	}
+	return strings.Repeat("*", len(value)-visible) + value[len(value)-visible:]
+}
+
+func getProfileSuccessResponse(message string, statusCode int, profile *pb.UserProfile) *pb.GetProfileResponse {
+	return &pb.GetProfileResponse{Message: message, Status: true, StatusCode: int32(statusCode), Profile: profile}
+}
+
+func getProfileErrorResponse(message string, statusCode int) *pb.GetProfileResponse {
+	return &pb.GetProfileResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func updateProfileSuccessResponse(message string, statusCode int, profile *pb.UserProfile) *pb.UpdateProfileResponse {
+	return &pb.UpdateProfileResponse{Message: message, Status: true, StatusCode: int32(statusCode), Profile: profile}
+}
+
+func updateProfileErrorResponse(message string, statusCode int) *pb.UpdateProfileResponse {
+	return &pb.UpdateProfileResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Roles
+const (
+	RoleUser          = "user"
+	RoleLoanOfficer   = "loan_officer"
+	RoleCreditManager = "credit_manager"
+	RoleFinance       = "finance"
+	RoleSupport       = "support"
+	RoleAuditor       = "auditor"
+	RoleSuperadmin    = "superadmin"
+)
+
+// Permissions
+const (
+	PermissionLoanApprove           = "loan.approve"
+	PermissionLoanApproveAboveLimit = "loan.approve.above_limit"
+	PermissionLoanReject            = "loan.reject"
+	PermissionLoanViewAny           = "loan.view_any"
+	PermissionWalletViewAny         = "wallet.view_any"
+	PermissionUserView              = "user.view"
+	PermissionUserManage            = "user.manage"
+	PermissionAuditView             = "audit.view"
+	PermissionApiKeyManage          = "apikey.manage"
+	PermissionKycReview             = "kyc.review"
+	PermissionWithdrawalResolve     = "withdrawal.resolve"
+	PermissionWalletAdjust          = "wallet.adjust"
+)
+
+var allPermissions = []string{
+	PermissionLoanApprove,
+	PermissionLoanApproveAboveLimit,
+	PermissionLoanReject,
+	PermissionLoanViewAny,
+	PermissionWalletViewAny,
+	PermissionUserView,
+	PermissionUserManage,
+	PermissionAuditView,
+	PermissionApiKeyManage,
+	PermissionKycReview,
+	PermissionWithdrawalResolve,
+	PermissionWalletAdjust,
+}
+
+// rolePermissions is the permission set granted by each role
+var rolePermissions = map[string][]string{
+	RoleUser:          {},
+	RoleLoanOfficer:   {PermissionLoanApprove, PermissionLoanReject, PermissionLoanViewAny},
+	RoleCreditManager: {PermissionLoanApprove, PermissionLoanApproveAboveLimit, PermissionLoanReject, PermissionLoanViewAny, PermissionKycReview},
+	RoleFinance:       {PermissionLoanViewAny, PermissionWalletViewAny, PermissionWithdrawalResolve, PermissionWalletAdjust},
+	RoleSupport:       {PermissionLoanViewAny, PermissionUserView, PermissionKycReview},
+	RoleAuditor:       {PermissionLoanViewAny, PermissionWalletViewAny, PermissionUserView, PermissionAuditView},
+	RoleSuperadmin:    allPermissions,
+}
+
+// mfaPermissions can only be used by accounts with MFA enabled
+var mfaPermissions = []string{PermissionLoanApprove, PermissionLoanApproveAboveLimit}
+
+// RequiresMfa reports whether role grants a permission that needs MFA
+func RequiresMfa(role string) bool {
+	for _, permission := range mfaPermissions {
+		if HasPermission(role, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+func permissionRequiresMfa(permission string) bool {
+	for _, p := range mfaPermissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidRole reports whether role is one of the known roles
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission reports whether role grants permission
+func HasPermission(role string, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// MigrateLegacyRoles turns users with the old "admin" role into superadmins
+func MigrateLegacyRoles(ctx context.Context) {
+	usersCollection := database.GetCollection("users")
+
+	result, err := usersCollection.UpdateMany(ctx, bson.M{"role": "admin"}, bson.M{"$set": bson.M{"role": RoleSuperadmin}})
+	if err != nil {
+		log.Println("Failed to migrate legacy admin roles:", err)
+		return
+	}
+
+	if result.ModifiedCount > 0 {
+		log.Printf("Migrated %d admin users to the %s role", result.ModifiedCount, RoleSuperadmin)
+	}
+}
+
+// BootstrapSuperadmin promotes the configured user to superadmin when no superadmin exists yet
+func BootstrapSuperadmin(ctx context.Context) {
+	username := configs.Env.BOOTSTRAP_SUPERADMIN
+	if username == "" {
+		return
+	}
+
+	usersCollection := database.GetCollection("users")
+
+	count, err := usersCollection.CountDocuments(ctx, bson.M{"role": RoleSuperadmin})
+	if err != nil {
+		log.Println("Failed to count superadmins:", err)
+		return
+	}
+	if count > 0 {
+		return
+	}
+
+	var user User
+	err = usersCollection.FindOne(ctx, bson.M{"username": username}).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			log.Printf("Bootstrap superadmin %q not found; register the user and restart", username)
+		} else {
+			log.Println("Failed to bootstrap superadmin:", err)
+		}
+		return
+	}
+
+	_, err = usersCollection.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{"$set": bson.M{"role": RoleSuperadmin, "status": StatusActive, "updatedAt": time.Now()}})
+	if err != nil {
+		log.Println("Failed to bootstrap superadmin:", err)
+		return
+	}
+
+	recordAudit(ctx, AuditLog{TargetUserID: user.ID, Action: "role_changed", From: user.Role, To: RoleSuperadmin, Reason: "bootstrap"})
+	log.Printf("Promoted %q to %s", username, RoleSuperadmin)
+}
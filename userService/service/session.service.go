@@ -0,0 +1,386 @@
+package service
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/helpers"
+	pb "github.com/manlikehenryy/loan-management-system-grpc/userService/user"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// rotatedHashLimit bounds how many superseded refresh token hashes a session remembers for reuse detection
+const rotatedHashLimit = 20
+
+// Session is a login session kept alive by a rotating refresh token
+type Session struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty"`
+	UserID           primitive.ObjectID `bson:"userId"`
+	RefreshTokenHash string             `bson:"refreshTokenHash"`
+	RotatedHashes    []string           `bson:"rotatedHashes,omitempty"` // most recent superseded refresh token hashes
+	UserAgent        string             `bson:"userAgent,omitempty"`
+	IPAddress        string             `bson:"ipAddress,omitempty"`
+	CreatedAt        time.Time          `bson:"createdAt"`
+	LastUsedAt       time.Time          `bson:"lastUsedAt"`
+	ExpiresAt        time.Time          `bson:"expiresAt"`
+	RevokedAt        *time.Time         `bson:"revokedAt,omitempty"`
+	RevokedReason    string             `bson:"revokedReason,omitempty"` // "logout", "revoked", "reuse_detected", "suspended"
+}
+
+func (s *UserServiceServer) RefreshToken(ctx context.Context, req *pb.RefreshTokenRequest) (*pb.RefreshTokenResponse, error) {
+	sessionIdStr, err := helpers.ParseRefreshToken(req.GetRefreshToken())
+	if err != nil {
+		return refreshTokenErrorResponse("Unauthorized: Invalid refresh token", http.StatusUnauthorized), nil
+	}
+
+	sessionId, err := primitive.ObjectIDFromHex(sessionIdStr)
+	if err != nil {
+		return refreshTokenErrorResponse("Unauthorized: Invalid refresh token", http.StatusUnauthorized), nil
+	}
+
+	session, message, statusCode := findSession(ctx, sessionId)
+	if statusCode == http.StatusNotFound {
+		return refreshTokenErrorResponse("Unauthorized: Invalid refresh token", http.StatusUnauthorized), nil
+	}
+	if statusCode != http.StatusOK {
+		return refreshTokenErrorResponse(message, statusCode), nil
+	}
+
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return refreshTokenErrorResponse("Unauthorized: Session expired", http.StatusUnauthorized), nil
+	}
+
+	presentedHash := helpers.HashToken(req.GetRefreshToken())
+	if presentedHash != session.RefreshTokenHash {
+		// Only a token this session really issued proves a stolen copy; a made-up
+		// secret for a known session ID must not be able to log the user out
+		if slices.Contains(session.RotatedHashes, presentedHash) {
+			log.Println("Refresh token reuse detected for session", sessionIdStr)
+			revokeSession(ctx, bson.M{"_id": sessionId}, "reuse_detected")
+		}
+		return refreshTokenErrorResponse("Unauthorized: Invalid refresh token", http.StatusUnauthorized), nil
+	}
+
+	user, message, statusCode := findUser(ctx, session.UserID)
+	if statusCode == http.StatusNotFound {
+		return refreshTokenErrorResponse("Unauthorized: User not found", http.StatusUnauthorized), nil
+	}
+	if statusCode != http.StatusOK {
+		return refreshTokenErrorResponse(message, statusCode), nil
+	}
+	if user.Status == StatusSuspended {
+		return refreshTokenErrorResponse("Account suspended", http.StatusForbidden), nil
+	}
+
+	refreshToken, refreshTokenHash, err := helpers.GenerateRefreshToken(sessionIdStr)
+	if err != nil {
+		log.Println("Token generation error:", err)
+		return refreshTokenErrorResponse("Failed to generate token", http.StatusInternalServerError), nil
+	}
+
+	fields := bson.M{"refreshTokenHash": refreshTokenHash, "lastUsedAt": time.Now()}
+	if req.GetUserAgent() != "" {
+		fields["userAgent"] = req.GetUserAgent()
+	}
+	if req.GetIpAddress() != "" {
+		fields["ipAddress"] = req.GetIpAddress()
+	}
+
+	// Only rotate if nobody else rotated the token in the meantime
+	result, err := database.GetCollection("sessions").UpdateOne(ctx, bson.M{
+		"_id":              sessionId,
+		"refreshTokenHash": presentedHash,
+		"revokedAt":        nil,
+	}, bson.M{
+		"$set":  fields,
+		"$push": bson.M{"rotatedHashes": bson.M{"$each": bson.A{presentedHash}, "$slice": -rotatedHashLimit}},
+	})
+	if err != nil {
+		log.Println("Database error:", err)
+		return refreshTokenErrorResponse("Failed to refresh token", http.StatusInternalServerError), nil
+	}
+	if result.MatchedCount == 0 {
+		log.Println("Refresh token reuse detected for session", sessionIdStr)
+		revokeSession(ctx, bson.M{"_id": sessionId}, "reuse_detected")
+		return refreshTokenErrorResponse("Unauthorized: Invalid refresh token", http.StatusUnauthorized), nil
+	}
+
+	token, err := helpers.GenerateJwt(user.ID.Hex(), sessionIdStr, []string{user.Role})
+	if err != nil {
+		log.Println("Token generation error:", err)
+		return refreshTokenErrorResponse("Failed to generate token", http.StatusInternalServerError), nil
+	}
+
+	return refreshTokenSuccessResponse("Token refreshed", http.StatusOK, sessionIdStr, token, refreshToken, time.Until(session.ExpiresAt)), nil
+}
+
+func (s *UserServiceServer) RevokeSession(ctx context.Context, req *pb.RevokeSessionRequest) (*pb.RevokeSessionResponse, error) {
+	// Logging out with the refresh token proves ownership of the session
+	if req.GetRefreshToken() != "" {
+		sessionIdStr, err := helpers.ParseRefreshToken(req.GetRefreshToken())
+		if err != nil {
+			return revokeSessionErrorResponse("Invalid refresh token", http.StatusBadRequest), nil
+		}
+		sessionId, err := primitive.ObjectIDFromHex(sessionIdStr)
+		if err != nil {
+			return revokeSessionErrorResponse("Invalid refresh token", http.StatusBadRequest), nil
+		}
+
+		message, statusCode := revokeSession(ctx, bson.M{"_id": sessionId, "refreshTokenHash": helpers.HashToken(req.GetRefreshToken())}, "logout")
+		if statusCode != http.StatusOK {
+			return revokeSessionErrorResponse(message, statusCode), nil
+		}
+		return revokeSessionSuccessResponse("Logged out successfully", http.StatusOK), nil
+	}
+
+	principal, ok := helpers.PrincipalFromContext(ctx)
+	if !ok {
+		return revokeSessionErrorResponse("Unauthorized: No identity provided", http.StatusUnauthorized), nil
+	}
+
+	sessionId, err := primitive.ObjectIDFromHex(req.GetSessionId())
+	if err != nil {
+		return revokeSessionErrorResponse("Invalid session ID", http.StatusBadRequest), nil
+	}
+
+	session, message, statusCode := findSession(ctx, sessionId)
+	if statusCode != http.StatusOK {
+		return revokeSessionErrorResponse(message, statusCode), nil
+	}
+
+	// Users may revoke their own sessions; anyone else needs user.manage
+	if session.UserID.Hex() != principal.UserId {
+		if _, message, statusCode := authorizeActor(ctx, PermissionUserManage); statusCode != http.StatusOK {
+			if statusCode == http.StatusForbidden {
+				return revokeSessionErrorResponse("Session not found", http.StatusNotFound), nil
+			}
+			return revokeSessionErrorResponse(message, statusCode), nil
+		}
+	}
+
+	if message, statusCode := revokeSession(ctx, bson.M{"_id": sessionId}, "revoked"); statusCode != http.StatusOK {
+		return revokeSessionErrorResponse(message, statusCode), nil
+	}
+
+	return revokeSessionSuccessResponse("Session revoked", http.StatusOK), nil
+}
+
+func (s *UserServiceServer) ListSessions(ctx context.Context, req *pb.ListSessionsRequest) (*pb.ListSessionsResponse, error) {
+	principal, ok := helpers.PrincipalFromContext(ctx)
+	if !ok {
+		return listSessionsErrorResponse("Unauthorized: No identity provided", http.StatusUnauthorized), nil
+	}
+
+	userId, err := primitive.ObjectIDFromHex(principal.UserId)
+	if err != nil {
+		return listSessionsErrorResponse("Unauthorized: Invalid user ID", http.StatusUnauthorized), nil
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "lastUsedAt", Value: -1}})
+	cursor, err := database.GetCollection("sessions").Find(ctx, bson.M{
+		"userId":    userId,
+		"revokedAt": nil,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}, findOptions)
+	if err != nil {
+		log.Println("Database error:", err)
+		return listSessionsErrorResponse("Failed to list sessions", http.StatusInternalServerError), nil
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []Session
+	if err := cursor.All(ctx, &sessions); err != nil {
+		log.Println("Database error:", err)
+		return listSessionsErrorResponse("Failed to list sessions", http.StatusInternalServerError), nil
+	}
+
+	details := make([]*pb.SessionDetails, 0, len(sessions))
+	for _, session := range sessions {
+		details = append(details, &pb.SessionDetails{
+			Id:         session.ID.Hex(),
+			UserAgent:  session.UserAgent,
+			IpAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt.Format(time.RFC3339),
+			LastUsedAt: session.LastUsedAt.Format(time.RFC3339),
+			ExpiresAt:  session.ExpiresAt.Format(time.RFC3339),
+		})
+	}
+
+	return listSessionsSuccessResponse("Sessions retrieved", http.StatusOK, details), nil
+}
+
+// IsSessionActive lets callers that verify access tokens locally check for revocation
+func (s *UserServiceServer) IsSessionActive(ctx context.Context, req *pb.IsSessionActiveRequest) (*pb.IsSessionActiveResponse, error) {
+	userId, err := primitive.ObjectIDFromHex(req.GetUserId())
+	if err != nil {
+		return isSessionActiveErrorResponse("Invalid user ID", http.StatusBadRequest), nil
+	}
+
+	message, statusCode := activeSession(ctx, req.GetSessionId(), userId)
+	if statusCode == http.StatusUnauthorized {
+		return isSessionActiveSuccessResponse(message, http.StatusOK, false), nil
+	}
+	if statusCode != http.StatusOK {
+		return isSessionActiveErrorResponse(message, statusCode), nil
+	}
+
+	return isSessionActiveSuccessResponse("Session active", http.StatusOK, true), nil
+}
+
+// createSession starts a session for user and returns its ID and access and refresh tokens
+func createSession(ctx context.Context, user *User, userAgent string, ipAddress string) (string, string, string, error) {
+	now := time.Now()
+	session := Session{
+		ID:         primitive.NewObjectID(),
+		UserID:     user.ID,
+		UserAgent:  userAgent,
+		IPAddress:  ipAddress,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(helpers.RefreshTokenTTL),
+	}
+
+	refreshToken, refreshTokenHash, err := helpers.GenerateRefreshToken(session.ID.Hex())
+	if err != nil {
+		return "", "", "", err
+	}
+	session.RefreshTokenHash = refreshTokenHash
+
+	token, err := helpers.GenerateJwt(user.ID.Hex(), session.ID.Hex(), []string{user.Role})
+	if err != nil {
+		return "", "", "", err
+	}
+
+	if _, err := database.GetCollection("sessions").InsertOne(ctx, session); err != nil {
+		return "", "", "", err
+	}
+
+	return session.ID.Hex(), token, refreshToken, nil
+}
+
+// activeSession checks that the session behind an access token is still live
+func activeSession(ctx context.Context, sessionIdStr string, userId primitive.ObjectID) (string, int) {
+	sessionId, err := primitive.ObjectIDFromHex(sessionIdStr)
+	if err != nil {
+		return "Unauthorized: Invalid session", http.StatusUnauthorized
+	}
+
+	session, message, statusCode := findSession(ctx, sessionId)
+	if statusCode == http.StatusNotFound {
+		return "Unauthorized: Invalid session", http.StatusUnauthorized
+	}
+	if statusCode != http.StatusOK {
+		return message, statusCode
+	}
+
+	if session.UserID != userId || session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return "Unauthorized: Session revoked", http.StatusUnauthorized
+	}
+
+	return "", http.StatusOK
+}
+
+func findSession(ctx context.Context, sessionId primitive.ObjectID) (*Session, string, int) {
+	var session Session
+	err := database.GetCollection("sessions").FindOne(ctx, bson.M{"_id": sessionId}).Decode(&session)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, "Session not found", http.StatusNotFound
+		}
+		log.Println("Database error:", err)
+		return nil, "Database error", http.StatusInternalServerError
+	}
+
+	return &session, "", http.StatusOK
+}
+
+// revokeSession revokes the active session matching filter
+func revokeSession(ctx context.Context, filter bson.M, reason string) (string, int) {
+	filter["revokedAt"] = nil
+
+	result, err := database.GetCollection("sessions").UpdateOne(ctx, filter, bson.M{
+		"$set": bson.M{"revokedAt": time.Now(), "revokedReason": reason},
+	})
+	if err != nil {
+		log.Println("Database error:", err)
+		return "Failed to revoke session", http.StatusInternalServerError
+	}
+	if result.MatchedCount == 0 {
+		return "Session not found", http.StatusNotFound
+	}
+
+	return "", http.StatusOK
+}
+
+// revokeUserSessions revokes every active session belonging to a user
+func revokeUserSessions(ctx context.Context, userId primitive.ObjectID, reason string) {
+	_, err := database.GetCollection("sessions").UpdateMany(ctx, bson.M{"userId": userId, "revokedAt": nil}, bson.M{
+		"$set": bson.M{"revokedAt": time.Now(), "revokedReason": reason},
+	})
+	if err != nil {
+		log.Println("Failed to revoke sessions:", err)
+	}
+}
+
+// revokeOtherSessions revokes all of the user's sessions except currentSessionId
+func revokeOtherSessions(ctx context.Context, userId primitive.ObjectID, currentSessionId string, reason string) {
+	filter := bson.M{"userId": userId, "revokedAt": nil}
+	if sessionId, err := primitive.ObjectIDFromHex(currentSessionId); err == nil {
+		filter["_id"] = bson.M{"$ne": sessionId}
+	}
+
+	_, err := database.GetCollection("sessions").UpdateMany(ctx, filter, bson.M{
+		"$set": bson.M{"revokedAt": time.Now(), "revokedReason": reason},
+	})
+	if err != nil {
+		log.Println("Failed to revoke sessions:", err)
+	}
+}
+
+func refreshTokenSuccessResponse(message string, statusCode int, sessionId string, token string, refreshToken string, refreshExpiresIn time.Duration) *pb.RefreshTokenResponse {
+	return &pb.RefreshTokenResponse{
+		Message:          message,
+		Status:           true,
+		StatusCode:       int32(statusCode),
+		SessionId:        sessionId,
+		Token:            token,
+		RefreshToken:     refreshToken,
+		ExpiresIn:        int64(helpers.AccessTokenTTL.Seconds()),
+		RefreshExpiresIn: int64(refreshExpiresIn.Seconds()),
+	}
+}
+
+func refreshTokenErrorResponse(message string, statusCode int) *pb.RefreshTokenResponse {
+	return &pb.RefreshTokenResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func revokeSessionSuccessResponse(message string, statusCode int) *pb.RevokeSessionResponse {
+	return &pb.RevokeSessionResponse{Message: message, Status: true, StatusCode: int32(statusCode)}
+}
+
+func revokeSessionErrorResponse(message string, statusCode int) *pb.RevokeSessionResponse {
+	return &pb.RevokeSessionResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func isSessionActiveSuccessResponse(message string, statusCode int, active bool) *pb.IsSessionActiveResponse {
+	return &pb.IsSessionActiveResponse{Message: message, Status: true, StatusCode: int32(statusCode), Active: active}
+}
+
+func isSessionActiveErrorResponse(message string, statusCode int) *pb.IsSessionActiveResponse {
+	return &pb.IsSessionActiveResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func listSessionsSuccessResponse(message string, statusCode int, sessions []*pb.SessionDetails) *pb.ListSessionsResponse {
+	return &pb.ListSessionsResponse{Message: message, Status: true, StatusCode: int32(statusCode), Sessions: sessions}
+}
+
+func listSessionsErrorResponse(message string, statusCode int) *pb.ListSessionsResponse {
+	return &pb.ListSessionsResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
@@ -2,32 +2,55 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"strings"
+	"time"
 
-	"github.com/manlikehenryy/loan-management-system-grpc/userService/configs"
 	"github.com/manlikehenryy/loan-management-system-grpc/userService/database"
-	"github.com/manlikehenryy/loan-management-system-grpc/userService/grpcclient"
 	"github.com/manlikehenryy/loan-management-system-grpc/userService/helpers"
 	pb "github.com/manlikehenryy/loan-management-system-grpc/userService/user"         // Import generated protobuf code
-	walletPb "github.com/manlikehenryy/loan-management-system-grpc/userService/wallet" // Import generated protobuf code
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // User struct
 type User struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty"`
-	Username  string             `bson:"username,omitempty"`
-	Password  []byte             `bson:"password,omitempty"`
-	FirstName string             `bson:"firstName,omitempty"`
-	LastName  string             `bson:"lastName,omitempty"`
-	Role      string             `bson:"role,omitempty"`
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	Username     string             `bson:"username,omitempty"`
+	Password     []byte             `bson:"password,omitempty"`
+	FirstName    string             `bson:"firstName,omitempty"`
+	LastName     string             `bson:"lastName,omitempty"`
+	Role         string             `bson:"role,omitempty"`
+	Status       string             `bson:"status,omitempty"` // "active", "suspended"
+	CreatedAt    time.Time          `bson:"createdAt,omitempty"`
+	UpdatedAt    time.Time          `bson:"updatedAt,omitempty"`
+	UpdatedBy    primitive.ObjectID `bson:"updatedBy,omitempty"`
+	MFA          MFA                `bson:"mfa"`
+	Profile      Profile            `bson:"profile"`
+	KycTier      int                `bson:"kycTier,omitempty"` // See kyc.service.go
+	Verification Verification       `bson:"verification"`      // See verification.service.go
 }
 
+// MFA holds a user's TOTP enrollment
+type MFA struct {
+	Enabled       bool     `bson:"enabled,omitempty"`
+	Secret        string   `bson:"secret,omitempty"`
+	PendingSecret string   `bson:"pendingSecret,omitempty"` // Set between EnrollMfa and ConfirmMfa
+	RecoveryCodes []string `bson:"recoveryCodes,omitempty"` // Hashes of the unused recovery codes
+	LastStep      int64    `bson:"lastStep,omitempty"`      // Last TOTP time step used, to stop replays
+}
+
+// Account statuses
+const (
+	StatusActive    = "active"
+	StatusSuspended = "suspended"
+)
+
 // UserServiceServer struct to implement gRPC functions
 type UserServiceServer struct {
 	pb.UnimplementedUserServiceServer
@@ -37,13 +60,52 @@ func NewUserServiceServer() *UserServiceServer {
 	return &UserServiceServer{}
 }
 
-func (user *User) SetPassword(password string) {
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), 14)
+// SetPassword checks password against the password policy and stores its hash. A policy
+// failure is a *helpers.PasswordPolicyError.
+func (user *User) SetPassword(password string) error {
+	if err := helpers.ValidatePassword(password, user.Username); err != nil {
+		return err
+	}
+
+	hashedPassword, err := helpers.HashPassword(password)
+	if err != nil {
+		return err
+	}
 	user.Password = hashedPassword
+	return nil
 }
 
 func (user *User) ComparePassword(password string) error {
-	return bcrypt.CompareHashAndPassword(user.Password, []byte(password))
+	return helpers.ComparePassword(user.Password, password)
+}
+
+// setPasswordError turns a SetPassword error into a response message and status code
+func setPasswordError(err error) (string, int) {
+	var policyErr *helpers.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		return policyErr.Reason, http.StatusBadRequest
+	}
+	log.Println("Password hashing error:", err)
+	return "Failed to set password", http.StatusInternalServerError
+}
+
+// rehashPassword re-hashes a just-verified password if the hashing settings have changed since it was stored
+func rehashPassword(ctx context.Context, user *User, password string) {
+	if !helpers.PasswordNeedsRehash(user.Password) {
+		return
+	}
+
+	// Skips the policy: the user already has this password, and may not be asked to change it here
+	hashedPassword, err := helpers.HashPassword(password)
+	if err != nil {
+		log.Println("Password rehash error:", err)
+		return
+	}
+	if _, err := database.GetCollection("users").UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{"$set": bson.M{"password": hashedPassword}}); err != nil {
+		log.Println("Failed to store rehashed password:", err)
+		return
+	}
+	user.Password = hashedPassword
 }
 
 // Register user
@@ -54,8 +116,10 @@ func (s *UserServiceServer) RegisterUser(ctx context.Context, req *pb.RegisterUs
 		return registerUserErrorResponse("Missing required field(s)", http.StatusBadRequest), nil
 	}
 
-	user := User{Role: "user", Username: req.GetUsername(), FirstName: req.GetFirstName(), LastName: req.GetLastName()}
-	user.SetPassword(req.GetPassword())
+	user := User{Role: RoleUser, Status: StatusActive, Username: req.GetUsername(), FirstName: req.GetFirstName(), LastName: req.GetLastName(), CreatedAt: time.Now()}
+	if err := user.SetPassword(req.GetPassword()); err != nil {
+		return registerUserErrorResponse(setPasswordError(err)), nil
+	}
 
 	var existingUser User
 	err := usersCollection.FindOne(context.Background(), bson.M{"username": strings.TrimSpace(req.GetUsername())}).Decode(&existingUser)
@@ -69,47 +133,35 @@ func (s *UserServiceServer) RegisterUser(ctx context.Context, req *pb.RegisterUs
 		return registerUserErrorResponse("Username already exists", http.StatusBadRequest), nil
 	}
 
-	result, error_ := usersCollection.InsertOne(context.Background(), user)
+	user.ID = primitive.NewObjectID()
+	error_ := database.WithTransaction(ctx, func(ctx mongo.SessionContext) error {
+		if _, err := usersCollection.InsertOne(ctx, user); err != nil {
+			return err
+		}
+		return recordUserEvent(ctx, EventUserRegistered, user.ID.Hex(), nil)
+	})
 	if error_ != nil {
 		log.Println("Database error:", error_)
 		return registerUserErrorResponse("Failed to create account", http.StatusInternalServerError), nil
 	}
 
-	// Initialize the gRPC client
-	walletServiceClient, cleanup, err := grpcclient.NewWalletServiceClient(ctx)
-	if err != nil {
-		log.Fatalf("Failed to connect to WalletService: %v", err)
-		return registerUserErrorResponse("Internal service error", http.StatusInternalServerError), nil
-	}
-	defer cleanup()
-
-	// Set up the context with authorization metadata
-	c := grpcclient.NewAuthContext(context.Background(), configs.Env.TOKEN)
-
-	createWalletReq := &walletPb.CreateWalletRequest{
-		UserId: result.InsertedID.(primitive.ObjectID).Hex(),
-	}
-	createWalletResp, err := walletServiceClient.CreateWallet(c, createWalletReq)
-
-	if createWalletResp == nil {
-		log.Println("Error in CreateWallet call:", err)
-		return registerUserErrorResponse("Unexpected service response", http.StatusInternalServerError), nil
-	}
-
-	if err != nil || !createWalletResp.Status {
-		return registerUserErrorResponse(createWalletResp.Message, int(createWalletResp.StatusCode)), nil
-	}
-
+	// walletService opens the user's wallet when UserRegistered reaches it through the broker
 	return registerUserSuccessResponse("User registered successfully!", http.StatusOK), nil
 }
 
 // Login user and generate JWT
 func (s *UserServiceServer) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (*pb.LoginUserResponse, error) {
+	// Checked before the password so throttled guesses don't cost a password hash comparison
+	if wait := loginThrottle(ctx, req.GetUsername(), req.GetIpAddress()); wait > 0 {
+		return loginThrottledResponse(wait), nil
+	}
+
 	usersCollection := database.GetCollection("users")
 	var user User
 	err := usersCollection.FindOne(ctx, bson.M{"username": req.GetUsername()}).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
+			recordLoginFailure(ctx, req.GetUsername(), req.GetIpAddress())
 			return loginUserErrorResponse("Incorrect username or password", http.StatusUnauthorized, ""), nil
 		}
 		log.Println("Database error:", err)
@@ -117,41 +169,84 @@ func (s *UserServiceServer) LoginUser(ctx context.Context, req *pb.LoginUserRequ
 	}
 
 	if err := user.ComparePassword(req.GetPassword()); err != nil {
+		recordLoginFailure(ctx, req.GetUsername(), req.GetIpAddress())
 		return loginUserErrorResponse("Incorrect username or password", http.StatusUnauthorized, ""), nil
 	}
 
-	token, err := helpers.GenerateJwt(user.ID.Hex())
+	if user.Status == StatusSuspended {
+		return loginUserErrorResponse("Account suspended", http.StatusForbidden, ""), nil
+	}
+
+	rehashPassword(ctx, &user, req.GetPassword())
+
+	// Failures are only cleared once the MFA step succeeds too, so MFA codes can't be guessed
+	// by re-entering a known password between attempts
+	if user.MFA.Enabled {
+		challenge, err := helpers.GenerateMfaChallenge(user.ID.Hex())
+		if err != nil {
+			log.Println("Token generation error:", err)
+			return loginUserErrorResponse("Failed to generate token", http.StatusInternalServerError, ""), nil
+		}
+		return mfaChallengeResponse(challenge), nil
+	}
+
+	clearLoginFailures(ctx, user.Username)
+
+	sessionId, token, refreshToken, err := createSession(ctx, &user, req.GetUserAgent(), req.GetIpAddress())
 	if err != nil {
 		log.Println("Token generation error:", err)
 
-		return loginUserErrorResponse("Failed to generate token", http.StatusInternalServerError, token), nil
+		return loginUserErrorResponse("Failed to generate token", http.StatusInternalServerError, ""), nil
 	}
 
-	return loginUserSuccessResponse("Logged in successfully", http.StatusOK, token), nil
+	return loginUserSuccessResponse("Logged in successfully", http.StatusOK, sessionId, token, refreshToken), nil
 }
 
 func (s *UserServiceServer) VerifyToken(ctx context.Context, req *pb.VerifyTokenRequest) (*pb.VerifyTokenResponse, error) {
 
-	userIdStr, err := helpers.ParseJwt(req.GetToken())
+	userIdStr, sessionId, err := helpers.ParseJwt(req.GetToken())
 	if err != nil {
 
 		return verifyTokenErrorResponse("Unauthorized: Invalid JWT token", http.StatusUnauthorized), nil
 	}
 
-	_, err_ := primitive.ObjectIDFromHex(userIdStr)
+	userId, err_ := primitive.ObjectIDFromHex(userIdStr)
 	if err_ != nil {
 
 		return verifyTokenErrorResponse("Unauthorized: Invalid user ID", http.StatusUnauthorized), nil
 	}
 
-	return verifyTokenSuccessResponse("token valid", http.StatusOK, userIdStr), nil
+	if message, statusCode := activeSession(ctx, sessionId, userId); statusCode != http.StatusOK {
+		return verifyTokenErrorResponse(message, statusCode), nil
+	}
+
+	usersCollection := database.GetCollection("users")
+	var user User
+	err = usersCollection.FindOne(ctx, bson.M{"_id": userId}).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return verifyTokenErrorResponse("Unauthorized: User not found", http.StatusUnauthorized), nil
+		}
+		log.Println("Database error:", err)
+		return verifyTokenErrorResponse("Database error", http.StatusInternalServerError), nil
+	}
+
+	if user.Status == StatusSuspended {
+		return verifyTokenErrorResponse("Unauthorized: Account suspended", http.StatusUnauthorized), nil
+	}
+
+	return verifyTokenSuccessResponse("token valid", http.StatusOK, userIdStr, []string{user.Role}, sessionId), nil
 }
 
-func (s *UserServiceServer) IsAdmin(ctx context.Context, req *pb.IsAdminRequest) (*pb.IsAdminResponse, error) {
+func (s *UserServiceServer) CheckPermission(ctx context.Context, req *pb.CheckPermissionRequest) (*pb.CheckPermissionResponse, error) {
 	userId, err_ := primitive.ObjectIDFromHex(req.GetUserId())
 	if err_ != nil {
 
-		return isAdminErrorResponse("Unauthorized: Invalid user ID", http.StatusUnauthorized), nil
+		return checkPermissionErrorResponse("Unauthorized: Invalid user ID", http.StatusUnauthorized), nil
+	}
+
+	if req.GetPermission() == "" {
+		return checkPermissionErrorResponse("Missing permission", http.StatusBadRequest), nil
 	}
 
 	usersCollection := database.GetCollection("users")
@@ -159,17 +254,27 @@ func (s *UserServiceServer) IsAdmin(ctx context.Context, req *pb.IsAdminRequest)
 	err := usersCollection.FindOne(ctx, bson.M{"_id": userId}).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return isAdminErrorResponse("Unauthorized", http.StatusUnauthorized), nil
+			return checkPermissionErrorResponse("Unauthorized", http.StatusUnauthorized), nil
 		}
 		log.Println("Database error:", err)
-		return isAdminErrorResponse("Database error", http.StatusInternalServerError), nil
+		return checkPermissionErrorResponse("Database error", http.StatusInternalServerError), nil
 	}
 
-	if user.Role != "admin" {
-		return isAdminErrorResponse("Unauthorized", http.StatusUnauthorized), nil
+	if !HasPermission(user.Role, req.GetPermission()) {
+		return checkPermissionErrorResponse("Forbidden: missing permission "+req.GetPermission(), http.StatusForbidden), nil
 	}
 
-	return isAdminSuccessResponse("Successful", http.StatusOK), nil
+	if permissionRequiresMfa(req.GetPermission()) {
+		if !user.MFA.Enabled {
+			return checkPermissionErrorResponse("Forbidden: enable MFA to use "+req.GetPermission(), http.StatusForbidden), nil
+		}
+		// An API key skips the MFA challenge, so it can't stand in for a session here
+		if principal, ok := helpers.PrincipalFromContext(ctx); ok && principal.ApiKeyId != "" {
+			return checkPermissionErrorResponse("Forbidden: API keys can't use "+req.GetPermission(), http.StatusForbidden), nil
+		}
+	}
+
+	return checkPermissionSuccessResponse("Successful", http.StatusOK), nil
 }
 
 func registerUserSuccessResponse(message string, statusCode int) *pb.RegisterUserResponse {
@@ -180,26 +285,45 @@ func registerUserErrorResponse(message string, statusCode int) *pb.RegisterUserR
 	return &pb.RegisterUserResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
 }
 
-func loginUserSuccessResponse(message string, statusCode int, token string) *pb.LoginUserResponse {
-	return &pb.LoginUserResponse{Message: message, Status: true, StatusCode: int32(statusCode), Token: token}
+func loginUserSuccessResponse(message string, statusCode int, sessionId string, token string, refreshToken string) *pb.LoginUserResponse {
+	return &pb.LoginUserResponse{
+		Message:          message,
+		Status:           true,
+		StatusCode:       int32(statusCode),
+		SessionId:        sessionId,
+		Token:            token,
+		RefreshToken:     refreshToken,
+		ExpiresIn:        int64(helpers.AccessTokenTTL.Seconds()),
+		RefreshExpiresIn: int64(helpers.RefreshTokenTTL.Seconds()),
+	}
 }
 
 func loginUserErrorResponse(message string, statusCode int, token string) *pb.LoginUserResponse {
 	return &pb.LoginUserResponse{Message: message, Status: false, StatusCode: int32(statusCode), Token: token}
 }
 
-func verifyTokenSuccessResponse(message string, statusCode int, userId string) *pb.VerifyTokenResponse {
-	return &pb.VerifyTokenResponse{Message: message, Valid: true, UserId: userId, StatusCode: int32(statusCode)}
+func loginThrottledResponse(wait time.Duration) *pb.LoginUserResponse {
+	retryAfter := int64(math.Ceil(wait.Seconds()))
+	return &pb.LoginUserResponse{
+		Message:    fmt.Sprintf("Too many failed login attempts. Try again in %d seconds", retryAfter),
+		Status:     false,
+		StatusCode: http.StatusTooManyRequests,
+		RetryAfter: retryAfter,
+	}
+}
+
+func verifyTokenSuccessResponse(message string, statusCode int, userId string, roles []string, sessionId string) *pb.VerifyTokenResponse {
+	return &pb.VerifyTokenResponse{Message: message, Valid: true, UserId: userId, Roles: roles, SessionId: sessionId, StatusCode: int32(statusCode)}
 }
 
 func verifyTokenErrorResponse(message string, statusCode int) *pb.VerifyTokenResponse {
 	return &pb.VerifyTokenResponse{Message: message, Valid: false, StatusCode: int32(statusCode)}
 }
 
-func isAdminSuccessResponse(message string, statusCode int) *pb.IsAdminResponse {
-	return &pb.IsAdminResponse{Message: message, Valid: true, StatusCode: int32(statusCode)}
+func checkPermissionSuccessResponse(message string, statusCode int) *pb.CheckPermissionResponse {
+	return &pb.CheckPermissionResponse{Message: message, Allowed: true, StatusCode: int32(statusCode)}
 }
 
-func isAdminErrorResponse(message string, statusCode int) *pb.IsAdminResponse {
-	return &pb.IsAdminResponse{Message: message, Valid: false, StatusCode: int32(statusCode)}
+func checkPermissionErrorResponse(message string, statusCode int) *pb.CheckPermissionResponse {
+	return &pb.CheckPermissionResponse{Message: message, Allowed: false, StatusCode: int32(statusCode)}
 }
@@ -0,0 +1,369 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/helpers"
+	"github.com/manlikehenryy/loan-management-system-grpc/userService/notifier"
+	pb "github.com/manlikehenryy/loan-management-system-grpc/userService/user"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Verification records when the email and phone on a user's profile were verified. Changing
+// either one clears its timestamp.
+type Verification struct {
+	EmailVerifiedAt *time.Time `bson:"emailVerifiedAt,omitempty"`
+	PhoneVerifiedAt *time.Time `bson:"phoneVerifiedAt,omitempty"`
+}
+
+// Verification statuses
+const (
+	VerificationUnverified = "unverified"
+	VerificationVerified   = "verified"
+)
+
+// Contacts that can be verified
+const (
+	ContactEmail = "email"
+	ContactPhone = "phone"
+)
+
+// VerificationCode is a one-time code sent to a contact. Only keyed hashes of the code and the
+// address it was sent to are stored, so a code stops working if the contact changes.
+type VerificationCode struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty"`
+	UserID          primitive.ObjectID `bson:"userId"`
+	Channel         string             `bson:"channel"`
+	DestinationHash string             `bson:"destinationHash"`
+	CodeHash        string             `bson:"codeHash"`
+	Attempts        int                `bson:"attempts"`
+	CreatedAt       time.Time          `bson:"createdAt"`
+	ExpiresAt       time.Time          `bson:"expiresAt"`
+	ConsumedAt      *time.Time         `bson:"consumedAt,omitempty"` // Set when used, replaced or locked out
+}
+
+const (
+	verificationCodeTTL         = 10 * time.Minute
+	verificationMaxAttempts     = 5
+	verificationResendInterval  = time.Minute
+	verificationMaxSendsPerHour = 5
+)
+
+// VerificationStatus is verified once both the email and the phone on the profile are verified
+func (user *User) VerificationStatus() string {
+	if user.Verification.EmailVerifiedAt != nil && user.Verification.PhoneVerifiedAt != nil {
+		return VerificationVerified
+	}
+	return VerificationUnverified
+}
+
+func (s *UserServiceServer) SendVerificationCode(ctx context.Context, req *pb.SendVerificationCodeRequest) (*pb.SendVerificationCodeResponse, error) {
+	user, message, statusCode := principalUser(ctx)
+	if statusCode != http.StatusOK {
+		return sendVerificationCodeErrorResponse(message, statusCode), nil
+	}
+
+	channel := req.GetChannel()
+	destination, message, statusCode := contactDestination(user, channel)
+	if statusCode != http.StatusOK {
+		return sendVerificationCodeErrorResponse(message, statusCode), nil
+	}
+	if contactVerified(user, channel) {
+		return sendVerificationCodeErrorResponse(fmt.Sprintf("Your %s is already verified", channel), http.StatusConflict), nil
+	}
+
+	codesCollection := database.GetCollection("verification_codes")
+	now := time.Now()
+
+	var last VerificationCode
+	err := codesCollection.FindOne(ctx, bson.M{"userId": user.ID, "channel": channel}, options.FindOne().SetSort(bson.M{"createdAt": -1})).Decode(&last)
+	if err != nil && err != mongo.ErrNoDocuments {
+		log.Println("Database error:", err)
+		return sendVerificationCodeErrorResponse("Database error", http.StatusInternalServerError), nil
+	}
+	if err == nil && now.Sub(last.CreatedAt) < verificationResendInterval {
+		return sendVerificationCodeErrorResponse("Wait a minute before requesting another code", http.StatusTooManyRequests), nil
+	}
+
+	sent, err := codesCollection.CountDocuments(ctx, bson.M{"userId": user.ID, "channel": channel, "createdAt": bson.M{"$gt": now.Add(-time.Hour)}})
+	if err != nil {
+		log.Println("Database error:", err)
+		return sendVerificationCodeErrorResponse("Database error", http.StatusInternalServerError), nil
+	}
+	if sent >= verificationMaxSendsPerHour {
+		return sendVerificationCodeErrorResponse("Too many codes requested. Try again later", http.StatusTooManyRequests), nil
+	}
+
+	code, err := helpers.GenerateVerificationCode()
+	if err != nil {
+		log.Println("Verification code generation error:", err)
+		return sendVerificationCodeErrorResponse("Failed to send verification code", http.StatusInternalServerError), nil
+	}
+	codeHash, err := helpers.KeyedHash(code, verificationCodeContext(user.ID, channel))
+	if err != nil {
+		log.Println("Verification code hashing error:", err)
+		return sendVerificationCodeErrorResponse("Failed to send verification code", http.StatusInternalServerError), nil
+	}
+	destinationHash, err := helpers.KeyedHash(destination, verificationDestinationContext(user.ID, channel))
+	if err != nil {
+		log.Println("Verification code hashing error:", err)
+		return sendVerificationCodeErrorResponse("Failed to send verification code", http.StatusInternalServerError), nil
+	}
+
+	// Only the newest code works
+	if _, err := codesCollection.UpdateMany(ctx, bson.M{"userId": user.ID, "channel": channel, "consumedAt": nil}, bson.M{"$set": bson.M{"consumedAt": now}}); err != nil {
+		log.Println("Database error:", err)
+		return sendVerificationCodeErrorResponse("Database error", http.StatusInternalServerError), nil
+	}
+
+	verificationCode := VerificationCode{UserID: user.ID, Channel: channel, DestinationHash: destinationHash, CodeHash: codeHash, CreatedAt: now, ExpiresAt: now.Add(verificationCodeTTL)}
+	if _, err := codesCollection.InsertOne(ctx, verificationCode); err != nil {
+		log.Println("Database error:", err)
+		return sendVerificationCodeErrorResponse("Failed to send verification code", http.StatusInternalServerError), nil
+	}
+
+	if err := notifier.Send(ctx, verificationMessage(user, channel, destination, code)); err != nil {
+		log.Println("Failed to send verification code:", err)
+		return sendVerificationCodeErrorResponse("Failed to send verification code", http.StatusInternalServerError), nil
+	}
+
+	recordAudit(ctx, AuditLog{ActorID: user.ID, TargetUserID: user.ID, Action: "verification_code_sent", Reason: channel})
+
+	return sendVerificationCodeSuccessResponse("Verification code sent", http.StatusOK, maskContact(channel, destination), int32(verificationCodeTTL.Seconds())), nil
+}
+
+func (s *UserServiceServer) VerifyContact(ctx context.Context, req *pb.VerifyContactRequest) (*pb.VerifyContactResponse, error) {
+	user, message, statusCode := principalUser(ctx)
+	if statusCode != http.StatusOK {
+		return verifyContactErrorResponse(message, statusCode), nil
+	}
+
+	channel := req.GetChannel()
+	code := strings.TrimSpace(req.GetCode())
+	if code == "" {
+		return verifyContactErrorResponse("Missing required field(s)", http.StatusBadRequest), nil
+	}
+
+	destination, message, statusCode := contactDestination(user, channel)
+	if statusCode != http.StatusOK {
+		return verifyContactErrorResponse(message, statusCode), nil
+	}
+	if contactVerified(user, channel) {
+		return verifyContactErrorResponse(fmt.Sprintf("Your %s is already verified", channel), http.StatusConflict), nil
+	}
+
+	codesCollection := database.GetCollection("verification_codes")
+	now := time.Now()
+
+	// Counting the attempt in the same write that finds the code keeps concurrent guesses within the limit
+	var verificationCode VerificationCode
+	err := codesCollection.FindOneAndUpdate(ctx,
+		bson.M{"userId": user.ID, "channel": channel, "consumedAt": nil, "expiresAt": bson.M{"$gt": now}, "attempts": bson.M{"$lt": verificationMaxAttempts}},
+		bson.M{"$inc": bson.M{"attempts": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&verificationCode)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return verifyContactErrorResponse("Invalid or expired code. Request a new one", http.StatusBadRequest), nil
+		}
+		log.Println("Database error:", err)
+		return verifyContactErrorResponse("Database error", http.StatusInternalServerError), nil
+	}
+
+	destinationHash, err := helpers.KeyedHash(destination, verificationDestinationContext(user.ID, channel))
+	if err != nil {
+		log.Println("Verification code hashing error:", err)
+		return verifyContactErrorResponse("Failed to verify code", http.StatusInternalServerError), nil
+	}
+	if destinationHash != verificationCode.DestinationHash {
+		consumeVerificationCode(ctx, verificationCode.ID, now)
+		return verifyContactErrorResponse(fmt.Sprintf("Your %s has changed since the code was sent. Request a new one", channel), http.StatusBadRequest), nil
+	}
+
+	codeHash, err := helpers.KeyedHash(code, verificationCodeContext(user.ID, channel))
+	if err != nil {
+		log.Println("Verification code hashing error:", err)
+		return verifyContactErrorResponse("Failed to verify code", http.StatusInternalServerError), nil
+	}
+	if subtle.ConstantTimeCompare([]byte(codeHash), []byte(verificationCode.CodeHash)) != 1 {
+		remaining := verificationMaxAttempts - verificationCode.Attempts
+		if remaining <= 0 {
+			consumeVerificationCode(ctx, verificationCode.ID, now)
+			recordAudit(ctx, AuditLog{ActorID: user.ID, TargetUserID: user.ID, Action: "verification_code_locked", Reason: channel})
+			return verifyContactErrorResponse("Too many incorrect attempts. Request a new code", http.StatusTooManyRequests), nil
+		}
+		return verifyContactErrorResponse(fmt.Sprintf("Incorrect code. %d attempt(s) left", remaining), http.StatusBadRequest), nil
+	}
+
+	if !consumeVerificationCode(ctx, verificationCode.ID, now) {
+		return verifyContactErrorResponse("Invalid or expired code. Request a new one", http.StatusBadRequest), nil
+	}
+
+	// The contact must still be the one the code was sent to when it's marked verified
+	filter := bson.M{"_id": user.ID}
+	field := "verification.emailVerifiedAt"
+	if channel == ContactEmail {
+		filter["profile.email"] = user.Profile.Email
+	} else {
+		filter["profile.phone"] = user.Profile.Phone
+		field = "verification.phoneVerifiedAt"
+	}
+	result, err := database.GetCollection("users").UpdateOne(ctx, filter, bson.M{"$set": bson.M{field: now, "updatedAt": now, "updatedBy": user.ID}})
+	if err != nil {
+		log.Println("Database error:", err)
+		return verifyContactErrorResponse("Failed to verify contact", http.StatusInternalServerError), nil
+	}
+	if result.MatchedCount == 0 {
+		return verifyContactErrorResponse(fmt.Sprintf("Your %s has changed since the code was sent. Request a new one", channel), http.StatusConflict), nil
+	}
+
+	if channel == ContactEmail {
+		user.Verification.EmailVerifiedAt = &now
+	} else {
+		user.Verification.PhoneVerifiedAt = &now
+	}
+	recordAudit(ctx, AuditLog{ActorID: user.ID, TargetUserID: user.ID, Action: "contact_verified", Reason: channel})
+
+	return verifyContactSuccessResponse(fmt.Sprintf("Your %s is verified", channel), http.StatusOK, user.VerificationStatus()), nil
+}
+
+func (s *UserServiceServer) GetUserContact(ctx context.Context, req *pb.GetUserContactRequest) (*pb.GetUserContactResponse, error) {
+	userId, err := primitive.ObjectIDFromHex(req.GetUserId())
+	if err != nil {
+		return getUserContactErrorResponse("Invalid user ID", http.StatusBadRequest), nil
+	}
+
+	user, message, statusCode := findUser(ctx, userId)
+	if statusCode != http.StatusOK {
+		return getUserContactErrorResponse(message, statusCode), nil
+	}
+
+	phone, err := helpers.DecryptField(user.Profile.Phone, profileFieldContext(user.ID, "phone"))
+	if err != nil {
+		log.Println("Profile decryption error:", err)
+		return getUserContactErrorResponse("Failed to read profile", http.StatusInternalServerError), nil
+	}
+
+	return getUserContactSuccessResponse("User contact retrieved", http.StatusOK, user, phone), nil
+}
+
+// contactDestination returns the address on user's profile for channel
+func contactDestination(user *User, channel string) (string, string, int) {
+	switch channel {
+	case ContactEmail:
+		if user.Profile.Email == "" {
+			return "", "Add an email address to your profile first", http.StatusBadRequest
+		}
+		return user.Profile.Email, "", http.StatusOK
+	case ContactPhone:
+		phone, err := helpers.DecryptField(user.Profile.Phone, profileFieldContext(user.ID, "phone"))
+		if err != nil {
+			log.Println("Profile decryption error:", err)
+			return "", "Failed to read profile", http.StatusInternalServerError
+		}
+		if phone == "" {
+			return "", "Add a phone number to your profile first", http.StatusBadRequest
+		}
+		return phone, "", http.StatusOK
+	default:
+		return "", "Channel must be email or phone", http.StatusBadRequest
+	}
+}
+
+func contactVerified(user *User, channel string) bool {
+	if channel == ContactEmail {
+		return user.Verification.EmailVerifiedAt != nil
+	}
+	return user.Verification.PhoneVerifiedAt != nil
+}
+
+// consumeVerificationCode marks a code used and reports whether this call was the one that did it
+func consumeVerificationCode(ctx context.Context, codeId primitive.ObjectID, now time.Time) bool {
+	result, err := database.GetCollection("verification_codes").UpdateOne(ctx, bson.M{"_id": codeId, "consumedAt": nil}, bson.M{"$set": bson.M{"consumedAt": now}})
+	if err != nil {
+		log.Println("Database error:", err)
+		return false
+	}
+	return result.ModifiedCount == 1
+}
+
+func verificationMessage(user *User, channel string, destination string, code string) notifier.Message {
+	minutes := int(verificationCodeTTL.Minutes())
+	if channel == ContactPhone {
+		return notifier.Message{
+			Channel: notifier.ChannelSMS,
+			To:      destination,
+			Body:    fmt.Sprintf("Your verification code is %s. It expires in %d minutes. Don't share it with anyone.", code, minutes),
+		}
+	}
+	return notifier.Message{
+		Channel: notifier.ChannelEmail,
+		To:      destination,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nYour verification code is %s. It expires in %d minutes.\n\nIf you didn't ask for this, you can ignore this email.",
+			user.FirstName, code, minutes),
+	}
+}
+
+// maskContact hides most of an address so it can be shown back to the user
+func maskContact(channel string, destination string) string {
+	if channel == ContactEmail {
+		local, domain, found := strings.Cut(destination, "@")
+		if !found {
+			return maskValue(destination, 0)
+		}
+		return local[:1] + strings.Repeat("*", len(local)-1) + "@" + domain
+	}
+	return maskValue(destination, 4)
+}
+
+func verificationCodeContext(userId primitive.ObjectID, channel string) string {
+	return "verification-code:" + userId.Hex() + ":" + channel
+}
+
+func verificationDestinationContext(userId primitive.ObjectID, channel string) string {
+	return "verification-destination:" + userId.Hex() + ":" + channel
+}
+
+func sendVerificationCodeSuccessResponse(message string, statusCode int, destination string, expiresInSeconds int32) *pb.SendVerificationCodeResponse {
+	return &pb.SendVerificationCodeResponse{Message: message, Status: true, StatusCode: int32(statusCode), Destination: destination, ExpiresInSeconds: expiresInSeconds}
+}
+
+func sendVerificationCodeErrorResponse(message string, statusCode int) *pb.SendVerificationCodeResponse {
+	return &pb.SendVerificationCodeResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func verifyContactSuccessResponse(message string, statusCode int, verificationStatus string) *pb.VerifyContactResponse {
+	return &pb.VerifyContactResponse{Message: message, Status: true, StatusCode: int32(statusCode), VerificationStatus: verificationStatus}
+}
+
+func verifyContactErrorResponse(message string, statusCode int) *pb.VerifyContactResponse {
+	return &pb.VerifyContactResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+func getUserContactSuccessResponse(message string, statusCode int, user *User, phone string) *pb.GetUserContactResponse {
+	return &pb.GetUserContactResponse{
+		Message:       message,
+		Status:        true,
+		StatusCode:    int32(statusCode),
+		FirstName:     user.FirstName,
+		Email:         user.Profile.Email,
+		EmailVerified: user.Verification.EmailVerifiedAt != nil,
+		Phone:         phone,
+		PhoneVerified: user.Verification.PhoneVerifiedAt != nil,
+	}
+}
+
+func getUserContactErrorResponse(message string, statusCode int) *pb.GetUserContactResponse {
+	return &pb.GetUserContactResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
@@ -1,163 +0,0 @@
-// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
-// versions:
-// - protoc-gen-go-grpc v1.5.1
-// - protoc             v5.28.3
-// source: wallet.proto
-
-package wallet
-
-import (
-	context "context"
-	grpc "google.golang.org/grpc"
-	codes "google.golang.org/grpc/codes"
-	status "google.golang.org/grpc/status"
-)
-
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-// Requires gRPC-Go v1.64.0 or later.
-const _ = grpc.SupportPackageIsVersion9
-
-const (
-	WalletService_CreateWallet_FullMethodName = "/WalletService/CreateWallet"
-	WalletService_CreditWallet_FullMethodName = "/WalletService/CreditWallet"
-)
-
-// WalletServiceClient is the client API for WalletService service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
-//
-// Wallet service definition
-type WalletServiceClient interface {
-	CreateWallet(ctx context.Context, in *CreateWalletRequest, opts ...grpc.CallOption) (*CreateWalletResponse, error)
-	CreditWallet(ctx context.Context, in *CreditWalletRequest, opts ...grpc.CallOption) (*CreditWalletResponse, error)
-}
-
-type walletServiceClient struct {
-	cc grpc.ClientConnInterface
-}
-
-func NewWalletServiceClient(cc grpc.ClientConnInterface) WalletServiceClient {
-	return &walletServiceClient{cc}
-}
-
-func (c *walletServiceClient) CreateWallet(ctx context.Context, in *CreateWalletRequest, opts ...grpc.CallOption) (*CreateWalletResponse, error) {
-	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(CreateWalletResponse)
-	err := c.cc.Invoke(ctx, WalletService_CreateWallet_FullMethodName, in, out, cOpts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
-}
-
-func (c *walletServiceClient) CreditWallet(ctx context.Context, in *CreditWalletRequest, opts ...grpc.CallOption) (*CreditWalletResponse, error) {
-	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(CreditWalletResponse)
-	err := c.cc.Invoke(ctx, WalletService_CreditWallet_FullMethodName, in, out, cOpts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
-}
-
-// WalletServiceServer is the server API for WalletService service.
-// All implementations must embed UnimplementedWalletServiceServer
-// for forward compatibility.
-//
-// Wallet service definition
-type WalletServiceServer interface {
-	CreateWallet(context.Context, *CreateWalletRequest) (*CreateWalletResponse, error)
-	CreditWallet(context.Context, *CreditWalletRequest) (*CreditWalletResponse, error)
-	mustEmbedUnimplementedWalletServiceServer()
-}
-
-// UnimplementedWalletServiceServer must be embedded to have
-// forward compatible implementations.
-//
-// NOTE: this should be embedded by value instead of pointer to avoid a nil
-// pointer dereference when methods are called.
-type UnimplementedWalletServiceServer struct{}
-
-func (UnimplementedWalletServiceServer) CreateWallet(context.Context, *CreateWalletRequest) (*CreateWalletResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreateWallet not implemented")
-}
-func (UnimplementedWalletServiceServer) CreditWallet(context.Context, *CreditWalletRequest) (*CreditWalletResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreditWallet not implemented")
-}
-func (UnimplementedWalletServiceServer) mustEmbedUnimplementedWalletServiceServer() {}
-func (UnimplementedWalletServiceServer) testEmbeddedByValue()                       {}
-
-// UnsafeWalletServiceServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to WalletServiceServer will
-// result in compilation errors.
-type UnsafeWalletServiceServer interface {
-	mustEmbedUnimplementedWalletServiceServer()
-}
-
-func RegisterWalletServiceServer(s grpc.ServiceRegistrar, srv WalletServiceServer) {
-	// If the following call pancis, it indicates UnimplementedWalletServiceServer was
-	// embedded by pointer and is nil.  This will cause panics if an
-	// unimplemented method is ever invoked, so we test this at initialization
-	// time to prevent it from happening at runtime later due to I/O.
-	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
-		t.testEmbeddedByValue()
-	}
-	s.RegisterService(&WalletService_ServiceDesc, srv)
-}
-
-func _WalletService_CreateWallet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreateWalletRequest)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(WalletServiceServer).CreateWallet(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: WalletService_CreateWallet_FullMethodName,
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(WalletServiceServer).CreateWallet(ctx, req.(*CreateWalletRequest))
-	}
-	return interceptor(ctx, in, info, handler)
-}
-
-func _WalletService_CreditWallet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreditWalletRequest)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(WalletServiceServer).CreditWallet(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: WalletService_CreditWallet_FullMethodName,
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(WalletServiceServer).CreditWallet(ctx, req.(*CreditWalletRequest))
-	}
-	return interceptor(ctx, in, info, handler)
-}
-
-// WalletService_ServiceDesc is the grpc.ServiceDesc for WalletService service.
-// It's only intended for direct use with grpc.RegisterService,
-// and not to be introspected or modified (even as a copy)
-var WalletService_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "WalletService",
-	HandlerType: (*WalletServiceServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "CreateWallet",
-			Handler:    _WalletService_CreateWallet_Handler,
-		},
-		{
-			MethodName: "CreditWallet",
-			Handler:    _WalletService_CreditWallet_Handler,
-		},
-	},
-	Streams:  []grpc.StreamDesc{},
-	Metadata: "wallet.proto",
-}
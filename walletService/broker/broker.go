@@ -0,0 +1,65 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/configs"
+)
+
+// Event is a domain event. ID is set once, when the event is recorded, and stays the same every
+// time it is delivered, so consumers use it to drop repeats.
+type Event struct {
+	ID          string            `bson:"_id"`
+	Type        string            `bson:"type"`   // e.g. "LoanApproved"
+	Source      string            `bson:"source"` // The service that recorded it
+	AggregateID string            `bson:"aggregateId"`
+	UserID      string            `bson:"userId"` // The user the event is about
+	Data        map[string]string `bson:"data,omitempty"`
+	OccurredAt  time.Time         `bson:"occurredAt"`
+}
+
+// Handler processes an event for a consumer. Events are delivered at least once, so handlers
+// must ignore events they have already processed. Returning an error redelivers the event later.
+type Handler func(ctx context.Context, event Event) error
+
+// Broker carries events from the services that record them to the services that consume them
+type Broker interface {
+	// Publish hands event to every subscriber interested in its type. Publishing the same event
+	// again is safe.
+	Publish(ctx context.Context, event Event) error
+	// Subscribe delivers events of the given types, or every type if none are given, to handler
+	// until ctx is done. Instances that subscribe with the same group share its events.
+	Subscribe(ctx context.Context, group string, eventTypes []string, handler Handler) error
+}
+
+// New returns the broker set by BROKER: "mongo" (the default) or "memory". The memory broker
+// only reaches subscribers in the same process, so it's for running a service on its own.
+func New() (Broker, error) {
+	switch configs.Env.BROKER {
+	case "", "mongo":
+		dbName := configs.Env.BROKER_DB
+		if dbName == "" {
+			dbName = "event_bus"
+		}
+		return NewMongoBroker(dbName), nil
+	case "memory":
+		return NewMemoryBroker(), nil
+	default:
+		return nil, fmt.Errorf("unknown broker %q", configs.Env.BROKER)
+	}
+}
+
+// subscribed reports whether a subscription to eventTypes covers eventType
+func subscribed(eventTypes []string, eventType string) bool {
+	if len(eventTypes) == 0 {
+		return true
+	}
+	for _, t := range eventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,8 @@
 package configs
 
 import (
+	"errors"
+	"io/fs"
 	"log"
 	"os"
 
@@ -8,10 +10,25 @@ import (
 )
 
 type Config struct {
-	PORT         string
-	MONGO_DB_URI string
-	MODE         string
-	TOKEN        string
+	PORT                          string
+	MONGO_DB_URI                  string
+	MODE                          string
+	TOKEN                         string
+	IDENTITY_SECRET               string
+	TLS_ENABLED                   string
+	TLS_CERT_FILE                 string
+	TLS_KEY_FILE                  string
+	TLS_CA_FILE                   string
+	TLS_DEV_MODE                  string
+	TLS_DEV_DIR                   string
+	POLICY_FILE                   string
+	BROKER                        string
+	BROKER_DB                     string
+	USER_SERVICE_URL              string
+	PAYMENT_PROVIDER              string
+	PAYMENT_WEBHOOK_SECRET        string
+	PAYMENT_SIMULATOR_WEBHOOK_URL string
+	TRUST_DECLARED_CALLER         string
 }
 
 var Env *Config
@@ -21,8 +38,9 @@ func init() {
 	Env = &Config{}
 
 	if os.Getenv("MODE") != "production" {
+		// Without a .env file, such as under go test, settings come from the environment alone
 		err := godotenv.Load()
-		if err != nil {
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
 			log.Fatalf("Error loading .env file: %v", err)
 		}
 
@@ -32,4 +50,19 @@ func init() {
 	Env.MONGO_DB_URI = os.Getenv("MONGO_DB_URI")
 	Env.MODE = os.Getenv("MODE")
 	Env.TOKEN = os.Getenv("TOKEN")
+	Env.IDENTITY_SECRET = os.Getenv("IDENTITY_SECRET")
+	Env.TLS_ENABLED = os.Getenv("TLS_ENABLED")
+	Env.TLS_CERT_FILE = os.Getenv("TLS_CERT_FILE")
+	Env.TLS_KEY_FILE = os.Getenv("TLS_KEY_FILE")
+	Env.TLS_CA_FILE = os.Getenv("TLS_CA_FILE")
+	Env.TLS_DEV_MODE = os.Getenv("TLS_DEV_MODE")
+	Env.TLS_DEV_DIR = os.Getenv("TLS_DEV_DIR")
+	Env.POLICY_FILE = os.Getenv("POLICY_FILE")
+	Env.BROKER = os.Getenv("BROKER")
+	Env.BROKER_DB = os.Getenv("BROKER_DB")
+	Env.USER_SERVICE_URL = os.Getenv("USER_SERVICE_URL")
+	Env.PAYMENT_PROVIDER = os.Getenv("PAYMENT_PROVIDER")
+	Env.PAYMENT_WEBHOOK_SECRET = os.Getenv("PAYMENT_WEBHOOK_SECRET")
+	Env.PAYMENT_SIMULATOR_WEBHOOK_URL = os.Getenv("PAYMENT_SIMULATOR_WEBHOOK_URL")
+	Env.TRUST_DECLARED_CALLER = os.Getenv("TRUST_DECLARED_CALLER")
 }
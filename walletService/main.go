@@ -1,12 +1,17 @@
 package main
 
 import (
+    "context"
     "fmt"
     "log"
     "net"
 
+    "github.com/manlikehenryy/loan-management-system-grpc/walletService/broker"
     "github.com/manlikehenryy/loan-management-system-grpc/walletService/configs"
     "github.com/manlikehenryy/loan-management-system-grpc/walletService/database"
+    "github.com/manlikehenryy/loan-management-system-grpc/walletService/helpers"
+    "github.com/manlikehenryy/loan-management-system-grpc/walletService/outbox"
+    "github.com/manlikehenryy/loan-management-system-grpc/walletService/payment"
     "github.com/manlikehenryy/loan-management-system-grpc/walletService/service"
     pb "github.com/manlikehenryy/loan-management-system-grpc/walletService/wallet"
     "google.golang.org/grpc"
@@ -25,7 +30,37 @@ func main() {
         log.Fatalf("Failed to listen: %v", err)
     }
 
-    grpcServer := grpc.NewServer(grpc.UnaryInterceptor(service.TokenInterceptor))
+    policyFile := configs.Env.POLICY_FILE
+    if policyFile == "" {
+        policyFile = "policy.json"
+    }
+    if err := service.LoadPolicy(policyFile); err != nil {
+        log.Fatalf("Failed to load authorization policy: %v", err)
+    }
+
+    creds, err := helpers.ServerCredentials()
+    if err != nil {
+        log.Fatalf("Failed to load TLS credentials: %v", err)
+    }
+
+    payment.Initialize()
+
+    eventBroker, err := broker.New()
+    if err != nil {
+        log.Fatalf("Failed to set up event broker: %v", err)
+    }
+    // The broker only queues events for undeclared groups once they have subscribed, so every
+    // subscription is registered before the relay starts publishing
+    if err := service.StartWalletProvisioning(context.Background(), eventBroker); err != nil {
+        log.Fatalf("Failed to start wallet provisioning: %v", err)
+    }
+    outbox.StartRelay(context.Background(), eventBroker)
+    service.StartHoldSweeper(context.Background())
+    service.StartWithdrawalProcessor(context.Background())
+    service.StartTopUpReconciler(context.Background())
+    service.StartLedgerBackfill(context.Background())
+
+    grpcServer := grpc.NewServer(grpc.Creds(creds), grpc.UnaryInterceptor(service.TokenInterceptor), grpc.StreamInterceptor(service.TokenStreamInterceptor))
     pb.RegisterWalletServiceServer(grpcServer, service.NewWalletServiceServer())
 
     fmt.Printf("Wallet Service running on port %s...\n", port)
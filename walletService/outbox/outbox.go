@@ -0,0 +1,104 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/broker"
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/helpers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	relayPollInterval  = time.Second
+	relayLease         = 30 * time.Second
+	relayRetryDelay    = time.Second // Doubled after each failed attempt, up to relayMaxRetryDelay
+	relayMaxRetryDelay = 5 * time.Minute
+)
+
+// record is an event in the outbox collection. Until it's published, nextAttemptAt is when the
+// relay may next try it; while the relay is publishing it, it's the end of the relay's lease.
+type record struct {
+	broker.Event  `bson:",inline"`
+	PublishedAt   *time.Time `bson:"publishedAt"`
+	Attempts      int        `bson:"attempts"`
+	LastError     string     `bson:"lastError,omitempty"`
+	NextAttemptAt time.Time  `bson:"nextAttemptAt"`
+}
+
+// Record writes event to the outbox. Called with a transaction's context, the event is only
+// kept if the transaction commits. An ID is generated unless event has one; an event recorded
+// again with the same ID fails with a duplicate key error.
+func Record(ctx context.Context, event broker.Event) error {
+	if event.ID == "" {
+		event.ID = primitive.NewObjectID().Hex()
+	}
+	event.Source = helpers.ServiceName
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	_, err := database.GetCollection("outbox").InsertOne(ctx, record{Event: event, NextAttemptAt: event.OccurredAt})
+	return err
+}
+
+// StartRelay publishes outbox events to b, oldest first, until ctx is done. An event is retried
+// until it's published, so every event is delivered at least once. Several instances can run at
+// once: each event is leased to one relay while it's being published.
+func StartRelay(ctx context.Context, b broker.Broker) {
+	go func() {
+		for {
+			relay(ctx, b)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(relayPollInterval):
+			}
+		}
+	}()
+}
+
+// relay publishes due events until there are none left
+func relay(ctx context.Context, b broker.Broker) {
+	outboxCollection := database.GetCollection("outbox")
+
+	for ctx.Err() == nil {
+		now := time.Now()
+
+		var pending record
+		err := outboxCollection.FindOneAndUpdate(ctx,
+			bson.M{"publishedAt": nil, "nextAttemptAt": bson.M{"$lte": now}},
+			bson.M{"$set": bson.M{"nextAttemptAt": now.Add(relayLease)}},
+			options.FindOneAndUpdate().SetSort(bson.M{"occurredAt": 1}).SetReturnDocument(options.After),
+		).Decode(&pending)
+		if err != nil {
+			if err != mongo.ErrNoDocuments {
+				log.Println("Failed to read outbox:", err)
+			}
+			return
+		}
+
+		update := bson.M{"attempts": pending.Attempts + 1}
+		if err := b.Publish(ctx, pending.Event); err != nil {
+			log.Printf("Failed to publish %s event %s: %v", pending.Type, pending.ID, err)
+			delay := relayRetryDelay << pending.Attempts
+			if delay > relayMaxRetryDelay || delay <= 0 {
+				delay = relayMaxRetryDelay
+			}
+			update["lastError"] = err.Error()
+			update["nextAttemptAt"] = time.Now().Add(delay)
+		} else {
+			update["publishedAt"] = time.Now()
+		}
+
+		if _, err := outboxCollection.UpdateOne(ctx, bson.M{"_id": pending.ID}, bson.M{"$set": update}); err != nil {
+			log.Println("Failed to update outbox:", err)
+		}
+	}
+}
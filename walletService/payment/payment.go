@@ -0,0 +1,158 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/configs"
+)
+
+// What a webhook is about
+const (
+	KindCheckout = "checkout"
+	KindPayout   = "payout"
+)
+
+// Outcomes a webhook can report. Checkouts succeed or fail; payouts are paid or fail, and a paid
+// payout can later be reversed by the bank.
+const (
+	PaymentPending   = "pending" // Only from CheckoutStatus, while the user hasn't paid
+	PaymentSucceeded = "succeeded"
+	PaymentFailed    = "failed"
+	PayoutPending    = "pending" // Only from PayoutStatus, while the payout is under way
+	PayoutPaid       = "paid"
+	PayoutFailed     = "failed"
+	PayoutReversed   = "reversed"
+)
+
+var (
+	ErrUnknownProvider  = errors.New("unknown payment provider")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrCheckoutNotFound = errors.New("checkout not found")
+	ErrPayoutNotFound   = errors.New("payout not found")
+)
+
+// Checkout asks the provider to collect Amount from a user. The provider reports Reference back
+// in its webhook.
+type Checkout struct {
+	Reference string
+	UserID    string
+	Amount    float32
+}
+
+// Session is a checkout the provider has accepted
+type Session struct {
+	ProviderReference string
+	CheckoutURL       string // Where the user pays; empty if the provider has no page
+}
+
+// Payout asks the provider to send Amount to a bank account. The provider reports Reference back
+// in its webhooks, and treats a payout it has already accepted with the same Reference as a repeat.
+type Payout struct {
+	Reference     string
+	UserID        string
+	Amount        float32
+	BankCode      string
+	AccountNumber string
+	AccountName   string
+}
+
+// Notification is the outcome of a checkout or payout, taken from a webhook whose signature
+// checked out
+type Notification struct {
+	EventID   string
+	Kind      string // KindCheckout or KindPayout
+	Reference string
+	Status    string
+	Amount    float32
+	Reason    string // Why a payout failed or was reversed
+}
+
+// Provider collects payments and pays out to bank accounts. Card processors and bank transfer
+// gateways implement it.
+type Provider interface {
+	Name() string
+	CreateCheckout(ctx context.Context, checkout Checkout) (Session, error)
+	// CheckoutStatus asks the provider what became of the checkout with reference, for when its
+	// webhook never arrives. It returns ErrCheckoutNotFound if the provider never created it.
+	CheckoutStatus(ctx context.Context, reference string) (Notification, error)
+	// CreatePayout submits a payout and returns the provider's reference for it. Its outcome comes
+	// later, in a webhook.
+	CreatePayout(ctx context.Context, payout Payout) (string, error)
+	// PayoutStatus asks the provider what became of the payout with reference, for when its
+	// webhook never arrives. It returns ErrPayoutNotFound if the provider never accepted it.
+	PayoutStatus(ctx context.Context, reference string) (Notification, error)
+	// ParseWebhook verifies a webhook and returns the checkout or payout it reports on. Header
+	// names are lowercase. It returns ErrInvalidSignature if the webhook wasn't sent by the provider.
+	ParseWebhook(payload []byte, headers map[string]string) (Notification, error)
+}
+
+var provider Provider
+
+// Initialize sets up the provider set by PAYMENT_PROVIDER. Only "simulator" is built in.
+func Initialize() {
+	p, err := New(configs.Env.PAYMENT_PROVIDER)
+	if err != nil {
+		panic("Failed to set up payment provider: " + err.Error())
+	}
+	provider = p
+}
+
+// New returns the payment provider of the given kind. The simulator confirms every checkout
+// without collecting any money, so it's refused in production.
+func New(kind string) (Provider, error) {
+	switch kind {
+	case "":
+		return nil, errors.New("PAYMENT_PROVIDER is not set")
+	case "simulator":
+		if configs.Env.MODE == "production" {
+			return nil, errors.New("the simulator can't be used in production")
+		}
+		if configs.Env.PAYMENT_WEBHOOK_SECRET == "" {
+			return nil, errors.New("PAYMENT_WEBHOOK_SECRET is not set")
+		}
+		webhookURL := configs.Env.PAYMENT_SIMULATOR_WEBHOOK_URL
+		if webhookURL == "" {
+			webhookURL = "http://localhost:50054/api/payments/webhook/simulator"
+		}
+		return NewSimulator(configs.Env.PAYMENT_WEBHOOK_SECRET, webhookURL, 2*time.Second), nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q", kind)
+	}
+}
+
+// Name returns the configured provider's name
+func Name() string {
+	return provider.Name()
+}
+
+// CreateCheckout starts a checkout with the configured provider
+func CreateCheckout(ctx context.Context, checkout Checkout) (Session, error) {
+	return provider.CreateCheckout(ctx, checkout)
+}
+
+// CreatePayout submits a payout to the configured provider
+func CreatePayout(ctx context.Context, payout Payout) (string, error) {
+	return provider.CreatePayout(ctx, payout)
+}
+
+// CheckoutStatus asks the configured provider for the outcome of a checkout
+func CheckoutStatus(ctx context.Context, reference string) (Notification, error) {
+	return provider.CheckoutStatus(ctx, reference)
+}
+
+// PayoutStatus asks the configured provider for the outcome of a payout
+func PayoutStatus(ctx context.Context, reference string) (Notification, error) {
+	return provider.PayoutStatus(ctx, reference)
+}
+
+// ParseWebhook verifies a webhook sent to the route for providerName. Webhooks for any provider
+// other than the configured one fail with ErrUnknownProvider.
+func ParseWebhook(providerName string, payload []byte, headers map[string]string) (Notification, error) {
+	if providerName != provider.Name() {
+		return Notification{}, ErrUnknownProvider
+	}
+	return provider.ParseWebhook(payload, headers)
+}
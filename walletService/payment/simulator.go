@@ -0,0 +1,321 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	simulatorSignatureHeader = "x-simulator-signature"
+	simulatorTolerance       = 5 * time.Minute // How old a signed webhook can be, against replays
+	simulatorMaxAttempts     = 5
+	simulatorRetryDelay      = time.Second // Doubled after each failed attempt
+)
+
+// Account numbers ending in these are handled like a bank would handle a bad account, so the
+// failure paths can be tried offline
+const (
+	simulatorFailingAccount   = "0000" // The payout fails
+	simulatorReversingAccount = "0001" // The payout is paid, then reversed
+)
+
+// simulatorWebhook is the body of the webhooks the simulator sends
+type simulatorWebhook struct {
+	ID   string `json:"id"`
+	Type string `json:"type"` // e.g. "payment.succeeded" or "payout.failed"
+	Data struct {
+		Reference         string  `json:"reference"`
+		ProviderReference string  `json:"providerReference"`
+		Amount            float32 `json:"amount"`
+		Reason            string  `json:"reason,omitempty"`
+	} `json:"data"`
+}
+
+var simulatorWebhookTypes = map[string]struct{ kind, status string }{
+	"payment.succeeded": {KindCheckout, PaymentSucceeded},
+	"payment.failed":    {KindCheckout, PaymentFailed},
+	"payout.paid":       {KindPayout, PayoutPaid},
+	"payout.failed":     {KindPayout, PayoutFailed},
+	"payout.reversed":   {KindPayout, PayoutReversed},
+}
+
+// Simulator stands in for a payment provider so top-ups and withdrawals can run offline. It has no
+// checkout page: each checkout or payout completes after delay, and the simulator posts a signed
+// webhook to webhookURL like a real provider would, retrying it until it gets a 2xx response.
+// Webhooks are signed as "t=<unix time>,v1=<hex HMAC-SHA256 of "<unix time>.<body>">" in
+// X-Simulator-Signature.
+type Simulator struct {
+	secret     []byte
+	webhookURL string
+	delay      time.Duration
+	client     *http.Client
+
+	mu        sync.Mutex
+	checkouts map[string]*simulatorRecord // The checkouts created, by reference
+	payouts   map[string]*simulatorRecord // The payouts accepted, by reference
+}
+
+// simulatorRecord is a checkout or payout the simulator accepted, as it stands. The simulator
+// only keeps them in memory, so it forgets them when the service restarts.
+type simulatorRecord struct {
+	providerReference string
+	amount            float32
+	status            string
+	reason            string
+}
+
+func NewSimulator(secret string, webhookURL string, delay time.Duration) *Simulator {
+	return &Simulator{
+		secret:     []byte(secret),
+		webhookURL: webhookURL,
+		delay:      delay,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		checkouts:  map[string]*simulatorRecord{},
+		payouts:    map[string]*simulatorRecord{},
+	}
+}
+
+func (s *Simulator) Name() string {
+	return "simulator"
+}
+
+func (s *Simulator) CreateCheckout(ctx context.Context, checkout Checkout) (Session, error) {
+	providerReference, err := randomID("sim_")
+	if err != nil {
+		return Session{}, err
+	}
+
+	webhook, err := newSimulatorWebhook("payment.succeeded", checkout.Reference, providerReference, checkout.Amount, "")
+	if err != nil {
+		return Session{}, err
+	}
+	s.mu.Lock()
+	s.checkouts[checkout.Reference] = &simulatorRecord{providerReference: providerReference, amount: checkout.Amount, status: PaymentPending}
+	s.mu.Unlock()
+
+	go func() {
+		time.Sleep(s.delay)
+		s.settle(*webhook)
+		s.deliver(*webhook)
+	}()
+
+	return Session{ProviderReference: providerReference}, nil
+}
+
+func (s *Simulator) CheckoutStatus(ctx context.Context, reference string) (Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	created, ok := s.checkouts[reference]
+	if !ok {
+		return Notification{}, ErrCheckoutNotFound
+	}
+	return Notification{
+		Kind:      KindCheckout,
+		Reference: reference,
+		Status:    created.status,
+		Amount:    created.amount,
+	}, nil
+}
+
+func (s *Simulator) CreatePayout(ctx context.Context, payout Payout) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if accepted, ok := s.payouts[payout.Reference]; ok {
+		return accepted.providerReference, nil
+	}
+
+	providerReference, err := randomID("po_")
+	if err != nil {
+		return "", err
+	}
+
+	eventType, reason := "payout.paid", ""
+	if strings.HasSuffix(payout.AccountNumber, simulatorFailingAccount) {
+		eventType, reason = "payout.failed", "account closed"
+	}
+	webhook, err := newSimulatorWebhook(eventType, payout.Reference, providerReference, payout.Amount, reason)
+	if err != nil {
+		return "", err
+	}
+
+	var reversal *simulatorWebhook
+	if strings.HasSuffix(payout.AccountNumber, simulatorReversingAccount) {
+		reversal, err = newSimulatorWebhook("payout.reversed", payout.Reference, providerReference, payout.Amount, "returned by the bank")
+		if err != nil {
+			return "", err
+		}
+	}
+
+	go func() {
+		time.Sleep(s.delay)
+		s.settle(*webhook)
+		s.deliver(*webhook)
+		if reversal != nil {
+			s.settle(*reversal)
+			s.deliver(*reversal)
+		}
+	}()
+
+	s.payouts[payout.Reference] = &simulatorRecord{providerReference: providerReference, amount: payout.Amount, status: PayoutPending}
+	return providerReference, nil
+}
+
+func (s *Simulator) PayoutStatus(ctx context.Context, reference string) (Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	accepted, ok := s.payouts[reference]
+	if !ok {
+		return Notification{}, ErrPayoutNotFound
+	}
+	return Notification{
+		Kind:      KindPayout,
+		Reference: reference,
+		Status:    accepted.status,
+		Amount:    accepted.amount,
+		Reason:    accepted.reason,
+	}, nil
+}
+
+// settle records the outcome a webhook reports, for CheckoutStatus and PayoutStatus
+func (s *Simulator) settle(webhook simulatorWebhook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := s.payouts
+	if simulatorWebhookTypes[webhook.Type].kind == KindCheckout {
+		records = s.checkouts
+	}
+	if record, ok := records[webhook.Data.Reference]; ok {
+		record.status = simulatorWebhookTypes[webhook.Type].status
+		record.reason = webhook.Data.Reason
+	}
+}
+
+func (s *Simulator) ParseWebhook(payload []byte, headers map[string]string) (Notification, error) {
+	timestamp, signature := "", ""
+	for _, part := range strings.Split(headers[simulatorSignatureHeader], ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || signature == "" {
+		return Notification{}, ErrInvalidSignature
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > simulatorTolerance || age < -simulatorTolerance {
+		return Notification{}, ErrInvalidSignature
+	}
+	expected, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, s.sign(timestamp, payload)) {
+		return Notification{}, ErrInvalidSignature
+	}
+
+	var webhook simulatorWebhook
+	if err := json.Unmarshal(payload, &webhook); err != nil {
+		return Notification{}, fmt.Errorf("invalid webhook body: %w", err)
+	}
+
+	outcome, ok := simulatorWebhookTypes[webhook.Type]
+	if !ok {
+		return Notification{}, fmt.Errorf("unknown webhook type %q", webhook.Type)
+	}
+	return Notification{
+		EventID:   webhook.ID,
+		Kind:      outcome.kind,
+		Reference: webhook.Data.Reference,
+		Status:    outcome.status,
+		Amount:    webhook.Data.Amount,
+		Reason:    webhook.Data.Reason,
+	}, nil
+}
+
+func newSimulatorWebhook(eventType string, reference string, providerReference string, amount float32, reason string) (*simulatorWebhook, error) {
+	eventID, err := randomID("evt_")
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := &simulatorWebhook{ID: eventID, Type: eventType}
+	webhook.Data.Reference = reference
+	webhook.Data.ProviderReference = providerReference
+	webhook.Data.Amount = amount
+	webhook.Data.Reason = reason
+	return webhook, nil
+}
+
+// deliver sends webhook, retrying failed attempts
+func (s *Simulator) deliver(webhook simulatorWebhook) {
+	payload, err := json.Marshal(webhook)
+	if err != nil {
+		log.Println("Failed to encode simulated webhook:", err)
+		return
+	}
+
+	for attempt := 0; attempt < simulatorMaxAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(simulatorRetryDelay << (attempt - 1))
+		}
+		if err = s.post(payload); err == nil {
+			return
+		}
+	}
+	log.Printf("Giving up on simulated webhook %s: %v", webhook.ID, err)
+}
+
+func (s *Simulator) post(payload []byte) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, s.webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(simulatorSignatureHeader, "t="+timestamp+",v1="+hex.EncodeToString(s.sign(timestamp, payload)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.New("webhook returned " + resp.Status)
+	}
+	return nil
+}
+
+func (s *Simulator) sign(timestamp string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func randomID(prefix string) (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
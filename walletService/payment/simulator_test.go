@@ -0,0 +1,198 @@
+package payment
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/configs"
+)
+
+const testSecret = "test-webhook-secret"
+
+// signedHeaders signs payload the way the simulator does, as of signedAt
+func signedHeaders(secret string, payload []byte, signedAt time.Time) map[string]string {
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	signature := NewSimulator(secret, "", 0).sign(timestamp, payload)
+	return map[string]string{simulatorSignatureHeader: "t=" + timestamp + ",v1=" + hex.EncodeToString(signature)}
+}
+
+func TestSimulatorParseWebhook(t *testing.T) {
+	payload := []byte(`{"id":"evt_1","type":"payment.succeeded","data":{"reference":"topup-1","providerReference":"sim_1","amount":2500}}`)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		payload []byte
+		headers map[string]string
+		wantErr error
+	}{
+		{name: "valid signature", payload: payload, headers: signedHeaders(testSecret, payload, now)},
+		{name: "signed a minute ago", payload: payload, headers: signedHeaders(testSecret, payload, now.Add(-time.Minute))},
+		{name: "no signature", payload: payload, headers: map[string]string{}, wantErr: ErrInvalidSignature},
+		{name: "signed with another secret", payload: payload, headers: signedHeaders("another-secret", payload, now), wantErr: ErrInvalidSignature},
+		{name: "body changed after signing", payload: []byte(`{"id":"evt_1","type":"payment.succeeded","data":{"reference":"topup-1","amount":999999}}`), headers: signedHeaders(testSecret, payload, now), wantErr: ErrInvalidSignature},
+		{name: "signature not hex", payload: payload, headers: map[string]string{simulatorSignatureHeader: "t=" + strconv.FormatInt(now.Unix(), 10) + ",v1=zz"}, wantErr: ErrInvalidSignature},
+		{name: "timestamp missing", payload: payload, headers: map[string]string{simulatorSignatureHeader: "v1=00"}, wantErr: ErrInvalidSignature},
+		{name: "signed more than 5 minutes ago", payload: payload, headers: signedHeaders(testSecret, payload, now.Add(-simulatorTolerance-time.Minute)), wantErr: ErrInvalidSignature},
+		{name: "signed more than 5 minutes ahead", payload: payload, headers: signedHeaders(testSecret, payload, now.Add(simulatorTolerance+time.Minute)), wantErr: ErrInvalidSignature},
+	}
+
+	simulator := NewSimulator(testSecret, "", 0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notification, err := simulator.ParseWebhook(tt.payload, tt.headers)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			want := Notification{EventID: "evt_1", Kind: KindCheckout, Reference: "topup-1", Status: PaymentSucceeded, Amount: 2500}
+			if notification != want {
+				t.Errorf("got %+v, want %+v", notification, want)
+			}
+		})
+	}
+}
+
+func TestSimulatorParseWebhookTypes(t *testing.T) {
+	tests := []struct {
+		webhookType string
+		wantKind    string
+		wantStatus  string
+		wantErr     bool
+	}{
+		{webhookType: "payment.succeeded", wantKind: KindCheckout, wantStatus: PaymentSucceeded},
+		{webhookType: "payment.failed", wantKind: KindCheckout, wantStatus: PaymentFailed},
+		{webhookType: "payout.paid", wantKind: KindPayout, wantStatus: PayoutPaid},
+		{webhookType: "payout.failed", wantKind: KindPayout, wantStatus: PayoutFailed},
+		{webhookType: "payout.reversed", wantKind: KindPayout, wantStatus: PayoutReversed},
+		{webhookType: "payment.refunded", wantErr: true},
+	}
+
+	simulator := NewSimulator(testSecret, "", 0)
+	for _, tt := range tests {
+		t.Run(tt.webhookType, func(t *testing.T) {
+			payload := []byte(`{"id":"evt_1","type":"` + tt.webhookType + `","data":{"reference":"ref-1","amount":10}}`)
+			notification, err := simulator.ParseWebhook(payload, signedHeaders(testSecret, payload, time.Now()))
+			if tt.wantErr {
+				if err == nil || errors.Is(err, ErrInvalidSignature) {
+					t.Fatalf("got error %v, want an unknown type error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if notification.Kind != tt.wantKind || notification.Status != tt.wantStatus {
+				t.Errorf("got %s/%s, want %s/%s", notification.Kind, notification.Status, tt.wantKind, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestSimulatorCheckoutStatus(t *testing.T) {
+	simulator := NewSimulator(testSecret, "", time.Hour)
+
+	if _, err := simulator.CheckoutStatus(context.Background(), "unknown"); !errors.Is(err, ErrCheckoutNotFound) {
+		t.Fatalf("got error %v, want ErrCheckoutNotFound", err)
+	}
+
+	checkout := Checkout{Reference: "topup-1", Amount: 2500}
+	if _, err := simulator.CreateCheckout(context.Background(), checkout); err != nil {
+		t.Fatalf("CreateCheckout: %v", err)
+	}
+
+	notification, err := simulator.CheckoutStatus(context.Background(), checkout.Reference)
+	if err != nil {
+		t.Fatalf("CheckoutStatus: %v", err)
+	}
+	if notification.Status != PaymentPending || notification.Amount != checkout.Amount {
+		t.Errorf("got %+v, want a pending checkout of %v", notification, checkout.Amount)
+	}
+
+	webhook, err := newSimulatorWebhook("payment.succeeded", checkout.Reference, "sim_1", checkout.Amount, "")
+	if err != nil {
+		t.Fatalf("newSimulatorWebhook: %v", err)
+	}
+	simulator.settle(*webhook)
+
+	notification, err = simulator.CheckoutStatus(context.Background(), checkout.Reference)
+	if err != nil {
+		t.Fatalf("CheckoutStatus: %v", err)
+	}
+	if notification.Status != PaymentSucceeded {
+		t.Errorf("got status %q, want %q", notification.Status, PaymentSucceeded)
+	}
+}
+
+func TestSimulatorPayoutStatus(t *testing.T) {
+	simulator := NewSimulator(testSecret, "", time.Hour)
+
+	if _, err := simulator.PayoutStatus(context.Background(), "unknown"); !errors.Is(err, ErrPayoutNotFound) {
+		t.Fatalf("got error %v, want ErrPayoutNotFound", err)
+	}
+
+	payout := Payout{Reference: "withdrawal-1", Amount: 100, AccountNumber: "0123456789"}
+	if _, err := simulator.CreatePayout(context.Background(), payout); err != nil {
+		t.Fatalf("CreatePayout: %v", err)
+	}
+
+	notification, err := simulator.PayoutStatus(context.Background(), payout.Reference)
+	if err != nil {
+		t.Fatalf("PayoutStatus: %v", err)
+	}
+	if notification.Status != PayoutPending || notification.Amount != payout.Amount {
+		t.Errorf("got %+v, want a pending payout of %v", notification, payout.Amount)
+	}
+
+	webhook, err := newSimulatorWebhook("payout.paid", payout.Reference, "po_1", payout.Amount, "")
+	if err != nil {
+		t.Fatalf("newSimulatorWebhook: %v", err)
+	}
+	simulator.settle(*webhook)
+
+	notification, err = simulator.PayoutStatus(context.Background(), payout.Reference)
+	if err != nil {
+		t.Fatalf("PayoutStatus: %v", err)
+	}
+	if notification.Status != PayoutPaid {
+		t.Errorf("got status %q, want %q", notification.Status, PayoutPaid)
+	}
+}
+
+func TestNew(t *testing.T) {
+	defer func(mode, secret string) {
+		configs.Env.MODE, configs.Env.PAYMENT_WEBHOOK_SECRET = mode, secret
+	}(configs.Env.MODE, configs.Env.PAYMENT_WEBHOOK_SECRET)
+
+	tests := []struct {
+		name    string
+		kind    string
+		mode    string
+		secret  string
+		wantErr bool
+	}{
+		{name: "provider not set", kind: "", mode: "development", secret: testSecret, wantErr: true},
+		{name: "unknown provider", kind: "acme", mode: "development", secret: testSecret, wantErr: true},
+		{name: "simulator in development", kind: "simulator", mode: "development", secret: testSecret},
+		{name: "simulator without a webhook secret", kind: "simulator", mode: "development", wantErr: true},
+		{name: "simulator in production", kind: "simulator", mode: "production", secret: testSecret, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configs.Env.MODE, configs.Env.PAYMENT_WEBHOOK_SECRET = tt.mode, tt.secret
+
+			_, err := New(tt.kind)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
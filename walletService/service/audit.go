@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/helpers"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditLog records a change staff made to a user's money
+type AuditLog struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	ActorID      primitive.ObjectID `bson:"actorId"`
+	TargetUserID primitive.ObjectID `bson:"targetUserId"`
+	Action       string             `bson:"action"`            // e.g. "withdrawal_resolved"
+	Subject      string             `bson:"subject,omitempty"` // The ID of what was changed
+	From         string             `bson:"from,omitempty"`
+	To           string             `bson:"to,omitempty"`
+	Reason       string             `bson:"reason,omitempty"`
+	RequestID    string             `bson:"requestId,omitempty"`
+	CreatedAt    time.Time          `bson:"createdAt"`
+}
+
+// PermissionWalletAdjust lets staff place, capture and release holds and debit wallets
+const PermissionWalletAdjust = "wallet.adjust"
+
+// authorizeActor returns the calling staff member's user ID if they have permission
+func authorizeActor(ctx context.Context, permission string) (primitive.ObjectID, string, int) {
+	actorID, message, statusCode := principalUserID(ctx)
+	if statusCode != http.StatusOK {
+		return primitive.NilObjectID, message, statusCode
+	}
+	if message, statusCode := checkPermission(ctx, actorID.Hex(), permission); statusCode != http.StatusOK {
+		return primitive.NilObjectID, message, statusCode
+	}
+	return actorID, "", http.StatusOK
+}
+
+// recordAudit writes entry to the audit log. Pass a transaction's context to record it together
+// with the change it describes.
+func recordAudit(ctx context.Context, entry AuditLog) error {
+	if principal, ok := helpers.PrincipalFromContext(ctx); ok {
+		entry.RequestID = principal.RequestId
+	}
+	entry.CreatedAt = time.Now()
+
+	_, err := database.GetCollection("wallet_audit_logs").InsertOne(ctx, entry)
+	return err
+}
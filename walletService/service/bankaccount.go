@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/helpers"
+	pb "github.com/manlikehenryy/loan-management-system-grpc/walletService/wallet"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	maxBankAccounts      = 5
+	maxAccountNameLength = 100
+)
+
+var (
+	bankCodePattern      = regexp.MustCompile(`^[A-Za-z0-9]{3,11}$`)
+	accountNumberPattern = regexp.MustCompile(`^[0-9]{6,20}$`)
+)
+
+// BankAccount is an external account a user can withdraw to
+type BankAccount struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	UserID        primitive.ObjectID `bson:"userId"`
+	BankCode      string             `bson:"bankCode"`
+	AccountNumber string             `bson:"accountNumber"`
+	AccountName   string             `bson:"accountName"`
+	CreatedAt     time.Time          `bson:"createdAt"`
+}
+
+func (s *WalletServiceServer) AddBankAccount(ctx context.Context, req *pb.AddBankAccountRequest) (*pb.AddBankAccountResponse, error) {
+	userID, message, statusCode := principalUserID(ctx)
+	if statusCode != http.StatusOK {
+		return addBankAccountErrorResponse(message, statusCode), nil
+	}
+
+	bankCode := strings.ToUpper(strings.TrimSpace(req.GetBankCode()))
+	accountNumber := strings.TrimSpace(req.GetAccountNumber())
+	accountName := strings.TrimSpace(req.GetAccountName())
+	if !bankCodePattern.MatchString(bankCode) {
+		return addBankAccountErrorResponse("Bank code must be 3 to 11 letters or digits", http.StatusBadRequest), nil
+	}
+	if !accountNumberPattern.MatchString(accountNumber) {
+		return addBankAccountErrorResponse("Account number must be 6 to 20 digits", http.StatusBadRequest), nil
+	}
+	if accountName == "" || len(accountName) > maxAccountNameLength {
+		return addBankAccountErrorResponse("Account name must be 1 to 100 characters", http.StatusBadRequest), nil
+	}
+
+	bankAccountsCollection := database.GetCollection("bankaccounts")
+
+	count, err := bankAccountsCollection.CountDocuments(ctx, bson.M{"userId": userID})
+	if err != nil {
+		log.Println("Database error:", err)
+		return addBankAccountErrorResponse("Failed to add bank account", http.StatusInternalServerError), nil
+	}
+	if count >= maxBankAccounts {
+		return addBankAccountErrorResponse("You can link at most 5 bank accounts", http.StatusConflict), nil
+	}
+
+	err = bankAccountsCollection.FindOne(ctx, bson.M{"userId": userID, "bankCode": bankCode, "accountNumber": accountNumber}).Err()
+	if err == nil {
+		return addBankAccountErrorResponse("Bank account already linked", http.StatusConflict), nil
+	}
+	if err != mongo.ErrNoDocuments {
+		log.Println("Database error:", err)
+		return addBankAccountErrorResponse("Failed to add bank account", http.StatusInternalServerError), nil
+	}
+
+	bankAccount := BankAccount{
+		ID:            primitive.NewObjectID(),
+		UserID:        userID,
+		BankCode:      bankCode,
+		AccountNumber: accountNumber,
+		AccountName:   accountName,
+		CreatedAt:     time.Now(),
+	}
+	if _, err := bankAccountsCollection.InsertOne(ctx, bankAccount); err != nil {
+		log.Println("Database error:", err)
+		return addBankAccountErrorResponse("Failed to add bank account", http.StatusInternalServerError), nil
+	}
+
+	return &pb.AddBankAccountResponse{
+		BankAccount: bankAccountDetails(&bankAccount),
+		Message:     "Bank account added successfully",
+		Status:      true,
+		StatusCode:  http.StatusCreated,
+	}, nil
+}
+
+func (s *WalletServiceServer) ListBankAccounts(ctx context.Context, req *pb.ListBankAccountsRequest) (*pb.ListBankAccountsResponse, error) {
+	userID, message, statusCode := principalUserID(ctx)
+	if statusCode != http.StatusOK {
+		return listBankAccountsErrorResponse(message, statusCode), nil
+	}
+
+	cursor, err := database.GetCollection("bankaccounts").Find(ctx,
+		bson.M{"userId": userID},
+		options.Find().SetSort(bson.M{"createdAt": 1}),
+	)
+	if err != nil {
+		log.Println("Database error:", err)
+		return listBankAccountsErrorResponse("Failed to list bank accounts", http.StatusInternalServerError), nil
+	}
+
+	var bankAccounts []BankAccount
+	if err := cursor.All(ctx, &bankAccounts); err != nil {
+		log.Println("Database error:", err)
+		return listBankAccountsErrorResponse("Failed to list bank accounts", http.StatusInternalServerError), nil
+	}
+
+	details := make([]*pb.BankAccount, 0, len(bankAccounts))
+	for i := range bankAccounts {
+		details = append(details, bankAccountDetails(&bankAccounts[i]))
+	}
+
+	return &pb.ListBankAccountsResponse{
+		BankAccounts: details,
+		Message:      "Bank accounts retrieved successfully",
+		Status:       true,
+		StatusCode:   http.StatusOK,
+	}, nil
+}
+
+// RemoveBankAccount unlinks a bank account. Withdrawals already requested to it carry on, since
+// they keep a copy of its details.
+func (s *WalletServiceServer) RemoveBankAccount(ctx context.Context, req *pb.RemoveBankAccountRequest) (*pb.RemoveBankAccountResponse, error) {
+	userID, message, statusCode := principalUserID(ctx)
+	if statusCode != http.StatusOK {
+		return removeBankAccountErrorResponse(message, statusCode), nil
+	}
+
+	bankAccountID, err := primitive.ObjectIDFromHex(req.GetBankAccountId())
+	if err != nil {
+		return removeBankAccountErrorResponse("Invalid bank account ID", http.StatusBadRequest), nil
+	}
+
+	result, err := database.GetCollection("bankaccounts").DeleteOne(ctx, bson.M{"_id": bankAccountID, "userId": userID})
+	if err != nil {
+		log.Println("Database error:", err)
+		return removeBankAccountErrorResponse("Failed to remove bank account", http.StatusInternalServerError), nil
+	}
+	if result.DeletedCount == 0 {
+		return removeBankAccountErrorResponse("Bank account not found", http.StatusNotFound), nil
+	}
+
+	return &pb.RemoveBankAccountResponse{Message: "Bank account removed successfully", Status: true, StatusCode: http.StatusOK}, nil
+}
+
+// principalUserID returns the ID of the user the call is made for. It returns http.StatusOK
+// if there is one.
+func principalUserID(ctx context.Context) (primitive.ObjectID, string, int) {
+	principal, ok := helpers.PrincipalFromContext(ctx)
+	if !ok {
+		return primitive.NilObjectID, "No identity provided", http.StatusUnauthorized
+	}
+	userID, err := primitive.ObjectIDFromHex(principal.UserId)
+	if err != nil {
+		return primitive.NilObjectID, "Invalid user ID", http.StatusBadRequest
+	}
+	return userID, "", http.StatusOK
+}
+
+func bankAccountDetails(bankAccount *BankAccount) *pb.BankAccount {
+	return &pb.BankAccount{
+		Id:            bankAccount.ID.Hex(),
+		BankCode:      bankAccount.BankCode,
+		AccountNumber: maskAccountNumber(bankAccount.AccountNumber),
+		AccountName:   bankAccount.AccountName,
+		CreatedAt:     bankAccount.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// maskAccountNumber hides all but the last 4 digits of an account number
+func maskAccountNumber(accountNumber string) string {
+	if len(accountNumber) <= 4 {
+		return accountNumber
+	}
+	return strings.Repeat("*", len(accountNumber)-4) + accountNumber[len(accountNumber)-4:]
+}
+
+// Error Response function for AddBankAccount
+func addBankAccountErrorResponse(message string, statusCode int) *pb.AddBankAccountResponse {
+	return &pb.AddBankAccountResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+// Error Response function for ListBankAccounts
+func listBankAccountsErrorResponse(message string, statusCode int) *pb.ListBankAccountsResponse {
+	return &pb.ListBankAccountsResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+// Error Response function for RemoveBankAccount
+func removeBankAccountErrorResponse(message string, statusCode int) *pb.RemoveBankAccountResponse {
+	return &pb.RemoveBankAccountResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/broker"
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/outbox"
+)
+
+// Domain events recorded in the outbox
+const (
+	EventWalletCredited = "WalletCredited"
+	EventWalletDebited  = "WalletDebited"
+	EventHoldPlaced     = "HoldPlaced"
+	EventHoldCaptured   = "HoldCaptured"
+	EventHoldReleased   = "HoldReleased"
+	EventHoldExpired    = "HoldExpired"
+	EventTopUpFailed    = "TopUpFailed"
+
+	EventWithdrawalRequested = "WithdrawalRequested"
+	EventWithdrawalPaid      = "WithdrawalPaid"
+	EventWithdrawalFailed    = "WithdrawalFailed"
+	EventWithdrawalReversed  = "WithdrawalReversed"
+)
+
+// recordWalletEvent writes an event about userId's wallet to the outbox. Pass a transaction's
+// context to record it together with the change it describes.
+func recordWalletEvent(ctx context.Context, eventType string, walletId string, userId string, data map[string]string) error {
+	return outbox.Record(ctx, broker.Event{Type: eventType, AggregateID: walletId, UserID: userId, Data: data})
+}
+
+func formatAmount(amount float32) string {
+	return fmt.Sprintf("%.2f", amount)
+}
@@ -0,0 +1,481 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/database"
+	pb "github.com/manlikehenryy/loan-management-system-grpc/walletService/wallet"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Hold statuses. Only an active hold counts against the available balance.
+const (
+	HoldActive   = "active"
+	HoldCaptured = "captured"
+	HoldReleased = "released"
+	HoldExpired  = "expired"
+)
+
+// Reasons a hold can be placed for. Withdrawal holds are only placed by RequestWithdrawal.
+const (
+	HoldReasonRepayment  = "repayment"
+	HoldReasonTransfer   = "transfer"
+	HoldReasonFee        = "fee"
+	HoldReasonWithdrawal = "withdrawal"
+)
+
+var holdReasons = map[string]bool{
+	HoldReasonRepayment: true,
+	HoldReasonTransfer:  true,
+	HoldReasonFee:       true,
+}
+
+const (
+	defaultHoldExpiry  = 24 * time.Hour
+	maxHoldExpiry      = 30 * 24 * time.Hour
+	holdSweepInterval  = time.Minute
+	holdSweepBatchSize = 100
+)
+
+var (
+	errInsufficientFunds  = errors.New("insufficient available funds")
+	errHoldNotActive      = errors.New("hold is not active")
+	errHoldExpired        = errors.New("hold has expired")
+	errHoldReferenceInUse = errors.New("an active hold with this reference is for a different amount")
+	errCaptureExceedsHold = errors.New("capture amount exceeds the hold")
+	errWithdrawalHold     = errors.New("hold belongs to a withdrawal")
+)
+
+// Hold earmarks part of a wallet's balance. The funds stay in the ledger balance but can't be
+// debited or held again until the hold is captured, released or expires.
+type Hold struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	WalletID       primitive.ObjectID `bson:"walletId"`
+	UserID         primitive.ObjectID `bson:"userId"`
+	Amount         float32            `bson:"amount"`
+	CapturedAmount float32            `bson:"capturedAmount,omitempty"`
+	Reason         string             `bson:"reason"`
+	Reference      string             `bson:"reference,omitempty"`
+	Status         string             `bson:"status"`
+	ExpiresAt      time.Time          `bson:"expiresAt"`
+	CreatedAt      time.Time          `bson:"createdAt"`
+	ClosedAt       *time.Time         `bson:"closedAt,omitempty"`
+}
+
+// expired reports whether the hold is past its expiry. Withdrawal holds don't expire: their
+// withdrawal captures or releases them, however long the payout takes.
+func (h *Hold) expired(now time.Time) bool {
+	return h.Reason != HoldReasonWithdrawal && !h.ExpiresAt.After(now)
+}
+
+// availableAtLeast matches wallets whose available balance covers amount. Wallets created
+// before holds have no held field, so it counts as zero.
+func availableAtLeast(amount float32) bson.M {
+	return bson.M{"$gte": bson.A{
+		bson.M{"$subtract": bson.A{bson.M{"$ifNull": bson.A{"$balance", 0}}, bson.M{"$ifNull": bson.A{"$held", 0}}}},
+		amount,
+	}}
+}
+
+// PlaceHold lets staff earmark funds in a user's wallet, such as a repayment that is due. Each
+// hold placed is kept in the audit log.
+func (s *WalletServiceServer) PlaceHold(ctx context.Context, req *pb.PlaceHoldRequest) (*pb.PlaceHoldResponse, error) {
+	actorID, message, statusCode := authorizeActor(ctx, PermissionWalletAdjust)
+	if statusCode != http.StatusOK {
+		return placeHoldErrorResponse(message, statusCode), nil
+	}
+
+	userID, err := primitive.ObjectIDFromHex(req.GetUserId())
+	if err != nil {
+		return placeHoldErrorResponse("Invalid user ID", http.StatusBadRequest), nil
+	}
+	if req.GetAmount() <= 0 {
+		return placeHoldErrorResponse("Amount must be greater than zero", http.StatusBadRequest), nil
+	}
+	if !holdReasons[req.GetReason()] {
+		return placeHoldErrorResponse("Reason must be repayment, transfer or fee", http.StatusBadRequest), nil
+	}
+
+	expiresIn := defaultHoldExpiry
+	if req.GetExpiresInSeconds() != 0 {
+		expiresIn = time.Duration(req.GetExpiresInSeconds()) * time.Second
+		if expiresIn <= 0 || expiresIn > maxHoldExpiry {
+			return placeHoldErrorResponse("Hold expiry must be between 1 second and 30 days", http.StatusBadRequest), nil
+		}
+	}
+
+	var hold *Hold
+	placed := false
+	err = database.WithTransaction(ctx, func(ctx mongo.SessionContext) error {
+		var err error
+		hold, placed, err = placeHold(ctx, userID, req.GetAmount(), req.GetReason(), req.GetReference(), expiresIn)
+		if err != nil || !placed {
+			return err
+		}
+		return recordAudit(ctx, AuditLog{
+			ActorID:      actorID,
+			TargetUserID: userID,
+			Action:       "hold_placed",
+			Subject:      hold.ID.Hex(),
+			To:           HoldActive,
+			Reason:       hold.Reason,
+		})
+	})
+	if err != nil {
+		switch {
+		case err == mongo.ErrNoDocuments:
+			return placeHoldErrorResponse("Wallet not found", http.StatusNotFound), nil
+		case errors.Is(err, errInsufficientFunds):
+			return placeHoldErrorResponse("Insufficient available balance", http.StatusConflict), nil
+		case errors.Is(err, errHoldReferenceInUse):
+			return placeHoldErrorResponse("An active hold with this reference is for a different amount", http.StatusConflict), nil
+		}
+		log.Println("Database error:", err)
+		return placeHoldErrorResponse("Failed to place hold", http.StatusInternalServerError), nil
+	}
+
+	if !placed {
+		return placeHoldSuccessResponse(hold, "Hold already placed", http.StatusOK), nil
+	}
+	return placeHoldSuccessResponse(hold, "Hold placed successfully", http.StatusCreated), nil
+}
+
+// placeHold holds amount in userId's wallet, in the transaction ctx belongs to. If reference is
+// set and an active hold for the same amount has it, that hold is returned, with placed false.
+func placeHold(ctx mongo.SessionContext, userID primitive.ObjectID, amount float32, reason string, reference string, expiresIn time.Duration) (hold *Hold, placed bool, err error) {
+	holdsCollection := database.GetCollection("holds")
+
+	// Two holds with the same reference both update the wallet, so one of them hits a write
+	// conflict and is retried, by which time it sees the other
+	if reference != "" {
+		var existing Hold
+		err := holdsCollection.FindOne(ctx, bson.M{"userId": userID, "reference": reference, "status": HoldActive}).Decode(&existing)
+		if err == nil {
+			if existing.Amount != amount {
+				return nil, false, errHoldReferenceInUse
+			}
+			return &existing, false, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return nil, false, err
+		}
+	}
+
+	var wallet Wallet
+	err = database.GetCollection("wallets").FindOneAndUpdate(ctx,
+		bson.M{"userId": userID, "$expr": availableAtLeast(amount)},
+		bson.M{"$inc": bson.M{"held": amount}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&wallet)
+	if err == mongo.ErrNoDocuments {
+		return nil, false, walletMissingOr(ctx, userID, errInsufficientFunds)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	now := time.Now()
+	hold = &Hold{
+		ID:        primitive.NewObjectID(),
+		WalletID:  wallet.ID,
+		UserID:    userID,
+		Amount:    amount,
+		Reason:    reason,
+		Reference: reference,
+		Status:    HoldActive,
+		ExpiresAt: now.Add(expiresIn),
+		CreatedAt: now,
+	}
+	if _, err := holdsCollection.InsertOne(ctx, hold); err != nil {
+		return nil, false, err
+	}
+
+	if err := recordWalletEvent(ctx, EventHoldPlaced, wallet.ID.Hex(), userID.Hex(), holdEventData(hold, &wallet)); err != nil {
+		return nil, false, err
+	}
+	return hold, true, nil
+}
+
+func (s *WalletServiceServer) CaptureHold(ctx context.Context, req *pb.CaptureHoldRequest) (*pb.CaptureHoldResponse, error) {
+	actorID, message, statusCode := authorizeActor(ctx, PermissionWalletAdjust)
+	if statusCode != http.StatusOK {
+		return captureHoldErrorResponse(message, statusCode), nil
+	}
+
+	holdID, err := primitive.ObjectIDFromHex(req.GetHoldId())
+	if err != nil {
+		return captureHoldErrorResponse("Invalid hold ID", http.StatusBadRequest), nil
+	}
+	if req.GetAmount() < 0 {
+		return captureHoldErrorResponse("Amount can't be negative", http.StatusBadRequest), nil
+	}
+
+	hold, err := closeHold(ctx, holdID, HoldCaptured, req.GetAmount(), actorID)
+	if err != nil {
+		message, statusCode := closeHoldError(err, "Failed to capture hold")
+		return captureHoldErrorResponse(message, statusCode), nil
+	}
+	return captureHoldSuccessResponse(hold, "Hold captured successfully", http.StatusOK), nil
+}
+
+func (s *WalletServiceServer) ReleaseHold(ctx context.Context, req *pb.ReleaseHoldRequest) (*pb.ReleaseHoldResponse, error) {
+	actorID, message, statusCode := authorizeActor(ctx, PermissionWalletAdjust)
+	if statusCode != http.StatusOK {
+		return releaseHoldErrorResponse(message, statusCode), nil
+	}
+
+	holdID, err := primitive.ObjectIDFromHex(req.GetHoldId())
+	if err != nil {
+		return releaseHoldErrorResponse("Invalid hold ID", http.StatusBadRequest), nil
+	}
+
+	hold, err := closeHold(ctx, holdID, HoldReleased, 0, actorID)
+	if err != nil {
+		message, statusCode := closeHoldError(err, "Failed to release hold")
+		return releaseHoldErrorResponse(message, statusCode), nil
+	}
+	return releaseHoldSuccessResponse(hold, "Hold released successfully", http.StatusOK), nil
+}
+
+// closeHold ends an active hold with status, in a transaction of its own. A withdrawal's hold
+// can't be closed this way: only the withdrawal itself captures or releases it, once the payment
+// provider reports how the payout went. A hold closed by staff is kept in the audit log, under
+// actorID; the sweeper passes primitive.NilObjectID.
+func closeHold(ctx context.Context, holdID primitive.ObjectID, status string, captureAmount float32, actorID primitive.ObjectID) (*Hold, error) {
+	var hold *Hold
+	err := database.WithTransaction(ctx, func(ctx mongo.SessionContext) error {
+		var existing Hold
+		if err := database.GetCollection("holds").FindOne(ctx, bson.M{"_id": holdID}).Decode(&existing); err != nil {
+			return err
+		}
+		if existing.Reason == HoldReasonWithdrawal {
+			return errWithdrawalHold
+		}
+
+		var err error
+		hold, err = closeHoldIn(ctx, holdID, status, captureAmount)
+		if err != nil || actorID.IsZero() {
+			return err
+		}
+		return recordAudit(ctx, AuditLog{
+			ActorID:      actorID,
+			TargetUserID: hold.UserID,
+			Action:       "hold_" + status,
+			Subject:      hold.ID.Hex(),
+			From:         HoldActive,
+			To:           status,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// closeHoldIn ends an active hold with status, in the transaction ctx belongs to. Capturing debits
+// captureAmount from the wallet, or the whole hold if it is zero. Whatever isn't captured becomes
+// available again.
+func closeHoldIn(ctx mongo.SessionContext, holdID primitive.ObjectID, status string, captureAmount float32) (*Hold, error) {
+	holdsCollection := database.GetCollection("holds")
+
+	var hold Hold
+	if err := holdsCollection.FindOne(ctx, bson.M{"_id": holdID}).Decode(&hold); err != nil {
+		return nil, err
+	}
+	if hold.Status != HoldActive {
+		return nil, errHoldNotActive
+	}
+
+	now := time.Now()
+	captured := float32(0)
+	if status == HoldCaptured {
+		if hold.expired(now) {
+			return nil, errHoldExpired
+		}
+		captured = captureAmount
+		if captured == 0 {
+			captured = hold.Amount
+		}
+		if captured > hold.Amount {
+			return nil, errCaptureExceedsHold
+		}
+	}
+
+	result, err := holdsCollection.UpdateOne(ctx,
+		bson.M{"_id": holdID, "status": HoldActive},
+		bson.M{"$set": bson.M{"status": status, "capturedAmount": captured, "closedAt": now}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if result.ModifiedCount == 0 {
+		return nil, errHoldNotActive
+	}
+	hold.Status = status
+	hold.CapturedAmount = captured
+	hold.ClosedAt = &now
+
+	var wallet Wallet
+	err = database.GetCollection("wallets").FindOneAndUpdate(ctx,
+		bson.M{"_id": hold.WalletID},
+		bson.M{"$inc": bson.M{"balance": -captured, "held": -hold.Amount}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&wallet)
+	if err != nil {
+		return nil, err
+	}
+
+	if captured > 0 {
+		if err := recordLedgerEntry(ctx, &wallet, LedgerDebit, captured, holdLedgerDescriptions[hold.Reason], hold.Reference); err != nil {
+			return nil, err
+		}
+	}
+	if err := recordWalletEvent(ctx, holdEventTypes[status], wallet.ID.Hex(), hold.UserID.Hex(), holdEventData(&hold, &wallet)); err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+func closeHoldError(err error, failure string) (string, int) {
+	switch {
+	case err == mongo.ErrNoDocuments:
+		return "Hold not found", http.StatusNotFound
+	case errors.Is(err, errHoldNotActive):
+		return "Hold is no longer active", http.StatusConflict
+	case errors.Is(err, errHoldExpired):
+		return "Hold has expired", http.StatusConflict
+	case errors.Is(err, errCaptureExceedsHold):
+		return "Amount exceeds the hold", http.StatusBadRequest
+	case errors.Is(err, errWithdrawalHold):
+		return "Withdrawal holds are closed by their withdrawal", http.StatusConflict
+	}
+	log.Println("Database error:", err)
+	return failure, http.StatusInternalServerError
+}
+
+// walletMissingOr tells a conditional wallet update that matched nothing apart: it returns
+// mongo.ErrNoDocuments if userId has no wallet, and err if the condition failed
+func walletMissingOr(ctx context.Context, userID primitive.ObjectID, err error) error {
+	count, countErr := database.GetCollection("wallets").CountDocuments(ctx, bson.M{"userId": userID})
+	if countErr != nil {
+		return countErr
+	}
+	if count == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return err
+}
+
+// StartHoldSweeper expires active holds once they are past their expiry, making their funds
+// available again. Each hold is expired in its own transaction, so when several instances
+// sweep at once only one of them expires it.
+func StartHoldSweeper(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(holdSweepInterval)
+		defer ticker.Stop()
+
+		for {
+			sweepExpiredHolds(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func sweepExpiredHolds(ctx context.Context) {
+	cursor, err := database.GetCollection("holds").Find(ctx,
+		bson.M{"status": HoldActive, "reason": bson.M{"$ne": HoldReasonWithdrawal}, "expiresAt": bson.M{"$lte": time.Now()}},
+		options.Find().SetSort(bson.M{"expiresAt": 1}).SetLimit(holdSweepBatchSize).SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		log.Println("Failed to find expired holds:", err)
+		return
+	}
+
+	var holds []Hold
+	if err := cursor.All(ctx, &holds); err != nil {
+		log.Println("Failed to read expired holds:", err)
+		return
+	}
+
+	for _, hold := range holds {
+		if _, err := closeHold(ctx, hold.ID, HoldExpired, 0, primitive.NilObjectID); err != nil && !errors.Is(err, errHoldNotActive) {
+			log.Printf("Failed to expire hold %s: %v", hold.ID.Hex(), err)
+		}
+	}
+}
+
+var holdEventTypes = map[string]string{
+	HoldCaptured: EventHoldCaptured,
+	HoldReleased: EventHoldReleased,
+	HoldExpired:  EventHoldExpired,
+}
+
+func holdEventData(hold *Hold, wallet *Wallet) map[string]string {
+	data := map[string]string{
+		"holdId":           hold.ID.Hex(),
+		"amount":           formatAmount(hold.Amount),
+		"reason":           hold.Reason,
+		"balance":          formatAmount(wallet.Balance),
+		"availableBalance": formatAmount(wallet.Available()),
+	}
+	if hold.Reference != "" {
+		data["reference"] = hold.Reference
+	}
+	if hold.Status == HoldCaptured {
+		data["capturedAmount"] = formatAmount(hold.CapturedAmount)
+	}
+	return data
+}
+
+func holdDetails(hold *Hold) *pb.Hold {
+	return &pb.Hold{
+		Id:             hold.ID.Hex(),
+		UserId:         hold.UserID.Hex(),
+		Amount:         hold.Amount,
+		CapturedAmount: hold.CapturedAmount,
+		Reason:         hold.Reason,
+		Reference:      hold.Reference,
+		Status:         hold.Status,
+		ExpiresAt:      hold.ExpiresAt.Format(time.RFC3339),
+		CreatedAt:      hold.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// Success and Error Response functions for PlaceHold
+func placeHoldSuccessResponse(hold *Hold, message string, statusCode int) *pb.PlaceHoldResponse {
+	return &pb.PlaceHoldResponse{Hold: holdDetails(hold), Message: message, Status: true, StatusCode: int32(statusCode)}
+}
+
+func placeHoldErrorResponse(message string, statusCode int) *pb.PlaceHoldResponse {
+	return &pb.PlaceHoldResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+// Success and Error Response functions for CaptureHold
+func captureHoldSuccessResponse(hold *Hold, message string, statusCode int) *pb.CaptureHoldResponse {
+	return &pb.CaptureHoldResponse{Hold: holdDetails(hold), Message: message, Status: true, StatusCode: int32(statusCode)}
+}
+
+func captureHoldErrorResponse(message string, statusCode int) *pb.CaptureHoldResponse {
+	return &pb.CaptureHoldResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+// Success and Error Response functions for ReleaseHold
+func releaseHoldSuccessResponse(hold *Hold, message string, statusCode int) *pb.ReleaseHoldResponse {
+	return &pb.ReleaseHoldResponse{Hold: holdDetails(hold), Message: message, Status: true, StatusCode: int32(statusCode)}
+}
+
+func releaseHoldErrorResponse(message string, statusCode int) *pb.ReleaseHoldResponse {
+	return &pb.ReleaseHoldResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Ledger entry types
+const (
+	LedgerCredit = "credit"
+	LedgerDebit  = "debit"
+)
+
+// openingBalanceReference marks the entry that gives a wallet the balance it had before the ledger
+const openingBalanceReference = "opening-balance"
+
+// What each captured hold is shown as on statements, by reason
+var holdLedgerDescriptions = map[string]string{
+	HoldReasonRepayment:  "Loan repayment",
+	HoldReasonTransfer:   "Transfer",
+	HoldReasonFee:        "Fee",
+	HoldReasonWithdrawal: "Withdrawal",
+}
+
+// LedgerEntry records one change to a wallet's ledger balance. Holds don't move money, so they
+// only get an entry when they are captured.
+type LedgerEntry struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	WalletID     primitive.ObjectID `bson:"walletId"`
+	UserID       primitive.ObjectID `bson:"userId"`
+	Type         string             `bson:"type"`
+	Amount       float32            `bson:"amount"`       // Always positive; Type gives the direction
+	BalanceAfter float32            `bson:"balanceAfter"` // The ledger balance once the entry was applied
+	Description  string             `bson:"description"`
+	Reference    string             `bson:"reference,omitempty"`
+	CreatedAt    time.Time          `bson:"createdAt"`
+}
+
+// Change returns the entry's effect on the balance
+func (e *LedgerEntry) Change() float32 {
+	if e.Type == LedgerDebit {
+		return -e.Amount
+	}
+	return e.Amount
+}
+
+// recordLedgerEntry adds an entry for a change that left wallet as it is now. Pass the context of
+// the transaction that made the change.
+func recordLedgerEntry(ctx context.Context, wallet *Wallet, entryType string, amount float32, description string, reference string) error {
+	_, err := database.GetCollection("ledger").InsertOne(ctx, LedgerEntry{
+		ID:           primitive.NewObjectID(),
+		WalletID:     wallet.ID,
+		UserID:       wallet.UserID,
+		Type:         entryType,
+		Amount:       amount,
+		BalanceAfter: wallet.Balance,
+		Description:  description,
+		Reference:    reference,
+		CreatedAt:    time.Now(),
+	})
+	return err
+}
+
+// creditedFor reports whether userId's wallet has already been credited with reference
+func creditedFor(ctx context.Context, userID primitive.ObjectID, reference string) (bool, error) {
+	err := database.GetCollection("ledger").FindOne(ctx, bson.M{"userId": userID, "type": LedgerCredit, "reference": reference}).Err()
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// StartLedgerBackfill gives every wallet whose balance predates the ledger an opening entry for it,
+// so its statements add up. It runs once, in the background; a wallet that already has one, or whose
+// ledger started from zero, is skipped, so running it on every start is safe.
+func StartLedgerBackfill(ctx context.Context) {
+	go func() {
+		count, err := backfillOpeningBalances(ctx)
+		if err != nil {
+			log.Println("Failed to backfill opening balances:", err)
+			return
+		}
+		if count > 0 {
+			log.Printf("Backfilled opening balances for %d wallets", count)
+		}
+	}()
+}
+
+func backfillOpeningBalances(ctx context.Context) (int, error) {
+	cursor, err := database.GetCollection("wallets").Find(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	count := 0
+	for cursor.Next(ctx) {
+		var wallet Wallet
+		if err := cursor.Decode(&wallet); err != nil {
+			return count, err
+		}
+		added, err := backfillOpeningBalance(ctx, &wallet)
+		if err != nil {
+			return count, err
+		}
+		if added {
+			count++
+		}
+	}
+	return count, cursor.Err()
+}
+
+// backfillOpeningBalance adds wallet's opening entry if it needs one. The wallet must have been
+// read before calling, as for balanceAt.
+func backfillOpeningBalance(ctx context.Context, wallet *Wallet) (bool, error) {
+	ledgerCollection := database.GetCollection("ledger")
+
+	opening, err := balanceAt(ctx, wallet, time.Time{})
+	if err != nil || opening == 0 {
+		return false, err
+	}
+
+	// Date it when the wallet was created, which is before any entry it has
+	createdAt := wallet.ID.Timestamp()
+	var first LedgerEntry
+	err = ledgerCollection.FindOne(ctx,
+		bson.M{"walletId": wallet.ID},
+		options.FindOne().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}),
+	).Decode(&first)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return false, err
+	}
+	if err == nil && !createdAt.Before(first.CreatedAt) {
+		createdAt = first.CreatedAt.Add(-time.Millisecond)
+	}
+
+	entryType, amount := LedgerCredit, opening
+	if opening < 0 {
+		entryType, amount = LedgerDebit, -opening
+	}
+
+	// An upsert, so two instances backfilling at once add only one entry
+	result, err := ledgerCollection.UpdateOne(ctx,
+		bson.M{"walletId": wallet.ID, "reference": openingBalanceReference},
+		bson.M{"$setOnInsert": LedgerEntry{
+			ID:           primitive.NewObjectID(),
+			WalletID:     wallet.ID,
+			UserID:       wallet.UserID,
+			Type:         entryType,
+			Amount:       amount,
+			BalanceAfter: opening,
+			Description:  "Opening balance",
+			Reference:    openingBalanceReference,
+			CreatedAt:    createdAt,
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.UpsertedCount > 0, nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/broker"
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/grpcclient"
+	userPb "github.com/manlikehenryy/loan-management-system-grpc/walletService/user"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EventUserRegistered is recorded by userService when an account is created
+const EventUserRegistered = "UserRegistered"
+
+// provisioningGroup is the broker subscription every walletService instance shares to open
+// wallets for new users
+const provisioningGroup = "walletService.provisioning"
+
+const (
+	walletReconcileInterval = 6 * time.Hour
+	walletReconcilePageSize = 500
+)
+
+// StartWalletProvisioning opens a wallet for every user userService registers until ctx is done.
+// The broker retries an event whose wallet couldn't be created. Users registered before wallets
+// were opened this way, and any whose event the broker gave up on, are caught by a reconciler
+// that runs at startup and every walletReconcileInterval after that.
+func StartWalletProvisioning(ctx context.Context, b broker.Broker) error {
+	if err := b.Subscribe(ctx, provisioningGroup, []string{EventUserRegistered}, provisionWallet); err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			reconcileWallets(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(walletReconcileInterval):
+			}
+		}
+	}()
+	return nil
+}
+
+func provisionWallet(ctx context.Context, event broker.Event) error {
+	userID, err := primitive.ObjectIDFromHex(event.UserID)
+	if err != nil {
+		// Retrying won't help, so the event is acknowledged and dropped
+		log.Printf("Dropping %s event %s: invalid user ID %q", event.Type, event.ID, event.UserID)
+		return nil
+	}
+
+	return createWallet(ctx, userID)
+}
+
+// reconcileWallets pages through every user userService has and opens a wallet for each one
+// that has none
+func reconcileWallets(ctx context.Context) {
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(ctx)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		return
+	}
+	defer cleanup()
+
+	c := grpcclient.NewAuthContext(ctx, configs.Env.TOKEN)
+	walletsCollection := database.GetCollection("wallets")
+
+	afterID, opened := "", 0
+	for ctx.Err() == nil {
+		listUserIdsResp, err := userServiceClient.ListUserIds(c, &userPb.ListUserIdsRequest{AfterId: afterID, Limit: walletReconcilePageSize})
+		if listUserIdsResp == nil || listUserIdsResp.StatusCode != http.StatusOK {
+			log.Println("Error in ListUserIds call:", err, listUserIdsResp.GetMessage())
+			return
+		}
+		if len(listUserIdsResp.UserIds) == 0 {
+			break
+		}
+
+		userIDs := make([]primitive.ObjectID, 0, len(listUserIdsResp.UserIds))
+		for _, id := range listUserIdsResp.UserIds {
+			if userID, err := primitive.ObjectIDFromHex(id); err == nil {
+				userIDs = append(userIDs, userID)
+			}
+		}
+
+		existing, err := walletsCollection.Distinct(ctx, "userId", bson.M{"userId": bson.M{"$in": userIDs}})
+		if err != nil {
+			log.Println("Failed to read wallets:", err)
+			return
+		}
+		hasWallet := make(map[primitive.ObjectID]bool, len(existing))
+		for _, id := range existing {
+			if userID, ok := id.(primitive.ObjectID); ok {
+				hasWallet[userID] = true
+			}
+		}
+
+		for _, userID := range userIDs {
+			if hasWallet[userID] {
+				continue
+			}
+			if err := createWallet(ctx, userID); err != nil {
+				log.Printf("Failed to open wallet for user %s: %v", userID.Hex(), err)
+				continue
+			}
+			opened++
+		}
+
+		afterID = listUserIdsResp.UserIds[len(listUserIdsResp.UserIds)-1]
+		if len(listUserIdsResp.UserIds) < walletReconcilePageSize {
+			break
+		}
+	}
+
+	if opened > 0 {
+		log.Printf("Opened %d missing wallets", opened)
+	}
+}
+
+// createWallet opens an empty wallet for the user unless they already have one, so a repeated
+// event creates it once. The broker hands each event to one instance at a time.
+func createWallet(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := database.GetCollection("wallets").UpdateOne(ctx,
+		bson.M{"userId": userID},
+		bson.M{"$setOnInsert": bson.M{"userId": userID}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/helpers"
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/statement"
+	pb "github.com/manlikehenryy/loan-management-system-grpc/walletService/wallet"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultStatementPeriod = 30 * 24 * time.Hour
+	statementChunkSize     = 32 * 1024
+	statementBatchSize     = 500
+	statementDateLayout    = "2006-01-02"
+)
+
+// GetStatement streams a wallet's statement for a period: its opening balance, the ledger entries
+// made in the period, and its closing balance. The document is rendered as entries are read and
+// sent in chunks, so a long period never has to fit in memory.
+func (s *WalletServiceServer) GetStatement(req *pb.GetStatementRequest, stream grpc.ServerStreamingServer[pb.StatementChunk]) error {
+	ctx := stream.Context()
+
+	principal, ok := helpers.PrincipalFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "No identity provided")
+	}
+
+	userId := req.GetUserId()
+	if userId == "" {
+		userId = principal.UserId
+	}
+	userID, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "Invalid user ID")
+	}
+
+	format := strings.ToLower(req.GetFormat())
+	if format == "" {
+		format = statement.FormatJSON
+	}
+	out := bufio.NewWriterSize(statementChunkWriter{stream}, statementChunkSize)
+	writer, err := statement.NewWriter(format, out)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "Format must be json, csv or pdf")
+	}
+
+	now := time.Now().UTC()
+	to := now
+	if req.GetTo() != "" {
+		if to, err = parseStatementTime(req.GetTo(), true); err != nil {
+			return status.Error(codes.InvalidArgument, "Invalid to date; use YYYY-MM-DD or RFC 3339")
+		}
+	}
+	from := to.Add(-defaultStatementPeriod)
+	if req.GetFrom() != "" {
+		if from, err = parseStatementTime(req.GetFrom(), false); err != nil {
+			return status.Error(codes.InvalidArgument, "Invalid from date; use YYYY-MM-DD or RFC 3339")
+		}
+	}
+	if !from.Before(to) {
+		return status.Error(codes.InvalidArgument, "from must be before to")
+	}
+
+	if userId != principal.UserId {
+		if message, statusCode := checkPermission(ctx, principal.UserId, PermissionWalletViewAny); statusCode != http.StatusOK {
+			return status.Error(codes.PermissionDenied, message)
+		}
+	}
+
+	wallet, err := findWallet(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	openingBalance, err := balanceAt(ctx, wallet, from)
+	if err != nil {
+		log.Println("Database error:", err)
+		return status.Error(codes.Internal, "Failed to read statement")
+	}
+
+	cursor, err := database.GetCollection("ledger").Find(ctx,
+		bson.M{"walletId": wallet.ID, "createdAt": bson.M{"$gte": from, "$lt": to}},
+		options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}).SetBatchSize(statementBatchSize),
+	)
+	if err != nil {
+		log.Println("Database error:", err)
+		return status.Error(codes.Internal, "Failed to read statement")
+	}
+	defer cursor.Close(context.Background())
+
+	// Describe the document first, so the caller can set headers before any content arrives
+	filename := "statement-" + from.Format("20060102") + "-" + to.Format("20060102") + "." + writer.Extension()
+	if err := stream.Send(&pb.StatementChunk{ContentType: writer.ContentType(), Filename: filename}); err != nil {
+		return err
+	}
+
+	err = writer.Begin(statement.Header{UserID: userId, From: from, To: to, OpeningBalance: openingBalance, GeneratedAt: now})
+	if err != nil {
+		return err
+	}
+
+	footer := statement.Footer{ClosingBalance: openingBalance}
+	for cursor.Next(ctx) {
+		var entry LedgerEntry
+		if err := cursor.Decode(&entry); err != nil {
+			log.Println("Failed to decode ledger entry:", err)
+			return status.Error(codes.Internal, "Failed to read statement")
+		}
+
+		err := writer.Line(statement.Line{
+			ID:          entry.ID.Hex(),
+			Date:        entry.CreatedAt,
+			Type:        entry.Type,
+			Description: entry.Description,
+			Reference:   entry.Reference,
+			Amount:      entry.Amount,
+			Balance:     entry.BalanceAfter,
+		})
+		if err != nil {
+			return err
+		}
+
+		if entry.Type == LedgerDebit {
+			footer.TotalDebits += entry.Amount
+		} else {
+			footer.TotalCredits += entry.Amount
+		}
+		footer.ClosingBalance = entry.BalanceAfter
+	}
+	if err := cursor.Err(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Println("Database error:", err)
+		return status.Error(codes.Internal, "Failed to read statement")
+	}
+
+	if err := writer.End(footer); err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+// balanceAt works out what wallet's ledger balance was at t. Each entry records the balance it
+// left, so the first entry from t on gives it away. If there is none, nothing has changed since t.
+// The wallet must have been read before calling, so an entry made in between is found.
+func balanceAt(ctx context.Context, wallet *Wallet, t time.Time) (float32, error) {
+	var entry LedgerEntry
+	err := database.GetCollection("ledger").FindOne(ctx,
+		bson.M{"walletId": wallet.ID, "createdAt": bson.M{"$gte": t}},
+		options.FindOne().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}),
+	).Decode(&entry)
+	if err == mongo.ErrNoDocuments {
+		return wallet.Balance, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return entry.BalanceAfter - entry.Change(), nil
+}
+
+// parseStatementTime reads a date or an RFC 3339 time. A date means the start of that day in UTC,
+// or the end of it if end is set.
+func parseStatementTime(value string, end bool) (time.Time, error) {
+	if day, err := time.Parse(statementDateLayout, value); err == nil {
+		if end {
+			return day.AddDate(0, 0, 1), nil
+		}
+		return day, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t.UTC(), err
+}
+
+// statementChunkWriter sends everything written to it as statement chunks
+type statementChunkWriter struct {
+	stream grpc.ServerStreamingServer[pb.StatementChunk]
+}
+
+func (w statementChunkWriter) Write(p []byte) (int, error) {
+	// Send marshals the chunk before returning, so p can be reused afterwards
+	if err := w.stream.Send(&pb.StatementChunk{Data: p}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,368 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/helpers"
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/payment"
+	pb "github.com/manlikehenryy/loan-management-system-grpc/walletService/wallet"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Top-up statuses
+const (
+	TopUpPending   = "pending"
+	TopUpSucceeded = "succeeded"
+	TopUpFailed    = "failed"
+)
+
+const (
+	maxTopUpAmount         = 1000000
+	topUpExpiry            = time.Hour // How long a top-up waits for its webhook before the provider is asked
+	topUpReconcileInterval = 5 * time.Minute
+)
+
+var errAmountMismatch = errors.New("webhook amount doesn't match")
+
+// TopUp is a payment into a wallet through the payment provider. Its ID is the reference the
+// provider reports back, and it moves out of pending exactly once, when the first webhook about
+// it is processed.
+type TopUp struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty"`
+	WalletID          primitive.ObjectID `bson:"walletId"`
+	UserID            primitive.ObjectID `bson:"userId"`
+	Amount            float32            `bson:"amount"`
+	Provider          string             `bson:"provider"`
+	ProviderReference string             `bson:"providerReference,omitempty"`
+	CheckoutURL       string             `bson:"checkoutUrl,omitempty"`
+	ProviderEventID   string             `bson:"providerEventId,omitempty"` // The webhook that completed it
+	Status            string             `bson:"status"`
+	CreatedAt         time.Time          `bson:"createdAt"`
+	ExpiresAt         time.Time          `bson:"expiresAt"` // When the provider is asked about it, if it's still pending
+	CompletedAt       *time.Time         `bson:"completedAt,omitempty"`
+}
+
+func (s *WalletServiceServer) InitiateTopUp(ctx context.Context, req *pb.InitiateTopUpRequest) (*pb.InitiateTopUpResponse, error) {
+	principal, ok := helpers.PrincipalFromContext(ctx)
+	if !ok {
+		return initiateTopUpErrorResponse("No identity provided", http.StatusUnauthorized), nil
+	}
+	userID, err := primitive.ObjectIDFromHex(principal.UserId)
+	if err != nil {
+		return initiateTopUpErrorResponse("Invalid user ID", http.StatusBadRequest), nil
+	}
+
+	if req.GetAmount() <= 0 || req.GetAmount() > maxTopUpAmount {
+		return initiateTopUpErrorResponse("Amount must be greater than zero and at most 1,000,000", http.StatusBadRequest), nil
+	}
+
+	topUpsCollection := database.GetCollection("topups")
+
+	var wallet Wallet
+	err = database.GetCollection("wallets").FindOne(ctx, bson.M{"userId": userID}).Decode(&wallet)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return initiateTopUpErrorResponse("Wallet not found", http.StatusNotFound), nil
+		}
+		log.Println("Database error:", err)
+		return initiateTopUpErrorResponse("Failed to start top-up", http.StatusInternalServerError), nil
+	}
+
+	now := time.Now()
+	// Saved before the checkout starts, so a webhook that arrives straight away finds it
+	topUp := TopUp{
+		ID:        primitive.NewObjectID(),
+		WalletID:  wallet.ID,
+		UserID:    userID,
+		Amount:    req.GetAmount(),
+		Provider:  payment.Name(),
+		Status:    TopUpPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(topUpExpiry),
+	}
+	if _, err := topUpsCollection.InsertOne(ctx, topUp); err != nil {
+		log.Println("Database error:", err)
+		return initiateTopUpErrorResponse("Failed to start top-up", http.StatusInternalServerError), nil
+	}
+
+	session, err := payment.CreateCheckout(ctx, payment.Checkout{Reference: topUp.ID.Hex(), UserID: principal.UserId, Amount: topUp.Amount})
+	if err != nil {
+		// The provider may have created the charge before the call failed, so the top-up is left
+		// pending for its webhook, or the reconciler once it expires, to decide
+		log.Printf("Failed to create checkout for top-up %s: %v", topUp.ID.Hex(), err)
+		return initiateTopUpErrorResponse("Payment provider unavailable", http.StatusBadGateway), nil
+	}
+
+	topUp.ProviderReference = session.ProviderReference
+	topUp.CheckoutURL = session.CheckoutURL
+	_, err = topUpsCollection.UpdateOne(ctx,
+		bson.M{"_id": topUp.ID},
+		bson.M{"$set": bson.M{"providerReference": topUp.ProviderReference, "checkoutUrl": topUp.CheckoutURL}},
+	)
+	if err != nil {
+		log.Println("Database error:", err)
+		return initiateTopUpErrorResponse("Failed to start top-up", http.StatusInternalServerError), nil
+	}
+
+	return &pb.InitiateTopUpResponse{
+		TopUp:      topUpDetails(&topUp),
+		Message:    "Top-up started successfully",
+		Status:     true,
+		StatusCode: http.StatusCreated,
+	}, nil
+}
+
+func (s *WalletServiceServer) GetTopUp(ctx context.Context, req *pb.GetTopUpRequest) (*pb.GetTopUpResponse, error) {
+	principal, ok := helpers.PrincipalFromContext(ctx)
+	if !ok {
+		return getTopUpErrorResponse("No identity provided", http.StatusUnauthorized), nil
+	}
+
+	topUpID, err := primitive.ObjectIDFromHex(req.GetTopUpId())
+	if err != nil {
+		return getTopUpErrorResponse("Invalid top-up ID", http.StatusBadRequest), nil
+	}
+
+	var topUp TopUp
+	err = database.GetCollection("topups").FindOne(ctx, bson.M{"_id": topUpID}).Decode(&topUp)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return getTopUpErrorResponse("Top-up not found", http.StatusNotFound), nil
+		}
+		log.Println("Database error:", err)
+		return getTopUpErrorResponse("Failed to read top-up", http.StatusInternalServerError), nil
+	}
+
+	if topUp.UserID.Hex() != principal.UserId {
+		if message, statusCode := checkPermission(ctx, principal.UserId, PermissionWalletViewAny); statusCode != http.StatusOK {
+			return getTopUpErrorResponse(message, statusCode), nil
+		}
+	}
+
+	return &pb.GetTopUpResponse{
+		TopUp:      topUpDetails(&topUp),
+		Message:    "Top-up retrieved successfully",
+		Status:     true,
+		StatusCode: http.StatusOK,
+	}, nil
+}
+
+// HandlePaymentWebhook completes the top-up or withdrawal a verified webhook reports on. Providers
+// send a webhook again until it succeeds, so a top-up that is already complete is acknowledged
+// without being credited again, and likewise for a withdrawal that already has the status reported.
+func (s *WalletServiceServer) HandlePaymentWebhook(ctx context.Context, req *pb.HandlePaymentWebhookRequest) (*pb.HandlePaymentWebhookResponse, error) {
+	notification, err := payment.ParseWebhook(req.GetProvider(), req.GetPayload(), req.GetHeaders())
+	if err != nil {
+		switch {
+		case errors.Is(err, payment.ErrUnknownProvider):
+			return handlePaymentWebhookErrorResponse("Unknown payment provider", http.StatusNotFound), nil
+		case errors.Is(err, payment.ErrInvalidSignature):
+			log.Printf("Rejected %s webhook with an invalid signature", req.GetProvider())
+			return handlePaymentWebhookErrorResponse("Invalid signature", http.StatusUnauthorized), nil
+		}
+		log.Println("Invalid webhook:", err)
+		return handlePaymentWebhookErrorResponse("Invalid webhook", http.StatusBadRequest), nil
+	}
+
+	if notification.Kind == payment.KindPayout {
+		return handlePayoutWebhook(ctx, notification), nil
+	}
+
+	topUpID, err := primitive.ObjectIDFromHex(notification.Reference)
+	if err != nil {
+		log.Printf("Webhook %s has an unknown reference %q", notification.EventID, notification.Reference)
+		return handlePaymentWebhookErrorResponse("Top-up not found", http.StatusNotFound), nil
+	}
+
+	completed, err := completeTopUp(ctx, topUpID, notification)
+	if err != nil {
+		switch {
+		case err == mongo.ErrNoDocuments:
+			log.Printf("Webhook %s has an unknown reference %q", notification.EventID, notification.Reference)
+			return handlePaymentWebhookErrorResponse("Top-up not found", http.StatusNotFound), nil
+		case errors.Is(err, errAmountMismatch):
+			log.Printf("Webhook %s is for a different amount than top-up %s", notification.EventID, notification.Reference)
+			return handlePaymentWebhookErrorResponse("Amount doesn't match the top-up", http.StatusBadRequest), nil
+		}
+		log.Println("Database error:", err)
+		return handlePaymentWebhookErrorResponse("Failed to process webhook", http.StatusInternalServerError), nil
+	}
+
+	if !completed {
+		return handlePaymentWebhookSuccessResponse("Webhook already processed", http.StatusOK), nil
+	}
+	return handlePaymentWebhookSuccessResponse("Webhook processed successfully", http.StatusOK), nil
+}
+
+// completeTopUp moves a pending top-up to the outcome in notification, crediting the wallet if
+// the payment succeeded. It returns false if the top-up was already complete.
+func completeTopUp(ctx context.Context, topUpID primitive.ObjectID, notification payment.Notification) (bool, error) {
+	topUpsCollection := database.GetCollection("topups")
+
+	completed := false
+	err := database.WithTransaction(ctx, func(ctx mongo.SessionContext) error {
+		completed = false
+
+		var topUp TopUp
+		if err := topUpsCollection.FindOne(ctx, bson.M{"_id": topUpID}).Decode(&topUp); err != nil {
+			return err
+		}
+		if topUp.Status != TopUpPending {
+			return nil
+		}
+		if notification.Status == payment.PaymentSucceeded && notification.Amount != topUp.Amount {
+			return errAmountMismatch
+		}
+
+		status := TopUpFailed
+		if notification.Status == payment.PaymentSucceeded {
+			status = TopUpSucceeded
+		}
+
+		// Two deliveries of the same webhook both write the top-up, so one of them is retried
+		// and finds it complete
+		result, err := topUpsCollection.UpdateOne(ctx,
+			bson.M{"_id": topUpID, "status": TopUpPending},
+			bson.M{"$set": bson.M{"status": status, "providerEventId": notification.EventID, "completedAt": time.Now()}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.ModifiedCount == 0 {
+			return nil
+		}
+		completed = true
+
+		if status == TopUpFailed {
+			return recordWalletEvent(ctx, EventTopUpFailed, topUp.WalletID.Hex(), topUp.UserID.Hex(), map[string]string{
+				"topUpId": topUp.ID.Hex(),
+				"amount":  formatAmount(topUp.Amount),
+			})
+		}
+
+		var wallet Wallet
+		err = database.GetCollection("wallets").FindOneAndUpdate(ctx,
+			bson.M{"_id": topUp.WalletID},
+			bson.M{"$inc": bson.M{"balance": topUp.Amount}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&wallet)
+		if err != nil {
+			return err
+		}
+
+		if err := recordLedgerEntry(ctx, &wallet, LedgerCredit, topUp.Amount, "Top-up", topUp.ID.Hex()); err != nil {
+			return err
+		}
+		return recordWalletEvent(ctx, EventWalletCredited, wallet.ID.Hex(), topUp.UserID.Hex(), map[string]string{
+			"amount":           formatAmount(topUp.Amount),
+			"balance":          formatAmount(wallet.Balance),
+			"availableBalance": formatAmount(wallet.Available()),
+			"topUpId":          topUp.ID.Hex(),
+		})
+	})
+	return completed, err
+}
+
+// StartTopUpReconciler settles top-ups whose webhook never arrived. Every topUpReconcileInterval,
+// the provider is asked about each top-up still pending past its expiry.
+func StartTopUpReconciler(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(topUpReconcileInterval):
+			}
+
+			reconcileTopUps(ctx, time.Now())
+		}
+	}()
+}
+
+// reconcileTopUps applies the provider's answer to each expired top-up. One the provider has no
+// record of fails, since no money was collected for it; one the user still hasn't paid is asked
+// about again next time.
+func reconcileTopUps(ctx context.Context, now time.Time) {
+	cursor, err := database.GetCollection("topups").Find(ctx, bson.M{"status": TopUpPending, "$or": []bson.M{
+		{"expiresAt": bson.M{"$lte": now}},
+		// Started before top-ups had an expiry
+		{"expiresAt": nil, "createdAt": bson.M{"$lte": now.Add(-topUpExpiry)}},
+	}})
+	if err != nil {
+		log.Println("Failed to read top-ups:", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var topUp TopUp
+		if err := cursor.Decode(&topUp); err != nil {
+			log.Println("Failed to decode top-up:", err)
+			continue
+		}
+
+		notification, err := payment.CheckoutStatus(ctx, topUp.ID.Hex())
+		switch {
+		case errors.Is(err, payment.ErrCheckoutNotFound):
+			notification = payment.Notification{Kind: payment.KindCheckout, Reference: topUp.ID.Hex(), Status: payment.PaymentFailed, Amount: topUp.Amount}
+		case err != nil:
+			log.Printf("Failed to check checkout for top-up %s: %v", topUp.ID.Hex(), err)
+			continue
+		case notification.Status == payment.PaymentPending:
+			continue
+		}
+
+		completed, err := completeTopUp(ctx, topUp.ID, notification)
+		if err != nil {
+			log.Printf("Failed to settle top-up %s as %s: %v", topUp.ID.Hex(), notification.Status, err)
+			continue
+		}
+		if completed {
+			log.Printf("Top-up %s settled as %s from the provider's checkout status", topUp.ID.Hex(), notification.Status)
+		}
+	}
+}
+
+func topUpDetails(topUp *TopUp) *pb.TopUp {
+	details := &pb.TopUp{
+		Id:                topUp.ID.Hex(),
+		UserId:            topUp.UserID.Hex(),
+		Amount:            topUp.Amount,
+		Provider:          topUp.Provider,
+		ProviderReference: topUp.ProviderReference,
+		CheckoutUrl:       topUp.CheckoutURL,
+		Status:            topUp.Status,
+		CreatedAt:         topUp.CreatedAt.Format(time.RFC3339),
+	}
+	if topUp.CompletedAt != nil {
+		details.CompletedAt = topUp.CompletedAt.Format(time.RFC3339)
+	}
+	return details
+}
+
+// Error Response function for InitiateTopUp
+func initiateTopUpErrorResponse(message string, statusCode int) *pb.InitiateTopUpResponse {
+	return &pb.InitiateTopUpResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+// Error Response function for GetTopUp
+func getTopUpErrorResponse(message string, statusCode int) *pb.GetTopUpResponse {
+	return &pb.GetTopUpResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+// Success and Error Response functions for HandlePaymentWebhook
+func handlePaymentWebhookSuccessResponse(message string, statusCode int) *pb.HandlePaymentWebhookResponse {
+	return &pb.HandlePaymentWebhookResponse{Message: message, Status: true, StatusCode: int32(statusCode)}
+}
+
+func handlePaymentWebhookErrorResponse(message string, statusCode int) *pb.HandlePaymentWebhookResponse {
+	return &pb.HandlePaymentWebhookResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/payment"
+	pb "github.com/manlikehenryy/loan-management-system-grpc/walletService/wallet"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const testWebhookSecret = "test-webhook-secret"
+
+var setUpTestDatabase sync.Once
+
+// testDatabase points the service at a fresh database on the MongoDB replica set in MONGO_TEST_URI,
+// dropped when the test ends, and sets up the simulator. Tests that need it are skipped without one.
+func testDatabase(t *testing.T) {
+	t.Helper()
+
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI is not set; these tests need a MongoDB replica set")
+	}
+
+	setUpTestDatabase.Do(func() {
+		configs.Env.MONGO_DB_URI = uri
+		configs.Env.PAYMENT_PROVIDER = "simulator"
+		configs.Env.PAYMENT_WEBHOOK_SECRET = testWebhookSecret
+		database.Connect()
+		payment.Initialize()
+	})
+
+	database.DB = database.Client.Database("wallet_service_test_" + primitive.NewObjectID().Hex())
+	t.Cleanup(func() {
+		if err := database.DB.Drop(context.Background()); err != nil {
+			t.Log("Failed to drop test database:", err)
+		}
+	})
+}
+
+// createTestWallet opens an empty wallet for a new user and returns it
+func createTestWallet(t *testing.T) Wallet {
+	t.Helper()
+
+	userID := primitive.NewObjectID()
+	if err := createWallet(context.Background(), userID); err != nil {
+		t.Fatal(err)
+	}
+
+	var wallet Wallet
+	if err := database.GetCollection("wallets").FindOne(context.Background(), bson.M{"userId": userID}).Decode(&wallet); err != nil {
+		t.Fatal(err)
+	}
+	return wallet
+}
+
+// walletState returns a wallet's balance and the number of ledger credits made with reference
+func walletState(t *testing.T, walletID primitive.ObjectID, reference string) (float32, int64) {
+	t.Helper()
+	ctx := context.Background()
+
+	var wallet Wallet
+	if err := database.GetCollection("wallets").FindOne(ctx, bson.M{"_id": walletID}).Decode(&wallet); err != nil {
+		t.Fatal(err)
+	}
+	credits, err := database.GetCollection("ledger").CountDocuments(ctx, bson.M{"walletId": walletID, "type": LedgerCredit, "reference": reference})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wallet.Balance, credits
+}
+
+// simulatorWebhook returns a simulator webhook body and its headers, signed as of signedAt
+func simulatorWebhook(eventID string, eventType string, reference string, amount float32, signedAt time.Time) ([]byte, map[string]string) {
+	payload := []byte(`{"id":"` + eventID + `","type":"` + eventType + `","data":{"reference":"` + reference + `","amount":` + strconv.FormatFloat(float64(amount), 'f', -1, 32) + `}}`)
+
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+
+	return payload, map[string]string{"x-simulator-signature": "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))}
+}
+
+func TestHandlePaymentWebhookReplays(t *testing.T) {
+	testDatabase(t)
+	ctx := context.Background()
+	server := NewWalletServiceServer()
+
+	type delivery struct {
+		eventID   string
+		eventType string
+		amount    float32
+		signedAt  time.Duration // Relative to now
+		replay    bool          // Sends the previous delivery's body and headers again
+		want      int
+	}
+
+	tests := []struct {
+		name        string
+		deliveries  []delivery
+		wantStatus  string
+		wantBalance float32
+		wantCredits int64
+	}{
+		{
+			name: "same webhook delivered twice",
+			deliveries: []delivery{
+				{eventID: "evt_1", eventType: "payment.succeeded", amount: 2500, want: http.StatusOK},
+				{replay: true, want: http.StatusOK},
+			},
+			wantStatus: TopUpSucceeded, wantBalance: 2500, wantCredits: 1,
+		},
+		{
+			name: "second success under another event ID",
+			deliveries: []delivery{
+				{eventID: "evt_1", eventType: "payment.succeeded", amount: 2500, want: http.StatusOK},
+				{eventID: "evt_2", eventType: "payment.succeeded", amount: 2500, want: http.StatusOK},
+			},
+			wantStatus: TopUpSucceeded, wantBalance: 2500, wantCredits: 1,
+		},
+		{
+			name: "success after the top-up failed",
+			deliveries: []delivery{
+				{eventID: "evt_1", eventType: "payment.failed", amount: 2500, want: http.StatusOK},
+				{eventID: "evt_2", eventType: "payment.succeeded", amount: 2500, want: http.StatusOK},
+			},
+			wantStatus: TopUpFailed, wantBalance: 0, wantCredits: 0,
+		},
+		{
+			name: "replayed after the signature expired",
+			deliveries: []delivery{
+				{eventID: "evt_1", eventType: "payment.succeeded", amount: 2500, signedAt: -10 * time.Minute, want: http.StatusUnauthorized},
+			},
+			wantStatus: TopUpPending, wantBalance: 0, wantCredits: 0,
+		},
+		{
+			name: "success for a different amount",
+			deliveries: []delivery{
+				{eventID: "evt_1", eventType: "payment.succeeded", amount: 9999, want: http.StatusBadRequest},
+			},
+			wantStatus: TopUpPending, wantBalance: 0, wantCredits: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wallet := createTestWallet(t)
+			topUp := TopUp{
+				ID:        primitive.NewObjectID(),
+				WalletID:  wallet.ID,
+				UserID:    wallet.UserID,
+				Amount:    2500,
+				Provider:  "simulator",
+				Status:    TopUpPending,
+				CreatedAt: time.Now(),
+			}
+			if _, err := database.GetCollection("topups").InsertOne(ctx, topUp); err != nil {
+				t.Fatal(err)
+			}
+
+			var payload []byte
+			var headers map[string]string
+			for i, d := range tt.deliveries {
+				if !d.replay {
+					payload, headers = simulatorWebhook(d.eventID, d.eventType, topUp.ID.Hex(), d.amount, time.Now().Add(d.signedAt))
+				}
+
+				resp, err := server.HandlePaymentWebhook(ctx, &pb.HandlePaymentWebhookRequest{Provider: "simulator", Payload: payload, Headers: headers})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if int(resp.StatusCode) != d.want {
+					t.Errorf("delivery %d: got status %d (%s), want %d", i+1, resp.StatusCode, resp.Message, d.want)
+				}
+			}
+
+			var stored TopUp
+			if err := database.GetCollection("topups").FindOne(ctx, bson.M{"_id": topUp.ID}).Decode(&stored); err != nil {
+				t.Fatal(err)
+			}
+			if stored.Status != tt.wantStatus {
+				t.Errorf("got top-up status %s, want %s", stored.Status, tt.wantStatus)
+			}
+
+			balance, credits := walletState(t, wallet.ID, topUp.ID.Hex())
+			if balance != tt.wantBalance || credits != tt.wantCredits {
+				t.Errorf("got balance %.2f with %d credits, want %.2f with %d", balance, credits, tt.wantBalance, tt.wantCredits)
+			}
+		})
+	}
+}
+
+func TestReconcileTopUps(t *testing.T) {
+	testDatabase(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		createdAt  time.Time
+		expiresAt  time.Time
+		wantStatus string
+	}{
+		{name: "expired and unknown to the provider", createdAt: now.Add(-2 * time.Hour), expiresAt: now.Add(-time.Hour), wantStatus: TopUpFailed},
+		{name: "not expired yet", createdAt: now, expiresAt: now.Add(topUpExpiry), wantStatus: TopUpPending},
+		{name: "started before top-ups expired", createdAt: now.Add(-2 * topUpExpiry), wantStatus: TopUpFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wallet := createTestWallet(t)
+			topUp := bson.M{
+				"_id":       primitive.NewObjectID(),
+				"walletId":  wallet.ID,
+				"userId":    wallet.UserID,
+				"amount":    float32(2500),
+				"provider":  "simulator",
+				"status":    TopUpPending,
+				"createdAt": tt.createdAt,
+			}
+			if !tt.expiresAt.IsZero() {
+				topUp["expiresAt"] = tt.expiresAt
+			}
+			if _, err := database.GetCollection("topups").InsertOne(ctx, topUp); err != nil {
+				t.Fatal(err)
+			}
+			topUpID := topUp["_id"].(primitive.ObjectID)
+
+			reconcileTopUps(ctx, now)
+
+			var stored TopUp
+			if err := database.GetCollection("topups").FindOne(ctx, bson.M{"_id": topUpID}).Decode(&stored); err != nil {
+				t.Fatal(err)
+			}
+			if stored.Status != tt.wantStatus {
+				t.Errorf("got top-up status %s, want %s", stored.Status, tt.wantStatus)
+			}
+
+			balance, credits := walletState(t, wallet.ID, topUpID.Hex())
+			if balance != 0 || credits != 0 {
+				t.Errorf("got balance %.2f with %d credits, want nothing credited", balance, credits)
+			}
+		})
+	}
+}
@@ -2,19 +2,29 @@ package service
 
 import (
     "context"
+    "errors"
     "log"
     "net/http"
 
     "github.com/manlikehenryy/loan-management-system-grpc/walletService/database"
+    "github.com/manlikehenryy/loan-management-system-grpc/walletService/helpers"
     pb "github.com/manlikehenryy/loan-management-system-grpc/walletService/wallet"
     "go.mongodb.org/mongo-driver/bson"
     "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type Wallet struct {
     ID      primitive.ObjectID `bson:"_id,omitempty"`
     UserID  primitive.ObjectID `bson:"userId,omitempty"`
-    Balance float32            `bson:"balance,omitempty"`
+    Balance float32            `bson:"balance,omitempty"` // The ledger balance, including held funds
+    Held    float32            `bson:"held,omitempty"`    // The total of active holds
+}
+
+// Available returns the part of the balance that isn't held
+func (w *Wallet) Available() float32 {
+    return w.Balance - w.Held
 }
 
 type WalletServiceServer struct {
@@ -25,53 +35,181 @@ func NewWalletServiceServer() *WalletServiceServer {
     return &WalletServiceServer{}
 }
 
-func (s *WalletServiceServer) CreateWallet(ctx context.Context, req *pb.CreateWalletRequest) (*pb.CreateWalletResponse, error) {
+func (s *WalletServiceServer) CreditWallet(ctx context.Context, req *pb.CreditWalletRequest) (*pb.CreditWalletResponse, error) {
     walletsCollection := database.GetCollection("wallets")
 
     userID, err := primitive.ObjectIDFromHex(req.GetUserId())
     if err != nil {
-        return createWalletErrorResponse("Invalid user ID", http.StatusBadRequest), nil
+        return creditWalletErrorResponse("Invalid user ID", http.StatusBadRequest), nil
+    }
+    if req.GetAmount() <= 0 {
+        return creditWalletErrorResponse("Amount must be greater than zero", http.StatusBadRequest), nil
     }
 
-    wallet := Wallet{UserID: userID, Balance: 0}
-    _, err = walletsCollection.InsertOne(ctx, wallet)
+    alreadyCredited := false
+    err = database.WithTransaction(ctx, func(ctx mongo.SessionContext) error {
+        // A retried credit finds the ledger entry of the first one. Two at once both update the
+        // wallet, so one of them hits a write conflict and is retried, and then finds it.
+        if req.GetReference() != "" {
+            credited, err := creditedFor(ctx, userID, req.GetReference())
+            if err != nil || credited {
+                alreadyCredited = credited
+                return err
+            }
+        }
+
+        var wallet Wallet
+        err := walletsCollection.FindOneAndUpdate(
+            ctx,
+            bson.M{"userId": userID},
+            bson.M{"$inc": bson.M{"balance": req.GetAmount()}},
+            options.FindOneAndUpdate().SetReturnDocument(options.After),
+        ).Decode(&wallet)
+        if err != nil {
+            return err
+        }
+        if err := recordLedgerEntry(ctx, &wallet, LedgerCredit, req.GetAmount(), "Credit", req.GetReference()); err != nil {
+            return err
+        }
+
+        data := map[string]string{
+            "amount":           formatAmount(req.GetAmount()),
+            "balance":          formatAmount(wallet.Balance),
+            "availableBalance": formatAmount(wallet.Available()),
+        }
+        if req.GetReference() != "" {
+            data["reference"] = req.GetReference()
+        }
+        return recordWalletEvent(ctx, EventWalletCredited, wallet.ID.Hex(), userID.Hex(), data)
+    })
     if err != nil {
-        return createWalletErrorResponse("Wallet creation failed", http.StatusInternalServerError), nil
+        if err == mongo.ErrNoDocuments {
+            return creditWalletErrorResponse("Wallet not found", http.StatusNotFound), nil
+        }
+        log.Println("Database error:", err)
+        return creditWalletErrorResponse("Failed to credit wallet", http.StatusInternalServerError), nil
     }
-    return createWalletSuccessResponse("Wallet created successfully", http.StatusOK), nil
+
+    if alreadyCredited {
+        return creditWalletSuccessResponse("Wallet already credited for this reference", http.StatusOK), nil
+    }
+    return creditWalletSuccessResponse("Wallet credited successfully", http.StatusOK), nil
 }
 
-func (s *WalletServiceServer) CreditWallet(ctx context.Context, req *pb.CreditWalletRequest) (*pb.CreditWalletResponse, error) {
-    walletsCollection := database.GetCollection("wallets")
+func (s *WalletServiceServer) GetBalance(ctx context.Context, req *pb.GetBalanceRequest) (*pb.GetBalanceResponse, error) {
+    principal, ok := helpers.PrincipalFromContext(ctx)
+    if !ok {
+        return getBalanceErrorResponse("No identity provided", http.StatusUnauthorized), nil
+    }
 
-    userID, err := primitive.ObjectIDFromHex(req.GetUserId())
+    userId := req.GetUserId()
+    if userId == "" {
+        userId = principal.UserId
+    }
+    userID, err := primitive.ObjectIDFromHex(userId)
     if err != nil {
-        return creditWalletErrorResponse("Invalid user ID", http.StatusBadRequest), nil
+        return getBalanceErrorResponse("Invalid user ID", http.StatusBadRequest), nil
+    }
+
+    if userId != principal.UserId {
+        if message, statusCode := checkPermission(ctx, principal.UserId, PermissionWalletViewAny); statusCode != http.StatusOK {
+            return getBalanceErrorResponse(message, statusCode), nil
+        }
     }
 
-    updateResult, err := walletsCollection.UpdateOne(
-        ctx,
-        bson.M{"userId": userID},
-        bson.M{"$inc": bson.M{"balance": req.GetAmount()}},
-    )
+    var wallet Wallet
+    err = database.GetCollection("wallets").FindOne(ctx, bson.M{"userId": userID}).Decode(&wallet)
     if err != nil {
+        if err == mongo.ErrNoDocuments {
+            return getBalanceErrorResponse("Wallet not found", http.StatusNotFound), nil
+        }
         log.Println("Database error:", err)
-        return creditWalletErrorResponse("Failed to credit wallet", http.StatusInternalServerError), nil
-    }
-    if updateResult.MatchedCount == 0 {
-        return creditWalletErrorResponse("Wallet not found", http.StatusNotFound), nil
+        return getBalanceErrorResponse("Failed to read wallet", http.StatusInternalServerError), nil
     }
 
-    return creditWalletSuccessResponse("Wallet credited successfully", http.StatusOK), nil
+    return &pb.GetBalanceResponse{
+        LedgerBalance:    wallet.Balance,
+        AvailableBalance: wallet.Available(),
+        HeldBalance:      wallet.Held,
+        Message:          "Balance retrieved successfully",
+        Status:           true,
+        StatusCode:       http.StatusOK,
+    }, nil
 }
 
-// Success and Error Response functions for CreateWallet
-func createWalletSuccessResponse(message string, statusCode int) *pb.CreateWalletResponse {
-    return &pb.CreateWalletResponse{Message: message, Status: true, StatusCode: int32(statusCode)}
-}
+// DebitWallet takes amount out of the wallet. Held funds can't be debited; capture the hold instead.
+// DebitWallet lets staff take funds from a user's wallet, such as a fee. Each debit is kept in the
+// audit log.
+func (s *WalletServiceServer) DebitWallet(ctx context.Context, req *pb.DebitWalletRequest) (*pb.DebitWalletResponse, error) {
+    actorID, message, statusCode := authorizeActor(ctx, PermissionWalletAdjust)
+    if statusCode != http.StatusOK {
+        return debitWalletErrorResponse(message, statusCode), nil
+    }
+
+    walletsCollection := database.GetCollection("wallets")
 
-func createWalletErrorResponse(message string, statusCode int) *pb.CreateWalletResponse {
-    return &pb.CreateWalletResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+    userID, err := primitive.ObjectIDFromHex(req.GetUserId())
+    if err != nil {
+        return debitWalletErrorResponse("Invalid user ID", http.StatusBadRequest), nil
+    }
+    if req.GetAmount() <= 0 {
+        return debitWalletErrorResponse("Amount must be greater than zero", http.StatusBadRequest), nil
+    }
+
+    var wallet Wallet
+    err = database.WithTransaction(ctx, func(ctx mongo.SessionContext) error {
+        err := walletsCollection.FindOneAndUpdate(
+            ctx,
+            bson.M{"userId": userID, "$expr": availableAtLeast(req.GetAmount())},
+            bson.M{"$inc": bson.M{"balance": -req.GetAmount()}},
+            options.FindOneAndUpdate().SetReturnDocument(options.After),
+        ).Decode(&wallet)
+        if err == mongo.ErrNoDocuments {
+            return walletMissingOr(ctx, userID, errInsufficientFunds)
+        }
+        if err != nil {
+            return err
+        }
+        if err := recordLedgerEntry(ctx, &wallet, LedgerDebit, req.GetAmount(), "Debit", req.GetReference()); err != nil {
+            return err
+        }
+
+        data := map[string]string{
+            "amount":           formatAmount(req.GetAmount()),
+            "balance":          formatAmount(wallet.Balance),
+            "availableBalance": formatAmount(wallet.Available()),
+        }
+        if req.GetReference() != "" {
+            data["reference"] = req.GetReference()
+        }
+        if err := recordWalletEvent(ctx, EventWalletDebited, wallet.ID.Hex(), userID.Hex(), data); err != nil {
+            return err
+        }
+        return recordAudit(ctx, AuditLog{
+            ActorID:      actorID,
+            TargetUserID: userID,
+            Action:       "wallet_debited",
+            Subject:      wallet.ID.Hex(),
+            Reason:       req.GetReference(),
+        })
+    })
+    if err != nil {
+        if err == mongo.ErrNoDocuments {
+            return debitWalletErrorResponse("Wallet not found", http.StatusNotFound), nil
+        }
+        if errors.Is(err, errInsufficientFunds) {
+            return debitWalletErrorResponse("Insufficient available balance", http.StatusConflict), nil
+        }
+        log.Println("Database error:", err)
+        return debitWalletErrorResponse("Failed to debit wallet", http.StatusInternalServerError), nil
+    }
+
+    return &pb.DebitWalletResponse{
+        AvailableBalance: wallet.Available(),
+        Message:          "Wallet debited successfully",
+        Status:           true,
+        StatusCode:       http.StatusOK,
+    }, nil
 }
 
 // Success and Error Response functions for CreditWallet
@@ -82,3 +220,13 @@ func creditWalletSuccessResponse(message string, statusCode int) *pb.CreditWalle
 func creditWalletErrorResponse(message string, statusCode int) *pb.CreditWalletResponse {
     return &pb.CreditWalletResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
 }
+
+// Error Response function for GetBalance
+func getBalanceErrorResponse(message string, statusCode int) *pb.GetBalanceResponse {
+    return &pb.GetBalanceResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+// Error Response function for DebitWallet
+func debitWalletErrorResponse(message string, statusCode int) *pb.DebitWalletResponse {
+    return &pb.DebitWalletResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
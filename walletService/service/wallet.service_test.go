@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	pb "github.com/manlikehenryy/loan-management-system-grpc/walletService/wallet"
+)
+
+func TestCreditWalletByReference(t *testing.T) {
+	testDatabase(t)
+	ctx := context.Background()
+	server := NewWalletServiceServer()
+
+	type credit struct {
+		reference string
+		amount    float32
+		want      int
+	}
+
+	tests := []struct {
+		name        string
+		credits     []credit
+		wantBalance float32
+	}{
+		{
+			name:        "retried credit",
+			credits:     []credit{{reference: "loan-1", amount: 100, want: http.StatusOK}, {reference: "loan-1", amount: 100, want: http.StatusOK}},
+			wantBalance: 100,
+		},
+		{
+			name:        "different references",
+			credits:     []credit{{reference: "loan-1", amount: 100, want: http.StatusOK}, {reference: "loan-2", amount: 50, want: http.StatusOK}},
+			wantBalance: 150,
+		},
+		{
+			name:        "no reference",
+			credits:     []credit{{amount: 10, want: http.StatusOK}, {amount: 10, want: http.StatusOK}},
+			wantBalance: 20,
+		},
+		{
+			name:        "non-positive amount",
+			credits:     []credit{{reference: "loan-1", amount: 0, want: http.StatusBadRequest}, {reference: "loan-2", amount: -5, want: http.StatusBadRequest}},
+			wantBalance: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wallet := createTestWallet(t)
+
+			for i, c := range tt.credits {
+				resp, err := server.CreditWallet(ctx, &pb.CreditWalletRequest{UserId: wallet.UserID.Hex(), Amount: c.amount, Reference: c.reference})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if int(resp.StatusCode) != c.want {
+					t.Errorf("credit %d: got status %d (%s), want %d", i+1, resp.StatusCode, resp.Message, c.want)
+				}
+			}
+
+			balance, _ := walletState(t, wallet.ID, "")
+			if balance != tt.wantBalance {
+				t.Errorf("got balance %.2f, want %.2f", balance, tt.wantBalance)
+			}
+		})
+	}
+}
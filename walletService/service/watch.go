@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/configs"
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/grpcclient"
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/helpers"
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/outbox"
+	userPb "github.com/manlikehenryy/loan-management-system-grpc/walletService/user"
+	pb "github.com/manlikehenryy/loan-management-system-grpc/walletService/wallet"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PermissionWalletViewAny lets staff watch wallets that aren't theirs
+const PermissionWalletViewAny = "wallet.view_any"
+
+// UpdateSnapshot is the type of the first update sent to a watcher that isn't resuming
+const UpdateSnapshot = "Snapshot"
+
+// WatchWallet streams a wallet's events as they are recorded. Updates come from the outbox, so a
+// client that reconnects with its last resume token gets every event it missed, in order.
+func (s *WalletServiceServer) WatchWallet(req *pb.WatchWalletRequest, stream grpc.ServerStreamingServer[pb.WalletUpdate]) error {
+	ctx := stream.Context()
+
+	principal, ok := helpers.PrincipalFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "No identity provided")
+	}
+
+	userId := req.GetUserId()
+	if userId == "" {
+		userId = principal.UserId
+	}
+	userID, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "Invalid user ID")
+	}
+
+	if userId != principal.UserId {
+		if message, statusCode := checkPermission(ctx, principal.UserId, PermissionWalletViewAny); statusCode != http.StatusOK {
+			return status.Error(codes.PermissionDenied, message)
+		}
+	}
+
+	if _, err := findWallet(ctx, userID); err != nil {
+		return err
+	}
+
+	watcher, err := outbox.Watch(ctx, bson.M{"userId": userId}, req.GetResumeToken())
+	if err != nil {
+		if errors.Is(err, outbox.ErrInvalidResumeToken) {
+			return status.Error(codes.OutOfRange, "Resume token is invalid or expired; watch again without one")
+		}
+		log.Println("Failed to watch wallet:", err)
+		return status.Error(codes.Internal, "Failed to watch wallet")
+	}
+	defer watcher.Close(context.Background())
+
+	if req.GetResumeToken() == "" {
+		// Read after the watch starts, so a change made in between is sent as an update
+		wallet, err := findWallet(ctx, userID)
+		if err != nil {
+			return err
+		}
+		snapshot := &pb.WalletUpdate{Type: UpdateSnapshot, Balance: wallet.Balance, AvailableBalance: wallet.Available(), ResumeToken: watcher.ResumeToken()}
+		if err := stream.Send(snapshot); err != nil {
+			return err
+		}
+	}
+
+	for {
+		event, err := watcher.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Println("Wallet watch interrupted:", err)
+			return status.Error(codes.Unavailable, "Wallet updates interrupted; resume with the last token")
+		}
+
+		wallet, err := findWallet(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		update := &pb.WalletUpdate{
+			Type:             event.Type,
+			EventId:          event.ID,
+			Balance:          wallet.Balance,
+			AvailableBalance: wallet.Available(),
+			Data:             event.Data,
+			OccurredAt:       event.OccurredAt.Format(time.RFC3339),
+			ResumeToken:      watcher.ResumeToken(),
+		}
+		if err := stream.Send(update); err != nil {
+			return err
+		}
+	}
+}
+
+// findWallet loads userId's wallet for a stream, returning a gRPC status error if it can't
+func findWallet(ctx context.Context, userId primitive.ObjectID) (*Wallet, error) {
+	var wallet Wallet
+	err := database.GetCollection("wallets").FindOne(ctx, bson.M{"userId": userId}).Decode(&wallet)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, status.Error(codes.NotFound, "Wallet not found")
+		}
+		log.Println("Database error:", err)
+		return nil, status.Error(codes.Internal, "Failed to read wallet")
+	}
+	return &wallet, nil
+}
+
+// checkPermission asks userService whether userId has permission. It returns http.StatusOK if they do.
+func checkPermission(ctx context.Context, userId string, permission string) (string, int) {
+	userServiceClient, cleanup, err := grpcclient.NewUserServiceClient(ctx)
+	if err != nil {
+		log.Println("Failed to connect to UserService:", err)
+		return "Internal service error", http.StatusInternalServerError
+	}
+	defer cleanup()
+
+	c := grpcclient.NewIdentityContext(ctx, configs.Env.TOKEN)
+
+	checkPermissionResp, err := userServiceClient.CheckPermission(c, &userPb.CheckPermissionRequest{UserId: userId, Permission: permission})
+	if checkPermissionResp == nil {
+		log.Println("Error in CheckPermission call:", err)
+		return "Unexpected service response", http.StatusInternalServerError
+	}
+	if err != nil || !checkPermissionResp.Allowed {
+		return checkPermissionResp.Message, int(checkPermissionResp.StatusCode)
+	}
+	return "", http.StatusOK
+}
@@ -0,0 +1,697 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/database"
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/helpers"
+	"github.com/manlikehenryy/loan-management-system-grpc/walletService/payment"
+	pb "github.com/manlikehenryy/loan-management-system-grpc/walletService/wallet"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Withdrawal statuses. A withdrawal is requested, submitted to the payout provider (processing),
+// and then paid or failed. A paid withdrawal is reversed if the bank sends the money back. One that
+// couldn't be submitted needs review, since a submission that seemed to fail may still have
+// reached the provider; it is settled by asking the provider, or by staff with ResolveWithdrawal.
+const (
+	WithdrawalRequested   = "requested"
+	WithdrawalProcessing  = "processing"
+	WithdrawalNeedsReview = "needs_review"
+	WithdrawalPaid        = "paid"
+	WithdrawalFailed      = "failed"
+	WithdrawalReversed    = "reversed"
+)
+
+// withdrawalTransitions lists the statuses each status can move to. A provider may report the
+// outcome before the submission is recorded, so a requested withdrawal can be paid or fail
+// straight away.
+var withdrawalTransitions = map[string][]string{
+	WithdrawalRequested:   {WithdrawalProcessing, WithdrawalNeedsReview, WithdrawalPaid, WithdrawalFailed},
+	WithdrawalProcessing:  {WithdrawalPaid, WithdrawalFailed},
+	WithdrawalNeedsReview: {WithdrawalPaid, WithdrawalFailed},
+	WithdrawalPaid:        {WithdrawalReversed},
+}
+
+var withdrawalEventTypes = map[string]string{
+	WithdrawalPaid:     EventWithdrawalPaid,
+	WithdrawalFailed:   EventWithdrawalFailed,
+	WithdrawalReversed: EventWithdrawalReversed,
+}
+
+var payoutStatuses = map[string]string{
+	payment.PayoutPaid:     WithdrawalPaid,
+	payment.PayoutFailed:   WithdrawalFailed,
+	payment.PayoutReversed: WithdrawalReversed,
+}
+
+// PermissionWithdrawalResolve lets staff settle withdrawals that need review
+const PermissionWithdrawalResolve = "withdrawal.resolve"
+
+const (
+	maxWithdrawalAmount     = 1000000
+	payoutPollInterval      = time.Second
+	payoutLease             = time.Minute // While submitting, so another instance doesn't submit it too
+	payoutRetryDelay        = 30 * time.Second
+	payoutMaxAttempts       = 5
+	payoutReconcileInterval = 5 * time.Minute
+	payoutReconcileAfter    = time.Hour // How long a processing withdrawal waits for its webhook before the provider is asked
+	payoutUnavailableReason = "payout provider unavailable"
+	defaultPageSize         = 20
+	maxPageSize             = 100
+)
+
+var (
+	errWithdrawalChanged = errors.New("withdrawal changed status")
+	errInvalidTransition = errors.New("invalid withdrawal status change")
+	errNotInReview       = errors.New("withdrawal doesn't need review")
+)
+
+// Withdrawal pays part of a wallet out to a bank account. Its amount is held in the wallet while
+// the payout is under way: paying it captures the hold and failing it releases the hold.
+type Withdrawal struct {
+	ID                primitive.ObjectID     `bson:"_id,omitempty"`
+	UserID            primitive.ObjectID     `bson:"userId"`
+	WalletID          primitive.ObjectID     `bson:"walletId"`
+	HoldID            primitive.ObjectID     `bson:"holdId"`
+	Amount            float32                `bson:"amount"`
+	BankAccount       BankAccount            `bson:"bankAccount"` // A copy, so unlinking the account doesn't affect it
+	Status            string                 `bson:"status"`
+	FailureReason     string                 `bson:"failureReason,omitempty"`
+	Provider          string                 `bson:"provider"`
+	ProviderReference string                 `bson:"providerReference,omitempty"`
+	Attempts          int                    `bson:"attempts"` // Submissions to the provider that failed
+	LastError         string                 `bson:"lastError,omitempty"`
+	NextAttemptAt     time.Time              `bson:"nextAttemptAt"` // When a requested withdrawal is next submitted
+	History           []WithdrawalTransition `bson:"history"`
+	CreatedAt         time.Time              `bson:"createdAt"`
+	UpdatedAt         time.Time              `bson:"updatedAt"`
+}
+
+// WithdrawalTransition records a withdrawal reaching a status
+type WithdrawalTransition struct {
+	Status string    `bson:"status"`
+	Reason string    `bson:"reason,omitempty"`
+	At     time.Time `bson:"at"`
+}
+
+func (s *WalletServiceServer) RequestWithdrawal(ctx context.Context, req *pb.RequestWithdrawalRequest) (*pb.RequestWithdrawalResponse, error) {
+	userID, message, statusCode := principalUserID(ctx)
+	if statusCode != http.StatusOK {
+		return requestWithdrawalErrorResponse(message, statusCode), nil
+	}
+
+	if req.GetAmount() <= 0 || req.GetAmount() > maxWithdrawalAmount {
+		return requestWithdrawalErrorResponse("Amount must be greater than zero and at most 1,000,000", http.StatusBadRequest), nil
+	}
+
+	bankAccountID, err := primitive.ObjectIDFromHex(req.GetBankAccountId())
+	if err != nil {
+		return requestWithdrawalErrorResponse("Invalid bank account ID", http.StatusBadRequest), nil
+	}
+
+	var bankAccount BankAccount
+	err = database.GetCollection("bankaccounts").FindOne(ctx, bson.M{"_id": bankAccountID, "userId": userID}).Decode(&bankAccount)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return requestWithdrawalErrorResponse("Bank account not found", http.StatusNotFound), nil
+		}
+		log.Println("Database error:", err)
+		return requestWithdrawalErrorResponse("Failed to request withdrawal", http.StatusInternalServerError), nil
+	}
+
+	var withdrawal Withdrawal
+	err = database.WithTransaction(ctx, func(ctx mongo.SessionContext) error {
+		withdrawalID := primitive.NewObjectID()
+		hold, _, err := placeHold(ctx, userID, req.GetAmount(), HoldReasonWithdrawal, "withdrawal:"+withdrawalID.Hex(), maxHoldExpiry)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		withdrawal = Withdrawal{
+			ID:            withdrawalID,
+			UserID:        userID,
+			WalletID:      hold.WalletID,
+			HoldID:        hold.ID,
+			Amount:        req.GetAmount(),
+			BankAccount:   bankAccount,
+			Status:        WithdrawalRequested,
+			Provider:      payment.Name(),
+			NextAttemptAt: now,
+			History:       []WithdrawalTransition{{Status: WithdrawalRequested, At: now}},
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		if _, err := database.GetCollection("withdrawals").InsertOne(ctx, withdrawal); err != nil {
+			return err
+		}
+
+		return recordWalletEvent(ctx, EventWithdrawalRequested, hold.WalletID.Hex(), userID.Hex(), withdrawalEventData(&withdrawal, nil))
+	})
+	if err != nil {
+		switch {
+		case err == mongo.ErrNoDocuments:
+			return requestWithdrawalErrorResponse("Wallet not found", http.StatusNotFound), nil
+		case errors.Is(err, errInsufficientFunds):
+			return requestWithdrawalErrorResponse("Insufficient available balance", http.StatusConflict), nil
+		}
+		log.Println("Database error:", err)
+		return requestWithdrawalErrorResponse("Failed to request withdrawal", http.StatusInternalServerError), nil
+	}
+
+	return &pb.RequestWithdrawalResponse{
+		Withdrawal: withdrawalDetails(&withdrawal),
+		Message:    "Withdrawal requested successfully",
+		Status:     true,
+		StatusCode: http.StatusCreated,
+	}, nil
+}
+
+func (s *WalletServiceServer) GetWithdrawal(ctx context.Context, req *pb.GetWithdrawalRequest) (*pb.GetWithdrawalResponse, error) {
+	principal, ok := helpers.PrincipalFromContext(ctx)
+	if !ok {
+		return getWithdrawalErrorResponse("No identity provided", http.StatusUnauthorized), nil
+	}
+
+	withdrawalID, err := primitive.ObjectIDFromHex(req.GetWithdrawalId())
+	if err != nil {
+		return getWithdrawalErrorResponse("Invalid withdrawal ID", http.StatusBadRequest), nil
+	}
+
+	var withdrawal Withdrawal
+	err = database.GetCollection("withdrawals").FindOne(ctx, bson.M{"_id": withdrawalID}).Decode(&withdrawal)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return getWithdrawalErrorResponse("Withdrawal not found", http.StatusNotFound), nil
+		}
+		log.Println("Database error:", err)
+		return getWithdrawalErrorResponse("Failed to read withdrawal", http.StatusInternalServerError), nil
+	}
+
+	if withdrawal.UserID.Hex() != principal.UserId {
+		if message, statusCode := checkPermission(ctx, principal.UserId, PermissionWalletViewAny); statusCode != http.StatusOK {
+			return getWithdrawalErrorResponse(message, statusCode), nil
+		}
+	}
+
+	return &pb.GetWithdrawalResponse{
+		Withdrawal: withdrawalDetails(&withdrawal),
+		Message:    "Withdrawal retrieved successfully",
+		Status:     true,
+		StatusCode: http.StatusOK,
+	}, nil
+}
+
+func (s *WalletServiceServer) ListWithdrawals(ctx context.Context, req *pb.ListWithdrawalsRequest) (*pb.ListWithdrawalsResponse, error) {
+	userID, message, statusCode := principalUserID(ctx)
+	if statusCode != http.StatusOK {
+		return listWithdrawalsErrorResponse(message, statusCode), nil
+	}
+
+	page, limit := int64(req.GetPage()), int64(req.GetLimit())
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	filter := bson.M{"userId": userID}
+	if req.GetAllUsers() {
+		if message, statusCode := checkPermission(ctx, userID.Hex(), PermissionWalletViewAny); statusCode != http.StatusOK {
+			return listWithdrawalsErrorResponse(message, statusCode), nil
+		}
+		filter = bson.M{}
+	}
+	if req.GetStatus() != "" {
+		filter["status"] = req.GetStatus()
+	}
+
+	withdrawalsCollection := database.GetCollection("withdrawals")
+
+	total, err := withdrawalsCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		log.Println("Database error:", err)
+		return listWithdrawalsErrorResponse("Failed to list withdrawals", http.StatusInternalServerError), nil
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+
+	cursor, err := withdrawalsCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		log.Println("Database error:", err)
+		return listWithdrawalsErrorResponse("Failed to list withdrawals", http.StatusInternalServerError), nil
+	}
+
+	var withdrawals []Withdrawal
+	if err := cursor.All(ctx, &withdrawals); err != nil {
+		log.Println("Database error:", err)
+		return listWithdrawalsErrorResponse("Failed to list withdrawals", http.StatusInternalServerError), nil
+	}
+
+	details := make([]*pb.Withdrawal, 0, len(withdrawals))
+	for i := range withdrawals {
+		details = append(details, withdrawalDetails(&withdrawals[i]))
+	}
+
+	return &pb.ListWithdrawalsResponse{
+		Withdrawals: details,
+		Total:       total,
+		Message:     "Withdrawals retrieved successfully",
+		Status:      true,
+		StatusCode:  http.StatusOK,
+	}, nil
+}
+
+// StartWithdrawalProcessor submits requested withdrawals to the payout provider. A submission
+// that fails is retried, 30 seconds later and twice as long after each failure after that; after
+// payoutMaxAttempts the withdrawal needs review and keeps its hold until it is settled. Every
+// payoutReconcileInterval, the provider is asked about withdrawals that need review or have been
+// processing for longer than payoutReconcileAfter, in case their webhook never arrives.
+func StartWithdrawalProcessor(ctx context.Context) {
+	go func() {
+		for {
+			submitWithdrawals(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(payoutPollInterval):
+			}
+		}
+	}()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(payoutReconcileInterval):
+			}
+
+			reconcileWithdrawals(ctx, time.Now())
+		}
+	}()
+}
+
+// submitWithdrawals submits due withdrawals until there are none left
+func submitWithdrawals(ctx context.Context) {
+	withdrawalsCollection := database.GetCollection("withdrawals")
+
+	for ctx.Err() == nil {
+		now := time.Now()
+
+		var withdrawal Withdrawal
+		err := withdrawalsCollection.FindOneAndUpdate(ctx,
+			bson.M{"status": WithdrawalRequested, "nextAttemptAt": bson.M{"$lte": now}},
+			bson.M{"$set": bson.M{"nextAttemptAt": now.Add(payoutLease)}},
+			options.FindOneAndUpdate().SetSort(bson.M{"nextAttemptAt": 1}).SetReturnDocument(options.After),
+		).Decode(&withdrawal)
+		if err != nil {
+			if err != mongo.ErrNoDocuments {
+				log.Println("Failed to read withdrawals:", err)
+			}
+			return
+		}
+
+		// The withdrawal's ID is the payout's reference, so the provider drops a resubmission
+		providerReference, err := payment.CreatePayout(ctx, payment.Payout{
+			Reference:     withdrawal.ID.Hex(),
+			UserID:        withdrawal.UserID.Hex(),
+			Amount:        withdrawal.Amount,
+			BankCode:      withdrawal.BankAccount.BankCode,
+			AccountNumber: withdrawal.BankAccount.AccountNumber,
+			AccountName:   withdrawal.BankAccount.AccountName,
+		})
+		if err != nil {
+			log.Printf("Failed to submit withdrawal %s: %v", withdrawal.ID.Hex(), err)
+			retryWithdrawal(ctx, &withdrawal, err)
+			continue
+		}
+
+		if _, err := withdrawalsCollection.UpdateOne(ctx,
+			bson.M{"_id": withdrawal.ID},
+			bson.M{"$set": bson.M{"providerReference": providerReference}},
+		); err != nil {
+			log.Println("Failed to update withdrawal:", err)
+		}
+
+		// The provider may already have reported the outcome, in which case there's nothing to do
+		err = database.WithTransaction(ctx, func(ctx mongo.SessionContext) error {
+			return transitionWithdrawal(ctx, &withdrawal, WithdrawalProcessing, "")
+		})
+		if err != nil && !errors.Is(err, errWithdrawalChanged) {
+			log.Println("Failed to update withdrawal:", err)
+		}
+	}
+}
+
+// retryWithdrawal schedules a failed submission again. After payoutMaxAttempts it stops and the
+// withdrawal needs review: a submission that timed out may still be paid, so its hold is kept
+// until the provider or staff settle it.
+func retryWithdrawal(ctx context.Context, withdrawal *Withdrawal, submitErr error) {
+	failure := bson.M{"$set": bson.M{
+		"attempts":      withdrawal.Attempts + 1,
+		"lastError":     submitErr.Error(),
+		"nextAttemptAt": time.Now().Add(payoutRetryDelay << withdrawal.Attempts),
+	}}
+
+	if withdrawal.Attempts+1 >= payoutMaxAttempts {
+		err := database.WithTransaction(ctx, func(ctx mongo.SessionContext) error {
+			_, err := database.GetCollection("withdrawals").UpdateOne(ctx, bson.M{"_id": withdrawal.ID}, failure)
+			if err != nil {
+				return err
+			}
+			return transitionWithdrawal(ctx, withdrawal, WithdrawalNeedsReview, payoutUnavailableReason)
+		})
+		if err != nil && !errors.Is(err, errWithdrawalChanged) {
+			log.Println("Failed to mark withdrawal for review:", err)
+			return
+		}
+		if err == nil {
+			log.Printf("Withdrawal %s needs review after %d failed submissions", withdrawal.ID.Hex(), payoutMaxAttempts)
+		}
+		return
+	}
+
+	_, err := database.GetCollection("withdrawals").UpdateOne(ctx,
+		bson.M{"_id": withdrawal.ID, "status": WithdrawalRequested},
+		failure,
+	)
+	if err != nil {
+		log.Println("Failed to record withdrawal failure:", err)
+	}
+}
+
+// reconcileWithdrawals settles withdrawals whose outcome never arrived by asking the provider.
+// Only a definite answer settles one: a withdrawal the provider has no payout for is left for
+// staff, since the provider may have lost track of a payout it sent, as the simulator does when
+// the service restarts.
+func reconcileWithdrawals(ctx context.Context, now time.Time) {
+	cursor, err := database.GetCollection("withdrawals").Find(ctx, bson.M{"$or": []bson.M{
+		{"status": WithdrawalNeedsReview},
+		{"status": WithdrawalProcessing, "updatedAt": bson.M{"$lte": now.Add(-payoutReconcileAfter)}},
+	}})
+	if err != nil {
+		log.Println("Failed to read withdrawals:", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var withdrawal Withdrawal
+		if err := cursor.Decode(&withdrawal); err != nil {
+			log.Println("Failed to decode withdrawal:", err)
+			continue
+		}
+
+		notification, err := payment.PayoutStatus(ctx, withdrawal.ID.Hex())
+		switch {
+		case errors.Is(err, payment.ErrPayoutNotFound) && withdrawal.Status == WithdrawalNeedsReview:
+			log.Printf("Provider has no payout for withdrawal %s; leaving it for review", withdrawal.ID.Hex())
+			continue
+		case errors.Is(err, payment.ErrPayoutNotFound):
+			log.Printf("ALERT: provider has no payout for withdrawal %s, which it accepted", withdrawal.ID.Hex())
+			continue
+		case err != nil:
+			log.Printf("Failed to check payout for withdrawal %s: %v", withdrawal.ID.Hex(), err)
+			continue
+		case notification.Status == payment.PayoutPending:
+			continue
+		}
+
+		completed, err := completeWithdrawal(ctx, withdrawal.ID, notification)
+		if err != nil && !errors.Is(err, errWithdrawalChanged) {
+			log.Printf("Failed to settle withdrawal %s as %s: %v", withdrawal.ID.Hex(), notification.Status, err)
+			continue
+		}
+		if completed {
+			log.Printf("Withdrawal %s settled as %s from the provider's payout status", withdrawal.ID.Hex(), notification.Status)
+		}
+	}
+}
+
+// ResolveWithdrawal lets staff settle a withdrawal that needs review once they know what became
+// of its payout. The change is kept in the audit log with the reason given.
+func (s *WalletServiceServer) ResolveWithdrawal(ctx context.Context, req *pb.ResolveWithdrawalRequest) (*pb.ResolveWithdrawalResponse, error) {
+	actorID, message, statusCode := principalUserID(ctx)
+	if statusCode != http.StatusOK {
+		return resolveWithdrawalErrorResponse(message, statusCode), nil
+	}
+	if message, statusCode := checkPermission(ctx, actorID.Hex(), PermissionWithdrawalResolve); statusCode != http.StatusOK {
+		return resolveWithdrawalErrorResponse(message, statusCode), nil
+	}
+
+	withdrawalID, err := primitive.ObjectIDFromHex(req.GetWithdrawalId())
+	if err != nil {
+		return resolveWithdrawalErrorResponse("Invalid withdrawal ID", http.StatusBadRequest), nil
+	}
+	status := req.GetStatus()
+	if status != WithdrawalPaid && status != WithdrawalFailed {
+		return resolveWithdrawalErrorResponse("Status must be paid or failed", http.StatusBadRequest), nil
+	}
+	reason := strings.TrimSpace(req.GetReason())
+	if reason == "" {
+		return resolveWithdrawalErrorResponse("A reason is required", http.StatusBadRequest), nil
+	}
+
+	var withdrawal Withdrawal
+	err = database.WithTransaction(ctx, func(ctx mongo.SessionContext) error {
+		withdrawal = Withdrawal{}
+		if err := database.GetCollection("withdrawals").FindOne(ctx, bson.M{"_id": withdrawalID}).Decode(&withdrawal); err != nil {
+			return err
+		}
+		if withdrawal.Status != WithdrawalNeedsReview {
+			return errNotInReview
+		}
+
+		if err := transitionWithdrawal(ctx, &withdrawal, status, reason); err != nil {
+			return err
+		}
+		return recordAudit(ctx, AuditLog{
+			ActorID:      actorID,
+			TargetUserID: withdrawal.UserID,
+			Action:       "withdrawal_resolved",
+			Subject:      withdrawal.ID.Hex(),
+			From:         WithdrawalNeedsReview,
+			To:           status,
+			Reason:       reason,
+		})
+	})
+	if err != nil {
+		switch {
+		case err == mongo.ErrNoDocuments:
+			return resolveWithdrawalErrorResponse("Withdrawal not found", http.StatusNotFound), nil
+		case errors.Is(err, errNotInReview), errors.Is(err, errWithdrawalChanged):
+			return resolveWithdrawalErrorResponse("Withdrawal doesn't need review", http.StatusConflict), nil
+		}
+		log.Println("Database error:", err)
+		return resolveWithdrawalErrorResponse("Failed to resolve withdrawal", http.StatusInternalServerError), nil
+	}
+
+	log.Printf("Withdrawal %s resolved as %s by %s", withdrawal.ID.Hex(), status, actorID.Hex())
+	return &pb.ResolveWithdrawalResponse{
+		Withdrawal: withdrawalDetails(&withdrawal),
+		Message:    "Withdrawal resolved successfully",
+		Status:     true,
+		StatusCode: http.StatusOK,
+	}, nil
+}
+
+// handlePayoutWebhook applies a verified payout webhook to its withdrawal
+func handlePayoutWebhook(ctx context.Context, notification payment.Notification) *pb.HandlePaymentWebhookResponse {
+	withdrawalID, err := primitive.ObjectIDFromHex(notification.Reference)
+	if err != nil {
+		log.Printf("Webhook %s has an unknown reference %q", notification.EventID, notification.Reference)
+		return handlePaymentWebhookErrorResponse("Withdrawal not found", http.StatusNotFound)
+	}
+
+	completed, err := completeWithdrawal(ctx, withdrawalID, notification)
+	if err != nil {
+		switch {
+		case err == mongo.ErrNoDocuments:
+			log.Printf("Webhook %s has an unknown reference %q", notification.EventID, notification.Reference)
+			return handlePaymentWebhookErrorResponse("Withdrawal not found", http.StatusNotFound)
+		case errors.Is(err, errAmountMismatch):
+			log.Printf("Webhook %s is for a different amount than withdrawal %s", notification.EventID, notification.Reference)
+			return handlePaymentWebhookErrorResponse("Amount doesn't match the withdrawal", http.StatusBadRequest)
+		case errors.Is(err, errInvalidTransition):
+			log.Printf("Webhook %s reports %s for withdrawal %s, which can't move to it", notification.EventID, notification.Status, notification.Reference)
+			return handlePaymentWebhookErrorResponse("Withdrawal can't move to that status", http.StatusConflict)
+		}
+		log.Println("Database error:", err)
+		return handlePaymentWebhookErrorResponse("Failed to process webhook", http.StatusInternalServerError)
+	}
+
+	if !completed {
+		return handlePaymentWebhookSuccessResponse("Webhook already processed", http.StatusOK)
+	}
+	return handlePaymentWebhookSuccessResponse("Webhook processed successfully", http.StatusOK)
+}
+
+// completeWithdrawal applies the outcome a payout webhook reports. It returns false if the
+// withdrawal already has that status.
+func completeWithdrawal(ctx context.Context, withdrawalID primitive.ObjectID, notification payment.Notification) (bool, error) {
+	status, ok := payoutStatuses[notification.Status]
+	if !ok {
+		return false, errInvalidTransition
+	}
+
+	completed := false
+	err := database.WithTransaction(ctx, func(ctx mongo.SessionContext) error {
+		completed = false
+
+		var withdrawal Withdrawal
+		if err := database.GetCollection("withdrawals").FindOne(ctx, bson.M{"_id": withdrawalID}).Decode(&withdrawal); err != nil {
+			return err
+		}
+		if withdrawal.Status == status {
+			return nil
+		}
+		if notification.Amount != withdrawal.Amount {
+			return errAmountMismatch
+		}
+
+		if err := transitionWithdrawal(ctx, &withdrawal, status, notification.Reason); err != nil {
+			return err
+		}
+		completed = true
+		return nil
+	})
+	return completed, err
+}
+
+// transitionWithdrawal moves a withdrawal to status, in the transaction ctx belongs to, and
+// settles its hold: paid captures it and failed releases it. A withdrawal that needs review keeps
+// its hold. A reversal credits the wallet back, since the hold was captured when the withdrawal
+// was paid.
+func transitionWithdrawal(ctx mongo.SessionContext, withdrawal *Withdrawal, status string, reason string) error {
+	if !contains(withdrawalTransitions[withdrawal.Status], status) {
+		return errInvalidTransition
+	}
+
+	now := time.Now()
+	set := bson.M{"status": status, "updatedAt": now}
+	if status == WithdrawalFailed || status == WithdrawalReversed {
+		set["failureReason"] = reason
+	}
+
+	result, err := database.GetCollection("withdrawals").UpdateOne(ctx,
+		bson.M{"_id": withdrawal.ID, "status": withdrawal.Status},
+		bson.M{
+			"$set":  set,
+			"$push": bson.M{"history": WithdrawalTransition{Status: status, Reason: reason, At: now}},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount == 0 {
+		return errWithdrawalChanged
+	}
+	withdrawal.Status = status
+	withdrawal.UpdatedAt = now
+	if status == WithdrawalFailed || status == WithdrawalReversed {
+		withdrawal.FailureReason = reason
+	}
+
+	var wallet *Wallet
+	switch status {
+	case WithdrawalPaid:
+		if _, err := closeHoldIn(ctx, withdrawal.HoldID, HoldCaptured, 0); err != nil {
+			return err
+		}
+	case WithdrawalFailed:
+		if _, err := closeHoldIn(ctx, withdrawal.HoldID, HoldReleased, 0); err != nil {
+			return err
+		}
+	case WithdrawalReversed:
+		wallet = &Wallet{}
+		err := database.GetCollection("wallets").FindOneAndUpdate(ctx,
+			bson.M{"_id": withdrawal.WalletID},
+			bson.M{"$inc": bson.M{"balance": withdrawal.Amount}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(wallet)
+		if err != nil {
+			return err
+		}
+		if err := recordLedgerEntry(ctx, wallet, LedgerCredit, withdrawal.Amount, "Withdrawal reversed", "withdrawal:"+withdrawal.ID.Hex()); err != nil {
+			return err
+		}
+	}
+
+	eventType, ok := withdrawalEventTypes[status]
+	if !ok {
+		return nil
+	}
+	return recordWalletEvent(ctx, eventType, withdrawal.WalletID.Hex(), withdrawal.UserID.Hex(), withdrawalEventData(withdrawal, wallet))
+}
+
+// withdrawalEventData describes a withdrawal for its events. wallet is set when the withdrawal
+// changed the balance itself, rather than through its hold.
+func withdrawalEventData(withdrawal *Withdrawal, wallet *Wallet) map[string]string {
+	data := map[string]string{
+		"withdrawalId":  withdrawal.ID.Hex(),
+		"amount":        formatAmount(withdrawal.Amount),
+		"bankCode":      withdrawal.BankAccount.BankCode,
+		"accountNumber": maskAccountNumber(withdrawal.BankAccount.AccountNumber),
+	}
+	if withdrawal.FailureReason != "" {
+		data["reason"] = withdrawal.FailureReason
+	}
+	if wallet != nil {
+		data["balance"] = formatAmount(wallet.Balance)
+		data["availableBalance"] = formatAmount(wallet.Available())
+	}
+	return data
+}
+
+func withdrawalDetails(withdrawal *Withdrawal) *pb.Withdrawal {
+	return &pb.Withdrawal{
+		Id:                withdrawal.ID.Hex(),
+		UserId:            withdrawal.UserID.Hex(),
+		Amount:            withdrawal.Amount,
+		BankAccount:       bankAccountDetails(&withdrawal.BankAccount),
+		Status:            withdrawal.Status,
+		FailureReason:     withdrawal.FailureReason,
+		Provider:          withdrawal.Provider,
+		ProviderReference: withdrawal.ProviderReference,
+		CreatedAt:         withdrawal.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:         withdrawal.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// Error Response function for RequestWithdrawal
+func requestWithdrawalErrorResponse(message string, statusCode int) *pb.RequestWithdrawalResponse {
+	return &pb.RequestWithdrawalResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+// Error Response function for GetWithdrawal
+func getWithdrawalErrorResponse(message string, statusCode int) *pb.GetWithdrawalResponse {
+	return &pb.GetWithdrawalResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+// Error Response function for ListWithdrawals
+func listWithdrawalsErrorResponse(message string, statusCode int) *pb.ListWithdrawalsResponse {
+	return &pb.ListWithdrawalsResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
+
+// Error Response function for ResolveWithdrawal
+func resolveWithdrawalErrorResponse(message string, statusCode int) *pb.ResolveWithdrawalResponse {
+	return &pb.ResolveWithdrawalResponse{Message: message, Status: false, StatusCode: int32(statusCode)}
+}
@@ -0,0 +1,54 @@
+package statement
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"time"
+)
+
+// csvWriter renders a statement as a table with a row for each transaction, between rows for the
+// opening and closing balances
+type csvWriter struct {
+	w  *csv.Writer
+	to time.Time
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) ContentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+func (c *csvWriter) Extension() string {
+	return "csv"
+}
+
+func (c *csvWriter) Begin(header Header) error {
+	c.to = header.To
+	c.w.Write([]string{"Date", "Description", "Reference", "Debit", "Credit", "Balance"})
+	c.w.Write([]string{header.From.Format(time.RFC3339), "Opening balance", "", "", "", formatAmount(header.OpeningBalance)})
+	return c.w.Error()
+}
+
+func (c *csvWriter) Line(line Line) error {
+	debit, credit := "", formatAmount(line.Amount)
+	if line.Type == "debit" {
+		debit, credit = credit, ""
+	}
+	c.w.Write([]string{line.Date.Format(time.RFC3339), line.Description, line.Reference, debit, credit, formatAmount(line.Balance)})
+	return c.w.Error()
+}
+
+func (c *csvWriter) End(footer Footer) error {
+	c.w.Write([]string{"", "Total", "", formatAmount(footer.TotalDebits), formatAmount(footer.TotalCredits), ""})
+	c.w.Write([]string{c.to.Format(time.RFC3339), "Closing balance", "", "", "", formatAmount(footer.ClosingBalance)})
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func formatAmount(amount float32) string {
+	return fmt.Sprintf("%.2f", amount)
+}
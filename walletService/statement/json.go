@@ -0,0 +1,98 @@
+package statement
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// jsonWriter renders a statement as one JSON object, with its transactions in an array between
+// the opening and closing balances
+type jsonWriter struct {
+	w     io.Writer
+	lines int
+}
+
+type jsonHeader struct {
+	UserID         string  `json:"userId"`
+	From           string  `json:"from"`
+	To             string  `json:"to"`
+	GeneratedAt    string  `json:"generatedAt"`
+	OpeningBalance float32 `json:"openingBalance"`
+}
+
+type jsonLine struct {
+	ID          string  `json:"id"`
+	Date        string  `json:"date"`
+	Type        string  `json:"type"`
+	Description string  `json:"description"`
+	Reference   string  `json:"reference,omitempty"`
+	Amount      float32 `json:"amount"`
+	Balance     float32 `json:"balance"`
+}
+
+type jsonFooter struct {
+	TotalCredits   float32 `json:"totalCredits"`
+	TotalDebits    float32 `json:"totalDebits"`
+	ClosingBalance float32 `json:"closingBalance"`
+}
+
+func (j *jsonWriter) ContentType() string {
+	return "application/json"
+}
+
+func (j *jsonWriter) Extension() string {
+	return "json"
+}
+
+func (j *jsonWriter) Begin(header Header) error {
+	head, err := json.Marshal(jsonHeader{
+		UserID:         header.UserID,
+		From:           header.From.Format(time.RFC3339),
+		To:             header.To.Format(time.RFC3339),
+		GeneratedAt:    header.GeneratedAt.Format(time.RFC3339),
+		OpeningBalance: header.OpeningBalance,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Leave the object open for the transactions
+	head = append(head[:len(head)-1], `,"transactions":[`...)
+	_, err = j.w.Write(head)
+	return err
+}
+
+func (j *jsonWriter) Line(line Line) error {
+	data, err := json.Marshal(jsonLine{
+		ID:          line.ID,
+		Date:        line.Date.Format(time.RFC3339),
+		Type:        line.Type,
+		Description: line.Description,
+		Reference:   line.Reference,
+		Amount:      line.Amount,
+		Balance:     line.Balance,
+	})
+	if err != nil {
+		return err
+	}
+
+	if j.lines > 0 {
+		data = append([]byte{','}, data...)
+	}
+	j.lines++
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jsonWriter) End(footer Footer) error {
+	tail, err := json.Marshal(jsonFooter(footer))
+	if err != nil {
+		return err
+	}
+
+	// Close the array, then carry on the object with the footer's fields
+	tail = append([]byte("],"), tail[1:]...)
+	_, err = j.w.Write(tail)
+	return err
+}
@@ -0,0 +1,266 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Page layout, in points on an A4 page
+const (
+	pdfPageWidth    = 595
+	pdfPageHeight   = 842
+	pdfMargin       = 40
+	pdfFooterY      = 25
+	pdfFontSize     = 9
+	pdfLineHeight   = 13
+	pdfTitleSize    = 16
+	pdfDateFormat   = "02 Jan 2006 15:04"
+	pdfPeriodFormat = "02 Jan 2006 15:04 MST"
+)
+
+// Where each column starts or, for amounts, ends
+const (
+	pdfDateX        = pdfMargin
+	pdfDescriptionX = 120
+	pdfReferenceX   = 222
+	pdfDebitX       = 445
+	pdfCreditX      = 500
+	pdfBalanceX     = pdfPageWidth - pdfMargin
+)
+
+// The widest a description or reference can be, in characters
+const (
+	pdfDescriptionLength = 20
+	pdfReferenceLength   = 35
+)
+
+// Objects with fixed numbers. Each page adds a content stream and a page object after them.
+const (
+	pdfCatalogObject     = 1
+	pdfPagesObject       = 2
+	pdfRegularFontObject = 3
+	pdfBoldFontObject    = 4
+)
+
+// pdfWriter renders a statement as a PDF document using the standard Helvetica fonts, so nothing
+// has to be embedded. Each page is written as soon as it is full; the page tree and the
+// cross-reference table, which have to list every page, come last.
+type pdfWriter struct {
+	w       io.Writer
+	written int64
+	offsets map[int]int64 // Where each object starts, by object number
+	next    int           // The next free object number
+	pages   []int         // The page objects written so far
+
+	page  bytes.Buffer // The content of the current page
+	y     float64      // Where the next line goes on the current page
+	lines int
+	err   error
+}
+
+func newPDFWriter(w io.Writer) *pdfWriter {
+	return &pdfWriter{w: w, offsets: map[int]int64{}, next: pdfBoldFontObject + 1}
+}
+
+func (p *pdfWriter) ContentType() string {
+	return "application/pdf"
+}
+
+func (p *pdfWriter) Extension() string {
+	return "pdf"
+}
+
+func (p *pdfWriter) Begin(header Header) error {
+	// The binary comment tells tools the file isn't plain text
+	p.write("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	p.object(pdfRegularFontObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	p.object(pdfBoldFontObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	p.startPage()
+	p.text("F2", pdfTitleSize, pdfMargin, p.y, "Wallet statement")
+	p.y -= pdfTitleSize + 10
+	p.text("F1", pdfFontSize, pdfMargin, p.y, "Period: "+header.From.UTC().Format(pdfPeriodFormat)+" to "+header.To.UTC().Format(pdfPeriodFormat))
+	p.y -= pdfLineHeight
+	p.text("F1", pdfFontSize, pdfMargin, p.y, "User: "+header.UserID)
+	p.y -= pdfLineHeight
+	p.text("F1", pdfFontSize, pdfMargin, p.y, "Generated: "+header.GeneratedAt.UTC().Format(pdfPeriodFormat))
+	p.y -= pdfLineHeight * 2
+	p.summary("Opening balance", header.OpeningBalance)
+	p.y -= pdfLineHeight
+	p.columnHeadings()
+	return p.err
+}
+
+func (p *pdfWriter) Line(line Line) error {
+	if p.y < pdfMargin {
+		p.finishPage()
+		p.startPage()
+		p.columnHeadings()
+	}
+
+	debit, credit := "", formatAmount(line.Amount)
+	if line.Type == "debit" {
+		debit, credit = credit, ""
+	}
+	p.text("F1", pdfFontSize, pdfDateX, p.y, line.Date.UTC().Format(pdfDateFormat))
+	p.text("F1", pdfFontSize, pdfDescriptionX, p.y, truncate(line.Description, pdfDescriptionLength))
+	p.text("F1", pdfFontSize, pdfReferenceX, p.y, truncate(line.Reference, pdfReferenceLength))
+	p.rightText("F1", pdfDebitX, debit)
+	p.rightText("F1", pdfCreditX, credit)
+	p.rightText("F1", pdfBalanceX, formatAmount(line.Balance))
+	p.y -= pdfLineHeight
+	p.lines++
+	return p.err
+}
+
+func (p *pdfWriter) End(footer Footer) error {
+	// Keep the totals on the same page
+	if p.y < pdfMargin+pdfLineHeight*4 {
+		p.finishPage()
+		p.startPage()
+	}
+
+	if p.lines == 0 {
+		p.text("F1", pdfFontSize, pdfMargin, p.y, "No transactions in this period")
+		p.y -= pdfLineHeight
+	}
+	p.rule(p.y + pdfLineHeight - 4)
+	p.y -= 4
+	p.text("F2", pdfFontSize, pdfDescriptionX, p.y, "Total")
+	p.rightText("F2", pdfDebitX, formatAmount(footer.TotalDebits))
+	p.rightText("F2", pdfCreditX, formatAmount(footer.TotalCredits))
+	p.y -= pdfLineHeight * 2
+	p.summary("Closing balance", footer.ClosingBalance)
+	p.finishPage()
+
+	kids := make([]string, len(p.pages))
+	for i, page := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", page)
+	}
+	p.object(pdfPagesObject, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages)))
+	p.object(pdfCatalogObject, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPagesObject))
+
+	xref := p.written
+	p.write(fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", p.next))
+	for n := 1; n < p.next; n++ {
+		p.write(fmt.Sprintf("%010d 00000 n \n", p.offsets[n]))
+	}
+	p.write(fmt.Sprintf("trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", p.next, pdfCatalogObject, xref))
+	return p.err
+}
+
+func (p *pdfWriter) startPage() {
+	p.page.Reset()
+	p.y = pdfPageHeight - pdfMargin
+}
+
+// finishPage numbers the current page and writes it out
+func (p *pdfWriter) finishPage() {
+	number := fmt.Sprintf("Page %d", len(p.pages)+1)
+	p.text("F1", pdfFontSize, pdfBalanceX-textWidth(number, pdfFontSize), pdfFooterY, number)
+
+	content, page := p.next, p.next+1
+	p.next += 2
+	p.object(content, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", p.page.Len(), p.page.Bytes()))
+	p.object(page, fmt.Sprintf(
+		"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>",
+		pdfPagesObject, pdfPageWidth, pdfPageHeight, pdfRegularFontObject, pdfBoldFontObject, content,
+	))
+	p.pages = append(p.pages, page)
+}
+
+func (p *pdfWriter) columnHeadings() {
+	p.text("F2", pdfFontSize, pdfDateX, p.y, "Date")
+	p.text("F2", pdfFontSize, pdfDescriptionX, p.y, "Description")
+	p.text("F2", pdfFontSize, pdfReferenceX, p.y, "Reference")
+	p.rightText("F2", pdfDebitX, "Debit")
+	p.rightText("F2", pdfCreditX, "Credit")
+	p.rightText("F2", pdfBalanceX, "Balance")
+	p.rule(p.y - 4)
+	p.y -= pdfLineHeight + 2
+}
+
+func (p *pdfWriter) summary(label string, amount float32) {
+	p.text("F2", pdfFontSize+1, pdfMargin, p.y, label)
+	value := formatAmount(amount)
+	p.text("F2", pdfFontSize+1, pdfBalanceX-textWidth(value, pdfFontSize+1), p.y, value)
+}
+
+func (p *pdfWriter) text(font string, size float64, x float64, y float64, s string) {
+	fmt.Fprintf(&p.page, "BT /%s %g Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, escapePDFText(s))
+}
+
+// rightText writes s on the current line so that it ends at x
+func (p *pdfWriter) rightText(font string, x float64, s string) {
+	if s == "" {
+		return
+	}
+	p.text(font, pdfFontSize, x-textWidth(s, pdfFontSize), p.y, s)
+}
+
+// rule draws a thin line across the page at y
+func (p *pdfWriter) rule(y float64) {
+	fmt.Fprintf(&p.page, "0.5 w %d %.2f m %d %.2f l S\n", pdfMargin, y, pdfPageWidth-pdfMargin, y)
+}
+
+func (p *pdfWriter) object(number int, body string) {
+	p.offsets[number] = p.written
+	p.write(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", number, body))
+}
+
+// write sends s to the output, keeping track of the offset object positions are given from. After
+// the first error, it does nothing.
+func (p *pdfWriter) write(s string) {
+	if p.err != nil {
+		return
+	}
+	n, err := io.WriteString(p.w, s)
+	p.written += int64(n)
+	p.err = err
+}
+
+// textWidth estimates the width of s in Helvetica. Amounts, which are right-aligned, only use
+// characters whose widths are known; anything else counts as a digit.
+func textWidth(s string, size float64) float64 {
+	width := 0
+	for _, r := range s {
+		switch {
+		case r == '.' || r == ',' || r == ' ':
+			width += 278
+		case r == '-':
+			width += 333
+		case r >= 'a' && r <= 'z':
+			width += 500
+		default:
+			width += 556
+		}
+	}
+	return float64(width) * size / 1000
+}
+
+// escapePDFText makes s safe to put in a PDF string. The standard fonts only cover Latin
+// characters, so anything outside printable ASCII becomes "?".
+func escapePDFText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+	return s[:length-3] + "..."
+}
@@ -0,0 +1,67 @@
+package statement
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// Formats a statement can be rendered in
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+	FormatPDF  = "pdf"
+)
+
+var ErrUnknownFormat = errors.New("unknown statement format")
+
+// Header describes a statement and the balance it opens with
+type Header struct {
+	UserID         string
+	From           time.Time
+	To             time.Time // Exclusive
+	OpeningBalance float32
+	GeneratedAt    time.Time
+}
+
+// Line is a transaction on a statement
+type Line struct {
+	ID          string
+	Date        time.Time
+	Type        string // "credit" or "debit"
+	Description string
+	Reference   string
+	Amount      float32 // Always positive; Type gives the direction
+	Balance     float32 // The balance once the transaction was applied
+}
+
+// Footer closes a statement
+type Footer struct {
+	TotalCredits   float32
+	TotalDebits    float32
+	ClosingBalance float32
+}
+
+// Writer renders a statement as it is read, so a statement of any length can be streamed without
+// holding it in memory. Call Begin once, Line for each transaction in order, then End.
+type Writer interface {
+	ContentType() string
+	Extension() string
+	Begin(header Header) error
+	Line(line Line) error
+	End(footer Footer) error
+}
+
+// NewWriter returns a Writer that renders a statement in format to w
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatJSON:
+		return &jsonWriter{w: w}, nil
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatPDF:
+		return newPDFWriter(w), nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}